@@ -1,11 +1,14 @@
 package consumer
 
 import (
+	"context"
 	"encoding/json"
 	"log"
 
 	"log-beacon/cmd/archiver/internal/writer"
+	"log-beacon/internal/config"
 	"log-beacon/internal/model"
+	"log-beacon/internal/queue"
 
 	"github.com/nats-io/nats.go"
 )
@@ -15,12 +18,14 @@ type Consumer struct {
 	nc      *nats.Conn
 	js      nats.JetStreamContext
 	writer  *writer.MinioWriter
+	stream  string
+	subject string
 	Sub     *nats.Subscription
 }
 
 // NewConsumer creates a new NATS consumer for the archiver.
-func NewConsumer(natsURL string, writer *writer.MinioWriter) (*Consumer, error) {
-	nc, err := nats.Connect(natsURL)
+func NewConsumer(cfg config.NATS, writer *writer.MinioWriter) (*Consumer, error) {
+	nc, err := nats.Connect(cfg.URL)
 	if err != nil {
 		return nil, err
 	}
@@ -29,24 +34,31 @@ func NewConsumer(natsURL string, writer *writer.MinioWriter) (*Consumer, error)
 		nc.Close()
 		return nil, err
 	}
-	return &Consumer{nc: nc, js: js, writer: writer}, nil
+	return &Consumer{nc: nc, js: js, writer: writer, stream: cfg.Stream, subject: cfg.Subject}, nil
 }
 
 // Start begins listening for NATS messages.
 func (c *Consumer) Start() error {
 	var err error
-	c.Sub, err = c.js.QueueSubscribe("log.events", "archiver-processor", c.handleMessage, nats.Durable("archiver-processor"), nats.ManualAck())
+	c.Sub, err = queue.SubscribeDurable(c.js, c.stream, c.subject, "archiver-processor", c.handleMessage)
 	return err
 }
 
-// Close gracefully closes the NATS connection.
-func (c *Consumer) Close() {
-	if c.Sub != nil {
-		c.Sub.Unsubscribe()
-	}
-	if c.nc != nil {
-		c.nc.Close()
+// Close stops taking new messages and waits for the ones already delivered
+// to be handled before closing the NATS connection, so that no write is cut
+// off. Messages not yet delivered stay with the durable consumer for the
+// next run. If ctx expires first, the connection is closed at once.
+func (c *Consumer) Close(ctx context.Context) error {
+	return queue.Drain(ctx, c.nc)
+}
+
+// Check verifies that the connection, the stream and the durable consumer
+// are usable.
+func (c *Consumer) Check(ctx context.Context) error {
+	if err := queue.CheckStream(ctx, c.nc, c.js, c.stream); err != nil {
+		return err
 	}
+	return queue.CheckConsumer(ctx, c.Sub)
 }
 
 // handleMessage processes a single NATS message.
@@ -54,17 +66,20 @@ func (c *Consumer) handleMessage(msg *nats.Msg) {
 	var logEntry model.Log
 	if err := json.Unmarshal(msg.Data, &logEntry); err != nil {
 		log.Printf("Error unmarshalling log: %v", err)
+		queue.ObserveMessage(msg, queue.ResultInvalid)
 		msg.Ack()
 		return
 	}
 
 	if err := c.writer.WriteLog(&logEntry); err != nil {
 		log.Printf("Error writing log to MinIO: %v", err)
+		queue.ObserveMessage(msg, queue.ResultError)
 		// We will Ack the message to prevent infinite retries for now.
 		// A more robust solution might involve a dead-letter queue.
 		msg.Ack()
 		return
 	}
 
+	queue.ObserveMessage(msg, queue.ResultOK)
 	msg.Ack()
 }
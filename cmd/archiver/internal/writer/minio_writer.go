@@ -9,22 +9,43 @@ import (
 	"log"
 	"time"
 
+	"log-beacon/internal/config"
 	"log-beacon/internal/model"
 	"log-beacon/internal/storage"
 
 	"github.com/google/uuid"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
 )
 
-const logBucketName = "logs"
+var (
+	archivedObjects = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "beacon_archive_objects_total",
+		Help: "Objects written to MinIO.",
+	})
+	archivedBytes = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "beacon_archive_bytes_total",
+		Help: "Compressed bytes written to MinIO.",
+	})
+	archiveErrors = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "beacon_archive_errors_total",
+		Help: "Failed writes to MinIO.",
+	})
+	archiveDuration = promauto.NewHistogram(prometheus.HistogramOpts{
+		Name: "beacon_archive_write_duration_seconds",
+		Help: "Latency of object writes to MinIO.",
+	})
+)
 
 // MinioWriter handles writing log data to MinIO.
 type MinioWriter struct {
-	store *storage.MinioStorage
+	store  *storage.MinioStorage
+	bucket string
 }
 
-// NewMinioWriter creates a new writer for MinIO.
-func NewMinioWriter(endpoint, accessKey, secretKey string) (*MinioWriter, error) {
-	store, err := storage.NewMinioStorage(endpoint, accessKey, secretKey, false)
+// NewMinioWriter creates a new writer for the bucket of cfg.
+func NewMinioWriter(cfg config.MinIO) (*MinioWriter, error) {
+	store, err := storage.NewMinioStorage(cfg.Endpoint, cfg.AccessKeyID, cfg.SecretAccessKey, cfg.UseSSL)
 	if err != nil {
 		return nil, err
 	}
@@ -32,7 +53,7 @@ func NewMinioWriter(endpoint, accessKey, secretKey string) (*MinioWriter, error)
 	// Ensure the log bucket exists, retrying for up to 30 seconds.
 	var bucketErr error
 	for i := 0; i < 10; i++ {
-		bucketErr = store.EnsureBucket(context.Background(), logBucketName)
+		bucketErr = store.EnsureBucket(context.Background(), cfg.Bucket)
 		if bucketErr == nil {
 			log.Println("Successfully connected to MinIO and ensured bucket exists.")
 			break
@@ -44,7 +65,12 @@ func NewMinioWriter(endpoint, accessKey, secretKey string) (*MinioWriter, error)
 		return nil, fmt.Errorf("failed to ensure MinIO bucket after multiple retries: %w", bucketErr)
 	}
 
-	return &MinioWriter{store: store}, nil
+	return &MinioWriter{store: store, bucket: cfg.Bucket}, nil
+}
+
+// Check verifies that the log bucket is reachable.
+func (w *MinioWriter) Check(ctx context.Context) error {
+	return w.store.CheckBucket(ctx, w.bucket)
 }
 
 // WriteLog compresses and writes a single log entry to MinIO.
@@ -56,11 +82,16 @@ func (w *MinioWriter) WriteLog(logEntry *model.Log) error {
 
 	objectName := fmt.Sprintf("%s/%s.gz", logEntry.Timestamp.Format("2006/01/02"), uuid.New().String())
 
-	if err := w.store.Write(context.Background(), logBucketName, objectName, compressedData, "application/gzip"); err != nil {
+	start := time.Now()
+	if err := w.store.Write(context.Background(), w.bucket, objectName, compressedData, "application/gzip"); err != nil {
+		archiveErrors.Inc()
 		return fmt.Errorf("error writing to MinIO: %w", err)
 	}
+	archiveDuration.Observe(time.Since(start).Seconds())
+	archivedObjects.Inc()
+	archivedBytes.Add(float64(len(compressedData)))
 
-	log.Printf("Successfully archived log %s in bucket %s", objectName, logBucketName)
+	log.Printf("Successfully archived log %s in bucket %s", objectName, w.bucket)
 	return nil
 }
 
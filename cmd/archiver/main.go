@@ -1,42 +1,63 @@
 package main
 
 import (
+	"context"
 	"log"
+	"net/http"
 	"os"
 	"os/signal"
 	"syscall"
+	"time"
 
 	"log-beacon/cmd/archiver/internal/consumer"
 	"log-beacon/cmd/archiver/internal/writer"
+	"log-beacon/internal/config"
+	"log-beacon/internal/health"
+	"log-beacon/internal/queue"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
 )
 
 func main() {
 	// --- Initialization ---
-	minioEndpoint := os.Getenv("MINIO_ENDPOINT")
-	minioAccessKey := os.Getenv("MINIO_ACCESS_KEY_ID")
-	minioSecretKey := os.Getenv("MINIO_SECRET_ACCESS_KEY")
+	cfg := config.MustLoad(config.ServiceArchiver)
 
-	minioWriter, err := writer.NewMinioWriter(minioEndpoint, minioAccessKey, minioSecretKey)
+	minioWriter, err := writer.NewMinioWriter(cfg.Archiver.MinIO)
 	if err != nil {
 		log.Fatalf("Failed to create MinIO writer: %v", err)
 	}
 
-	natsURL := os.Getenv("NATS_URL")
-	if natsURL == "" {
-		log.Fatal("NATS_URL environment variable not set.")
-	}
-
-	consumer, err := consumer.NewConsumer(natsURL, minioWriter)
+	consumer, err := consumer.NewConsumer(cfg.NATS, minioWriter)
 	if err != nil {
 		log.Fatalf("Failed to create NATS consumer: %v", err)
 	}
-	defer consumer.Close()
 
 	// --- Start Services ---
 	if err := consumer.Start(); err != nil {
 		log.Fatalf("Failed to start NATS consumer: %v", err)
 	}
 
+	// Serve /metrics and the health probes; the archiver has no other HTTP
+	// endpoints.
+	queue.RegisterConsumerLag(prometheus.DefaultRegisterer, consumer.Sub)
+	checker := health.NewChecker(0)
+	checker.Add("nats", consumer.Check)
+	checker.Add("minio", minioWriter.Check)
+	metricsAddr := cfg.Archiver.MetricsAddr
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", promhttp.Handler())
+	mux.HandleFunc("/livez", health.Live)
+	mux.Handle("/readyz", checker)
+	metricsSrv := &http.Server{Addr: metricsAddr, Handler: mux}
+	go func() {
+		log.Printf("Metrics server listening on %s", metricsAddr)
+		if err := metricsSrv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			log.Fatalf("Metrics server error: %v", err)
+		}
+	}()
+	defer metricsSrv.Close()
+
 	log.Println("Archiver service is running.")
 
 	// --- Graceful Shutdown ---
@@ -45,6 +66,12 @@ func main() {
 	<-signalChan
 
 	log.Println("Shutting down archiver service...")
-	// Consumer is closed by its deferred call
+	// Finish archiving the messages already delivered. Writes to MinIO are
+	// not buffered, so nothing is left to flush once the handlers return.
+	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(cfg.Archiver.ShutdownTimeout))
+	defer cancel()
+	if err := consumer.Close(ctx); err != nil {
+		log.Printf("Failed to drain NATS consumer: %v", err)
+	}
 	log.Println("Archiver service shut down gracefully.")
 }
\ No newline at end of file
@@ -0,0 +1,136 @@
+package agent
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"log-beacon/cmd/beacon-agent/internal/multiline"
+	"log-beacon/cmd/beacon-agent/internal/shipper"
+	"log-beacon/cmd/beacon-agent/internal/tailer"
+	"log-beacon/internal/model"
+)
+
+// FileLabel is the label holding the path a log line was read from.
+const FileLabel = "file"
+
+// Config configures the agent loop.
+type Config struct {
+	// Labels are attached to every entry.
+	Labels        map[string]string
+	BatchSize     int
+	PollInterval  time.Duration
+	FlushInterval time.Duration
+}
+
+// Agent tails files, assembles multi-line entries and ships them in batches.
+// Read positions are only committed once a batch has been delivered or
+// spooled, so entries are shipped at least once across restarts.
+type Agent struct {
+	cfg     Config
+	tailer  *tailer.Tailer
+	lines   *multiline.Aggregator
+	shipper *shipper.Shipper
+
+	batch     []model.Log
+	offsets   map[string]multiline.Entry
+	lastFlush time.Time
+}
+
+// New creates an agent from its parts.
+func New(cfg Config, t *tailer.Tailer, lines *multiline.Aggregator, s *shipper.Shipper) *Agent {
+	return &Agent{
+		cfg:       cfg,
+		tailer:    t,
+		lines:     lines,
+		shipper:   s,
+		offsets:   make(map[string]multiline.Entry),
+		lastFlush: time.Now(),
+	}
+}
+
+// Run polls the files until ctx is cancelled, then ships what is pending.
+func (a *Agent) Run(ctx context.Context) error {
+	ticker := time.NewTicker(a.cfg.PollInterval)
+	defer ticker.Stop()
+
+	a.shipper.Drain(ctx)
+	for {
+		select {
+		case <-ctx.Done():
+			// Ship whatever is pending with a fresh context so that the final
+			// batch is delivered or spooled before exiting.
+			shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+			defer cancel()
+			a.Step(shutdownCtx, time.Now(), true)
+			return a.tailer.Close()
+		case now := <-ticker.C:
+			a.Step(ctx, now, false)
+		}
+	}
+}
+
+// Step runs one poll cycle: read new lines, assemble entries and ship a batch
+// when it is full or the flush interval elapsed. final flushes everything.
+func (a *Agent) Step(ctx context.Context, now time.Time, final bool) {
+	lines, err := a.tailer.Poll()
+	if err != nil {
+		log.Printf("Error polling files: %v", err)
+	}
+	for _, line := range lines {
+		for _, entry := range a.lines.Add(line, now) {
+			a.add(entry, now)
+		}
+	}
+	for _, entry := range a.lines.Flush(now, final) {
+		a.add(entry, now)
+	}
+
+	if len(a.batch) == 0 {
+		if now.Sub(a.lastFlush) >= a.cfg.FlushInterval {
+			a.shipper.Drain(ctx)
+			a.lastFlush = now
+		}
+		return
+	}
+	if final || len(a.batch) >= a.cfg.BatchSize || now.Sub(a.lastFlush) >= a.cfg.FlushInterval {
+		a.flush(ctx, now)
+	}
+}
+
+func (a *Agent) add(entry multiline.Entry, now time.Time) {
+	labels := make(map[string]string, len(a.cfg.Labels)+1)
+	for k, v := range a.cfg.Labels {
+		labels[k] = v
+	}
+	labels[FileLabel] = entry.Path
+
+	a.batch = append(a.batch, model.Log{Timestamp: now.UTC(), Message: entry.Text, Labels: labels})
+	a.offsets[entry.FileID] = entry
+}
+
+// flush ships the current batch and commits the read positions it covers.
+// A batch that could not even be spooled is kept and retried on the next step.
+func (a *Agent) flush(ctx context.Context, now time.Time) {
+	a.lastFlush = now
+	for len(a.batch) > 0 {
+		n := len(a.batch)
+		if n > a.cfg.BatchSize {
+			n = a.cfg.BatchSize
+		}
+		if err := a.shipper.Ship(ctx, a.batch[:n]); err != nil {
+			log.Printf("Error shipping batch: %v", err)
+			return
+		}
+		a.batch = a.batch[n:]
+	}
+	a.batch = nil
+
+	for id, entry := range a.offsets {
+		a.tailer.Commit(id, entry.Path, entry.Offset)
+	}
+	a.offsets = make(map[string]multiline.Entry)
+	if err := a.tailer.Save(); err != nil {
+		log.Printf("Error saving read positions: %v", err)
+	}
+}
@@ -0,0 +1,80 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sync"
+	"testing"
+	"time"
+
+	"log-beacon/cmd/beacon-agent/internal/multiline"
+	"log-beacon/cmd/beacon-agent/internal/shipper"
+	"log-beacon/cmd/beacon-agent/internal/tailer"
+	"log-beacon/internal/model"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAgent_ShipsAndCommits(t *testing.T) {
+	var mu sync.Mutex
+	var received []model.Log
+	api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var batch []model.Log
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&batch))
+		mu.Lock()
+		received = append(received, batch...)
+		mu.Unlock()
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer api.Close()
+
+	dir := t.TempDir()
+	logPath := filepath.Join(dir, "app.log")
+	require.NoError(t, os.WriteFile(logPath, []byte("ERROR boom\n\tat main.go:10\nINFO ok\n"), 0o644))
+	statePath := filepath.Join(dir, "positions.json")
+
+	newAgent := func() *Agent {
+		tl, err := tailer.New([]string{logPath}, statePath)
+		require.NoError(t, err)
+		spool, err := shipper.NewSpool(filepath.Join(dir, "buffer"), 1<<20)
+		require.NoError(t, err)
+		s, err := shipper.New(shipper.Config{URL: api.URL, Compression: shipper.CompressionNone}, spool)
+		require.NoError(t, err)
+		return New(Config{
+			Labels:        map[string]string{"env": "test"},
+			BatchSize:     100,
+			PollInterval:  10 * time.Millisecond,
+			FlushInterval: time.Second,
+		}, tl, multiline.New(regexp.MustCompile(`^\t`), 100, time.Second), s)
+	}
+
+	a := newAgent()
+	now := time.Now()
+	a.Step(context.Background(), now, false)
+	assert.Empty(t, received, "entries wait for the flush interval")
+
+	a.Step(context.Background(), now.Add(time.Second), false)
+	require.Len(t, received, 2)
+	assert.Equal(t, "ERROR boom\n\tat main.go:10", received[0].Message)
+	assert.Equal(t, "INFO ok", received[1].Message)
+	assert.Equal(t, map[string]string{"env": "test", FileLabel: logPath}, received[0].Labels)
+	require.NoError(t, a.tailer.Close())
+
+	// A restarted agent resumes after the committed entries.
+	f, err := os.OpenFile(logPath, os.O_APPEND|os.O_WRONLY, 0o644)
+	require.NoError(t, err)
+	_, err = f.WriteString("WARN later\n")
+	require.NoError(t, err)
+	require.NoError(t, f.Close())
+
+	restarted := newAgent()
+	restarted.Step(context.Background(), now, true)
+	require.Len(t, received, 3)
+	assert.Equal(t, "WARN later", received[2].Message)
+}
@@ -0,0 +1,90 @@
+package multiline
+
+import (
+	"regexp"
+	"strings"
+	"time"
+
+	"log-beacon/cmd/beacon-agent/internal/tailer"
+)
+
+// Entry is a complete log record assembled from one or more lines of a file.
+type Entry struct {
+	Path   string
+	FileID string
+	Text   string
+	// Offset is the byte offset just past the last line of the entry.
+	Offset int64
+}
+
+type pendingEntry struct {
+	entry    Entry
+	lines    []string
+	lastLine time.Time
+}
+
+// Aggregator joins continuation lines, such as the frames of a stack trace,
+// onto the line that precedes them. A line matching the continuation pattern
+// belongs to the previous entry; any other line starts a new entry.
+type Aggregator struct {
+	continuation *regexp.Regexp
+	maxLines     int
+	timeout      time.Duration
+	pending      map[string]*pendingEntry
+}
+
+// New creates an aggregator. With a nil pattern every line is its own entry.
+// Entries are cut at maxLines lines and flushed once no line has been added
+// for timeout.
+func New(continuation *regexp.Regexp, maxLines int, timeout time.Duration) *Aggregator {
+	return &Aggregator{
+		continuation: continuation,
+		maxLines:     maxLines,
+		timeout:      timeout,
+		pending:      make(map[string]*pendingEntry),
+	}
+}
+
+// Add feeds a line and returns the entries it completes.
+func (a *Aggregator) Add(line tailer.Line, now time.Time) []Entry {
+	if a.continuation == nil {
+		return []Entry{{Path: line.Path, FileID: line.FileID, Text: line.Text, Offset: line.Offset}}
+	}
+
+	var done []Entry
+	p, ok := a.pending[line.FileID]
+	if ok && a.continuation.MatchString(line.Text) && len(p.lines) < a.maxLines {
+		p.lines = append(p.lines, line.Text)
+		p.entry.Offset = line.Offset
+		p.lastLine = now
+		return nil
+	}
+	if ok {
+		done = append(done, p.complete())
+	}
+	a.pending[line.FileID] = &pendingEntry{
+		entry:    Entry{Path: line.Path, FileID: line.FileID, Offset: line.Offset},
+		lines:    []string{line.Text},
+		lastLine: now,
+	}
+	return done
+}
+
+// Flush returns the pending entries that have been idle for the timeout, or
+// every pending entry when force is set.
+func (a *Aggregator) Flush(now time.Time, force bool) []Entry {
+	var done []Entry
+	for id, p := range a.pending {
+		if force || now.Sub(p.lastLine) >= a.timeout {
+			done = append(done, p.complete())
+			delete(a.pending, id)
+		}
+	}
+	return done
+}
+
+func (p *pendingEntry) complete() Entry {
+	entry := p.entry
+	entry.Text = strings.Join(p.lines, "\n")
+	return entry
+}
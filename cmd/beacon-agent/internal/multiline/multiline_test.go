@@ -0,0 +1,69 @@
+package multiline
+
+import (
+	"regexp"
+	"testing"
+	"time"
+
+	"log-beacon/cmd/beacon-agent/internal/tailer"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func line(text string, offset int64) tailer.Line {
+	return tailer.Line{Path: "/var/log/app.log", FileID: "1:2", Text: text, Offset: offset}
+}
+
+func TestAggregator_JoinsStackTraces(t *testing.T) {
+	a := New(regexp.MustCompile(`^[\t ]|^Caused by:`), 100, time.Second)
+	now := time.Now()
+
+	assert.Empty(t, a.Add(line("Exception in thread main java.lang.IllegalStateException: boom", 10), now))
+	assert.Empty(t, a.Add(line("\tat com.example.App.run(App.java:42)", 20), now))
+	assert.Empty(t, a.Add(line("Caused by: java.io.IOException: disk full", 30), now))
+	assert.Empty(t, a.Add(line("\tat com.example.Disk.write(Disk.java:7)", 40), now))
+
+	done := a.Add(line("INFO next request", 50), now)
+	require.Len(t, done, 1)
+	assert.Equal(t, Entry{
+		Path:   "/var/log/app.log",
+		FileID: "1:2",
+		Text: "Exception in thread main java.lang.IllegalStateException: boom\n" +
+			"\tat com.example.App.run(App.java:42)\n" +
+			"Caused by: java.io.IOException: disk full\n" +
+			"\tat com.example.Disk.write(Disk.java:7)",
+		Offset: 40,
+	}, done[0])
+
+	// The last entry is flushed once it has been idle for the timeout.
+	assert.Empty(t, a.Flush(now.Add(500*time.Millisecond), false))
+	done = a.Flush(now.Add(time.Second), false)
+	require.Len(t, done, 1)
+	assert.Equal(t, "INFO next request", done[0].Text)
+	assert.Equal(t, int64(50), done[0].Offset)
+}
+
+func TestAggregator_MaxLines(t *testing.T) {
+	a := New(regexp.MustCompile(`^ `), 2, time.Second)
+	now := time.Now()
+
+	assert.Empty(t, a.Add(line("start", 1), now))
+	assert.Empty(t, a.Add(line(" one", 2), now))
+	done := a.Add(line(" two", 3), now)
+	require.Len(t, done, 1)
+	assert.Equal(t, "start\n one", done[0].Text)
+
+	done = a.Flush(now, true)
+	require.Len(t, done, 1)
+	assert.Equal(t, " two", done[0].Text)
+}
+
+func TestAggregator_Disabled(t *testing.T) {
+	a := New(nil, 100, time.Second)
+
+	done := a.Add(line("  indented but standalone", 5), time.Now())
+	require.Len(t, done, 1)
+	assert.Equal(t, "  indented but standalone", done[0].Text)
+	assert.Empty(t, a.Flush(time.Now(), true))
+}
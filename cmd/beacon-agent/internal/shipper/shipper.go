@@ -0,0 +1,116 @@
+package shipper
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"log-beacon/internal/model"
+	"log-beacon/pkg/client"
+)
+
+// Supported values for Config.Compression.
+const (
+	CompressionNone = client.CompressionNone
+	CompressionGzip = client.CompressionGzip
+	CompressionZstd = client.CompressionZstd
+)
+
+// Config configures a Shipper.
+type Config struct {
+	// URL is the ingest endpoint, e.g. http://localhost:8080/api/v1/ingest.
+	URL         string
+	Compression string
+	// Retries is the number of extra attempts before a batch is spooled.
+	Retries int
+	// Backoff is the delay before the first retry; it doubles on every retry.
+	Backoff time.Duration
+	Timeout time.Duration
+}
+
+// Shipper sends batches of log entries to the ingest API with the transport
+// of the client SDK. Batches that cannot be delivered are buffered in a Spool
+// and replayed, in order, once the API is reachable again.
+type Shipper struct {
+	cfg    Config
+	sender *client.Sender
+	spool  *Spool
+}
+
+// New creates a shipper buffering undeliverable batches in spool.
+func New(cfg Config, spool *Spool) (*Shipper, error) {
+	switch cfg.Compression {
+	case "", CompressionNone, CompressionGzip, CompressionZstd:
+	default:
+		return nil, fmt.Errorf("unsupported compression %q", cfg.Compression)
+	}
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = 10 * time.Second
+	}
+	sender := &client.Sender{
+		URL:        cfg.URL,
+		HTTPClient: &http.Client{Timeout: cfg.Timeout},
+		Retries:    cfg.Retries,
+		Backoff:    cfg.Backoff,
+	}
+	return &Shipper{cfg: cfg, sender: sender, spool: spool}, nil
+}
+
+// Ship delivers a batch, or spools it when the API is unavailable. It returns
+// nil once the batch is delivered or safely on disk, so the caller can commit
+// the read positions of the entries.
+func (s *Shipper) Ship(ctx context.Context, batch []model.Log) error {
+	if len(batch) == 0 {
+		return nil
+	}
+	payload, encoding, err := client.Encode(batch, s.cfg.Compression)
+	if err != nil {
+		return err
+	}
+
+	// Keep batches in order: while older batches are spooled, new ones queue behind them.
+	if !s.spool.Empty() {
+		if err := s.spool.Push(payload, encoding); err != nil {
+			return err
+		}
+		s.Drain(ctx)
+		return nil
+	}
+
+	err = s.sender.SendWithRetry(ctx, payload, encoding)
+	switch {
+	case err == nil:
+		return nil
+	case errors.Is(err, client.ErrRefused):
+		log.Printf("Discarding batch of %d entries: %v", len(batch), err)
+		return nil
+	default:
+		log.Printf("Ingest API unavailable, spooling batch of %d entries: %v", len(batch), err)
+		return s.spool.Push(payload, encoding)
+	}
+}
+
+// Drain delivers spooled batches, oldest first, until the spool is empty or
+// a delivery fails.
+func (s *Shipper) Drain(ctx context.Context) {
+	for ctx.Err() == nil {
+		name, payload, encoding, ok, err := s.spool.Peek()
+		if err != nil {
+			log.Printf("Error reading spool: %v", err)
+			return
+		}
+		if !ok {
+			return
+		}
+		err = s.sender.Send(ctx, payload, encoding)
+		if errors.Is(err, client.ErrRefused) {
+			log.Printf("Discarding spooled batch %s: %v", name, err)
+		} else if err != nil {
+			return
+		}
+		s.spool.Remove(name)
+	}
+}
@@ -0,0 +1,174 @@
+package shipper
+
+import (
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"log-beacon/internal/model"
+
+	"github.com/klauspost/compress/zstd"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeAPI records the batches it receives and answers with status.
+type fakeAPI struct {
+	mu       sync.Mutex
+	status   int
+	batches  [][]model.Log
+	encoding []string
+}
+
+func (f *fakeAPI) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.status != http.StatusAccepted {
+		w.WriteHeader(f.status)
+		return
+	}
+
+	var body io.Reader = r.Body
+	switch r.Header.Get("Content-Encoding") {
+	case "gzip":
+		gz, err := gzip.NewReader(r.Body)
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		body = gz
+	case "zstd":
+		zr, err := zstd.NewReader(r.Body)
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		defer zr.Close()
+		body = zr
+	}
+	var batch []model.Log
+	if err := json.NewDecoder(body).Decode(&batch); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	f.batches = append(f.batches, batch)
+	f.encoding = append(f.encoding, r.Header.Get("Content-Encoding"))
+	w.WriteHeader(http.StatusAccepted)
+}
+
+func (f *fakeAPI) setStatus(status int) {
+	f.mu.Lock()
+	f.status = status
+	f.mu.Unlock()
+}
+
+func messages(batches [][]model.Log) []string {
+	var out []string
+	for _, batch := range batches {
+		for _, l := range batch {
+			out = append(out, l.Message)
+		}
+	}
+	return out
+}
+
+func newTestShipper(t *testing.T, url, compression string) (*Shipper, *Spool) {
+	t.Helper()
+	spool, err := NewSpool(t.TempDir(), 1<<20)
+	require.NoError(t, err)
+	s, err := New(Config{URL: url, Compression: compression, Retries: 1, Backoff: time.Millisecond}, spool)
+	require.NoError(t, err)
+	return s, spool
+}
+
+func batchOf(msgs ...string) []model.Log {
+	var batch []model.Log
+	for _, m := range msgs {
+		batch = append(batch, model.Log{Message: m, Labels: map[string]string{"file": "/var/log/app.log"}})
+	}
+	return batch
+}
+
+func TestShipper_Delivers(t *testing.T) {
+	for _, compression := range []string{CompressionGzip, CompressionZstd, CompressionNone} {
+		t.Run(compression, func(t *testing.T) {
+			api := &fakeAPI{status: http.StatusAccepted}
+			server := httptest.NewServer(api)
+			defer server.Close()
+
+			s, spool := newTestShipper(t, server.URL, compression)
+			require.NoError(t, s.Ship(context.Background(), batchOf("one", "two")))
+
+			assert.Equal(t, []string{"one", "two"}, messages(api.batches))
+			assert.True(t, spool.Empty())
+		})
+	}
+}
+
+func TestShipper_SpoolsWhileUnavailable(t *testing.T) {
+	api := &fakeAPI{status: http.StatusServiceUnavailable}
+	server := httptest.NewServer(api)
+	defer server.Close()
+
+	s, spool := newTestShipper(t, server.URL, CompressionGzip)
+	ctx := context.Background()
+
+	require.NoError(t, s.Ship(ctx, batchOf("one")))
+	require.NoError(t, s.Ship(ctx, batchOf("two")))
+	assert.False(t, spool.Empty())
+	assert.Empty(t, api.batches)
+
+	// Once the API recovers, spooled batches are delivered first and in order.
+	api.setStatus(http.StatusAccepted)
+	require.NoError(t, s.Ship(ctx, batchOf("three")))
+
+	assert.Equal(t, []string{"one", "two", "three"}, messages(api.batches))
+	assert.Equal(t, []string{"gzip", "gzip", "gzip"}, api.encoding)
+	assert.True(t, spool.Empty())
+}
+
+func TestShipper_DiscardsRefusedBatches(t *testing.T) {
+	api := &fakeAPI{status: http.StatusBadRequest}
+	server := httptest.NewServer(api)
+	defer server.Close()
+
+	s, spool := newTestShipper(t, server.URL, CompressionNone)
+	require.NoError(t, s.Ship(context.Background(), batchOf("bad")))
+	assert.True(t, spool.Empty())
+}
+
+func TestSpool_DiscardsOldestWhenFull(t *testing.T) {
+	dir := t.TempDir()
+	spool, err := NewSpool(dir, 10)
+	require.NoError(t, err)
+
+	require.NoError(t, spool.Push([]byte("aaaaaa"), "gzip"))
+	require.NoError(t, spool.Push([]byte("bbbbbb"), ""))
+
+	name, payload, encoding, ok, err := spool.Peek()
+	require.NoError(t, err)
+	require.True(t, ok)
+	assert.Equal(t, []byte("bbbbbb"), payload)
+	assert.Equal(t, "", encoding)
+
+	// A reopened spool sees the remaining batch.
+	reopened, err := NewSpool(dir, 10)
+	require.NoError(t, err)
+	assert.False(t, reopened.Empty())
+
+	spool.Remove(name)
+	assert.True(t, spool.Empty())
+}
+
+func TestNew_UnsupportedCompression(t *testing.T) {
+	spool, err := NewSpool(t.TempDir(), 1<<20)
+	require.NoError(t, err)
+	_, err = New(Config{URL: "http://localhost", Compression: "brotli"}, spool)
+	assert.Error(t, err)
+}
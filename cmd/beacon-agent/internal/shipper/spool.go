@@ -0,0 +1,143 @@
+package shipper
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Spool is a disk buffer of encoded batches waiting to be delivered. Batches
+// are kept as one file each and replayed oldest first. When the spool grows
+// beyond its size limit the oldest batches are discarded.
+type Spool struct {
+	dir      string
+	maxBytes int64
+
+	mu   sync.Mutex
+	seq  int64
+	size int64
+}
+
+// NewSpool opens (creating if needed) a spool in dir holding at most maxBytes.
+func NewSpool(dir string, maxBytes int64) (*Spool, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create spool directory: %w", err)
+	}
+	s := &Spool{dir: dir, maxBytes: maxBytes, seq: time.Now().UnixNano()}
+
+	names, err := s.list()
+	if err != nil {
+		return nil, err
+	}
+	for _, name := range names {
+		if info, err := os.Stat(filepath.Join(dir, name)); err == nil {
+			s.size += info.Size()
+		}
+	}
+	return s, nil
+}
+
+// Push stores an encoded batch. encoding is the Content-Encoding of payload.
+func (s *Spool) Push(payload []byte, encoding string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.seq++
+	name := fmt.Sprintf("%020d.%s.batch", s.seq, encodingName(encoding))
+	tmp := filepath.Join(s.dir, name+".tmp")
+	if err := os.WriteFile(tmp, payload, 0o644); err != nil {
+		return fmt.Errorf("failed to write spooled batch: %w", err)
+	}
+	if err := os.Rename(tmp, filepath.Join(s.dir, name)); err != nil {
+		return fmt.Errorf("failed to write spooled batch: %w", err)
+	}
+	s.size += int64(len(payload))
+
+	for s.size > s.maxBytes {
+		names, err := s.list()
+		if err != nil || len(names) <= 1 {
+			break
+		}
+		log.Printf("Spool exceeds %d bytes, discarding oldest batch %s", s.maxBytes, names[0])
+		s.removeLocked(names[0])
+	}
+	return nil
+}
+
+// Peek returns the oldest spooled batch.
+func (s *Spool) Peek() (name string, payload []byte, encoding string, ok bool, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	names, err := s.list()
+	if err != nil || len(names) == 0 {
+		return "", nil, "", false, err
+	}
+	name = names[0]
+	payload, err = os.ReadFile(filepath.Join(s.dir, name))
+	if err != nil {
+		return "", nil, "", false, fmt.Errorf("failed to read spooled batch: %w", err)
+	}
+	parts := strings.Split(name, ".")
+	return name, payload, encodingFromName(parts[1]), true, nil
+}
+
+// Remove deletes a delivered batch.
+func (s *Spool) Remove(name string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.removeLocked(name)
+}
+
+// Empty reports whether no batches are waiting.
+func (s *Spool) Empty() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	names, err := s.list()
+	return err == nil && len(names) == 0
+}
+
+func (s *Spool) removeLocked(name string) {
+	path := filepath.Join(s.dir, name)
+	if info, err := os.Stat(path); err == nil {
+		s.size -= info.Size()
+	}
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		log.Printf("Error removing spooled batch %s: %v", name, err)
+	}
+}
+
+// list returns the spooled batch files, oldest first.
+func (s *Spool) list() ([]string, error) {
+	entries, err := os.ReadDir(s.dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list spool: %w", err)
+	}
+	var names []string
+	for _, entry := range entries {
+		if strings.HasSuffix(entry.Name(), ".batch") && strings.Count(entry.Name(), ".") == 2 {
+			names = append(names, entry.Name())
+		}
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+func encodingName(encoding string) string {
+	if encoding == "" {
+		return "identity"
+	}
+	return encoding
+}
+
+func encodingFromName(name string) string {
+	if name == "identity" {
+		return ""
+	}
+	return name
+}
@@ -0,0 +1,11 @@
+//go:build !unix
+
+package tailer
+
+import "os"
+
+// fileID falls back to the path on platforms without inodes; renamed files
+// are then treated as new files.
+func fileID(path string, info os.FileInfo) string {
+	return path
+}
@@ -0,0 +1,270 @@
+package tailer
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+const (
+	// maxReadPerPoll bounds how much of a single file is read per poll so
+	// that one busy file cannot starve the others.
+	maxReadPerPoll = 1 << 20
+	// MaxLineBytes bounds a single line; longer lines are split.
+	MaxLineBytes = 256 * 1024
+)
+
+// Line is a single line read from a file.
+type Line struct {
+	// Path is the path the file was found under when the line was read.
+	Path string
+	// FileID identifies the file across renames.
+	FileID string
+	Text   string
+	// Offset is the byte offset just past this line. Committing it resumes
+	// reading after this line on restart.
+	Offset int64
+}
+
+// Position is the persisted read position of a file.
+type Position struct {
+	Path   string `json:"path"`
+	Offset int64  `json:"offset"`
+}
+
+// tailedFile is an open file being followed.
+type tailedFile struct {
+	id      string
+	path    string
+	file    *os.File
+	offset  int64 // bytes consumed, including partial
+	partial []byte
+}
+
+// Tailer follows every file matching a set of glob patterns. Files are
+// tracked by identity rather than path, so a renamed (rotated) file is read
+// to the end before it is dropped and a truncated file is read from the start.
+type Tailer struct {
+	patterns  []string
+	statePath string
+
+	files     map[string]*tailedFile
+	positions map[string]Position
+}
+
+// New creates a tailer for the given glob patterns. Read positions are loaded
+// from and saved to statePath.
+func New(patterns []string, statePath string) (*Tailer, error) {
+	for _, pattern := range patterns {
+		if _, err := filepath.Match(pattern, ""); err != nil {
+			return nil, fmt.Errorf("invalid path pattern %q: %w", pattern, err)
+		}
+	}
+
+	t := &Tailer{
+		patterns:  patterns,
+		statePath: statePath,
+		files:     make(map[string]*tailedFile),
+		positions: make(map[string]Position),
+	}
+
+	data, err := os.ReadFile(statePath)
+	switch {
+	case errors.Is(err, os.ErrNotExist):
+	case err != nil:
+		return nil, fmt.Errorf("failed to read tailer state: %w", err)
+	default:
+		if err := json.Unmarshal(data, &t.positions); err != nil {
+			return nil, fmt.Errorf("failed to parse tailer state %s: %w", statePath, err)
+		}
+	}
+	return t, nil
+}
+
+// Poll discovers new files and returns the complete lines written since the last poll.
+func (t *Tailer) Poll() ([]Line, error) {
+	seen := make(map[string]bool)
+	var paths []string
+	for _, pattern := range t.patterns {
+		matches, err := filepath.Glob(pattern)
+		if err != nil {
+			return nil, err
+		}
+		paths = append(paths, matches...)
+	}
+	sort.Strings(paths)
+
+	var lines []Line
+	for _, path := range paths {
+		info, err := os.Stat(path)
+		if err != nil || !info.Mode().IsRegular() {
+			continue
+		}
+		id := fileID(path, info)
+		if seen[id] {
+			continue
+		}
+		seen[id] = true
+
+		tf, ok := t.files[id]
+		if !ok {
+			tf, err = t.open(id, path, info)
+			if err != nil {
+				log.Printf("Error opening %s: %v", path, err)
+				continue
+			}
+			t.files[id] = tf
+		}
+		tf.path = path
+
+		if info.Size() < tf.offset {
+			log.Printf("File %s was truncated, reading from the start", path)
+			if _, err := tf.file.Seek(0, io.SeekStart); err != nil {
+				log.Printf("Error rewinding %s: %v", path, err)
+				continue
+			}
+			tf.offset = 0
+			tf.partial = nil
+		}
+
+		read, err := t.read(tf, false)
+		if err != nil {
+			log.Printf("Error reading %s: %v", path, err)
+		}
+		lines = append(lines, read...)
+	}
+
+	// Files no longer matched were rotated away or deleted: finish them and stop following.
+	for id, tf := range t.files {
+		if seen[id] {
+			continue
+		}
+		read, err := t.read(tf, true)
+		if err != nil {
+			log.Printf("Error reading %s: %v", tf.path, err)
+		}
+		lines = append(lines, read...)
+		tf.file.Close()
+		delete(t.files, id)
+	}
+	return lines, nil
+}
+
+// open starts following a file, resuming from its saved position if the file
+// is no shorter than that position.
+func (t *Tailer) open(id, path string, info os.FileInfo) (*tailedFile, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	tf := &tailedFile{id: id, path: path, file: file}
+	if pos, ok := t.positions[id]; ok && pos.Offset <= info.Size() {
+		if _, err := file.Seek(pos.Offset, io.SeekStart); err != nil {
+			file.Close()
+			return nil, err
+		}
+		tf.offset = pos.Offset
+	}
+	return tf, nil
+}
+
+// read returns the complete lines available in tf. When final is set, a
+// trailing line without a newline is returned as well.
+func (t *Tailer) read(tf *tailedFile, final bool) ([]Line, error) {
+	var lines []Line
+	buf := make([]byte, 64*1024)
+	var total int
+	var readErr error
+	for total < maxReadPerPoll || final {
+		n, err := tf.file.Read(buf)
+		if n > 0 {
+			total += n
+			lines = append(lines, tf.consume(buf[:n])...)
+		}
+		if err != nil {
+			if err != io.EOF {
+				readErr = err
+			}
+			break
+		}
+	}
+
+	if final && len(tf.partial) > 0 {
+		lines = append(lines, Line{Path: tf.path, FileID: tf.id, Text: string(tf.partial), Offset: tf.offset})
+		tf.partial = nil
+	}
+	return lines, readErr
+}
+
+// consume splits newly read data into lines, keeping an unterminated tail for later.
+func (tf *tailedFile) consume(data []byte) []Line {
+	var lines []Line
+	start := tf.offset - int64(len(tf.partial))
+	tf.offset += int64(len(data))
+	tf.partial = append(tf.partial, data...)
+
+	for {
+		i := bytes.IndexByte(tf.partial, '\n')
+		if i < 0 && len(tf.partial) <= MaxLineBytes {
+			break
+		}
+		if i < 0 || i > MaxLineBytes {
+			// Split overlong lines at MaxLineBytes.
+			i = MaxLineBytes - 1
+		}
+		text := bytes.TrimSuffix(tf.partial[:i+1], []byte("\n"))
+		text = bytes.TrimSuffix(text, []byte("\r"))
+		start += int64(i + 1)
+		lines = append(lines, Line{Path: tf.path, FileID: tf.id, Text: string(text), Offset: start})
+		tf.partial = tf.partial[i+1:]
+	}
+	tf.partial = append([]byte(nil), tf.partial...)
+	return lines
+}
+
+// Commit records that every line of a file up to offset has been shipped.
+// It takes effect on disk at the next Save.
+func (t *Tailer) Commit(fileID, path string, offset int64) {
+	t.positions[fileID] = Position{Path: path, Offset: offset}
+}
+
+// Save writes the committed positions to the state file, forgetting files
+// that are no longer followed unless they are still present under the same path.
+func (t *Tailer) Save() error {
+	for id, pos := range t.positions {
+		if _, tracked := t.files[id]; tracked {
+			continue
+		}
+		if info, err := os.Stat(pos.Path); err != nil || fileID(pos.Path, info) != id {
+			delete(t.positions, id)
+		}
+	}
+
+	data, err := json.Marshal(t.positions)
+	if err != nil {
+		return err
+	}
+	tmp := t.statePath + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return fmt.Errorf("failed to write tailer state: %w", err)
+	}
+	if err := os.Rename(tmp, t.statePath); err != nil {
+		return fmt.Errorf("failed to write tailer state: %w", err)
+	}
+	return nil
+}
+
+// Close closes every open file.
+func (t *Tailer) Close() error {
+	for id, tf := range t.files {
+		tf.file.Close()
+		delete(t.files, id)
+	}
+	return nil
+}
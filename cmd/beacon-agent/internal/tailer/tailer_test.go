@@ -0,0 +1,152 @@
+package tailer
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func appendFile(t *testing.T, path, data string) {
+	t.Helper()
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	require.NoError(t, err)
+	_, err = f.WriteString(data)
+	require.NoError(t, err)
+	require.NoError(t, f.Close())
+}
+
+func texts(lines []Line) []string {
+	var out []string
+	for _, l := range lines {
+		out = append(out, l.Text)
+	}
+	return out
+}
+
+func TestTailer_ReadsCompleteLines(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "app.log")
+	appendFile(t, path, "first\nsecond\r\nthird")
+
+	tl, err := New([]string{filepath.Join(dir, "*.log")}, filepath.Join(dir, "state.json"))
+	require.NoError(t, err)
+	defer tl.Close()
+
+	lines, err := tl.Poll()
+	require.NoError(t, err)
+	assert.Equal(t, []string{"first", "second"}, texts(lines))
+	assert.Equal(t, int64(6), lines[0].Offset)
+	assert.Equal(t, int64(14), lines[1].Offset)
+	assert.Equal(t, path, lines[0].Path)
+
+	appendFile(t, path, " line\nfourth\n")
+	lines, err = tl.Poll()
+	require.NoError(t, err)
+	assert.Equal(t, []string{"third line", "fourth"}, texts(lines))
+	assert.Equal(t, int64(25), lines[0].Offset)
+
+	lines, err = tl.Poll()
+	require.NoError(t, err)
+	assert.Empty(t, lines)
+}
+
+func TestTailer_ResumesFromCommittedOffset(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "app.log")
+	statePath := filepath.Join(dir, "state.json")
+	appendFile(t, path, "one\ntwo\nthree\n")
+
+	tl, err := New([]string{path}, statePath)
+	require.NoError(t, err)
+	lines, err := tl.Poll()
+	require.NoError(t, err)
+	require.Len(t, lines, 3)
+
+	// Only the first two lines were shipped before the restart.
+	tl.Commit(lines[1].FileID, lines[1].Path, lines[1].Offset)
+	require.NoError(t, tl.Save())
+	tl.Close()
+
+	restarted, err := New([]string{path}, statePath)
+	require.NoError(t, err)
+	defer restarted.Close()
+	lines, err = restarted.Poll()
+	require.NoError(t, err)
+	assert.Equal(t, []string{"three"}, texts(lines))
+}
+
+func TestTailer_Truncation(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "app.log")
+	appendFile(t, path, "a long line before truncation\n")
+
+	tl, err := New([]string{path}, filepath.Join(dir, "state.json"))
+	require.NoError(t, err)
+	defer tl.Close()
+	_, err = tl.Poll()
+	require.NoError(t, err)
+
+	require.NoError(t, os.Truncate(path, 0))
+	appendFile(t, path, "after\n")
+
+	lines, err := tl.Poll()
+	require.NoError(t, err)
+	assert.Equal(t, []string{"after"}, texts(lines))
+	assert.Equal(t, int64(6), lines[0].Offset)
+}
+
+func TestTailer_Rotation(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "app.log")
+	appendFile(t, path, "old 1\n")
+
+	tl, err := New([]string{path}, filepath.Join(dir, "state.json"))
+	require.NoError(t, err)
+	defer tl.Close()
+	lines, err := tl.Poll()
+	require.NoError(t, err)
+	assert.Equal(t, []string{"old 1"}, texts(lines))
+
+	// The writer appends a final line and the file is rotated away.
+	appendFile(t, path, "old 2\nold partial")
+	require.NoError(t, os.Rename(path, path+".1"))
+	appendFile(t, path, "new 1\n")
+
+	lines, err = tl.Poll()
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{"new 1", "old 2", "old partial"}, texts(lines))
+
+	appendFile(t, path, "new 2\n")
+	lines, err = tl.Poll()
+	require.NoError(t, err)
+	assert.Equal(t, []string{"new 2"}, texts(lines))
+}
+
+func TestTailer_SplitsOverlongLines(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "app.log")
+	long := make([]byte, MaxLineBytes+10)
+	for i := range long {
+		long[i] = 'x'
+	}
+	appendFile(t, path, string(long)+"\n")
+
+	tl, err := New([]string{path}, filepath.Join(dir, "state.json"))
+	require.NoError(t, err)
+	defer tl.Close()
+
+	lines, err := tl.Poll()
+	require.NoError(t, err)
+	require.Len(t, lines, 2)
+	assert.Len(t, lines[0].Text, MaxLineBytes)
+	assert.Len(t, lines[1].Text, 10)
+	assert.Equal(t, int64(len(long)+1), lines[1].Offset)
+}
+
+func TestNew_InvalidPattern(t *testing.T) {
+	_, err := New([]string{"[invalid"}, filepath.Join(t.TempDir(), "state.json"))
+	assert.Error(t, err)
+}
@@ -0,0 +1,122 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"syscall"
+	"time"
+
+	"log-beacon/cmd/beacon-agent/internal/agent"
+	"log-beacon/cmd/beacon-agent/internal/multiline"
+	"log-beacon/cmd/beacon-agent/internal/shipper"
+	"log-beacon/cmd/beacon-agent/internal/tailer"
+)
+
+// listFlag collects a repeatable string flag.
+type listFlag []string
+
+func (l *listFlag) String() string { return strings.Join(*l, ",") }
+
+func (l *listFlag) Set(v string) error {
+	*l = append(*l, v)
+	return nil
+}
+
+func main() {
+	var paths, labels listFlag
+	flag.Var(&paths, "path", "glob of files to tail (repeatable)")
+	flag.Var(&labels, "label", "static label key=value attached to every entry (repeatable)")
+	url := flag.String("url", "http://localhost:8080/api/v1/ingest", "ingest API endpoint")
+	stateDir := flag.String("state-dir", "/var/lib/beacon-agent", "directory for read positions and the disk buffer")
+	multilinePattern := flag.String("multiline-pattern", `^[\t ]|^Caused by:`, "lines matching this pattern are appended to the previous entry; empty disables multi-line joining")
+	multilineMaxLines := flag.Int("multiline-max-lines", 500, "maximum number of lines in one entry")
+	batchSize := flag.Int("batch-size", 500, "maximum entries per request")
+	pollInterval := flag.Duration("poll-interval", 250*time.Millisecond, "how often files are checked for new lines")
+	flushInterval := flag.Duration("flush-interval", time.Second, "maximum time an entry waits before it is shipped")
+	compression := flag.String("compression", shipper.CompressionGzip, "request compression: gzip, zstd or none")
+	retries := flag.Int("retries", 3, "delivery attempts before a batch is buffered on disk")
+	bufferMaxBytes := flag.Int64("buffer-max-bytes", 256<<20, "maximum size of the disk buffer")
+	flag.Parse()
+
+	if len(paths) == 0 {
+		log.Fatal("At least one -path is required.")
+	}
+
+	staticLabels, err := parseLabels(labels)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if _, ok := staticLabels["host"]; !ok {
+		if hostname, err := os.Hostname(); err == nil {
+			staticLabels["host"] = hostname
+		}
+	}
+
+	var continuation *regexp.Regexp
+	if *multilinePattern != "" {
+		continuation, err = regexp.Compile(*multilinePattern)
+		if err != nil {
+			log.Fatalf("Invalid -multiline-pattern: %v", err)
+		}
+	}
+
+	// --- Initialization ---
+	if err := os.MkdirAll(*stateDir, 0o755); err != nil {
+		log.Fatalf("Failed to create state directory: %v", err)
+	}
+	t, err := tailer.New(paths, filepath.Join(*stateDir, "positions.json"))
+	if err != nil {
+		log.Fatalf("Failed to create tailer: %v", err)
+	}
+
+	spool, err := shipper.NewSpool(filepath.Join(*stateDir, "buffer"), *bufferMaxBytes)
+	if err != nil {
+		log.Fatalf("Failed to open disk buffer: %v", err)
+	}
+	s, err := shipper.New(shipper.Config{
+		URL:         *url,
+		Compression: *compression,
+		Retries:     *retries,
+		Backoff:     500 * time.Millisecond,
+	}, spool)
+	if err != nil {
+		log.Fatalf("Failed to create shipper: %v", err)
+	}
+
+	a := agent.New(agent.Config{
+		Labels:        staticLabels,
+		BatchSize:     *batchSize,
+		PollInterval:  *pollInterval,
+		FlushInterval: *flushInterval,
+	}, t, multiline.New(continuation, *multilineMaxLines, *flushInterval), s)
+
+	// --- Run until interrupted ---
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	log.Printf("Beacon agent shipping %s to %s", strings.Join(paths, ", "), *url)
+	if err := a.Run(ctx); err != nil {
+		log.Fatalf("Agent error: %v", err)
+	}
+	log.Println("Beacon agent shut down gracefully.")
+}
+
+// parseLabels parses key=value pairs.
+func parseLabels(pairs []string) (map[string]string, error) {
+	labels := make(map[string]string, len(pairs))
+	for _, pair := range pairs {
+		key, value, ok := strings.Cut(pair, "=")
+		if !ok || key == "" {
+			return nil, fmt.Errorf("invalid -label %q, expected key=value", pair)
+		}
+		labels[key] = value
+	}
+	return labels, nil
+}
@@ -0,0 +1,221 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"log-beacon/internal/model"
+
+	"github.com/gorilla/websocket"
+)
+
+// MaxPageSize is the largest page the search API returns.
+const MaxPageSize = 100
+
+// ErrUnauthorized is returned when the API refuses the credentials or token.
+var ErrUnauthorized = errors.New("not authorized")
+
+// Client talks to the Log Beacon API.
+type Client struct {
+	baseURL string
+	token   string
+	http    *http.Client
+}
+
+// NewClient creates a client for the API at baseURL, authenticating with token.
+func NewClient(baseURL, token string) *Client {
+	return &Client{
+		baseURL: strings.TrimRight(baseURL, "/"),
+		token:   token,
+		http:    &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+// Login exchanges a username and password for a token.
+func (c *Client) Login(ctx context.Context, username, password string) (string, error) {
+	body, err := json.Marshal(map[string]string{"username": username, "password": password})
+	if err != nil {
+		return "", err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.baseURL+"/api/v1/auth/login", bytes.NewReader(body))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	var resp struct {
+		Token string `json:"token"`
+	}
+	if err := c.do(req, &resp); err != nil {
+		return "", err
+	}
+	if resp.Token == "" {
+		return "", fmt.Errorf("login response did not contain a token")
+	}
+	return resp.Token, nil
+}
+
+// SearchParams selects the logs returned by Search.
+type SearchParams struct {
+	Query string
+	// From and To bound the timestamps; zero values leave the range open.
+	From, To time.Time
+	// Order is "asc", "desc" or empty for relevance.
+	Order string
+	// Limit is the maximum number of logs returned.
+	Limit int
+}
+
+// Search runs a query, following pages until Limit logs are returned or the
+// results are exhausted.
+func (c *Client) Search(ctx context.Context, params SearchParams) ([]model.Log, error) {
+	var results []model.Log
+	for page := 1; len(results) < params.Limit; page++ {
+		size := MaxPageSize
+		if remaining := params.Limit - len(results); remaining < size {
+			size = remaining
+		}
+
+		q := url.Values{}
+		q.Set("q", params.Query)
+		// The API computes offsets from the page size, so keep it fixed across pages.
+		q.Set("page", strconv.Itoa(page))
+		q.Set("size", strconv.Itoa(MaxPageSize))
+		if !params.From.IsZero() {
+			q.Set("from", params.From.UTC().Format(time.RFC3339Nano))
+		}
+		if !params.To.IsZero() {
+			q.Set("to", params.To.UTC().Format(time.RFC3339Nano))
+		}
+		if params.Order != "" {
+			q.Set("order", params.Order)
+		}
+
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.baseURL+"/api/v1/search?"+q.Encode(), nil)
+		if err != nil {
+			return nil, err
+		}
+		var logs []model.Log
+		if err := c.do(req, &logs); err != nil {
+			return nil, err
+		}
+		if len(logs) > size {
+			logs = logs[:size]
+		}
+		results = append(results, logs...)
+		if len(logs) < MaxPageSize {
+			break
+		}
+	}
+	return results, nil
+}
+
+// Tail streams live logs to fn until ctx is cancelled, the connection drops
+// or fn returns an error. A non-zero afterSeq resumes after the log with that
+// sequence number. When the server skipped logs because the client fell
+// behind, onDropped, if set, receives how many.
+func (c *Client) Tail(ctx context.Context, afterSeq uint64, fn func(model.TailEvent) error, onDropped func(n uint64)) error {
+	u, err := url.Parse(c.baseURL + "/api/v1/tail")
+	if err != nil {
+		return err
+	}
+	if afterSeq > 0 {
+		u.RawQuery = url.Values{"after_seq": {strconv.FormatUint(afterSeq, 10)}}.Encode()
+	}
+	switch u.Scheme {
+	case "https":
+		u.Scheme = "wss"
+	default:
+		u.Scheme = "ws"
+	}
+
+	header := http.Header{}
+	header.Set("Authorization", "Bearer "+c.token)
+	ws, resp, err := websocket.DefaultDialer.DialContext(ctx, u.String(), header)
+	if err != nil {
+		if resp != nil && resp.StatusCode == http.StatusUnauthorized {
+			return ErrUnauthorized
+		}
+		return fmt.Errorf("failed to connect to live tail: %w", err)
+	}
+	defer ws.Close()
+
+	// Unblock the read below when the caller gives up.
+	stop := context.AfterFunc(ctx, func() { ws.Close() })
+	defer stop()
+
+	for {
+		_, data, err := ws.ReadMessage()
+		if err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			return fmt.Errorf("live tail connection lost: %w", err)
+		}
+
+		// Notices share the connection with logs and are told apart by their type.
+		var notice struct {
+			Type    string `json:"type"`
+			Dropped uint64 `json:"dropped"`
+		}
+		if err := json.Unmarshal(data, &notice); err != nil {
+			return fmt.Errorf("failed to decode live tail message: %w", err)
+		}
+		if notice.Type == "dropped" {
+			if onDropped != nil {
+				onDropped(notice.Dropped)
+			}
+			continue
+		}
+
+		var event model.TailEvent
+		if err := json.Unmarshal(data, &event); err != nil {
+			return fmt.Errorf("failed to decode live tail message: %w", err)
+		}
+		if err := fn(event); err != nil {
+			return err
+		}
+	}
+}
+
+// do sends an authenticated request and decodes a JSON response into out.
+func (c *Client) do(req *http.Request, out interface{}) error {
+	if c.token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.token)
+	}
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 64<<20))
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode == http.StatusUnauthorized {
+		return ErrUnauthorized
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		var apiErr struct {
+			Error string `json:"error"`
+		}
+		if json.Unmarshal(body, &apiErr) == nil && apiErr.Error != "" {
+			return fmt.Errorf("API returned %s: %s", resp.Status, apiErr.Error)
+		}
+		return fmt.Errorf("API returned %s", resp.Status)
+	}
+	if err := json.Unmarshal(body, out); err != nil {
+		return fmt.Errorf("failed to decode API response: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,130 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+
+	"log-beacon/internal/model"
+
+	"github.com/gorilla/websocket"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestClient_SearchFollowsPages(t *testing.T) {
+	var pages []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		page, _ := strconv.Atoi(r.URL.Query().Get("page"))
+		pages = append(pages, r.URL.Query().Get("page"))
+		assert.Equal(t, "100", r.URL.Query().Get("size"))
+
+		// Two full pages followed by a partial one.
+		n := MaxPageSize
+		if page == 3 {
+			n = 10
+		}
+		logs := make([]model.Log, n)
+		for i := range logs {
+			logs[i] = model.Log{Message: strconv.Itoa((page-1)*MaxPageSize + i)}
+		}
+		json.NewEncoder(w).Encode(logs)
+	}))
+	defer server.Close()
+
+	c := NewClient(server.URL, "token")
+	logs, err := c.Search(context.Background(), SearchParams{Query: "x", Limit: 150})
+	require.NoError(t, err)
+	assert.Len(t, logs, 150)
+	assert.Equal(t, "149", logs[149].Message)
+	assert.Equal(t, []string{"1", "2"}, pages)
+
+	pages = nil
+	logs, err = c.Search(context.Background(), SearchParams{Query: "x", Limit: 1000})
+	require.NoError(t, err)
+	assert.Len(t, logs, 210)
+	assert.Equal(t, []string{"1", "2", "3"}, pages)
+}
+
+func TestClient_SearchErrors(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer good" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(`{"error":"Query parameter 'from' must be an RFC 3339 timestamp"}`))
+	}))
+	defer server.Close()
+
+	_, err := NewClient(server.URL, "bad").Search(context.Background(), SearchParams{Query: "x", Limit: 1})
+	assert.ErrorIs(t, err, ErrUnauthorized)
+
+	_, err = NewClient(server.URL, "good").Search(context.Background(), SearchParams{Query: "x", Limit: 1})
+	assert.EqualError(t, err, "API returned 400 Bad Request: Query parameter 'from' must be an RFC 3339 timestamp")
+}
+
+func TestClient_Tail(t *testing.T) {
+	upgrader := websocket.Upgrader{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer good" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		assert.Equal(t, "41", r.URL.Query().Get("after_seq"))
+		ws, err := upgrader.Upgrade(w, r, nil)
+		require.NoError(t, err)
+		defer ws.Close()
+		require.NoError(t, ws.WriteJSON(model.TailEvent{Seq: 42, Log: model.Log{Message: "one"}}))
+		require.NoError(t, ws.WriteJSON(map[string]interface{}{"type": "dropped", "dropped": 5}))
+		require.NoError(t, ws.WriteJSON(model.TailEvent{Seq: 48, Log: model.Log{Message: "two"}}))
+		ws.ReadMessage()
+	}))
+	defer server.Close()
+
+	err := NewClient(server.URL, "bad").Tail(context.Background(), 41, func(model.TailEvent) error { return nil }, nil)
+	assert.ErrorIs(t, err, ErrUnauthorized)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	var got []string
+	var seqs []uint64
+	var dropped uint64
+	err = NewClient(server.URL, "good").Tail(ctx, 41, func(event model.TailEvent) error {
+		got = append(got, event.Message)
+		seqs = append(seqs, event.Seq)
+		if len(got) == 2 {
+			cancel()
+		}
+		return nil
+	}, func(n uint64) { dropped += n })
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.Equal(t, []string{"one", "two"}, got)
+	assert.Equal(t, []uint64{42, 48}, seqs)
+	assert.Equal(t, uint64(5), dropped)
+}
+
+func TestCredentials(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "beacon", "credentials.json")
+	creds, err := LoadCredentials(path)
+	require.NoError(t, err)
+	assert.Empty(t, creds.Token("http://localhost:8080"))
+
+	creds.SetToken("http://localhost:8080/", "abc")
+	require.NoError(t, creds.Save())
+
+	reloaded, err := LoadCredentials(path)
+	require.NoError(t, err)
+	assert.Equal(t, "abc", reloaded.Token("http://localhost:8080"))
+	assert.Empty(t, reloaded.Token("http://other:8080"))
+
+	info, err := os.Stat(path)
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0o600), info.Mode().Perm())
+}
@@ -0,0 +1,80 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Credentials caches login tokens per API URL on disk, so that a login is
+// reused by later commands.
+type Credentials struct {
+	path   string
+	Tokens map[string]string `json:"tokens"`
+}
+
+// DefaultCredentialsPath returns the credentials file in the user's config directory.
+func DefaultCredentialsPath() (string, error) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "beacon", "credentials.json"), nil
+}
+
+// LoadCredentials reads the credentials file at path. A missing file yields
+// empty credentials.
+func LoadCredentials(path string) (*Credentials, error) {
+	creds := &Credentials{path: path, Tokens: make(map[string]string)}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return creds, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, creds); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+	if creds.Tokens == nil {
+		creds.Tokens = make(map[string]string)
+	}
+	return creds, nil
+}
+
+// Token returns the cached token for an API URL.
+func (c *Credentials) Token(baseURL string) string {
+	return c.Tokens[normalizeURL(baseURL)]
+}
+
+// SetToken caches a token for an API URL; an empty token removes it.
+func (c *Credentials) SetToken(baseURL, token string) {
+	if token == "" {
+		delete(c.Tokens, normalizeURL(baseURL))
+		return
+	}
+	c.Tokens[normalizeURL(baseURL)] = token
+}
+
+// Save writes the credentials file, readable only by the current user.
+func (c *Credentials) Save() error {
+	if err := os.MkdirAll(filepath.Dir(c.path), 0o700); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(c, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp := c.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, c.path)
+}
+
+func normalizeURL(baseURL string) string {
+	return strings.TrimRight(baseURL, "/")
+}
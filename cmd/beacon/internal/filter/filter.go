@@ -0,0 +1,72 @@
+package filter
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"log-beacon/internal/model"
+)
+
+// Filter selects live-tailed log entries on the client side. Empty fields
+// match every entry.
+type Filter struct {
+	// Levels lists the accepted levels, compared case-insensitively.
+	Levels []string
+	// Labels must all be present with the given values.
+	Labels map[string]string
+	// Pattern must match the message.
+	Pattern *regexp.Regexp
+}
+
+// New builds a filter from a comma-separated level list, key=value label
+// pairs and a message regular expression.
+func New(levels string, labels []string, pattern string) (*Filter, error) {
+	f := &Filter{Labels: make(map[string]string)}
+	for _, level := range strings.Split(levels, ",") {
+		if level = strings.TrimSpace(level); level != "" {
+			f.Levels = append(f.Levels, strings.ToLower(level))
+		}
+	}
+	for _, pair := range labels {
+		k, v, ok := strings.Cut(pair, "=")
+		if !ok || k == "" {
+			return nil, fmt.Errorf("invalid label %q, expected key=value", pair)
+		}
+		f.Labels[k] = v
+	}
+	if pattern != "" {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid pattern: %w", err)
+		}
+		f.Pattern = re
+	}
+	return f, nil
+}
+
+// Match reports whether an entry passes the filter.
+func (f *Filter) Match(l model.Log) bool {
+	if len(f.Levels) > 0 {
+		level := strings.ToLower(l.Level)
+		found := false
+		for _, want := range f.Levels {
+			if level == want {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	for k, v := range f.Labels {
+		if got, ok := l.Labels[k]; !ok || got != v {
+			return false
+		}
+	}
+	if f.Pattern != nil && !f.Pattern.MatchString(l.Message) {
+		return false
+	}
+	return true
+}
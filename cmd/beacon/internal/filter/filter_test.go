@@ -0,0 +1,43 @@
+package filter
+
+import (
+	"testing"
+
+	"log-beacon/internal/model"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFilter_Match(t *testing.T) {
+	f, err := New("error, WARN", []string{"service=api"}, `timeout|refused`)
+	require.NoError(t, err)
+
+	tests := []struct {
+		name string
+		log  model.Log
+		want bool
+	}{
+		{"Match", model.Log{Level: "ERROR", Message: "upstream timeout", Labels: map[string]string{"service": "api"}}, true},
+		{"Wrong level", model.Log{Level: "info", Message: "upstream timeout", Labels: map[string]string{"service": "api"}}, false},
+		{"Wrong label", model.Log{Level: "warn", Message: "connection refused", Labels: map[string]string{"service": "web"}}, false},
+		{"Missing label", model.Log{Level: "warn", Message: "connection refused"}, false},
+		{"Message mismatch", model.Log{Level: "warn", Message: "ok", Labels: map[string]string{"service": "api"}}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, f.Match(tt.log))
+		})
+	}
+
+	empty, err := New("", nil, "")
+	require.NoError(t, err)
+	assert.True(t, empty.Match(model.Log{Message: "anything"}))
+}
+
+func TestNew_Invalid(t *testing.T) {
+	_, err := New("", []string{"service"}, "")
+	assert.Error(t, err)
+	_, err = New("", nil, "[")
+	assert.Error(t, err)
+}
@@ -0,0 +1,150 @@
+package output
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+
+	"log-beacon/internal/model"
+)
+
+// Supported output formats.
+const (
+	FormatPretty = "pretty"
+	FormatJSON   = "json"
+	FormatNDJSON = "ndjson"
+	FormatCSV    = "csv"
+)
+
+// Writer renders log entries one at a time. Close completes the output,
+// e.g. the closing bracket of a JSON array, and must be called once.
+type Writer interface {
+	Write(model.Log) error
+	Close() error
+}
+
+// New creates a writer for format.
+func New(format string, w io.Writer) (Writer, error) {
+	switch format {
+	case FormatPretty:
+		return &prettyWriter{w: w}, nil
+	case FormatJSON:
+		return &jsonWriter{w: w}, nil
+	case FormatNDJSON:
+		return &ndjsonWriter{enc: json.NewEncoder(w)}, nil
+	case FormatCSV:
+		return &csvWriter{w: csv.NewWriter(w)}, nil
+	default:
+		return nil, fmt.Errorf("unknown output format %q (want pretty, json, ndjson or csv)", format)
+	}
+}
+
+// prettyWriter prints one human-readable line per entry:
+// timestamp, level, message and sorted key=value labels.
+type prettyWriter struct {
+	w io.Writer
+}
+
+func (p *prettyWriter) Write(l model.Log) error {
+	var b strings.Builder
+	b.WriteString(l.Timestamp.UTC().Format(time.RFC3339Nano))
+	fmt.Fprintf(&b, " %-5s %s", strings.ToUpper(l.Level), l.Message)
+	for _, k := range sortedKeys(l.Labels) {
+		fmt.Fprintf(&b, " %s=%s", k, quoteIfNeeded(l.Labels[k]))
+	}
+	b.WriteByte('\n')
+	_, err := io.WriteString(p.w, b.String())
+	return err
+}
+
+func (p *prettyWriter) Close() error { return nil }
+
+// jsonWriter streams entries as the elements of an indented JSON array.
+type jsonWriter struct {
+	w     io.Writer
+	count int
+}
+
+func (j *jsonWriter) Write(l model.Log) error {
+	data, err := json.MarshalIndent(l, "  ", "  ")
+	if err != nil {
+		return err
+	}
+	sep := ",\n  "
+	if j.count == 0 {
+		sep = "[\n  "
+	}
+	j.count++
+	_, err = fmt.Fprintf(j.w, "%s%s", sep, data)
+	return err
+}
+
+func (j *jsonWriter) Close() error {
+	if j.count == 0 {
+		_, err := io.WriteString(j.w, "[]\n")
+		return err
+	}
+	_, err := io.WriteString(j.w, "\n]\n")
+	return err
+}
+
+// ndjsonWriter prints one JSON object per line.
+type ndjsonWriter struct {
+	enc *json.Encoder
+}
+
+func (n *ndjsonWriter) Write(l model.Log) error { return n.enc.Encode(l) }
+
+func (n *ndjsonWriter) Close() error { return nil }
+
+// csvWriter prints a header row followed by timestamp, level, message and
+// the labels as a JSON object.
+type csvWriter struct {
+	w      *csv.Writer
+	header bool
+}
+
+func (c *csvWriter) Write(l model.Log) error {
+	if !c.header {
+		c.header = true
+		if err := c.w.Write([]string{"timestamp", "level", "message", "labels"}); err != nil {
+			return err
+		}
+	}
+	labels, err := json.Marshal(l.Labels)
+	if err != nil {
+		return err
+	}
+	if err := c.w.Write([]string{l.Timestamp.UTC().Format(time.RFC3339Nano), l.Level, l.Message, string(labels)}); err != nil {
+		return err
+	}
+	// Flush every row so that followed output appears immediately.
+	c.w.Flush()
+	return c.w.Error()
+}
+
+func (c *csvWriter) Close() error {
+	c.w.Flush()
+	return c.w.Error()
+}
+
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// quoteIfNeeded quotes label values that would be ambiguous on a pretty line.
+func quoteIfNeeded(v string) string {
+	if v == "" || strings.ContainsAny(v, " \t\n\"=") {
+		return fmt.Sprintf("%q", v)
+	}
+	return v
+}
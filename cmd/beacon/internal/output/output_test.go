@@ -0,0 +1,87 @@
+package output
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"log-beacon/internal/model"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWriters(t *testing.T) {
+	logs := []model.Log{
+		{
+			Timestamp: time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC),
+			Level:     "error",
+			Message:   "payment failed",
+			Labels:    map[string]string{"service": "api", "user": "Jane Doe"},
+		},
+		{
+			Timestamp: time.Date(2024, 5, 1, 12, 0, 1, 0, time.UTC),
+			Level:     "info",
+			Message:   "retry, then \"ok\"",
+		},
+	}
+
+	tests := []struct {
+		format   string
+		expected string
+	}{
+		{
+			format: FormatPretty,
+			expected: "2024-05-01T12:00:00Z ERROR payment failed service=api user=\"Jane Doe\"\n" +
+				"2024-05-01T12:00:01Z INFO  retry, then \"ok\"\n",
+		},
+		{
+			format: FormatNDJSON,
+			expected: `{"timestamp":"2024-05-01T12:00:00Z","level":"error","message":"payment failed","labels":{"service":"api","user":"Jane Doe"}}` + "\n" +
+				`{"timestamp":"2024-05-01T12:00:01Z","level":"info","message":"retry, then \"ok\"","labels":null}` + "\n",
+		},
+		{
+			format: FormatCSV,
+			expected: "timestamp,level,message,labels\n" +
+				`2024-05-01T12:00:00Z,error,payment failed,"{""service"":""api"",""user"":""Jane Doe""}"` + "\n" +
+				`2024-05-01T12:00:01Z,info,"retry, then ""ok""",null` + "\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.format, func(t *testing.T) {
+			var buf bytes.Buffer
+			w, err := New(tt.format, &buf)
+			require.NoError(t, err)
+			for _, l := range logs {
+				require.NoError(t, w.Write(l))
+			}
+			require.NoError(t, w.Close())
+			assert.Equal(t, tt.expected, buf.String())
+		})
+	}
+}
+
+func TestJSONWriter(t *testing.T) {
+	var buf bytes.Buffer
+	w, err := New(FormatJSON, &buf)
+	require.NoError(t, err)
+	require.NoError(t, w.Write(model.Log{Message: "one"}))
+	require.NoError(t, w.Write(model.Log{Message: "two"}))
+	require.NoError(t, w.Close())
+	assert.JSONEq(t, `[
+		{"timestamp":"0001-01-01T00:00:00Z","level":"","message":"one","labels":null},
+		{"timestamp":"0001-01-01T00:00:00Z","level":"","message":"two","labels":null}
+	]`, buf.String())
+
+	buf.Reset()
+	w, err = New(FormatJSON, &buf)
+	require.NoError(t, err)
+	require.NoError(t, w.Close())
+	assert.Equal(t, "[]\n", buf.String())
+}
+
+func TestNew_UnknownFormat(t *testing.T) {
+	_, err := New("xml", &bytes.Buffer{})
+	assert.Error(t, err)
+}
@@ -0,0 +1,374 @@
+// Command beacon searches and tails Log Beacon from a terminal or script.
+//
+//	beacon login -username alice
+//	beacon search -from 1h -o csv 'level:error AND service:api'
+//	beacon tail -level error,warn -label service=api
+//
+// It exits with status 0 on success, 1 on errors and 2 on usage errors.
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	"log-beacon/cmd/beacon/internal/api"
+	"log-beacon/cmd/beacon/internal/filter"
+	"log-beacon/cmd/beacon/internal/output"
+	"log-beacon/internal/model"
+
+	"golang.org/x/term"
+)
+
+const usage = `Usage: beacon <command> [flags]
+
+Commands:
+  login    log in and cache the token
+  logout   remove the cached token
+  search   search stored logs
+  tail     follow live logs
+
+Run 'beacon <command> -h' for the flags of a command.
+The API URL defaults to $BEACON_URL and a token in $BEACON_TOKEN overrides the cached one.
+`
+
+// errUsage marks invalid command lines that have already been reported.
+var errUsage = errors.New("usage error")
+
+// listFlag collects a repeatable string flag.
+type listFlag []string
+
+func (l *listFlag) String() string { return strings.Join(*l, ",") }
+
+func (l *listFlag) Set(v string) error {
+	*l = append(*l, v)
+	return nil
+}
+
+// app holds the process environment shared by the commands.
+type app struct {
+	stdin          io.Reader
+	stdout, stderr io.Writer
+	now            func() time.Time
+}
+
+func main() {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	a := &app{stdin: os.Stdin, stdout: os.Stdout, stderr: os.Stderr, now: time.Now}
+	os.Exit(a.run(ctx, os.Args[1:]))
+}
+
+// run executes a command line and returns the exit status.
+func (a *app) run(ctx context.Context, args []string) int {
+	if len(args) == 0 {
+		fmt.Fprint(a.stderr, usage)
+		return 2
+	}
+
+	var err error
+	switch args[0] {
+	case "login":
+		err = a.login(ctx, args[1:])
+	case "logout":
+		err = a.logout(args[1:])
+	case "search":
+		err = a.search(ctx, args[1:])
+	case "tail":
+		err = a.tail(ctx, args[1:])
+	case "help", "-h", "-help", "--help":
+		fmt.Fprint(a.stdout, usage)
+		return 0
+	default:
+		fmt.Fprintf(a.stderr, "beacon: unknown command %q\n\n%s", args[0], usage)
+		return 2
+	}
+
+	switch {
+	case err == nil, errors.Is(err, flag.ErrHelp):
+		return 0
+	case errors.Is(err, errUsage):
+		return 2
+	case errors.Is(err, api.ErrUnauthorized):
+		fmt.Fprintln(a.stderr, "beacon: not logged in or the token has expired; run 'beacon login'")
+		return 1
+	default:
+		fmt.Fprintf(a.stderr, "beacon: %v\n", err)
+		return 1
+	}
+}
+
+// connection holds the flags shared by every command.
+type connection struct {
+	server      string
+	token       string
+	credentials string
+}
+
+func (a *app) newFlagSet(name string, conn *connection) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.SetOutput(a.stderr)
+
+	server := os.Getenv("BEACON_URL")
+	if server == "" {
+		server = "http://localhost:8080"
+	}
+	credentials, _ := api.DefaultCredentialsPath()
+	fs.StringVar(&conn.server, "server", server, "API URL")
+	fs.StringVar(&conn.token, "token", os.Getenv("BEACON_TOKEN"), "API token, overriding the cached login")
+	fs.StringVar(&conn.credentials, "credentials", credentials, "file caching login tokens")
+	return fs
+}
+
+// parseFlags parses args, mapping parse errors to errUsage. The flag package
+// has already reported them.
+func parseFlags(fs *flag.FlagSet, args []string) error {
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return err
+		}
+		return errUsage
+	}
+	return nil
+}
+
+// client returns an API client authenticated with the explicit or cached token.
+func (conn *connection) client() (*api.Client, error) {
+	token := conn.token
+	if token == "" {
+		creds, err := api.LoadCredentials(conn.credentials)
+		if err != nil {
+			return nil, err
+		}
+		token = creds.Token(conn.server)
+	}
+	if token == "" {
+		return nil, api.ErrUnauthorized
+	}
+	return api.NewClient(conn.server, token), nil
+}
+
+func (a *app) login(ctx context.Context, args []string) error {
+	var conn connection
+	fs := a.newFlagSet("login", &conn)
+	username := fs.String("username", os.Getenv("BEACON_USERNAME"), "user name")
+	passwordStdin := fs.Bool("password-stdin", false, "read the password from stdin")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+
+	reader := bufio.NewReader(a.stdin)
+	if *username == "" {
+		fmt.Fprint(a.stderr, "Username: ")
+		*username = readLine(reader)
+	}
+	password := os.Getenv("BEACON_PASSWORD")
+	if *passwordStdin {
+		password = readLine(reader)
+	} else if password == "" {
+		fmt.Fprint(a.stderr, "Password: ")
+		var err error
+		if password, err = a.readPassword(reader); err != nil {
+			return fmt.Errorf("failed to read password: %w", err)
+		}
+	}
+	if *username == "" || password == "" {
+		return fmt.Errorf("a username and password are required")
+	}
+
+	token, err := api.NewClient(conn.server, "").Login(ctx, *username, password)
+	if errors.Is(err, api.ErrUnauthorized) {
+		return fmt.Errorf("invalid credentials")
+	}
+	if err != nil {
+		return err
+	}
+
+	creds, err := api.LoadCredentials(conn.credentials)
+	if err != nil {
+		return err
+	}
+	creds.SetToken(conn.server, token)
+	if err := creds.Save(); err != nil {
+		return fmt.Errorf("failed to cache token: %w", err)
+	}
+	fmt.Fprintf(a.stderr, "Logged in to %s as %s.\n", conn.server, *username)
+	return nil
+}
+
+func (a *app) logout(args []string) error {
+	var conn connection
+	fs := a.newFlagSet("logout", &conn)
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+
+	creds, err := api.LoadCredentials(conn.credentials)
+	if err != nil {
+		return err
+	}
+	creds.SetToken(conn.server, "")
+	return creds.Save()
+}
+
+func (a *app) search(ctx context.Context, args []string) error {
+	var conn connection
+	fs := a.newFlagSet("search", &conn)
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: beacon search [flags] <query>")
+		fs.PrintDefaults()
+	}
+	from := fs.String("from", "", "start of the time range: RFC 3339 timestamp or a duration ago, e.g. 15m")
+	to := fs.String("to", "", "end of the time range: RFC 3339 timestamp or a duration ago")
+	order := fs.String("order", "desc", "result order by timestamp: asc, desc or relevance")
+	limit := fs.Int("limit", 100, "maximum number of results")
+	format := fs.String("o", output.FormatPretty, "output format: pretty, json, ndjson or csv")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	if fs.NArg() == 0 {
+		fs.Usage()
+		return errUsage
+	}
+
+	params := api.SearchParams{Query: strings.Join(fs.Args(), " "), Limit: *limit}
+	var err error
+	if params.From, err = parseTime(*from, a.now()); err != nil {
+		return fmt.Errorf("invalid -from: %w", err)
+	}
+	if params.To, err = parseTime(*to, a.now()); err != nil {
+		return fmt.Errorf("invalid -to: %w", err)
+	}
+	switch *order {
+	case "asc", "desc":
+		params.Order = *order
+	case "relevance":
+	default:
+		return fmt.Errorf("invalid -order %q (want asc, desc or relevance)", *order)
+	}
+	if *limit < 1 {
+		return fmt.Errorf("-limit must be positive")
+	}
+	w, err := output.New(*format, a.stdout)
+	if err != nil {
+		return err
+	}
+
+	client, err := conn.client()
+	if err != nil {
+		return err
+	}
+	logs, err := client.Search(ctx, params)
+	if err != nil {
+		return err
+	}
+	for _, l := range logs {
+		if err := w.Write(l); err != nil {
+			return err
+		}
+	}
+	return w.Close()
+}
+
+func (a *app) tail(ctx context.Context, args []string) error {
+	var conn connection
+	var labels listFlag
+	fs := a.newFlagSet("tail", &conn)
+	levels := fs.String("level", "", "comma-separated levels to show, e.g. error,warn")
+	fs.Var(&labels, "label", "only show entries with this label key=value (repeatable)")
+	grep := fs.String("grep", "", "only show entries whose message matches this regular expression")
+	format := fs.String("o", output.FormatPretty, "output format: pretty, json, ndjson or csv")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+
+	f, err := filter.New(*levels, labels, *grep)
+	if err != nil {
+		return err
+	}
+	w, err := output.New(*format, a.stdout)
+	if err != nil {
+		return err
+	}
+	client, err := conn.client()
+	if err != nil {
+		return err
+	}
+
+	// Reconnect with backoff when the connection drops, like tail -F, resuming
+	// after the last log received so that nothing is missed in between.
+	backoff := time.Second
+	var lastSeq uint64
+	for {
+		connected := time.Now()
+		err = client.Tail(ctx, lastSeq, func(event model.TailEvent) error {
+			lastSeq = event.Seq
+			if !f.Match(event.Log) {
+				return nil
+			}
+			return w.Write(event.Log)
+		}, func(n uint64) {
+			fmt.Fprintf(a.stderr, "beacon: server dropped %d logs because output fell behind\n", n)
+		})
+		if ctx.Err() != nil {
+			return w.Close()
+		}
+		if errors.Is(err, api.ErrUnauthorized) {
+			w.Close()
+			return err
+		}
+		if time.Since(connected) > time.Minute {
+			backoff = time.Second
+		}
+		fmt.Fprintf(a.stderr, "beacon: %v; reconnecting in %s\n", err, backoff)
+		select {
+		case <-ctx.Done():
+			return w.Close()
+		case <-time.After(backoff):
+		}
+		if backoff < 30*time.Second {
+			backoff *= 2
+		}
+	}
+}
+
+// parseTime accepts an RFC 3339 timestamp or a duration before now.
+// An empty value returns the zero time.
+func parseTime(value string, now time.Time) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	if d, err := time.ParseDuration(value); err == nil {
+		return now.Add(-d), nil
+	}
+	t, err := time.Parse(time.RFC3339Nano, value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("%q is neither an RFC 3339 timestamp nor a duration", value)
+	}
+	return t, nil
+}
+
+// readPassword reads a password without echoing it when stdin is a
+// terminal, and a plain line otherwise.
+func (a *app) readPassword(r *bufio.Reader) (string, error) {
+	if f, ok := a.stdin.(*os.File); ok && term.IsTerminal(int(f.Fd())) {
+		password, err := term.ReadPassword(int(f.Fd()))
+		fmt.Fprintln(a.stderr)
+		return string(password), err
+	}
+	return readLine(r), nil
+}
+
+func readLine(r *bufio.Reader) string {
+	line, _ := r.ReadString('\n')
+	return strings.TrimRight(line, "\r\n")
+}
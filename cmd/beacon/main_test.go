@@ -0,0 +1,133 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"log-beacon/internal/model"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeAPI serves the login and search endpoints for the user alice.
+func fakeAPI(t *testing.T, searches *[]string) *httptest.Server {
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /api/v1/auth/login", func(w http.ResponseWriter, r *http.Request) {
+		var req map[string]string
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+		if req["username"] != "alice" || req["password"] != "secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			w.Write([]byte(`{"error":"Invalid credentials"}`))
+			return
+		}
+		w.Write([]byte(`{"token":"alice-token"}`))
+	})
+	mux.HandleFunc("GET /api/v1/search", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer alice-token" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		*searches = append(*searches, r.URL.RawQuery)
+		json.NewEncoder(w).Encode([]model.Log{{
+			Timestamp: time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC),
+			Level:     "error",
+			Message:   "payment failed",
+			Labels:    map[string]string{"service": "api"},
+		}})
+	})
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestRun_LoginAndSearch(t *testing.T) {
+	var searches []string
+	server := fakeAPI(t, &searches)
+	credentials := filepath.Join(t.TempDir(), "credentials.json")
+	t.Setenv("BEACON_TOKEN", "")
+	t.Setenv("BEACON_PASSWORD", "")
+
+	var stdout, stderr bytes.Buffer
+	a := &app{
+		stdin:  strings.NewReader("secret\n"),
+		stdout: &stdout,
+		stderr: &stderr,
+		now:    func() time.Time { return time.Date(2024, 5, 1, 13, 0, 0, 0, time.UTC) },
+	}
+	conn := []string{"-server", server.URL, "-credentials", credentials}
+
+	// Searching before logging in fails.
+	assert.Equal(t, 1, a.run(context.Background(), append([]string{"search"}, append(conn, "level:error")...)))
+	assert.Contains(t, stderr.String(), "beacon login")
+
+	require.Equal(t, 0, a.run(context.Background(), append(append([]string{"login"}, conn...), "-username", "alice")), stderr.String())
+
+	stdout.Reset()
+	args := append(append([]string{"search"}, conn...), "-from", "1h", "-o", "ndjson", "level:error")
+	require.Equal(t, 0, a.run(context.Background(), args), stderr.String())
+	assert.JSONEq(t, `{"timestamp":"2024-05-01T12:00:00Z","level":"error","message":"payment failed","labels":{"service":"api"}}`, stdout.String())
+	require.Len(t, searches, 1)
+	assert.Contains(t, searches[0], "from=2024-05-01T12%3A00%3A00Z")
+	assert.Contains(t, searches[0], "order=desc")
+
+	// Logging out removes the cached token.
+	require.Equal(t, 0, a.run(context.Background(), append([]string{"logout"}, conn...)))
+	assert.Equal(t, 1, a.run(context.Background(), append(append([]string{"search"}, conn...), "level:error")))
+}
+
+func TestRun_ExitCodes(t *testing.T) {
+	var searches []string
+	server := fakeAPI(t, &searches)
+	t.Setenv("BEACON_TOKEN", "alice-token")
+
+	tests := []struct {
+		name string
+		args []string
+		code int
+	}{
+		{"No command", nil, 2},
+		{"Unknown command", []string{"grep"}, 2},
+		{"Unknown flag", []string{"search", "-bogus", "x"}, 2},
+		{"Missing query", []string{"search", "-server", server.URL}, 2},
+		{"Help", []string{"search", "-h"}, 0},
+		{"Bad format", []string{"search", "-server", server.URL, "-o", "xml", "x"}, 1},
+		{"Bad time", []string{"search", "-server", server.URL, "-from", "yesterday", "x"}, 1},
+		{"Search", []string{"search", "-server", server.URL, "x"}, 0},
+		{"Wrong password", []string{"login", "-server", server.URL, "-username", "alice", "-password-stdin", "-credentials", filepath.Join(t.TempDir(), "c.json")}, 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var out bytes.Buffer
+			a := &app{stdin: strings.NewReader("wrong\n"), stdout: &out, stderr: &out, now: time.Now}
+			assert.Equal(t, tt.code, a.run(context.Background(), tt.args), out.String())
+		})
+	}
+}
+
+func TestParseTime(t *testing.T) {
+	now := time.Date(2024, 5, 1, 13, 0, 0, 0, time.UTC)
+
+	got, err := parseTime("15m", now)
+	require.NoError(t, err)
+	assert.Equal(t, now.Add(-15*time.Minute), got)
+
+	got, err = parseTime("2024-05-01T10:00:00Z", now)
+	require.NoError(t, err)
+	assert.Equal(t, time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC), got)
+
+	got, err = parseTime("", now)
+	require.NoError(t, err)
+	assert.True(t, got.IsZero())
+
+	_, err = parseTime("yesterday", now)
+	assert.Error(t, err)
+}
@@ -1,28 +1,41 @@
 package consumer
 
 import (
+	"context"
 	"encoding/json"
 	"log"
+	"time"
 
+	"log-beacon/internal/config"
 	"log-beacon/internal/model"
+	"log-beacon/internal/queue"
 	"log-beacon/cmd/hot-storage/internal/search"
 
 	"github.com/dgraph-io/badger/v4"
 	"github.com/google/uuid"
 	"github.com/nats-io/nats.go"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
 )
 
+var indexDuration = promauto.NewHistogram(prometheus.HistogramOpts{
+	Name: "beacon_index_duration_seconds",
+	Help: "Time to store and index a log entry.",
+})
+
 // Consumer handles subscribing to NATS and processing messages.
 type Consumer struct {
 	nc       *nats.Conn
 	js       nats.JetStreamContext
 	searcher *search.Searcher
+	stream   string
+	subject  string
 	Sub      *nats.Subscription
 }
 
 // NewConsumer creates a new NATS consumer.
-func NewConsumer(natsURL string, searcher *search.Searcher) (*Consumer, error) {
-	nc, err := nats.Connect(natsURL)
+func NewConsumer(cfg config.NATS, searcher *search.Searcher) (*Consumer, error) {
+	nc, err := nats.Connect(cfg.URL)
 	if err != nil {
 		return nil, err
 	}
@@ -31,24 +44,31 @@ func NewConsumer(natsURL string, searcher *search.Searcher) (*Consumer, error) {
 		nc.Close()
 		return nil, err
 	}
-	return &Consumer{nc: nc, js: js, searcher: searcher}, nil
+	return &Consumer{nc: nc, js: js, searcher: searcher, stream: cfg.Stream, subject: cfg.Subject}, nil
 }
 
 // Start begins listening for NATS messages.
 func (c *Consumer) Start() error {
 	var err error
-	c.Sub, err = c.js.QueueSubscribe("log.events", "hot-storage-processor", c.handleMessage, nats.Durable("hot-storage-processor"), nats.ManualAck())
+	c.Sub, err = queue.SubscribeDurable(c.js, c.stream, c.subject, "hot-storage-processor", c.handleMessage)
 	return err
 }
 
-// Close gracefully closes the NATS connection.
-func (c *Consumer) Close() {
-	if c.Sub != nil {
-		c.Sub.Unsubscribe()
-	}
-	if c.nc != nil {
-		c.nc.Close()
+// Close stops taking new messages and waits for the ones already delivered
+// to be handled before closing the NATS connection, so that no write is cut
+// off. Messages not yet delivered stay with the durable consumer for the
+// next run. If ctx expires first, the connection is closed at once.
+func (c *Consumer) Close(ctx context.Context) error {
+	return queue.Drain(ctx, c.nc)
+}
+
+// Check verifies that the connection, the stream and the durable consumer
+// are usable.
+func (c *Consumer) Check(ctx context.Context) error {
+	if err := queue.CheckStream(ctx, c.nc, c.js, c.stream); err != nil {
+		return err
 	}
+	return queue.CheckConsumer(ctx, c.Sub)
 }
 
 // handleMessage processes a single NATS message.
@@ -56,10 +76,12 @@ func (c *Consumer) handleMessage(msg *nats.Msg) {
 	var logEntry model.Log
 	if err := json.Unmarshal(msg.Data, &logEntry); err != nil {
 		log.Printf("Error unmarshalling log: %v", err)
+		queue.ObserveMessage(msg, queue.ResultInvalid)
 		msg.Ack()
 		return
 	}
 
+	start := time.Now()
 	logID := uuid.New().String()
 
 	err := c.searcher.DB.Update(func(txn *badger.Txn) error {
@@ -67,16 +89,20 @@ func (c *Consumer) handleMessage(msg *nats.Msg) {
 	})
 	if err != nil {
 		log.Printf("Error writing to BadgerDB: %v", err)
+		queue.ObserveMessage(msg, queue.ResultError)
 		msg.Nak()
 		return
 	}
 
 	if err := c.searcher.Index.Index(logID, logEntry); err != nil {
 		log.Printf("Error indexing in Bleve: %v", err)
+		queue.ObserveMessage(msg, queue.ResultError)
 		msg.Ack()
 		return
 	}
 
+	indexDuration.Observe(time.Since(start).Seconds())
+	queue.ObserveMessage(msg, queue.ResultOK)
 	log.Printf("Indexed log %s", logID)
 	msg.Ack()
 }
@@ -0,0 +1,127 @@
+package search
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"log-beacon/internal/model"
+
+	"github.com/blevesearch/bleve/v2"
+	"github.com/gin-gonic/gin"
+)
+
+// exportBatchSize is the number of hits fetched from the index per round trip.
+const exportBatchSize = 1000
+
+// HandleExport streams every log matching a query as NDJSON or CSV. Matches
+// are walked in timestamp order with search_after, so the export does not
+// degrade with depth like page-based search does. The export stops when the
+// client disconnects.
+//
+// Parameters are those of HandleSearch plus format=ndjson|csv and, for CSV,
+// columns: a comma-separated list of label keys added after the timestamp,
+// level and message columns.
+func (s *Searcher) HandleExport(c *gin.Context) {
+	query, order, ok := parseSearch(c)
+	if !ok {
+		return
+	}
+
+	var columns []string
+	for _, col := range strings.Split(c.Query("columns"), ",") {
+		if col = strings.TrimSpace(col); col != "" {
+			columns = append(columns, col)
+		}
+	}
+
+	var write func(model.Log) error
+	flush := func() error { return nil }
+	switch format := c.DefaultQuery("format", "ndjson"); format {
+	case "ndjson":
+		c.Header("Content-Type", "application/x-ndjson")
+		enc := json.NewEncoder(c.Writer)
+		write = func(l model.Log) error { return enc.Encode(l) }
+	case "csv":
+		c.Header("Content-Type", "text/csv; charset=utf-8")
+		w := csv.NewWriter(c.Writer)
+		header := append([]string{"timestamp", "level", "message"}, columns...)
+		if err := w.Write(header); err != nil {
+			return
+		}
+		write = func(l model.Log) error {
+			record := []string{l.Timestamp.UTC().Format(time.RFC3339Nano), l.Level, l.Message}
+			for _, col := range columns {
+				record = append(record, l.Labels[col])
+			}
+			return w.Write(record)
+		}
+		flush = func() error {
+			w.Flush()
+			return w.Error()
+		}
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Unsupported export format %q, expected ndjson or csv", format)})
+		return
+	}
+
+	// The ID breaks ties between equal timestamps so search_after never skips or repeats hits.
+	sortBy := []string{"timestamp", "_id"}
+	if order == "desc" {
+		sortBy = []string{"-timestamp", "-_id"}
+	}
+
+	ctx := c.Request.Context()
+	c.Status(http.StatusOK)
+	var after []string
+	exported := 0
+	for {
+		if ctx.Err() != nil {
+			log.Printf("Export cancelled by client after %d logs", exported)
+			return
+		}
+
+		req := bleve.NewSearchRequest(query)
+		req.Size = exportBatchSize
+		req.SortBy(sortBy)
+		if after != nil {
+			req.SetSearchAfter(after)
+		}
+		results, err := s.Index.SearchInContext(ctx, req)
+		if err != nil {
+			// The status line is already sent; a truncated body is all the client can see.
+			log.Printf("Export search failed after %d logs: %v", exported, err)
+			return
+		}
+		if len(results.Hits) == 0 {
+			break
+		}
+
+		logs, err := s.loadLogs(results.Hits)
+		if err != nil {
+			log.Printf("Export failed to load logs after %d logs: %v", exported, err)
+			return
+		}
+		for _, l := range logs {
+			if err := write(l); err != nil {
+				return
+			}
+		}
+		if err := flush(); err != nil {
+			return
+		}
+		c.Writer.Flush()
+		exported += len(logs)
+
+		if len(results.Hits) < exportBatchSize {
+			break
+		}
+		after = results.Hits[len(results.Hits)-1].Sort
+	}
+	flush()
+	c.Writer.Flush()
+}
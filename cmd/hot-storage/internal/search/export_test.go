@@ -0,0 +1,130 @@
+package search
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"log-beacon/internal/model"
+
+	"github.com/dgraph-io/badger/v4"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// indexLogs stores and indexes logs under sequential IDs.
+func indexLogs(t *testing.T, s *Searcher, logs []model.Log) {
+	t.Helper()
+	batch := s.Index.NewBatch()
+	err := s.DB.Update(func(txn *badger.Txn) error {
+		for i, l := range logs {
+			id := fmt.Sprintf("log-%05d", i)
+			val, err := json.Marshal(l)
+			if err != nil {
+				return err
+			}
+			if err := txn.Set([]byte(id), val); err != nil {
+				return err
+			}
+			if err := batch.Index(id, l); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	require.NoError(t, err)
+	require.NoError(t, s.Index.Batch(batch))
+}
+
+func TestHandleExport(t *testing.T) {
+	s, err := NewSearcher(t.TempDir()+"/test.bleve", t.TempDir()+"/test.badger")
+	require.NoError(t, err)
+	defer s.Close()
+
+	// More matches than one export batch, several sharing a timestamp.
+	base := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	var logs []model.Log
+	for i := 0; i < exportBatchSize+500; i++ {
+		level := "error"
+		if i%2 == 1 {
+			level = "info"
+		}
+		logs = append(logs, model.Log{
+			Timestamp: base.Add(time.Duration(i/3) * time.Second),
+			Level:     level,
+			Message:   fmt.Sprintf("event %d", i),
+			Labels:    map[string]string{"service": "api", "seq": fmt.Sprint(i)},
+		})
+	}
+	indexLogs(t, s, logs)
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.GET("/export", s.HandleExport)
+
+	export := func(params url.Values) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest("GET", "/export?"+params.Encode(), nil))
+		return w
+	}
+
+	t.Run("NDJSON", func(t *testing.T) {
+		w := export(url.Values{"q": {"level:error"}})
+		require.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, "application/x-ndjson", w.Header().Get("Content-Type"))
+
+		var seqs []string
+		scanner := bufio.NewScanner(w.Body)
+		for scanner.Scan() {
+			var l model.Log
+			require.NoError(t, json.Unmarshal(scanner.Bytes(), &l))
+			assert.Equal(t, "error", l.Level)
+			seqs = append(seqs, l.Labels["seq"])
+		}
+		require.Len(t, seqs, (exportBatchSize+500)/2)
+		// Every match exactly once, in timestamp order.
+		seen := make(map[string]bool)
+		for _, seq := range seqs {
+			assert.False(t, seen[seq], "duplicate %s", seq)
+			seen[seq] = true
+		}
+		assert.Equal(t, "0", seqs[0])
+	})
+
+	t.Run("CSV with time range and columns", func(t *testing.T) {
+		w := export(url.Values{
+			"q":       {"level:error"},
+			"format":  {"csv"},
+			"columns": {"service,seq,missing"},
+			"from":    {base.Add(time.Second).Format(time.RFC3339)},
+			"to":      {base.Add(3 * time.Second).Format(time.RFC3339)},
+			"order":   {"desc"},
+		})
+		require.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, "timestamp,level,message,service,seq,missing\n"+
+			"2024-05-01T12:00:02Z,error,event 8,api,8,\n"+
+			"2024-05-01T12:00:02Z,error,event 6,api,6,\n"+
+			"2024-05-01T12:00:01Z,error,event 4,api,4,\n", w.Body.String())
+	})
+
+	t.Run("Invalid format", func(t *testing.T) {
+		w := export(url.Values{"q": {"level:error"}, "format": {"xml"}})
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+
+	t.Run("Client disconnect", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest("GET", "/export?q=level:error", nil).WithContext(ctx))
+		assert.Empty(t, strings.TrimSpace(w.Body.String()))
+	})
+}
@@ -0,0 +1,22 @@
+package search
+
+import (
+	"context"
+	"errors"
+
+	"github.com/dgraph-io/badger/v4"
+)
+
+// CheckBadger verifies that the log store is open and readable.
+func (s *Searcher) CheckBadger(ctx context.Context) error {
+	if s.DB.IsClosed() {
+		return errors.New("badger is closed")
+	}
+	return s.DB.View(func(txn *badger.Txn) error { return nil })
+}
+
+// CheckBleve verifies that the index is open and readable.
+func (s *Searcher) CheckBleve(ctx context.Context) error {
+	_, err := s.Index.DocCount()
+	return err
+}
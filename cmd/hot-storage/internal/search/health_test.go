@@ -0,0 +1,22 @@
+package search
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestChecks(t *testing.T) {
+	s, err := NewSearcher(t.TempDir()+"/test.bleve", t.TempDir()+"/test.badger")
+	require.NoError(t, err)
+
+	ctx := context.Background()
+	assert.NoError(t, s.CheckBadger(ctx))
+	assert.NoError(t, s.CheckBleve(ctx))
+
+	s.Close()
+	assert.Error(t, s.CheckBadger(ctx))
+	assert.Error(t, s.CheckBleve(ctx))
+}
@@ -2,7 +2,10 @@ package search
 
 import (
 	"encoding/json"
+	"net/http"
+	"net/url"
 	"testing"
+	"time"
 
 	"log-beacon/internal/model"
 
@@ -122,3 +125,79 @@ func TestSearchIntegration(t *testing.T) {
 		})
 	}
 }
+
+func TestSearchTimeRange(t *testing.T) {
+	s, err := NewSearcher(t.TempDir()+"/test.bleve", t.TempDir()+"/test.badger")
+	require.NoError(t, err)
+	defer s.Close()
+
+	base := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	for i, msg := range []string{"first", "second", "third"} {
+		l := model.Log{Timestamp: base.Add(time.Duration(i) * time.Hour), Level: "error", Message: msg}
+		id := msg
+		val, _ := json.Marshal(l)
+		require.NoError(t, s.DB.Update(func(txn *badger.Txn) error {
+			return txn.Set([]byte(id), val)
+		}))
+		require.NoError(t, s.Index.Index(id, l))
+	}
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.GET("/search", s.HandleSearch)
+
+	tests := []struct {
+		name     string
+		params   url.Values
+		code     int
+		expected []string
+	}{
+		{
+			name:     "Ascending",
+			params:   url.Values{"q": {"level:error"}, "order": {"asc"}},
+			code:     http.StatusOK,
+			expected: []string{"first", "second", "third"},
+		},
+		{
+			name:     "Descending with lower bound",
+			params:   url.Values{"q": {"level:error"}, "order": {"desc"}, "from": {"2024-05-01T13:00:00Z"}},
+			code:     http.StatusOK,
+			expected: []string{"third", "second"},
+		},
+		{
+			name:     "Upper bound is exclusive",
+			params:   url.Values{"q": {"level:error"}, "from": {"2024-05-01T12:00:00Z"}, "to": {"2024-05-01T13:00:00Z"}},
+			code:     http.StatusOK,
+			expected: []string{"first"},
+		},
+		{
+			name:   "Invalid time",
+			params: url.Values{"q": {"level:error"}, "from": {"yesterday"}},
+			code:   http.StatusBadRequest,
+		},
+		{
+			name:   "Invalid order",
+			params: url.Values{"q": {"level:error"}, "order": {"random"}},
+			code:   http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, httptest.NewRequest("GET", "/search?"+tt.params.Encode(), nil))
+			require.Equal(t, tt.code, w.Code)
+			if tt.code != http.StatusOK {
+				return
+			}
+
+			var results []model.Log
+			require.NoError(t, json.Unmarshal(w.Body.Bytes(), &results))
+			var messages []string
+			for _, l := range results {
+				messages = append(messages, l.Message)
+			}
+			assert.Equal(t, tt.expected, messages)
+		})
+	}
+}
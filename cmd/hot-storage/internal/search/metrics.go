@@ -0,0 +1,53 @@
+package search
+
+import (
+	"io/fs"
+	"path/filepath"
+
+	"log-beacon/internal/metrics"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// RegisterMetrics reports the size of the index and the store in r. The
+// sizes are read on every scrape.
+func (s *Searcher) RegisterMetrics(r prometheus.Registerer) {
+	r.MustRegister(
+		metrics.NewGaugeFunc("beacon_badger_lsm_size_bytes", "Size of the BadgerDB LSM tree.", func() (float64, error) {
+			lsm, _ := s.DB.Size()
+			return float64(lsm), nil
+		}),
+		metrics.NewGaugeFunc("beacon_badger_vlog_size_bytes", "Size of the BadgerDB value log.", func() (float64, error) {
+			_, vlog := s.DB.Size()
+			return float64(vlog), nil
+		}),
+		metrics.NewGaugeFunc("beacon_bleve_size_bytes", "Size of the Bleve index on disk.", func() (float64, error) {
+			size, err := dirSize(s.blevePath)
+			return float64(size), err
+		}),
+		metrics.NewGaugeFunc("beacon_bleve_documents", "Documents in the Bleve index.", func() (float64, error) {
+			count, err := s.Index.DocCount()
+			return float64(count), err
+		}),
+	)
+}
+
+// dirSize returns the total size of the files under path.
+func dirSize(path string) (int64, error) {
+	var size int64
+	err := filepath.WalkDir(path, func(_ string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.IsDir() {
+			info, err := d.Info()
+			if err != nil {
+				// Segments are removed while the index merges them.
+				return nil
+			}
+			size += info.Size()
+		}
+		return nil
+	})
+	return size, err
+}
@@ -2,16 +2,18 @@ package search
 
 import (
 	"encoding/json"
+	"errors"
 	"log"
 	"net/http"
 	"regexp"
 	"strconv"
-
 	"strings"
+	"time"
 
 	"log-beacon/internal/model"
 
 	"github.com/blevesearch/bleve/v2"
+	"github.com/blevesearch/bleve/v2/search"
 	"github.com/blevesearch/bleve/v2/search/query"
 	"github.com/dgraph-io/badger/v4"
 	"github.com/gin-gonic/gin"
@@ -21,6 +23,8 @@ import (
 type Searcher struct {
 	Index bleve.Index
 	DB    *badger.DB
+
+	blevePath string
 }
 
 // NewSearcher creates a new searcher instance.
@@ -36,24 +40,26 @@ func NewSearcher(blevePath, badgerPath string) (*Searcher, error) {
 		return nil, err
 	}
 
-	return &Searcher{Index: index, DB: db}, nil
+	return &Searcher{Index: index, DB: db, blevePath: blevePath}, nil
 }
 
-// Close gracefully closes the database and index.
-func (s *Searcher) Close() {
+// Close gracefully closes the database and index, flushing pending writes
+// to disk.
+func (s *Searcher) Close() error {
+	var errs []error
 	if s.Index != nil {
-		s.Index.Close()
+		errs = append(errs, s.Index.Close())
 	}
 	if s.DB != nil {
-		s.DB.Close()
+		errs = append(errs, s.DB.Close())
 	}
+	return errors.Join(errs...)
 }
 
 // HandleSearch performs a paginated search against the index.
 func (s *Searcher) HandleSearch(c *gin.Context) {
-	queryStr := c.Query("q")
-	if queryStr == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Query parameter 'q' is required"})
+	query, order, ok := parseSearch(c)
+	if !ok {
 		return
 	}
 
@@ -67,11 +73,16 @@ func (s *Searcher) HandleSearch(c *gin.Context) {
 		size = 50 // Default and max size
 	}
 
-	// Build the Bleve search query.
-	query := parseQuery(queryStr)
+	// Build the Bleve search request.
 	searchRequest := bleve.NewSearchRequest(query)
 	searchRequest.Size = size
 	searchRequest.From = (page - 1) * size
+	switch order {
+	case "asc":
+		searchRequest.SortBy([]string{"timestamp"})
+	case "desc":
+		searchRequest.SortBy([]string{"-timestamp"})
+	}
 
 	// Execute the search.
 	searchResults, err := s.Index.Search(searchRequest)
@@ -80,9 +91,49 @@ func (s *Searcher) HandleSearch(c *gin.Context) {
 		return
 	}
 
+	results, err := s.loadLogs(searchResults.Hits)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve full logs"})
+		return
+	}
+
+	c.JSON(http.StatusOK, results)
+}
+
+// parseSearch reads the query, the optional time range and the ordering
+// shared by the search and export endpoints. On invalid parameters it
+// responds with 400 and returns false.
+func parseSearch(c *gin.Context) (query.Query, string, bool) {
+	queryStr := c.Query("q")
+	if queryStr == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Query parameter 'q' is required"})
+		return nil, "", false
+	}
+
+	from, err := parseTime(c.Query("from"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Query parameter 'from' must be an RFC 3339 timestamp"})
+		return nil, "", false
+	}
+	to, err := parseTime(c.Query("to"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Query parameter 'to' must be an RFC 3339 timestamp"})
+		return nil, "", false
+	}
+	order := c.Query("order")
+	if order != "" && order != "asc" && order != "desc" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Query parameter 'order' must be 'asc' or 'desc'"})
+		return nil, "", false
+	}
+
+	return withTimeRange(parseQuery(queryStr), from, to), order, true
+}
+
+// loadLogs reads the stored log entries of search hits from BadgerDB.
+func (s *Searcher) loadLogs(hits search.DocumentMatchCollection) ([]model.Log, error) {
 	var results []model.Log
-	err = s.DB.View(func(txn *badger.Txn) error {
-		for _, hit := range searchResults.Hits {
+	err := s.DB.View(func(txn *badger.Txn) error {
+		for _, hit := range hits {
 			item, err := txn.Get([]byte(hit.ID))
 			if err != nil {
 				return err
@@ -98,13 +149,7 @@ func (s *Searcher) HandleSearch(c *gin.Context) {
 		}
 		return nil
 	})
-
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve full logs"})
-		return
-	}
-
-	c.JSON(http.StatusOK, results)
+	return results, err
 }
 
 // parseQuery parses the query string and returns a Bleve query object.
@@ -121,6 +166,26 @@ func parseQuery(queryString string) query.Query {
 	return conj
 }
 
+// withTimeRange restricts q to logs with a timestamp in [from, to). A zero
+// bound leaves that side of the range open.
+func withTimeRange(q query.Query, from, to time.Time) query.Query {
+	if from.IsZero() && to.IsZero() {
+		return q
+	}
+	inclusive, exclusive := true, false
+	dateRange := bleve.NewDateRangeInclusiveQuery(from, to, &inclusive, &exclusive)
+	dateRange.SetField("timestamp")
+	return bleve.NewConjunctionQuery(q, dateRange)
+}
+
+// parseTime parses an optional RFC 3339 timestamp.
+func parseTime(value string) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	return time.Parse(time.RFC3339Nano, value)
+}
+
 // stripOuterParentheses removes the outer parentheses if the string is fully wrapped in them.
 func stripOuterParentheses(s string) string {
 	s = strings.TrimSpace(s)
@@ -149,14 +214,18 @@ func stripOuterParentheses(s string) string {
 	return s
 }
 
-var fieldRegex = regexp.MustCompile(`\b([a-zA-Z0-9_]+):`)
+var fieldRegex = regexp.MustCompile(`\b([a-zA-Z0-9_][a-zA-Z0-9_.\-]*):`)
 
 // rewriteQuery rewrites field names that are not top-level fields to be under "labels.".
+// Dotted names such as "http.status" refer to flattened labels.
 func rewriteQuery(q string) string {
 	return fieldRegex.ReplaceAllStringFunc(q, func(match string) string {
 		// match is like "service:"
-		field := match[:len(match)-1]
-		switch strings.ToLower(field) {
+		field := strings.ToLower(match[:len(match)-1])
+		if strings.HasPrefix(field, "labels.") {
+			return match
+		}
+		switch field {
 		case "level", "message", "timestamp", "labels":
 			return match
 		default:
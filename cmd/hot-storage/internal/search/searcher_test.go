@@ -78,3 +78,22 @@ func TestQueryStringQueryParentheses(t *testing.T) {
 	require.NoError(t, err)
 	assert.Equal(t, 2, int(res.Total))
 }
+
+func TestRewriteQuery(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{"level:error", "level:error"},
+		{"service:auth", "labels.service:auth"},
+		{"http.status:500", "labels.http.status:500"},
+		{"labels.service:auth", "labels.service:auth"},
+		{"level:error AND k8s.pod-name:api", "level:error AND labels.k8s.pod-name:api"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			assert.Equal(t, tt.want, rewriteQuery(tt.input))
+		})
+	}
+}
@@ -7,8 +7,11 @@ import (
 	"time"
 
 	"log-beacon/cmd/hot-storage/internal/search"
+	"log-beacon/internal/health"
+	"log-beacon/internal/metrics"
 
 	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
 )
 
 // Server wraps the internal HTTP server.
@@ -16,11 +19,17 @@ type Server struct {
 	httpSrv *http.Server
 }
 
-// NewServer creates a new internal HTTP server.
-func NewServer(addr string, searcher *search.Searcher) *Server {
+// NewServer creates a new internal HTTP server. Readiness is reported by
+// the checks of checker.
+func NewServer(addr string, searcher *search.Searcher, checker *health.Checker) *Server {
 	gin.SetMode(gin.ReleaseMode)
 	router := gin.Default()
+	router.Use(metrics.Middleware())
 	router.GET("/search", searcher.HandleSearch)
+	router.GET("/export", searcher.HandleExport)
+	router.GET("/metrics", gin.WrapH(promhttp.Handler()))
+	router.GET("/livez", gin.WrapF(health.Live))
+	router.GET("/readyz", gin.WrapH(checker))
 
 	httpSrv := &http.Server{
 		Addr:    addr,
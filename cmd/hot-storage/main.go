@@ -1,41 +1,44 @@
 package main
 
 import (
+	"context"
 	"log"
 	"os"
 	"os/signal"
 	"syscall"
+	"time"
 
 	"log-beacon/cmd/hot-storage/internal/consumer"
 	"log-beacon/cmd/hot-storage/internal/search"
 	"log-beacon/cmd/hot-storage/internal/server"
-)
+	"log-beacon/internal/config"
+	"log-beacon/internal/health"
+	"log-beacon/internal/queue"
 
-const (
-	blevePath  = "/data/logs.bleve"
-	badgerPath = "/data/badger"
+	"github.com/prometheus/client_golang/prometheus"
 )
 
 func main() {
 	// --- Initialization ---
-	searcher, err := search.NewSearcher(blevePath, badgerPath)
+	cfg := config.MustLoad(config.ServiceHotStorage)
+
+	searcher, err := search.NewSearcher(cfg.HotStorage.BlevePath, cfg.HotStorage.BadgerPath)
 	if err != nil {
 		log.Fatalf("Failed to create searcher: %v", err)
 	}
-	defer searcher.Close()
-
-	natsURL := os.Getenv("NATS_URL")
-	if natsURL == "" {
-		log.Fatal("NATS_URL environment variable not set.")
-	}
 
-	consumer, err := consumer.NewConsumer(natsURL, searcher)
+	consumer, err := consumer.NewConsumer(cfg.NATS, searcher)
 	if err != nil {
 		log.Fatalf("Failed to create NATS consumer: %v", err)
 	}
-	defer consumer.Close()
 
-	srv := server.NewServer(":8081", searcher)
+	// Ready once the store and index are open and the consumer is subscribed.
+	checker := health.NewChecker(0)
+	checker.Add("badger", searcher.CheckBadger)
+	checker.Add("bleve", searcher.CheckBleve)
+	checker.Add("nats", consumer.Check)
+
+	srv := server.NewServer(cfg.HotStorage.Addr, searcher, checker)
 
 	// --- Start Services ---
 	srv.Start()
@@ -43,6 +46,10 @@ func main() {
 		log.Fatalf("Failed to start NATS consumer: %v", err)
 	}
 
+	// Report storage sizes and consumer lag at /metrics.
+	searcher.RegisterMetrics(prometheus.DefaultRegisterer)
+	queue.RegisterConsumerLag(prometheus.DefaultRegisterer, consumer.Sub)
+
 	log.Println("Hot-storage service is running.")
 
 	// --- Graceful Shutdown ---
@@ -52,6 +59,15 @@ func main() {
 
 	log.Println("Shutting down hot-storage service...")
 	srv.Stop()
-	// Consumer and Searcher are closed by their deferred calls
+	// Finish the messages already delivered before closing the store and
+	// index they are written to.
+	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(cfg.HotStorage.ShutdownTimeout))
+	defer cancel()
+	if err := consumer.Close(ctx); err != nil {
+		log.Printf("Failed to drain NATS consumer: %v", err)
+	}
+	if err := searcher.Close(); err != nil {
+		log.Printf("Failed to close searcher: %v", err)
+	}
 	log.Println("Hot-storage service shut down gracefully.")
 }
\ No newline at end of file
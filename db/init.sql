@@ -10,3 +10,77 @@ CREATE TABLE IF NOT EXISTS users (
 
 -- Index for faster username lookups during login
 CREATE INDEX IF NOT EXISTS idx_users_username ON users(username);
+
+-- Alert rules evaluated against the log stream
+CREATE TABLE IF NOT EXISTS alert_rules (
+    id BIGSERIAL PRIMARY KEY,
+    name VARCHAR(255) NOT NULL,
+    query TEXT NOT NULL,
+    threshold INTEGER NOT NULL,
+    window_seconds BIGINT NOT NULL,
+    group_by VARCHAR(255) NOT NULL DEFAULT '',
+    enabled BOOLEAN NOT NULL DEFAULT TRUE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Silences suppress notifications of matching alerts for a time range
+CREATE TABLE IF NOT EXISTS alert_silences (
+    id BIGSERIAL PRIMARY KEY,
+    rule_id BIGINT NOT NULL DEFAULT 0,
+    group_value VARCHAR(255) NOT NULL DEFAULT '',
+    comment TEXT NOT NULL DEFAULT '',
+    created_by VARCHAR(255) NOT NULL,
+    starts_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    ends_at TIMESTAMP WITH TIME ZONE NOT NULL
+);
+
+-- Firing and resolved alerts. API instances evaluate the same rules, so
+-- there is at most one firing alert per rule and group, and notified records
+-- the last state announced.
+CREATE TABLE IF NOT EXISTS alerts (
+    id BIGSERIAL PRIMARY KEY,
+    rule_id BIGINT NOT NULL,
+    rule_name VARCHAR(255) NOT NULL,
+    group_value VARCHAR(255) NOT NULL DEFAULT '',
+    state VARCHAR(16) NOT NULL,
+    count INTEGER NOT NULL,
+    threshold INTEGER NOT NULL,
+    silenced BOOLEAN NOT NULL DEFAULT FALSE,
+    starts_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    resolved_at TIMESTAMP WITH TIME ZONE,
+    notified VARCHAR(16) NOT NULL DEFAULT ''
+);
+
+CREATE INDEX IF NOT EXISTS idx_alerts_state ON alerts(state);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_alerts_firing ON alerts(rule_id, group_value) WHERE state = 'firing';
+
+-- Notification attempts for alerts, per channel
+CREATE TABLE IF NOT EXISTS alert_deliveries (
+    id BIGSERIAL PRIMARY KEY,
+    alert_id BIGINT NOT NULL,
+    rule_name VARCHAR(255) NOT NULL,
+    alert_state VARCHAR(16) NOT NULL,
+    channel VARCHAR(255) NOT NULL,
+    status VARCHAR(16) NOT NULL,
+    attempts INTEGER NOT NULL,
+    error TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+CREATE INDEX IF NOT EXISTS idx_alert_deliveries_alert_id ON alert_deliveries(alert_id);
+
+-- Saved searches, shared by their random IDs
+CREATE TABLE IF NOT EXISTS saved_searches (
+    id VARCHAR(32) PRIMARY KEY,
+    name VARCHAR(255) NOT NULL,
+    query TEXT NOT NULL,
+    time_from VARCHAR(64) NOT NULL DEFAULT '',
+    time_to VARCHAR(64) NOT NULL DEFAULT '',
+    columns TEXT[] NOT NULL DEFAULT '{}',
+    owner VARCHAR(255) NOT NULL,
+    visibility VARCHAR(16) NOT NULL DEFAULT 'private',
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_saved_searches_owner ON saved_searches(owner);
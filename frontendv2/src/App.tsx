@@ -4,7 +4,20 @@ import Header from './components/Header';
 import Sidebar from './components/Sidebar';
 import LogList from './components/LogList';
 import Auth from './components/Auth';
-import { type LogEntry } from './types';
+import { type LogEntry, type SavedSearch } from './types';
+
+// resolveTimeBound turns a saved search bound, an RFC 3339 timestamp or a Go
+// duration before now such as 15m or 1h30m, into a timestamp.
+const resolveTimeBound = (bound: string): string | undefined => {
+  if (!bound) return undefined;
+  const units: Record<string, number> = { ms: 1, s: 1000, m: 60000, h: 3600000 };
+  const parts = [...bound.matchAll(/(\d+(?:\.\d+)?)(ms|s|m|h)/g)];
+  if (parts.length > 0 && parts.map(p => p[0]).join('') === bound) {
+    const ago = parts.reduce((sum, p) => sum + parseFloat(p[1]) * units[p[2]], 0);
+    return new Date(Date.now() - ago).toISOString();
+  }
+  return bound;
+};
 
 function App() {
   const [token, setToken] = useState<string | null>(localStorage.getItem('token'));
@@ -12,6 +25,7 @@ function App() {
   const [isAuthLoading, setIsAuthLoading] = useState(true);
 
   const [query, setQuery] = useState('');
+  const [savedSearch, setSavedSearch] = useState<SavedSearch | null>(null);
   const [selectedLevels, setSelectedLevels] = useState<string[]>([]);
   const [logs, setLogs] = useState<LogEntry[]>([]);
   const [isLoading, setIsLoading] = useState(false);
@@ -72,7 +86,13 @@ function App() {
 
       ws.onmessage = (event) => {
         try {
-          const logEntry: LogEntry = JSON.parse(event.data);
+          const message = JSON.parse(event.data);
+          if (message.type === 'dropped') {
+            // The server skipped logs because this tab fell behind.
+            console.warn(`Live Tail dropped ${message.dropped} logs`);
+            return;
+          }
+          const logEntry: LogEntry = message;
           setLogs(prevLogs => {
             const newLogs = [logEntry, ...prevLogs];
             if (newLogs.length > 1000) {
@@ -115,7 +135,7 @@ function App() {
     setIsLiveTail(!isLiveTail);
   };
 
-  const handleSearch = async () => {
+  const runSearch = async (query: string, saved: SavedSearch | null) => {
     if (isLiveTail) return;
 
     if (!query.trim()) {
@@ -138,7 +158,16 @@ function App() {
         }
       }
 
-      const response = await axios.get<LogEntry[]>(`/api/v1/search?q=${encodeURIComponent(finalQuery)}&size=50`, {
+      const params: Record<string, string> = { q: finalQuery, size: '50' };
+      // A loaded saved search keeps its time range while its query is unchanged.
+      if (saved && saved.query === query) {
+        const from = resolveTimeBound(saved.from);
+        const to = resolveTimeBound(saved.to);
+        if (from) params.from = from;
+        if (to) params.to = to;
+      }
+      const response = await axios.get<LogEntry[]>('/api/v1/search', {
+        params,
         headers: {
           Authorization: `Bearer ${token}`
         }
@@ -157,6 +186,58 @@ function App() {
     }
   };
 
+  const handleSearch = () => runSearch(query, savedSearch);
+
+  // Open the saved search shared as ?search=<id>.
+  useEffect(() => {
+    const id = new URLSearchParams(window.location.search).get('search');
+    if (!id || !token) return;
+    const loadSavedSearch = async () => {
+      try {
+        const response = await axios.get<SavedSearch>(`/api/v1/saved-searches/${encodeURIComponent(id)}`, {
+          headers: { Authorization: `Bearer ${token}` }
+        });
+        setSavedSearch(response.data);
+        setQuery(response.data.query);
+        runSearch(response.data.query, response.data);
+      } catch (err: any) {
+        console.error('Failed to load saved search:', err);
+        setError(err.response?.status === 404 ? 'Saved search not found.' : 'Failed to load saved search.');
+      }
+    };
+    loadSavedSearch();
+    // Only on sign-in: later searches replace the loaded one.
+    // eslint-disable-next-line react-hooks/exhaustive-deps
+  }, [token]);
+
+  // Save the current query as a shared search and put its link in the address bar.
+  const handleSave = async () => {
+    if (!query.trim()) return;
+    const name = window.prompt('Name this search', savedSearch?.name ?? query);
+    if (!name) return;
+    try {
+      const keepRange = savedSearch && savedSearch.query === query;
+      const response = await axios.post<SavedSearch>('/api/v1/saved-searches', {
+        name,
+        query,
+        from: keepRange ? savedSearch.from : '',
+        to: keepRange ? savedSearch.to : '',
+        columns: keepRange ? savedSearch.columns : [],
+        visibility: 'shared',
+      }, {
+        headers: { Authorization: `Bearer ${token}` }
+      });
+      setSavedSearch(response.data);
+      const url = new URL(window.location.href);
+      url.searchParams.set('search', response.data.id);
+      window.history.replaceState(null, '', url);
+      navigator.clipboard?.writeText(url.toString()).catch(() => {});
+    } catch (err: any) {
+      console.error('Failed to save search:', err);
+      setError('Failed to save search.');
+    }
+  };
+
   if (isAuthLoading) {
     return (
       <div className="flex h-screen items-center justify-center bg-background-dark text-white">
@@ -175,6 +256,7 @@ function App() {
         query={query}
         setQuery={setQuery}
         onSearch={handleSearch}
+        onSave={handleSave}
         isLiveTail={isLiveTail}
         onToggleLiveTail={toggleLiveTail}
         onLogout={handleLogout}
@@ -4,12 +4,13 @@ interface HeaderProps {
     query: string;
     setQuery: (query: string) => void;
     onSearch: () => void;
+    onSave: () => void;
     isLiveTail: boolean;
     onToggleLiveTail: () => void;
     onLogout: () => void;
 }
 
-const Header: React.FC<HeaderProps> = ({ query, setQuery, onSearch, isLiveTail, onToggleLiveTail, onLogout }) => {
+const Header: React.FC<HeaderProps> = ({ query, setQuery, onSearch, onSave, isLiveTail, onToggleLiveTail, onLogout }) => {
     const handleKeyDown = (e: React.KeyboardEvent) => {
         if (e.key === 'Enter' && !isLiveTail) {
             onSearch();
@@ -50,6 +51,15 @@ const Header: React.FC<HeaderProps> = ({ query, setQuery, onSearch, isLiveTail,
                 </label>
             </div>
             <div className="flex flex-initial items-center justify-end gap-2">
+                <button
+                    className={`flex max-w-[480px] cursor-pointer items-center justify-center overflow-hidden rounded-lg h-10 bg-panel-dark text-text-light gap-2 text-sm font-bold leading-normal tracking-[0.015em] min-w-0 px-4 ${isLiveTail || !query.trim() ? 'opacity-50 cursor-not-allowed' : ''}`}
+                    onClick={onSave}
+                    disabled={isLiveTail || !query.trim()}
+                    title="Save this search and copy a link to it"
+                >
+                    <span className="material-symbols-outlined">bookmark_add</span>
+                    <span className="truncate">Save</span>
+                </button>
                 <button
                     className={`flex max-w-[480px] cursor-pointer items-center justify-center overflow-hidden rounded-lg h-10 gap-2 text-sm font-bold leading-normal tracking-[0.015em] min-w-0 px-4 ${isLiveTail ? 'bg-green-600 text-white animate-pulse' : 'bg-panel-dark text-text-light'}`}
                     onClick={onToggleLiveTail}
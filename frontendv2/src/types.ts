@@ -9,3 +9,14 @@ export interface SearchResponse {
     data: LogEntry[];
     total: number;
 }
+
+export interface SavedSearch {
+    id: string;
+    name: string;
+    query: string;
+    from: string;
+    to: string;
+    columns: string[];
+    owner: string;
+    visibility: 'private' | 'shared';
+}
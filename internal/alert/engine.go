@@ -0,0 +1,389 @@
+package alert
+
+import (
+	"context"
+	"errors"
+	"log"
+	"sync"
+	"time"
+
+	"log-beacon/internal/config"
+	"log-beacon/internal/model"
+)
+
+// DefaultEvalInterval is how often the engine evaluates rules by default.
+const DefaultEvalInterval = config.DefaultAlertEvalInterval
+
+// maxGroups bounds the groups counted per rule, so that grouping by a label
+// with unbounded values cannot exhaust memory. Entries of further groups are
+// not counted.
+const maxGroups = 10000
+
+// Notifier is told when an alert starts firing and when it resolves.
+type Notifier interface {
+	Notify(ctx context.Context, alert Alert) error
+}
+
+// LogNotifier writes alert notifications to the process log.
+type LogNotifier struct{}
+
+// Notify implements Notifier.
+func (LogNotifier) Notify(ctx context.Context, a Alert) error {
+	log.Printf("Alert %q %s (group %q): %d matches, threshold %d", a.RuleName, a.State, a.Group, a.Count, a.Threshold)
+	return nil
+}
+
+// Engine counts the log entries matching each enabled rule and turns counts
+// above a rule's threshold into alerts. An alert is raised once per rule and
+// group and notified once when it starts firing and once when it resolves;
+// silenced alerts are tracked but not notified. Rules and silences are
+// reloaded from the store on every evaluation, so changes made through any
+// API instance take effect within one interval. Every instance counts the
+// whole stream; the store merges their alerts and lets one of them notify.
+type Engine struct {
+	store    Store
+	notifier Notifier
+	interval time.Duration
+	// now returns the current time. It is replaced in tests.
+	now func() time.Time
+
+	mu       sync.Mutex
+	rules    map[int64]*ruleState
+	silences []Silence
+	active   map[alertKey]*activeAlert
+	// loaded is set once the alerts left firing by a previous run were restored.
+	loaded bool
+}
+
+type alertKey struct {
+	ruleID int64
+	group  string
+}
+
+type activeAlert struct {
+	Alert
+	// notified is set once the alert's firing was announced, so that its
+	// resolution is only announced if its start was.
+	notified bool
+}
+
+type ruleState struct {
+	Rule
+	query *Query
+	// width is the time span of each counting bucket.
+	width   time.Duration
+	windows map[string]*window
+}
+
+// window counts entries over time in fixed-width buckets, oldest first.
+type window struct {
+	buckets []bucket
+}
+
+type bucket struct {
+	start time.Time
+	count int
+}
+
+// NewEngine creates an engine evaluating the rules of store every interval
+// and sending notifications to notifier. A zero interval uses DefaultEvalInterval.
+func NewEngine(store Store, notifier Notifier, interval time.Duration) *Engine {
+	if interval <= 0 {
+		interval = DefaultEvalInterval
+	}
+	return &Engine{
+		store:    store,
+		notifier: notifier,
+		interval: interval,
+		now:      time.Now,
+		rules:    make(map[int64]*ruleState),
+		active:   make(map[alertKey]*activeAlert),
+	}
+}
+
+// Run counts the entries of events and evaluates rules every interval until
+// ctx is done or events is closed.
+func (e *Engine) Run(ctx context.Context, events <-chan model.TailEvent) error {
+	if err := e.Reload(); err != nil {
+		log.Printf("Failed to load alert rules: %v", err)
+	}
+
+	ticker := time.NewTicker(e.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case event, ok := <-events:
+			if !ok {
+				return errors.New("log stream closed")
+			}
+			e.Observe(event.Log)
+		case <-ticker.C:
+			if err := e.Reload(); err != nil {
+				// Keep evaluating the rules loaded last.
+				log.Printf("Failed to reload alert rules: %v", err)
+			}
+			e.Evaluate(ctx)
+		}
+	}
+}
+
+// Reload reads rules and silences from the store. Counts are kept for rules
+// whose query, window and grouping did not change. The first successful
+// reload also restores the alerts a previous run left firing.
+func (e *Engine) Reload() error {
+	rules, err := e.store.ListRules()
+	if err != nil {
+		return err
+	}
+	silences, err := e.store.ListSilences()
+	if err != nil {
+		return err
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if !e.loaded {
+		firing, err := e.store.ListAlerts(StateFiring, 0)
+		if err != nil {
+			return err
+		}
+		for _, a := range firing {
+			e.active[alertKey{ruleID: a.RuleID, group: a.Group}] = &activeAlert{Alert: a, notified: !a.Silenced}
+		}
+		e.loaded = true
+	}
+
+	states := make(map[int64]*ruleState, len(rules))
+	for _, rule := range rules {
+		if !rule.Enabled {
+			continue
+		}
+		query, err := rule.Validate()
+		if err != nil {
+			log.Printf("Skipping invalid alert rule %d: %v", rule.ID, err)
+			continue
+		}
+		state := e.rules[rule.ID]
+		if state == nil || state.Query != rule.Query || state.Window != rule.Window || state.GroupBy != rule.GroupBy {
+			state = &ruleState{width: bucketWidth(rule.Window), windows: make(map[string]*window)}
+		}
+		state.Rule = rule
+		state.query = query
+		states[rule.ID] = state
+	}
+	e.rules = states
+	e.silences = silences
+	return nil
+}
+
+// Observe counts an entry against every rule it matches.
+func (e *Engine) Observe(l model.Log) {
+	now := e.now()
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	for _, rule := range e.rules {
+		if !rule.query.Match(l) {
+			continue
+		}
+		group := rule.group(l)
+		w, ok := rule.windows[group]
+		if !ok {
+			if len(rule.windows) >= maxGroups {
+				continue
+			}
+			w = &window{}
+			rule.windows[group] = w
+		}
+		w.add(now, rule.width)
+	}
+}
+
+// Evaluate compares the counts of every rule with its threshold, fires and
+// resolves alerts accordingly, saves them and sends the due notifications.
+// The store is only called once the counts are evaluated and the lock is
+// released, so that a slow store does not hold up Observe.
+func (e *Engine) Evaluate(ctx context.Context) {
+	now := e.now()
+
+	e.mu.Lock()
+	firing := make(map[alertKey]bool)
+	for id, rule := range e.rules {
+		for group, w := range rule.windows {
+			count := w.count(now, time.Duration(rule.Window), rule.width)
+			if count == 0 {
+				delete(rule.windows, group)
+			}
+			if count <= rule.Threshold {
+				continue
+			}
+
+			key := alertKey{ruleID: id, group: group}
+			firing[key] = true
+			a, ok := e.active[key]
+			if !ok {
+				a = &activeAlert{Alert: Alert{RuleID: id, Group: group, State: StateFiring, StartsAt: now}}
+				e.active[key] = a
+			}
+			// Above threshold again before its resolution could be saved.
+			a.State, a.ResolvedAt = StateFiring, nil
+			a.RuleName = rule.Name
+			a.Threshold = rule.Threshold
+			a.Count = count
+		}
+	}
+
+	updates := make([]alertUpdate, 0, len(e.active))
+	for key, a := range e.active {
+		if firing[key] {
+			a.Silenced = e.silenced(&a.Alert, now)
+		} else if a.State != StateResolved {
+			// Below threshold again, or the rule was disabled or deleted.
+			resolvedAt := now
+			a.State = StateResolved
+			a.ResolvedAt = &resolvedAt
+		}
+		updates = append(updates, alertUpdate{key: key, active: a, alert: a.Alert, notified: a.notified})
+	}
+	e.mu.Unlock()
+
+	var notifications []Alert
+	for _, u := range updates {
+		if a, ok := e.save(u, now); ok {
+			notifications = append(notifications, a)
+		}
+	}
+
+	for _, a := range notifications {
+		claimed, err := e.store.ClaimNotification(&a)
+		if err != nil {
+			log.Printf("Failed to claim notification for rule %q: %v", a.RuleName, err)
+			continue
+		}
+		if !claimed {
+			// Announced by another API instance.
+			continue
+		}
+		if err := e.notifier.Notify(ctx, a); err != nil {
+			log.Printf("Failed to notify alert for rule %q: %v", a.RuleName, err)
+			e.releaseNotification(a)
+		}
+	}
+}
+
+// alertUpdate is an active alert as evaluated, to be saved outside the lock.
+type alertUpdate struct {
+	key      alertKey
+	active   *activeAlert
+	alert    Alert
+	notified bool
+}
+
+// save stores an evaluated alert and applies the outcome to the active
+// alert. It returns the alert and true when a notification is due.
+func (e *Engine) save(u alertUpdate, now time.Time) (Alert, bool) {
+	a := u.alert
+	if a.State == StateResolved {
+		err := e.store.SaveAlert(&a)
+		if err != nil && !errors.Is(err, ErrNotFound) {
+			// Keep it active, already resolved, to retry at the next evaluation.
+			log.Printf("Failed to save alert for rule %q: %v", a.RuleName, err)
+			return Alert{}, false
+		}
+		e.mu.Lock()
+		if e.active[u.key] == u.active && u.active.State == StateResolved {
+			delete(e.active, u.key)
+		}
+		e.mu.Unlock()
+		// ErrNotFound: another instance resolved it already.
+		return a, err == nil && u.notified
+	}
+
+	notified := u.notified
+	err := e.store.SaveAlert(&a)
+	if errors.Is(err, ErrNotFound) {
+		// Another instance resolved it while this one still counts above
+		// the threshold: a new episode.
+		a.ID, a.StartsAt, notified = 0, now, false
+		err = e.store.SaveAlert(&a)
+	}
+	if err != nil {
+		log.Printf("Failed to save alert for rule %q: %v", a.RuleName, err)
+		return Alert{}, false
+	}
+	// An alert silenced when it started is announced once its silence ends.
+	due := !notified && !a.Silenced
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.active[u.key] != u.active {
+		return Alert{}, false
+	}
+	u.active.ID, u.active.StartsAt = a.ID, a.StartsAt
+	u.active.notified = notified || due
+	return a, due
+}
+
+// releaseNotification lets a failed announcement be claimed again, by this
+// instance at its next evaluation or by another one. A notification that was
+// queued for some channels only may then be sent twice, rather than never.
+func (e *Engine) releaseNotification(a Alert) {
+	if err := e.store.ReleaseNotification(&a); err != nil {
+		log.Printf("Failed to release notification for rule %q: %v", a.RuleName, err)
+		return
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if active := e.active[alertKey{ruleID: a.RuleID, group: a.Group}]; active != nil && active.ID == a.ID && a.State == StateFiring {
+		active.notified = false
+	}
+}
+
+// silenced reports whether an active silence covers a.
+func (e *Engine) silenced(a *Alert, now time.Time) bool {
+	for i := range e.silences {
+		if e.silences[i].Matches(a, now) {
+			return true
+		}
+	}
+	return false
+}
+
+// bucketWidth splits a window into about 60 buckets of at least a second.
+func bucketWidth(window Duration) time.Duration {
+	width := time.Duration(window) / 60
+	if width < time.Second {
+		width = time.Second
+	}
+	return width
+}
+
+func (w *window) add(now time.Time, width time.Duration) {
+	start := now.Truncate(width)
+	if n := len(w.buckets); n > 0 && w.buckets[n-1].start.Equal(start) {
+		w.buckets[n-1].count++
+		return
+	}
+	w.buckets = append(w.buckets, bucket{start: start, count: 1})
+}
+
+// count drops the buckets that ended before the window and sums the rest.
+func (w *window) count(now time.Time, window, width time.Duration) int {
+	cutoff := now.Add(-window)
+	i := 0
+	for i < len(w.buckets) && !w.buckets[i].start.Add(width).After(cutoff) {
+		i++
+	}
+	w.buckets = w.buckets[i:]
+
+	total := 0
+	for _, b := range w.buckets {
+		total += b.count
+	}
+	return total
+}
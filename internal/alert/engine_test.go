@@ -0,0 +1,408 @@
+package alert
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"log-beacon/internal/model"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// recordingNotifier keeps the notifications it receives.
+type recordingNotifier struct {
+	mu     sync.Mutex
+	alerts []Alert
+	// err fails the notifications, which are then not recorded.
+	err error
+}
+
+func (n *recordingNotifier) Notify(ctx context.Context, a Alert) error {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	if n.err != nil {
+		return n.err
+	}
+	n.alerts = append(n.alerts, a)
+	return nil
+}
+
+// states returns "<group>:<state>" for every notification received.
+func (n *recordingNotifier) states() []string {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	states := []string{}
+	for _, a := range n.alerts {
+		states = append(states, a.Group+":"+a.State)
+	}
+	return states
+}
+
+// testEngine returns an engine on a memory store with a controllable clock.
+func testEngine(t *testing.T, rules ...Rule) (*Engine, *MemoryStore, *recordingNotifier, *time.Time) {
+	t.Helper()
+	store := NewMemoryStore()
+	for i := range rules {
+		rules[i].Enabled = true
+		require.NoError(t, store.CreateRule(&rules[i]))
+	}
+	notifier := &recordingNotifier{}
+	e := NewEngine(store, notifier, time.Second)
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	e.now = func() time.Time { return now }
+	require.NoError(t, e.Reload())
+	return e, store, notifier, &now
+}
+
+func observe(e *Engine, n int, l model.Log) {
+	for i := 0; i < n; i++ {
+		e.Observe(l)
+	}
+}
+
+func TestEngine_FiresAndResolves(t *testing.T) {
+	e, store, notifier, now := testEngine(t, Rule{
+		Name:      "payment errors",
+		Query:     "service:payments AND level:error",
+		Threshold: 3,
+		Window:    Duration(5 * time.Minute),
+	})
+	paymentError := model.Log{Level: "error", Labels: map[string]string{"service": "payments"}}
+	ctx := context.Background()
+
+	// At the threshold nothing fires, and other entries are not counted.
+	observe(e, 3, paymentError)
+	observe(e, 10, model.Log{Level: "info", Labels: map[string]string{"service": "payments"}})
+	e.Evaluate(ctx)
+	assert.Empty(t, notifier.states())
+
+	observe(e, 1, paymentError)
+	e.Evaluate(ctx)
+	assert.Equal(t, []string{":firing"}, notifier.states())
+
+	// Still firing: saved with the latest count but not notified again.
+	*now = now.Add(time.Minute)
+	observe(e, 2, paymentError)
+	e.Evaluate(ctx)
+	assert.Equal(t, []string{":firing"}, notifier.states())
+	alerts, err := store.ListAlerts(StateFiring, 0)
+	require.NoError(t, err)
+	require.Len(t, alerts, 1)
+	assert.Equal(t, 6, alerts[0].Count)
+	assert.Equal(t, "payment errors", alerts[0].RuleName)
+
+	// The first four entries leave the window, counted in buckets of a 60th of it.
+	*now = now.Add(4*time.Minute + 5*time.Second)
+	e.Evaluate(ctx)
+	assert.Equal(t, []string{":firing", ":resolved"}, notifier.states())
+	alerts, err = store.ListAlerts("", 0)
+	require.NoError(t, err)
+	require.Len(t, alerts, 1)
+	assert.Equal(t, StateResolved, alerts[0].State)
+	require.NotNil(t, alerts[0].ResolvedAt)
+	assert.Equal(t, *now, *alerts[0].ResolvedAt)
+
+	// A new episode is a new alert.
+	observe(e, 4, paymentError)
+	e.Evaluate(ctx)
+	assert.Equal(t, []string{":firing", ":resolved", ":firing"}, notifier.states())
+	alerts, err = store.ListAlerts("", 0)
+	require.NoError(t, err)
+	assert.Len(t, alerts, 2)
+}
+
+func TestEngine_GroupBy(t *testing.T) {
+	e, _, notifier, _ := testEngine(t, Rule{
+		Name:      "errors per service",
+		Query:     "level:error",
+		Threshold: 1,
+		Window:    Duration(time.Minute),
+		GroupBy:   "service",
+	})
+
+	observe(e, 2, model.Log{Level: "error", Labels: map[string]string{"service": "payments"}})
+	observe(e, 1, model.Log{Level: "error", Labels: map[string]string{"service": "auth"}})
+	e.Evaluate(context.Background())
+	assert.Equal(t, []string{"payments:firing"}, notifier.states())
+}
+
+func TestEngine_Silences(t *testing.T) {
+	e, store, notifier, now := testEngine(t, Rule{
+		Name:      "errors",
+		Query:     "level:error",
+		Threshold: 0,
+		Window:    Duration(time.Hour),
+		GroupBy:   "service",
+	})
+	ctx := context.Background()
+	require.NoError(t, store.CreateSilence(&Silence{Group: "payments", StartsAt: *now, EndsAt: now.Add(10 * time.Minute)}))
+	require.NoError(t, e.Reload())
+
+	observe(e, 1, model.Log{Level: "error", Labels: map[string]string{"service": "payments"}})
+	observe(e, 1, model.Log{Level: "error", Labels: map[string]string{"service": "auth"}})
+	e.Evaluate(ctx)
+	assert.Equal(t, []string{"auth:firing"}, notifier.states())
+
+	alerts, err := store.ListAlerts(StateFiring, 0)
+	require.NoError(t, err)
+	silenced := map[string]bool{}
+	for _, a := range alerts {
+		silenced[a.Group] = a.Silenced
+	}
+	assert.Equal(t, map[string]bool{"payments": true, "auth": false}, silenced)
+
+	// Once the silence expires, the still firing alert is announced.
+	*now = now.Add(10 * time.Minute)
+	e.Evaluate(ctx)
+	assert.Equal(t, []string{"auth:firing", "payments:firing"}, notifier.states())
+}
+
+func TestEngine_RuleChanges(t *testing.T) {
+	e, store, notifier, _ := testEngine(t, Rule{
+		Name:      "errors",
+		Query:     "level:error",
+		Threshold: 0,
+		Window:    Duration(time.Minute),
+	})
+	ctx := context.Background()
+
+	observe(e, 1, model.Log{Level: "error"})
+	e.Evaluate(ctx)
+	assert.Equal(t, []string{":firing"}, notifier.states())
+
+	// Disabling the rule resolves its alerts.
+	rule, err := store.GetRule(1)
+	require.NoError(t, err)
+	rule.Enabled = false
+	require.NoError(t, store.UpdateRule(rule))
+	require.NoError(t, e.Reload())
+	e.Evaluate(ctx)
+	assert.Equal(t, []string{":firing", ":resolved"}, notifier.states())
+}
+
+func TestEngine_RestoresFiringAlerts(t *testing.T) {
+	store := NewMemoryStore()
+	rule := Rule{Name: "errors", Query: "level:error", Threshold: 0, Window: Duration(time.Minute), Enabled: true}
+	require.NoError(t, store.CreateRule(&rule))
+	left := Alert{RuleID: rule.ID, RuleName: rule.Name, State: StateFiring, Count: 1, StartsAt: time.Now().Add(-time.Hour)}
+	require.NoError(t, store.SaveAlert(&left))
+
+	// A restarted engine keeps the alert firing without announcing it again.
+	notifier := &recordingNotifier{}
+	e := NewEngine(store, notifier, time.Second)
+	require.NoError(t, e.Reload())
+	e.Observe(model.Log{Level: "error"})
+	e.Evaluate(context.Background())
+	assert.Empty(t, notifier.states())
+
+	alerts, err := store.ListAlerts("", 0)
+	require.NoError(t, err)
+	require.Len(t, alerts, 1)
+	assert.Equal(t, left.ID, alerts[0].ID)
+	assert.Equal(t, StateFiring, alerts[0].State)
+}
+
+func TestEngine_SharedStore(t *testing.T) {
+	// Two API instances count the same stream and share one store.
+	first, store, firstNotifier, now := testEngine(t, Rule{
+		Name:      "errors",
+		Query:     "level:error",
+		Threshold: 1,
+		Window:    Duration(time.Minute),
+	})
+	secondNotifier := &recordingNotifier{}
+	second := NewEngine(store, secondNotifier, time.Second)
+	second.now = func() time.Time { return *now }
+	require.NoError(t, second.Reload())
+	ctx := context.Background()
+	notifications := func() []string {
+		return append(firstNotifier.states(), secondNotifier.states()...)
+	}
+
+	for _, e := range []*Engine{first, second} {
+		observe(e, 2, model.Log{Level: "error"})
+		e.Evaluate(ctx)
+	}
+	assert.Equal(t, []string{":firing"}, notifications())
+	alerts, err := store.ListAlerts("", 0)
+	require.NoError(t, err)
+	require.Len(t, alerts, 1)
+
+	*now = now.Add(2 * time.Minute)
+	for _, e := range []*Engine{first, second} {
+		e.Evaluate(ctx)
+	}
+	assert.Equal(t, []string{":firing", ":resolved"}, notifications())
+	alerts, err = store.ListAlerts("", 0)
+	require.NoError(t, err)
+	require.Len(t, alerts, 1)
+	assert.Equal(t, StateResolved, alerts[0].State)
+
+	// An instance still above the threshold after another one resolved the
+	// alert starts a new one.
+	observe(first, 2, model.Log{Level: "error"})
+	first.Evaluate(ctx)
+	observe(second, 2, model.Log{Level: "error"})
+	second.Evaluate(ctx)
+	*now = now.Add(2 * time.Minute)
+	first.Evaluate(ctx)
+	observe(second, 2, model.Log{Level: "error"})
+	second.Evaluate(ctx)
+	alerts, err = store.ListAlerts(StateFiring, 0)
+	require.NoError(t, err)
+	assert.Len(t, alerts, 1)
+	assert.Equal(t, []string{":firing", ":resolved", ":firing", ":resolved", ":firing"}, notifications())
+}
+
+func TestEngine_RetriesFailedNotification(t *testing.T) {
+	e, _, notifier, _ := testEngine(t, Rule{Name: "errors", Query: "level:error", Threshold: 0, Window: Duration(time.Minute)})
+	ctx := context.Background()
+
+	notifier.err = fmt.Errorf("notifier closed")
+	observe(e, 1, model.Log{Level: "error"})
+	e.Evaluate(ctx)
+	assert.Empty(t, notifier.states())
+
+	// The claim was released, so the next evaluation announces it.
+	notifier.mu.Lock()
+	notifier.err = nil
+	notifier.mu.Unlock()
+	e.Evaluate(ctx)
+	assert.Equal(t, []string{":firing"}, notifier.states())
+}
+
+// flakyStore fails or holds up SaveAlert on demand.
+type flakyStore struct {
+	*MemoryStore
+	mu    sync.Mutex
+	fail  error
+	block chan struct{}
+}
+
+func (s *flakyStore) SaveAlert(a *Alert) error {
+	s.mu.Lock()
+	fail, block := s.fail, s.block
+	s.mu.Unlock()
+	if block != nil {
+		<-block
+	}
+	if fail != nil {
+		return fail
+	}
+	return s.MemoryStore.SaveAlert(a)
+}
+
+func (s *flakyStore) set(fail error, block chan struct{}) {
+	s.mu.Lock()
+	s.fail, s.block = fail, block
+	s.mu.Unlock()
+}
+
+func TestEngine_RetriesFailedResolution(t *testing.T) {
+	e, memory, notifier, now := testEngine(t, Rule{Name: "errors", Query: "level:error", Threshold: 0, Window: Duration(time.Minute)})
+	store := &flakyStore{MemoryStore: memory}
+	e.store = store
+	ctx := context.Background()
+
+	observe(e, 1, model.Log{Level: "error"})
+	e.Evaluate(ctx)
+	assert.Equal(t, []string{":firing"}, notifier.states())
+
+	// The resolution cannot be saved: it is retried, not forgotten.
+	*now = now.Add(2 * time.Minute)
+	store.set(fmt.Errorf("connection refused"), nil)
+	e.Evaluate(ctx)
+	assert.Equal(t, []string{":firing"}, notifier.states())
+	firing, err := memory.ListAlerts(StateFiring, 0)
+	require.NoError(t, err)
+	assert.Len(t, firing, 1)
+
+	store.set(nil, nil)
+	e.Evaluate(ctx)
+	assert.Equal(t, []string{":firing", ":resolved"}, notifier.states())
+	firing, err = memory.ListAlerts(StateFiring, 0)
+	require.NoError(t, err)
+	assert.Empty(t, firing)
+}
+
+func TestEngine_ObserveDuringSave(t *testing.T) {
+	e, memory, notifier, _ := testEngine(t, Rule{Name: "errors", Query: "level:error", Threshold: 0, Window: Duration(time.Minute)})
+	store := &flakyStore{MemoryStore: memory}
+	e.store = store
+	block := make(chan struct{})
+	store.set(nil, block)
+
+	observe(e, 1, model.Log{Level: "error"})
+	done := make(chan struct{})
+	go func() {
+		e.Evaluate(context.Background())
+		close(done)
+	}()
+
+	// Counting goes on while the store is slow.
+	observed := make(chan struct{})
+	go func() {
+		observe(e, 1, model.Log{Level: "error"})
+		close(observed)
+	}()
+	select {
+	case <-observed:
+	case <-time.After(2 * time.Second):
+		t.Fatal("Observe was held up by SaveAlert")
+	}
+
+	close(block)
+	<-done
+	assert.Equal(t, []string{":firing"}, notifier.states())
+}
+
+func TestEngine_Run(t *testing.T) {
+	store := NewMemoryStore()
+	rule := Rule{Name: "errors", Query: "level:error", Threshold: 2, Window: Duration(time.Minute), Enabled: true}
+	require.NoError(t, store.CreateRule(&rule))
+	notifier := &recordingNotifier{}
+	e := NewEngine(store, notifier, 20*time.Millisecond)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	events := make(chan model.TailEvent)
+	done := make(chan error)
+	go func() { done <- e.Run(ctx, events) }()
+
+	for i := 0; i < 3; i++ {
+		events <- model.TailEvent{Seq: uint64(i + 1), Log: model.Log{Level: "error", Message: fmt.Sprint(i)}}
+	}
+	assert.Eventually(t, func() bool { return len(notifier.states()) == 1 }, 2*time.Second, 10*time.Millisecond)
+
+	cancel()
+	assert.ErrorIs(t, <-done, context.Canceled)
+}
+
+func TestRule_Validate(t *testing.T) {
+	tests := []struct {
+		name    string
+		rule    Rule
+		wantErr bool
+	}{
+		{name: "Valid", rule: Rule{Name: "r", Query: "level:error", Threshold: 50, Window: Duration(5 * time.Minute)}},
+		{name: "Missing name", rule: Rule{Query: "level:error", Window: Duration(time.Minute)}, wantErr: true},
+		{name: "Negative threshold", rule: Rule{Name: "r", Query: "level:error", Threshold: -1, Window: Duration(time.Minute)}, wantErr: true},
+		{name: "Missing window", rule: Rule{Name: "r", Query: "level:error"}, wantErr: true},
+		{name: "Window too long", rule: Rule{Name: "r", Query: "level:error", Window: Duration(48 * time.Hour)}, wantErr: true},
+		{name: "Invalid query", rule: Rule{Name: "r", Query: "", Window: Duration(time.Minute)}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := tt.rule.Validate()
+			if tt.wantErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,140 @@
+package alert
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"log-beacon/internal/model"
+)
+
+// Query matches log entries against the subset of the hot storage search
+// syntax that means the same in both places: terms joined by AND, each either
+// field:value or a bare word looked up in the message, optionally wrapped in
+// parentheses. Fields are level, message or a label name, optionally written
+// labels.<name>. Values may contain * wildcards and are compared
+// case-insensitively; message values match anywhere in the message, and
+// values with spaces must be quoted. A term prefixed with NOT or "-" must not
+// match. A query of * matches every entry. OR, terms separated by bare spaces
+// and nested groups are rejected rather than evaluated differently from the
+// hot storage.
+type Query struct {
+	terms []term
+}
+
+type term struct {
+	field   string
+	pattern *regexp.Regexp
+	negate  bool
+}
+
+// ParseQuery compiles a query string.
+func ParseQuery(s string) (*Query, error) {
+	q := &Query{}
+	for _, part := range strings.Split(s, " AND ") {
+		part = stripParentheses(strings.TrimSpace(part))
+		if part == "" {
+			return nil, fmt.Errorf("invalid query %q: empty term", s)
+		}
+		if part == "*" {
+			continue
+		}
+
+		var t term
+		switch {
+		case strings.HasPrefix(part, "NOT "):
+			t.negate = true
+			part = strings.TrimSpace(strings.TrimPrefix(part, "NOT "))
+		case strings.HasPrefix(part, "-"):
+			t.negate = true
+			part = part[1:]
+		}
+		if err := checkTerm(part); err != nil {
+			return nil, fmt.Errorf("invalid query %q: %w", s, err)
+		}
+
+		value := part
+		t.field = "message"
+		if field, v, ok := strings.Cut(part, ":"); ok && field != "" && !strings.ContainsAny(field, " \"") {
+			t.field = strings.TrimPrefix(field, "labels.")
+			value = v
+		}
+		value = strings.Trim(value, "\"")
+		if value == "" {
+			return nil, fmt.Errorf("invalid query %q: term %q has no value", s, part)
+		}
+
+		expr := strings.ReplaceAll(regexp.QuoteMeta(value), `\*`, ".*")
+		if t.field != "message" {
+			expr = "^" + expr + "$"
+		}
+		t.pattern = regexp.MustCompile("(?i)" + expr)
+		q.terms = append(q.terms, t)
+	}
+	return q, nil
+}
+
+// stripParentheses removes parentheses wrapping the whole term.
+func stripParentheses(s string) string {
+	for len(s) >= 2 && s[0] == '(' && s[len(s)-1] == ')' {
+		depth := 0
+		for i, r := range s {
+			switch r {
+			case '(':
+				depth++
+			case ')':
+				depth--
+			}
+			if depth == 0 && i < len(s)-1 {
+				// e.g. "(a) (b)": not a single group.
+				return s
+			}
+		}
+		s = strings.TrimSpace(s[1 : len(s)-1])
+	}
+	return s
+}
+
+// checkTerm rejects a term that holds more than one term, which the hot
+// storage would combine with OR: spaces and parentheses outside quotes.
+func checkTerm(part string) error {
+	quoted := false
+	for i, r := range part {
+		switch {
+		case r == '"':
+			quoted = !quoted
+		case quoted:
+		case r == ' ' || r == '\t':
+			if rest := part[i+1:]; rest == "OR" || strings.HasPrefix(rest, "OR ") {
+				return fmt.Errorf("term %q: OR is not supported", part)
+			}
+			return fmt.Errorf("term %q: terms must be joined by AND", part)
+		case r == '(' || r == ')':
+			return fmt.Errorf("term %q: nested groups are not supported", part)
+		}
+	}
+	if quoted {
+		return fmt.Errorf("term %q: unterminated quote", part)
+	}
+	return nil
+}
+
+// Match reports whether an entry satisfies every term of the query.
+func (q *Query) Match(l model.Log) bool {
+	for _, t := range q.terms {
+		var value string
+		var ok bool
+		switch t.field {
+		case "level":
+			value, ok = l.Level, true
+		case "message":
+			value, ok = l.Message, true
+		default:
+			value, ok = l.Labels[t.field]
+		}
+		if (ok && t.pattern.MatchString(value)) == t.negate {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,68 @@
+package alert
+
+import (
+	"testing"
+
+	"log-beacon/internal/model"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestQuery_Match(t *testing.T) {
+	entry := model.Log{
+		Level:   "ERROR",
+		Message: "Payment declined for order 42",
+		Labels:  map[string]string{"service": "payments", "region": "eu-west-1"},
+	}
+
+	tests := []struct {
+		query string
+		want  bool
+	}{
+		{query: "*", want: true},
+		{query: "level:error", want: true},
+		{query: "level:warn", want: false},
+		{query: "service:payments AND level:error", want: true},
+		{query: "labels.service:payments", want: true},
+		{query: "(service:payments) AND (level:error)", want: true},
+		{query: "((level:error))", want: true},
+		{query: "message:\"declined for\" AND level:error", want: true},
+		{query: "service:pay*", want: true},
+		{query: "service:pay", want: false},
+		{query: "region:eu-*", want: true},
+		{query: "declined", want: true},
+		{query: "message:\"order 42\"", want: true},
+		{query: "timeout", want: false},
+		{query: "missing:value", want: false},
+		{query: "NOT service:payments", want: false},
+		{query: "-service:auth AND level:error", want: true},
+		{query: "NOT missing:value", want: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			q, err := ParseQuery(tt.query)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, q.Match(entry))
+		})
+	}
+}
+
+func TestParseQuery_Invalid(t *testing.T) {
+	for _, query := range []string{
+		"", "level:error AND ", "service:", "message:\"order",
+		// OR and space-separated terms mean "either" to the hot storage.
+		"(level:error OR level:info)",
+		"level:error OR level:info",
+		"level:error level:info",
+		"declined payment",
+		"service:payments AND (level:error OR level:warn)",
+		// Groups within a term cannot be expressed with AND alone.
+		"NOT (level:error AND service:payments)",
+		"(level:error AND service:payments)",
+	} {
+		_, err := ParseQuery(query)
+		assert.Error(t, err, query)
+	}
+}
@@ -0,0 +1,159 @@
+// Package alert evaluates alert rules against the log stream. A rule counts
+// the entries matching a query over a sliding window, optionally per value of
+// a label, and fires while the count exceeds its threshold.
+package alert
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"log-beacon/internal/model"
+)
+
+// Alert states.
+const (
+	StateFiring   = "firing"
+	StateResolved = "resolved"
+)
+
+// Delivery statuses.
+const (
+	DeliverySent   = "sent"
+	DeliveryFailed = "failed"
+)
+
+// MaxWindow is the longest window a rule may count over. Counts are kept in
+// memory, so the window bounds how much history the engine holds.
+const MaxWindow = 24 * time.Hour
+
+// ErrNotFound is returned by a Store for unknown rules and silences.
+var ErrNotFound = errors.New("not found")
+
+// Duration is a time.Duration written in JSON as a string such as "5m".
+type Duration time.Duration
+
+// MarshalJSON implements json.Marshaler.
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(time.Duration(d).String())
+}
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (d *Duration) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return fmt.Errorf("duration must be a string such as \"5m\"")
+	}
+	v, err := time.ParseDuration(s)
+	if err != nil {
+		return err
+	}
+	*d = Duration(v)
+	return nil
+}
+
+// Rule fires when more than Threshold entries matching Query arrive within
+// Window. With GroupBy set, entries are counted and alerted on separately
+// for each value of that label; "level" groups by level.
+type Rule struct {
+	ID        int64     `json:"id"`
+	Name      string    `json:"name"`
+	Query     string    `json:"query"`
+	Threshold int       `json:"threshold"`
+	Window    Duration  `json:"window"`
+	GroupBy   string    `json:"group_by,omitempty"`
+	Enabled   bool      `json:"enabled"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// Validate checks the rule and compiles its query.
+func (r *Rule) Validate() (*Query, error) {
+	if r.Name == "" {
+		return nil, fmt.Errorf("rule name is required")
+	}
+	if r.Threshold < 0 {
+		return nil, fmt.Errorf("rule %q: threshold must not be negative", r.Name)
+	}
+	if time.Duration(r.Window) < time.Second || time.Duration(r.Window) > MaxWindow {
+		return nil, fmt.Errorf("rule %q: window must be between 1s and %s", r.Name, MaxWindow)
+	}
+	q, err := ParseQuery(r.Query)
+	if err != nil {
+		return nil, fmt.Errorf("rule %q: %w", r.Name, err)
+	}
+	return q, nil
+}
+
+// group returns the value an entry is grouped under.
+func (r *Rule) group(l model.Log) string {
+	switch r.GroupBy {
+	case "":
+		return ""
+	case "level":
+		return l.Level
+	default:
+		return l.Labels[r.GroupBy]
+	}
+}
+
+// Silence suppresses the notifications of matching alerts between StartsAt
+// and EndsAt. A zero RuleID matches every rule and an empty Group every group.
+type Silence struct {
+	ID        int64     `json:"id"`
+	RuleID    int64     `json:"rule_id,omitempty"`
+	Group     string    `json:"group,omitempty"`
+	Comment   string    `json:"comment,omitempty"`
+	CreatedBy string    `json:"created_by"`
+	StartsAt  time.Time `json:"starts_at"`
+	EndsAt    time.Time `json:"ends_at"`
+}
+
+// Validate checks the silence.
+func (s *Silence) Validate() error {
+	if s.EndsAt.IsZero() || !s.EndsAt.After(s.StartsAt) {
+		return fmt.Errorf("silence must end after it starts")
+	}
+	return nil
+}
+
+// Matches reports whether the silence covers an alert at the given time.
+func (s *Silence) Matches(a *Alert, now time.Time) bool {
+	if now.Before(s.StartsAt) || !now.Before(s.EndsAt) {
+		return false
+	}
+	if s.RuleID != 0 && s.RuleID != a.RuleID {
+		return false
+	}
+	return s.Group == "" || s.Group == a.Group
+}
+
+// Alert is one firing, or since resolved, episode of a rule for a group.
+type Alert struct {
+	ID        int64  `json:"id"`
+	RuleID    int64  `json:"rule_id"`
+	RuleName  string `json:"rule_name"`
+	Group     string `json:"group,omitempty"`
+	State     string `json:"state"`
+	Count     int    `json:"count"`
+	Threshold int    `json:"threshold"`
+	// Silenced is set while a silence suppresses the alert's notifications.
+	Silenced   bool       `json:"silenced"`
+	StartsAt   time.Time  `json:"starts_at"`
+	ResolvedAt *time.Time `json:"resolved_at,omitempty"`
+}
+
+// Delivery records the attempts to send one notification through one
+// notification channel.
+type Delivery struct {
+	ID         int64     `json:"id"`
+	AlertID    int64     `json:"alert_id"`
+	RuleName   string    `json:"rule_name"`
+	AlertState string    `json:"alert_state"`
+	Channel    string    `json:"channel"`
+	Status     string    `json:"status"`
+	Attempts   int       `json:"attempts"`
+	Error      string    `json:"error,omitempty"`
+	CreatedAt  time.Time `json:"created_at"`
+}
@@ -0,0 +1,254 @@
+package alert
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// Store persists rules, silences and alerts. It is implemented by
+// repository.AlertRepository on Postgres.
+type Store interface {
+	ListRules() ([]Rule, error)
+	GetRule(id int64) (*Rule, error)
+	CreateRule(rule *Rule) error
+	UpdateRule(rule *Rule) error
+	DeleteRule(id int64) error
+
+	ListSilences() ([]Silence, error)
+	CreateSilence(silence *Silence) error
+	DeleteSilence(id int64) error
+
+	// SaveAlert inserts an alert with a zero ID, assigning its ID, or updates
+	// it. Every API instance evaluates the same rules, so a new firing alert
+	// is merged into the one another instance already raised for its rule and
+	// group, taking over its ID and start. Updating an alert that is no longer
+	// firing returns ErrNotFound.
+	SaveAlert(alert *Alert) error
+	// ClaimNotification records that the alert's state is being announced and
+	// reports whether it was not announced before, so that only one instance
+	// notifies. A resolution is only claimed once the firing was announced.
+	ClaimNotification(alert *Alert) (bool, error)
+	// ReleaseNotification undoes ClaimNotification when the announcement
+	// failed, so that it can be claimed again.
+	ReleaseNotification(alert *Alert) error
+	// ListAlerts returns the most recent alerts first, optionally only those in
+	// the given state. A limit of zero returns every alert.
+	ListAlerts(state string, limit int) ([]Alert, error)
+
+	// SaveDelivery records a notification delivery, assigning its ID.
+	SaveDelivery(delivery *Delivery) error
+	// ListDeliveries returns the most recent deliveries first, optionally only
+	// those of one alert. A limit of zero returns every delivery.
+	ListDeliveries(alertID int64, limit int) ([]Delivery, error)
+}
+
+// MemoryStore is a Store that keeps everything in memory, for tests and
+// single-process setups.
+type MemoryStore struct {
+	mu         sync.Mutex
+	nextID     int64
+	rules      map[int64]Rule
+	silences   map[int64]Silence
+	alerts     map[int64]Alert
+	notified   map[int64]string
+	deliveries []Delivery
+}
+
+// NewMemoryStore creates an empty MemoryStore.
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		rules:    make(map[int64]Rule),
+		silences: make(map[int64]Silence),
+		alerts:   make(map[int64]Alert),
+		notified: make(map[int64]string),
+	}
+}
+
+func (m *MemoryStore) id() int64 {
+	m.nextID++
+	return m.nextID
+}
+
+// ListRules implements Store.
+func (m *MemoryStore) ListRules() ([]Rule, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	rules := make([]Rule, 0, len(m.rules))
+	for _, r := range m.rules {
+		rules = append(rules, r)
+	}
+	sort.Slice(rules, func(i, j int) bool { return rules[i].ID < rules[j].ID })
+	return rules, nil
+}
+
+// GetRule implements Store.
+func (m *MemoryStore) GetRule(id int64) (*Rule, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	r, ok := m.rules[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return &r, nil
+}
+
+// CreateRule implements Store.
+func (m *MemoryStore) CreateRule(rule *Rule) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	rule.ID = m.id()
+	rule.CreatedAt = time.Now().UTC()
+	rule.UpdatedAt = rule.CreatedAt
+	m.rules[rule.ID] = *rule
+	return nil
+}
+
+// UpdateRule implements Store.
+func (m *MemoryStore) UpdateRule(rule *Rule) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	old, ok := m.rules[rule.ID]
+	if !ok {
+		return ErrNotFound
+	}
+	rule.CreatedAt = old.CreatedAt
+	rule.UpdatedAt = time.Now().UTC()
+	m.rules[rule.ID] = *rule
+	return nil
+}
+
+// DeleteRule implements Store.
+func (m *MemoryStore) DeleteRule(id int64) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.rules[id]; !ok {
+		return ErrNotFound
+	}
+	delete(m.rules, id)
+	return nil
+}
+
+// ListSilences implements Store.
+func (m *MemoryStore) ListSilences() ([]Silence, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	silences := make([]Silence, 0, len(m.silences))
+	for _, s := range m.silences {
+		silences = append(silences, s)
+	}
+	sort.Slice(silences, func(i, j int) bool { return silences[i].ID < silences[j].ID })
+	return silences, nil
+}
+
+// CreateSilence implements Store.
+func (m *MemoryStore) CreateSilence(silence *Silence) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	silence.ID = m.id()
+	m.silences[silence.ID] = *silence
+	return nil
+}
+
+// DeleteSilence implements Store.
+func (m *MemoryStore) DeleteSilence(id int64) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.silences[id]; !ok {
+		return ErrNotFound
+	}
+	delete(m.silences, id)
+	return nil
+}
+
+// SaveAlert implements Store.
+func (m *MemoryStore) SaveAlert(alert *Alert) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if alert.ID == 0 {
+		alert.ID = m.id()
+		for id, a := range m.alerts {
+			if alert.State == StateFiring && a.State == StateFiring && a.RuleID == alert.RuleID && a.Group == alert.Group {
+				alert.ID, alert.StartsAt = id, a.StartsAt
+				break
+			}
+		}
+	} else if a, ok := m.alerts[alert.ID]; !ok || a.State != StateFiring {
+		return ErrNotFound
+	}
+	m.alerts[alert.ID] = *alert
+	return nil
+}
+
+// ClaimNotification implements Store.
+func (m *MemoryStore) ClaimNotification(alert *Alert) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.notified[alert.ID] != announcedBefore(alert.State) {
+		return false, nil
+	}
+	m.notified[alert.ID] = alert.State
+	return true, nil
+}
+
+// ReleaseNotification implements Store.
+func (m *MemoryStore) ReleaseNotification(alert *Alert) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.notified[alert.ID] == alert.State {
+		m.notified[alert.ID] = announcedBefore(alert.State)
+	}
+	return nil
+}
+
+// announcedBefore returns the state an alert must have been announced in for
+// its state to be announced now: none before firing, firing before resolving.
+func announcedBefore(state string) string {
+	if state == StateResolved {
+		return StateFiring
+	}
+	return ""
+}
+
+// ListAlerts implements Store.
+func (m *MemoryStore) ListAlerts(state string, limit int) ([]Alert, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	alerts := []Alert{}
+	for _, a := range m.alerts {
+		if state == "" || a.State == state {
+			alerts = append(alerts, a)
+		}
+	}
+	sort.Slice(alerts, func(i, j int) bool { return alerts[i].ID > alerts[j].ID })
+	if limit > 0 && len(alerts) > limit {
+		alerts = alerts[:limit]
+	}
+	return alerts, nil
+}
+
+// SaveDelivery implements Store.
+func (m *MemoryStore) SaveDelivery(delivery *Delivery) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delivery.ID = m.id()
+	m.deliveries = append(m.deliveries, *delivery)
+	return nil
+}
+
+// ListDeliveries implements Store.
+func (m *MemoryStore) ListDeliveries(alertID int64, limit int) ([]Delivery, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	deliveries := []Delivery{}
+	for i := len(m.deliveries) - 1; i >= 0; i-- {
+		if alertID != 0 && m.deliveries[i].AlertID != alertID {
+			continue
+		}
+		deliveries = append(deliveries, m.deliveries[i])
+		if limit > 0 && len(deliveries) == limit {
+			break
+		}
+	}
+	return deliveries, nil
+}
@@ -16,9 +16,12 @@ type Claims struct {
 	jwt.RegisteredClaims
 }
 
-// HashPassword hashes a password using bcrypt.
-func HashPassword(password string) (string, error) {
-	bytes, err := bcrypt.GenerateFromPassword([]byte(password), 14)
+// DefaultBcryptCost is the bcrypt cost used for new password hashes.
+const DefaultBcryptCost = 14
+
+// HashPassword hashes a password using bcrypt with the given cost.
+func HashPassword(password string, cost int) (string, error) {
+	bytes, err := bcrypt.GenerateFromPassword([]byte(password), cost)
 	return string(bytes), err
 }
 
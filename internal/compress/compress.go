@@ -0,0 +1,137 @@
+// Package compress decodes HTTP request bodies sent with a Content-Encoding.
+package compress
+
+import (
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/golang/snappy"
+	"github.com/klauspost/compress/zstd"
+)
+
+// ErrUnsupportedEncoding is returned for a Content-Encoding that cannot be decoded.
+var ErrUnsupportedEncoding = errors.New("unsupported content encoding")
+
+// ErrTooLarge is returned when a body exceeds the size limit once decoded.
+var ErrTooLarge = errors.New("decoded body exceeds size limit")
+
+// snappyStreamMagic starts every body in the snappy framing format.
+var snappyStreamMagic = []byte("\xff\x06\x00\x00sNaPpY")
+
+// Decode reads body and undoes the codings listed in contentEncoding, which are
+// applied in the order given (e.g. "gzip, zstd"). Neither the body nor any
+// intermediate result may exceed maxBytes, which guards against zip bombs.
+func Decode(body io.Reader, contentEncoding string, maxBytes int64) ([]byte, error) {
+	data, err := ReadLimited(body, maxBytes)
+	if err != nil {
+		return nil, err
+	}
+
+	list := codings(contentEncoding)
+	for i := len(list) - 1; i >= 0; i-- {
+		if data, err = decodeOne(data, list[i], maxBytes); err != nil {
+			return nil, err
+		}
+	}
+	return data, nil
+}
+
+func decodeOne(data []byte, coding string, maxBytes int64) ([]byte, error) {
+	switch coding {
+	case "identity":
+		return data, nil
+	case "gzip", "x-gzip":
+		gz, err := gzip.NewReader(bytes.NewReader(data))
+		if err != nil {
+			return nil, fmt.Errorf("invalid gzip body: %w", err)
+		}
+		defer gz.Close()
+		return readDecoded(gz, "gzip", maxBytes)
+	case "deflate":
+		// HTTP deflate is zlib-wrapped, but some clients send raw deflate streams.
+		if isZlibHeader(data) {
+			zr, err := zlib.NewReader(bytes.NewReader(data))
+			if err != nil {
+				return nil, fmt.Errorf("invalid deflate body: %w", err)
+			}
+			defer zr.Close()
+			return readDecoded(zr, "deflate", maxBytes)
+		}
+		fr := flate.NewReader(bytes.NewReader(data))
+		defer fr.Close()
+		return readDecoded(fr, "deflate", maxBytes)
+	case "zstd":
+		zr, err := zstd.NewReader(bytes.NewReader(data), zstd.WithDecoderConcurrency(1), zstd.WithDecoderMaxMemory(uint64(maxBytes)))
+		if err != nil {
+			return nil, fmt.Errorf("invalid zstd body: %w", err)
+		}
+		defer zr.Close()
+		data, err := readDecoded(zr, "zstd", maxBytes)
+		if errors.Is(err, zstd.ErrDecoderSizeExceeded) {
+			return nil, ErrTooLarge
+		}
+		return data, err
+	case "snappy", "x-snappy-framed":
+		if bytes.HasPrefix(data, snappyStreamMagic) {
+			return readDecoded(snappy.NewReader(bytes.NewReader(data)), "snappy", maxBytes)
+		}
+		size, err := snappy.DecodedLen(data)
+		if err != nil {
+			return nil, fmt.Errorf("invalid snappy body: %w", err)
+		}
+		if int64(size) > maxBytes {
+			return nil, ErrTooLarge
+		}
+		decoded, err := snappy.Decode(nil, data)
+		if err != nil {
+			return nil, fmt.Errorf("invalid snappy body: %w", err)
+		}
+		return decoded, nil
+	default:
+		return nil, fmt.Errorf("%w %q", ErrUnsupportedEncoding, coding)
+	}
+}
+
+// readDecoded reads a decompressing reader, reporting corrupt input as an
+// invalid body of the given coding.
+func readDecoded(r io.Reader, coding string, maxBytes int64) ([]byte, error) {
+	data, err := ReadLimited(r, maxBytes)
+	if err != nil && !errors.Is(err, ErrTooLarge) {
+		return nil, fmt.Errorf("invalid %s body: %w", coding, err)
+	}
+	return data, err
+}
+
+// ReadLimited reads all of r, failing with ErrTooLarge past maxBytes.
+func ReadLimited(r io.Reader, maxBytes int64) ([]byte, error) {
+	data, err := io.ReadAll(io.LimitReader(r, maxBytes+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(data)) > maxBytes {
+		return nil, ErrTooLarge
+	}
+	return data, nil
+}
+
+// codings splits a Content-Encoding header value into lowercase codings.
+func codings(contentEncoding string) []string {
+	var list []string
+	for _, coding := range strings.Split(contentEncoding, ",") {
+		if coding = strings.ToLower(strings.TrimSpace(coding)); coding != "" {
+			list = append(list, coding)
+		}
+	}
+	return list
+}
+
+// isZlibHeader reports whether data starts with a valid zlib header (RFC 1950).
+func isZlibHeader(data []byte) bool {
+	return len(data) >= 2 && data[0]&0x0f == 8 && (uint16(data[0])<<8|uint16(data[1]))%31 == 0
+}
@@ -0,0 +1,121 @@
+package compress
+
+import (
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"strings"
+	"testing"
+
+	"github.com/golang/snappy"
+	"github.com/klauspost/compress/zstd"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func gzipBytes(t *testing.T, data []byte) []byte {
+	var buf bytes.Buffer
+	w := gzip.NewWriter(&buf)
+	_, err := w.Write(data)
+	require.NoError(t, err)
+	require.NoError(t, w.Close())
+	return buf.Bytes()
+}
+
+func zlibBytes(t *testing.T, data []byte) []byte {
+	var buf bytes.Buffer
+	w := zlib.NewWriter(&buf)
+	_, err := w.Write(data)
+	require.NoError(t, err)
+	require.NoError(t, w.Close())
+	return buf.Bytes()
+}
+
+func flateBytes(t *testing.T, data []byte) []byte {
+	var buf bytes.Buffer
+	w, err := flate.NewWriter(&buf, flate.DefaultCompression)
+	require.NoError(t, err)
+	_, err = w.Write(data)
+	require.NoError(t, err)
+	require.NoError(t, w.Close())
+	return buf.Bytes()
+}
+
+func zstdBytes(t *testing.T, data []byte) []byte {
+	enc, err := zstd.NewWriter(nil)
+	require.NoError(t, err)
+	defer enc.Close()
+	return enc.EncodeAll(data, nil)
+}
+
+func snappyStreamBytes(t *testing.T, data []byte) []byte {
+	var buf bytes.Buffer
+	w := snappy.NewBufferedWriter(&buf)
+	_, err := w.Write(data)
+	require.NoError(t, err)
+	require.NoError(t, w.Close())
+	return buf.Bytes()
+}
+
+func TestDecode(t *testing.T) {
+	payload := []byte(`{"level":"info","message":"hello"}`)
+
+	tests := []struct {
+		name     string
+		encoding string
+		body     []byte
+	}{
+		{"identity", "identity", payload},
+		{"gzip", "gzip", gzipBytes(t, payload)},
+		{"x-gzip uppercase", "X-GZIP", gzipBytes(t, payload)},
+		{"deflate zlib", "deflate", zlibBytes(t, payload)},
+		{"deflate raw", "deflate", flateBytes(t, payload)},
+		{"zstd", "zstd", zstdBytes(t, payload)},
+		{"snappy block", "snappy", snappy.Encode(nil, payload)},
+		{"snappy framed", "snappy", snappyStreamBytes(t, payload)},
+		{"multiple codings", "gzip, zstd", zstdBytes(t, gzipBytes(t, payload))},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Decode(bytes.NewReader(tt.body), tt.encoding, 1024)
+			require.NoError(t, err)
+			assert.Equal(t, payload, got)
+		})
+	}
+}
+
+func TestDecode_Errors(t *testing.T) {
+	bomb := []byte(strings.Repeat("a", 64*1024))
+
+	tests := []struct {
+		name     string
+		encoding string
+		body     []byte
+		wantErr  error
+	}{
+		{"unsupported encoding", "br", []byte("data"), ErrUnsupportedEncoding},
+		{"gzip too large", "gzip", gzipBytes(t, bomb), ErrTooLarge},
+		{"zstd too large", "zstd", zstdBytes(t, bomb), ErrTooLarge},
+		{"snappy too large", "snappy", snappy.Encode(nil, bomb), ErrTooLarge},
+		{"deflate too large", "deflate", zlibBytes(t, bomb), ErrTooLarge},
+		{"raw body too large", "identity", bomb, ErrTooLarge},
+		{"corrupt gzip", "gzip", []byte("not gzip"), nil},
+		{"corrupt zstd", "zstd", []byte("not zstd"), nil},
+		{"corrupt snappy", "snappy", []byte{0xff, 0xff, 0xff, 0xff, 0xff}, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Decode(bytes.NewReader(tt.body), tt.encoding, 1024)
+			require.Error(t, err)
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+			} else {
+				assert.NotErrorIs(t, err, ErrTooLarge)
+				assert.NotErrorIs(t, err, ErrUnsupportedEncoding)
+			}
+		})
+	}
+}
@@ -0,0 +1,349 @@
+// Package config loads the settings of the API, hot-storage and archiver
+// services.
+//
+// Every setting has a default, which a YAML or TOML file, an environment
+// variable and a command-line flag override in that order of precedence.
+// The file is named by the -config flag or the BEACON_CONFIG environment
+// variable and may hold the sections of every service, so that one file
+// configures a whole deployment. Each service reads the nats section and
+// its own section, and validates them before starting.
+package config
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"log-beacon/internal/auth"
+	"log-beacon/internal/queue"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// Services.
+const (
+	ServiceAPI        = "api"
+	ServiceHotStorage = "hot-storage"
+	ServiceArchiver   = "archiver"
+)
+
+// Defaults of the API settings, which the packages using them share.
+const (
+	// DefaultMaxBodyBytes bounds the decoded size of ingest requests.
+	DefaultMaxBodyBytes = 32 << 20
+	// DefaultAlertEvalInterval is how often alert rules are evaluated.
+	DefaultAlertEvalInterval = 15 * time.Second
+	// DefaultTailBufferSize is the number of logs buffered per tail client.
+	DefaultTailBufferSize = 1000
+	// DefaultTailPolicy is the slow consumer policy of tail clients.
+	DefaultTailPolicy = TailPolicyDropOldest
+
+	// Default Elasticsearch document fields, those of Filebeat and Fluent Bit.
+	DefaultESMessageField   = "message"
+	DefaultESLevelField     = "level"
+	DefaultESTimestampField = "@timestamp"
+)
+
+// Slow consumer policies of tail clients.
+const (
+	TailPolicyDropOldest = "drop_oldest"
+	TailPolicyDisconnect = "disconnect"
+)
+
+// FileEnv is the environment variable naming the configuration file when
+// the -config flag is not given.
+const FileEnv = "BEACON_CONFIG"
+
+// Config holds the settings of every service. Struct tags give each
+// setting's file key, environment variable, flag and description.
+type Config struct {
+	NATS       NATS       `yaml:"nats" toml:"nats"`
+	API        API        `yaml:"api" toml:"api"`
+	HotStorage HotStorage `yaml:"hot_storage" toml:"hot_storage"`
+	Archiver   Archiver   `yaml:"archiver" toml:"archiver"`
+}
+
+// NATS locates the log stream. It is shared by every service.
+type NATS struct {
+	URL     string `yaml:"url" toml:"url" env:"NATS_URL" flag:"nats-url" usage:"NATS server URL"`
+	Stream  string `yaml:"stream" toml:"stream" env:"NATS_STREAM" flag:"nats-stream" usage:"JetStream stream holding the logs"`
+	Subject string `yaml:"subject" toml:"subject" env:"NATS_SUBJECT" flag:"nats-subject" usage:"subject logs are published on"`
+}
+
+// API configures the API service.
+type API struct {
+	Addr          string `yaml:"addr" toml:"addr" env:"API_ADDR" flag:"addr" usage:"address to serve HTTP on"`
+	DatabaseURL   string `yaml:"database_url" toml:"database_url" env:"DB_URL" flag:"db-url" usage:"Postgres connection URL"`
+	HotStorageURL string `yaml:"hot_storage_url" toml:"hot_storage_url" env:"HOT_STORAGE_URL" flag:"hot-storage-url" usage:"base URL of the hot-storage service"`
+	BcryptCost    int    `yaml:"bcrypt_cost" toml:"bcrypt_cost" env:"BCRYPT_COST" flag:"bcrypt-cost" usage:"bcrypt cost of new password hashes"`
+	MaxBodyBytes  int64  `yaml:"max_body_bytes" toml:"max_body_bytes" env:"INGEST_MAX_BODY_BYTES" flag:"max-body-bytes" usage:"maximum decoded size of ingest requests"`
+
+	ShutdownTimeout Duration `yaml:"shutdown_timeout" toml:"shutdown_timeout" env:"SHUTDOWN_TIMEOUT" flag:"shutdown-timeout" usage:"how long to drain requests and tail clients before exiting"`
+
+	PipelineConfig      string   `yaml:"pipeline_config" toml:"pipeline_config" env:"PIPELINE_CONFIG" flag:"pipeline-config" usage:"JSON file of the ingest pipeline"`
+	AlertChannelsConfig string   `yaml:"alert_channels_config" toml:"alert_channels_config" env:"ALERT_CHANNELS_CONFIG" flag:"alert-channels-config" usage:"JSON file of the alert notification channels"`
+	AlertEvalInterval   Duration `yaml:"alert_eval_interval" toml:"alert_eval_interval" env:"ALERT_EVAL_INTERVAL" flag:"alert-eval-interval" usage:"how often alert rules are evaluated"`
+	LogMetricsConfig    string   `yaml:"log_metrics_config" toml:"log_metrics_config" env:"LOG_METRICS_CONFIG" flag:"log-metrics-config" usage:"JSON file of the metrics derived from logs"`
+
+	Tail    Tail    `yaml:"tail" toml:"tail"`
+	Elastic Elastic `yaml:"elastic" toml:"elastic"`
+	Syslog  Syslog  `yaml:"syslog" toml:"syslog"`
+
+	OTLPGRPCAddr string `yaml:"otlp_grpc_addr" toml:"otlp_grpc_addr" env:"OTLP_GRPC_ADDR" flag:"otlp-grpc-addr" usage:"address of the OTLP/gRPC logs receiver, disabled when empty"`
+}
+
+// Tail configures live tail.
+type Tail struct {
+	ReplayWindow       Duration `yaml:"replay_window" toml:"replay_window" env:"TAIL_REPLAY_WINDOW" flag:"tail-replay-window" usage:"how long the stream keeps logs for tail clients to resume; replaces interest retention, off when zero"`
+	BufferSize         int      `yaml:"buffer_size" toml:"buffer_size" env:"TAIL_BUFFER_SIZE" flag:"tail-buffer-size" usage:"logs buffered per tail client"`
+	SlowConsumerPolicy string   `yaml:"slow_consumer_policy" toml:"slow_consumer_policy" env:"TAIL_SLOW_CONSUMER_POLICY" flag:"tail-slow-consumer-policy" usage:"drop_oldest or disconnect"`
+}
+
+// Elastic maps Elasticsearch bulk documents onto log fields.
+type Elastic struct {
+	MessageField   string `yaml:"message_field" toml:"message_field" env:"ES_MESSAGE_FIELD" flag:"es-message-field" usage:"document field holding the message"`
+	LevelField     string `yaml:"level_field" toml:"level_field" env:"ES_LEVEL_FIELD" flag:"es-level-field" usage:"document field holding the level"`
+	TimestampField string `yaml:"timestamp_field" toml:"timestamp_field" env:"ES_TIMESTAMP_FIELD" flag:"es-timestamp-field" usage:"document field holding the timestamp"`
+}
+
+// Syslog enables the syslog listeners. Empty addresses disable them.
+type Syslog struct {
+	UDPAddr string `yaml:"udp_addr" toml:"udp_addr" env:"SYSLOG_UDP_ADDR" flag:"syslog-udp-addr" usage:"address of the syslog UDP listener"`
+	TCPAddr string `yaml:"tcp_addr" toml:"tcp_addr" env:"SYSLOG_TCP_ADDR" flag:"syslog-tcp-addr" usage:"address of the syslog TCP listener"`
+	TLSAddr string `yaml:"tls_addr" toml:"tls_addr" env:"SYSLOG_TLS_ADDR" flag:"syslog-tls-addr" usage:"address of the syslog TLS listener"`
+	TLSCert string `yaml:"tls_cert" toml:"tls_cert" env:"SYSLOG_TLS_CERT" flag:"syslog-tls-cert" usage:"certificate file of the syslog TLS listener"`
+	TLSKey  string `yaml:"tls_key" toml:"tls_key" env:"SYSLOG_TLS_KEY" flag:"syslog-tls-key" usage:"key file of the syslog TLS listener"`
+}
+
+// HotStorage configures the hot-storage service.
+type HotStorage struct {
+	Addr       string `yaml:"addr" toml:"addr" env:"HOT_STORAGE_ADDR" flag:"addr" usage:"address to serve search on"`
+	BlevePath  string `yaml:"bleve_path" toml:"bleve_path" env:"BLEVE_PATH" flag:"bleve-path" usage:"directory of the Bleve index"`
+	BadgerPath string `yaml:"badger_path" toml:"badger_path" env:"BADGER_PATH" flag:"badger-path" usage:"directory of the BadgerDB log store"`
+
+	ShutdownTimeout Duration `yaml:"shutdown_timeout" toml:"shutdown_timeout" env:"SHUTDOWN_TIMEOUT" flag:"shutdown-timeout" usage:"how long to finish delivered messages before exiting"`
+}
+
+// Archiver configures the archiver service.
+type Archiver struct {
+	MetricsAddr string `yaml:"metrics_addr" toml:"metrics_addr" env:"METRICS_ADDR" flag:"metrics-addr" usage:"address to serve metrics and health probes on"`
+	MinIO       MinIO  `yaml:"minio" toml:"minio"`
+
+	ShutdownTimeout Duration `yaml:"shutdown_timeout" toml:"shutdown_timeout" env:"SHUTDOWN_TIMEOUT" flag:"shutdown-timeout" usage:"how long to finish delivered messages before exiting"`
+}
+
+// MinIO locates the archive bucket.
+type MinIO struct {
+	Endpoint        string `yaml:"endpoint" toml:"endpoint" env:"MINIO_ENDPOINT" flag:"minio-endpoint" usage:"MinIO host:port"`
+	AccessKeyID     string `yaml:"access_key_id" toml:"access_key_id" env:"MINIO_ACCESS_KEY_ID" flag:"minio-access-key-id" usage:"MinIO access key"`
+	SecretAccessKey string `yaml:"secret_access_key" toml:"secret_access_key" env:"MINIO_SECRET_ACCESS_KEY" flag:"minio-secret-access-key" usage:"MinIO secret key"`
+	UseSSL          bool   `yaml:"use_ssl" toml:"use_ssl" env:"MINIO_USE_SSL" flag:"minio-use-ssl" usage:"connect to MinIO over TLS"`
+	Bucket          string `yaml:"bucket" toml:"bucket" env:"MINIO_BUCKET" flag:"minio-bucket" usage:"bucket logs are archived in"`
+}
+
+// Default returns the default settings. Connection URLs and credentials
+// have no default.
+func Default() Config {
+	return Config{
+		NATS: NATS{
+			Stream:  queue.DefaultStream,
+			Subject: queue.DefaultSubject,
+		},
+		API: API{
+			Addr:              ":8080",
+			BcryptCost:        auth.DefaultBcryptCost,
+			MaxBodyBytes:      DefaultMaxBodyBytes,
+			ShutdownTimeout:   Duration(30 * time.Second),
+			AlertEvalInterval: Duration(DefaultAlertEvalInterval),
+			Tail: Tail{
+				BufferSize:         DefaultTailBufferSize,
+				SlowConsumerPolicy: DefaultTailPolicy,
+			},
+			Elastic: Elastic{
+				MessageField:   DefaultESMessageField,
+				LevelField:     DefaultESLevelField,
+				TimestampField: DefaultESTimestampField,
+			},
+		},
+		HotStorage: HotStorage{
+			Addr:       ":8081",
+			BlevePath:  "/data/logs.bleve",
+			BadgerPath: "/data/badger",
+
+			ShutdownTimeout: Duration(30 * time.Second),
+		},
+		Archiver: Archiver{
+			MetricsAddr: ":8082",
+			MinIO: MinIO{
+				Bucket: "logs",
+			},
+			ShutdownTimeout: Duration(30 * time.Second),
+		},
+	}
+}
+
+// Load builds the configuration of service from its defaults, the
+// configuration file, the environment and the flags in args, and validates
+// it. It returns flag.ErrHelp when args ask for usage.
+func Load(service string, args []string) (*Config, error) {
+	cfg := Default()
+	settings, err := cfg.settings(service)
+	if err != nil {
+		return nil, err
+	}
+
+	// Flags are parsed first to find the file, and applied last.
+	fs := flag.NewFlagSet(service, flag.ContinueOnError)
+	path := fs.String("config", "", "YAML or TOML configuration file (env "+FileEnv+")")
+	var flagged []func() error
+	for _, s := range settings {
+		s := s
+		apply := func(value string) error {
+			if err := s.check(value); err != nil {
+				return err
+			}
+			flagged = append(flagged, func() error { return s.set(value) })
+			return nil
+		}
+		if s.isBool() {
+			fs.BoolFunc(s.flag, s.help(), apply)
+		} else {
+			fs.Func(s.flag, s.help(), apply)
+		}
+	}
+	if err := fs.Parse(args); err != nil {
+		return nil, err
+	}
+	if fs.NArg() > 0 {
+		return nil, fmt.Errorf("unexpected arguments: %s", strings.Join(fs.Args(), " "))
+	}
+
+	if *path == "" {
+		*path = os.Getenv(FileEnv)
+	}
+	if *path != "" {
+		if err := cfg.loadFile(*path); err != nil {
+			return nil, err
+		}
+	}
+	for _, s := range settings {
+		value, ok := os.LookupEnv(s.env)
+		if !ok || value == "" {
+			continue
+		}
+		if err := s.set(value); err != nil {
+			return nil, fmt.Errorf("invalid %s: %w", s.env, err)
+		}
+	}
+	for _, set := range flagged {
+		if err := set(); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := cfg.Validate(service); err != nil {
+		return nil, err
+	}
+	return &cfg, nil
+}
+
+// MustLoad loads the configuration of service from the command line of the
+// process. It exits after printing usage when asked to, and on invalid
+// configuration.
+func MustLoad(service string) *Config {
+	cfg, err := Load(service, os.Args[1:])
+	if errors.Is(err, flag.ErrHelp) {
+		os.Exit(0)
+	}
+	if err != nil {
+		log.Fatalf("Invalid configuration: %v", err)
+	}
+	return cfg
+}
+
+// Validate checks the nats section and the section of service, reporting
+// every invalid setting at once.
+func (c *Config) Validate(service string) error {
+	var errs []error
+	check := func(ok bool, format string, args ...any) {
+		if !ok {
+			errs = append(errs, fmt.Errorf(format, args...))
+		}
+	}
+	required := func(key, value string) {
+		check(value != "", "%s is required", key)
+	}
+
+	required("nats.url", c.NATS.URL)
+	required("nats.stream", c.NATS.Stream)
+	required("nats.subject", c.NATS.Subject)
+
+	switch service {
+	case ServiceAPI:
+		a := c.API
+		required("api.addr", a.Addr)
+		required("api.database_url", a.DatabaseURL)
+		required("api.hot_storage_url", a.HotStorageURL)
+		if a.HotStorageURL != "" {
+			u, err := url.Parse(a.HotStorageURL)
+			check(err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != "",
+				"api.hot_storage_url %q must be an http or https URL", a.HotStorageURL)
+		}
+		check(a.BcryptCost >= bcrypt.MinCost && a.BcryptCost <= bcrypt.MaxCost,
+			"api.bcrypt_cost must be between %d and %d", bcrypt.MinCost, bcrypt.MaxCost)
+		check(a.MaxBodyBytes > 0, "api.max_body_bytes must be positive")
+		check(a.ShutdownTimeout > 0, "api.shutdown_timeout must be positive")
+		check(a.AlertEvalInterval > 0, "api.alert_eval_interval must be positive")
+		check(a.Tail.ReplayWindow >= 0, "api.tail.replay_window must not be negative")
+		check(a.Tail.BufferSize > 0, "api.tail.buffer_size must be positive")
+		check(a.Tail.SlowConsumerPolicy == TailPolicyDropOldest || a.Tail.SlowConsumerPolicy == TailPolicyDisconnect,
+			"api.tail.slow_consumer_policy %q must be %q or %q", a.Tail.SlowConsumerPolicy, TailPolicyDropOldest, TailPolicyDisconnect)
+		if a.Syslog.TLSAddr != "" {
+			required("api.syslog.tls_cert", a.Syslog.TLSCert)
+			required("api.syslog.tls_key", a.Syslog.TLSKey)
+		}
+	case ServiceHotStorage:
+		required("hot_storage.addr", c.HotStorage.Addr)
+		required("hot_storage.bleve_path", c.HotStorage.BlevePath)
+		required("hot_storage.badger_path", c.HotStorage.BadgerPath)
+		check(c.HotStorage.ShutdownTimeout > 0, "hot_storage.shutdown_timeout must be positive")
+	case ServiceArchiver:
+		required("archiver.metrics_addr", c.Archiver.MetricsAddr)
+		required("archiver.minio.endpoint", c.Archiver.MinIO.Endpoint)
+		required("archiver.minio.access_key_id", c.Archiver.MinIO.AccessKeyID)
+		required("archiver.minio.secret_access_key", c.Archiver.MinIO.SecretAccessKey)
+		required("archiver.minio.bucket", c.Archiver.MinIO.Bucket)
+		check(c.Archiver.ShutdownTimeout > 0, "archiver.shutdown_timeout must be positive")
+	default:
+		return fmt.Errorf("unknown service %q", service)
+	}
+	return errors.Join(errs...)
+}
+
+// Duration is a time.Duration written as a string such as "15s" or "1h".
+type Duration time.Duration
+
+// UnmarshalText implements encoding.TextUnmarshaler.
+func (d *Duration) UnmarshalText(text []byte) error {
+	v, err := time.ParseDuration(string(text))
+	if err != nil {
+		return err
+	}
+	*d = Duration(v)
+	return nil
+}
+
+// MarshalText implements encoding.TextMarshaler.
+func (d Duration) MarshalText() ([]byte, error) {
+	return []byte(time.Duration(d).String()), nil
+}
+
+// String returns the duration formatted like time.Duration.
+func (d Duration) String() string {
+	return time.Duration(d).String()
+}
@@ -0,0 +1,187 @@
+package config
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func writeFile(t *testing.T, name, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o644))
+	return path
+}
+
+const yamlConfig = `
+nats:
+  url: nats://nats:4222
+api:
+  database_url: postgres://db/logbeacon
+  hot_storage_url: http://hot-storage:8081
+  bcrypt_cost: 10
+  alert_eval_interval: 30s
+  tail:
+    replay_window: 1h
+    slow_consumer_policy: disconnect
+hot_storage:
+  bleve_path: /var/lib/beacon/logs.bleve
+archiver:
+  minio:
+    endpoint: minio:9000
+    access_key_id: admin
+    secret_access_key: secret
+    bucket: archive
+`
+
+const tomlConfig = `
+[nats]
+url = "nats://nats:4222"
+subject = "beacon.logs"
+
+[api]
+database_url = "postgres://db/logbeacon"
+hot_storage_url = "http://hot-storage:8081"
+alert_eval_interval = "30s"
+
+[api.tail]
+replay_window = "1h"
+`
+
+func TestLoadDefaults(t *testing.T) {
+	cfg, err := Load(ServiceHotStorage, []string{"-nats-url", "nats://localhost:4222"})
+	require.NoError(t, err)
+	assert.Equal(t, "LOGS", cfg.NATS.Stream)
+	assert.Equal(t, "log.events", cfg.NATS.Subject)
+	assert.Equal(t, ":8081", cfg.HotStorage.Addr)
+	assert.Equal(t, "/data/logs.bleve", cfg.HotStorage.BlevePath)
+	assert.Equal(t, "/data/badger", cfg.HotStorage.BadgerPath)
+}
+
+func TestLoadFile(t *testing.T) {
+	t.Run("yaml", func(t *testing.T) {
+		path := writeFile(t, "beacon.yaml", yamlConfig)
+		cfg, err := Load(ServiceAPI, []string{"-config", path})
+		require.NoError(t, err)
+		assert.Equal(t, "nats://nats:4222", cfg.NATS.URL)
+		assert.Equal(t, "postgres://db/logbeacon", cfg.API.DatabaseURL)
+		assert.Equal(t, 10, cfg.API.BcryptCost)
+		assert.Equal(t, Duration(30*time.Second), cfg.API.AlertEvalInterval)
+		assert.Equal(t, Duration(time.Hour), cfg.API.Tail.ReplayWindow)
+		assert.EqualValues(t, "disconnect", cfg.API.Tail.SlowConsumerPolicy)
+		// Unset keys keep their defaults.
+		assert.Equal(t, ":8080", cfg.API.Addr)
+		assert.Equal(t, 1000, cfg.API.Tail.BufferSize)
+
+		// The same file configures the other services.
+		cfg, err = Load(ServiceArchiver, []string{"-config", path})
+		require.NoError(t, err)
+		assert.Equal(t, "archive", cfg.Archiver.MinIO.Bucket)
+	})
+
+	t.Run("toml", func(t *testing.T) {
+		t.Setenv(FileEnv, writeFile(t, "beacon.toml", tomlConfig))
+		cfg, err := Load(ServiceAPI, nil)
+		require.NoError(t, err)
+		assert.Equal(t, "beacon.logs", cfg.NATS.Subject)
+		assert.Equal(t, Duration(30*time.Second), cfg.API.AlertEvalInterval)
+		assert.Equal(t, Duration(time.Hour), cfg.API.Tail.ReplayWindow)
+	})
+
+	t.Run("unknown key", func(t *testing.T) {
+		path := writeFile(t, "beacon.yaml", "nats:\n  ulr: nats://nats:4222\n")
+		_, err := Load(ServiceHotStorage, []string{"-config", path})
+		assert.ErrorContains(t, err, "ulr")
+
+		path = writeFile(t, "beacon.toml", "[nats]\nulr = \"nats://nats:4222\"\n")
+		_, err = Load(ServiceHotStorage, []string{"-config", path})
+		assert.ErrorContains(t, err, "ulr")
+	})
+
+	t.Run("unknown format", func(t *testing.T) {
+		path := writeFile(t, "beacon.json", "{}")
+		_, err := Load(ServiceHotStorage, []string{"-config", path})
+		assert.ErrorContains(t, err, "must end in .yaml, .yml or .toml")
+	})
+}
+
+func TestLoadPrecedence(t *testing.T) {
+	path := writeFile(t, "beacon.yaml", yamlConfig)
+
+	// The environment overrides the file.
+	t.Setenv("BCRYPT_COST", "12")
+	t.Setenv("API_ADDR", ":9090")
+	cfg, err := Load(ServiceAPI, []string{"-config", path})
+	require.NoError(t, err)
+	assert.Equal(t, 12, cfg.API.BcryptCost)
+	assert.Equal(t, ":9090", cfg.API.Addr)
+
+	// Flags override the environment.
+	cfg, err = Load(ServiceAPI, []string{"-config", path, "-bcrypt-cost", "11", "-tail-replay-window=2h"})
+	require.NoError(t, err)
+	assert.Equal(t, 11, cfg.API.BcryptCost)
+	assert.Equal(t, Duration(2*time.Hour), cfg.API.Tail.ReplayWindow)
+	assert.Equal(t, ":9090", cfg.API.Addr)
+
+	t.Setenv("MINIO_USE_SSL", "true")
+	cfg, err = Load(ServiceArchiver, []string{"-config", path})
+	require.NoError(t, err)
+	assert.True(t, cfg.Archiver.MinIO.UseSSL)
+	cfg, err = Load(ServiceArchiver, []string{"-config", path, "-minio-use-ssl=false"})
+	require.NoError(t, err)
+	assert.False(t, cfg.Archiver.MinIO.UseSSL)
+}
+
+func TestLoadErrors(t *testing.T) {
+	path := writeFile(t, "beacon.yaml", yamlConfig)
+
+	t.Run("invalid environment variable", func(t *testing.T) {
+		t.Setenv("ALERT_EVAL_INTERVAL", "often")
+		_, err := Load(ServiceAPI, []string{"-config", path})
+		assert.ErrorContains(t, err, "invalid ALERT_EVAL_INTERVAL")
+	})
+
+	t.Run("invalid flag", func(t *testing.T) {
+		_, err := Load(ServiceAPI, []string{"-config", path, "-bcrypt-cost", "high"})
+		assert.ErrorContains(t, err, `"high" is not an integer`)
+	})
+
+	t.Run("flag of another service", func(t *testing.T) {
+		_, err := Load(ServiceArchiver, []string{"-config", path, "-bcrypt-cost", "10"})
+		assert.ErrorContains(t, err, "flag provided but not defined")
+	})
+
+	t.Run("help", func(t *testing.T) {
+		_, err := Load(ServiceAPI, []string{"-h"})
+		assert.ErrorIs(t, err, flag.ErrHelp)
+	})
+
+	t.Run("unknown service", func(t *testing.T) {
+		_, err := Load("indexer", nil)
+		assert.EqualError(t, err, `unknown service "indexer"`)
+	})
+
+	t.Run("every invalid setting is reported", func(t *testing.T) {
+		_, err := Load(ServiceAPI, []string{"-bcrypt-cost", "40", "-hot-storage-url", "hot-storage:8081", "-tail-slow-consumer-policy", "block"})
+		require.Error(t, err)
+		for _, msg := range []string{
+			"nats.url is required",
+			"api.database_url is required",
+			`api.hot_storage_url "hot-storage:8081" must be an http or https URL`,
+			"api.bcrypt_cost must be between 4 and 31",
+			`api.tail.slow_consumer_policy "block" must be "drop_oldest" or "disconnect"`,
+		} {
+			assert.ErrorContains(t, err, msg)
+		}
+	})
+
+	t.Run("archiver requires minio credentials", func(t *testing.T) {
+		_, err := Load(ServiceArchiver, []string{"-nats-url", "nats://localhost:4222", "-minio-endpoint", "minio:9000"})
+		assert.EqualError(t, err, "archiver.minio.access_key_id is required\narchiver.minio.secret_access_key is required")
+	})
+}
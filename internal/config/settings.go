@@ -0,0 +1,146 @@
+package config
+
+import (
+	"bytes"
+	"encoding"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/pelletier/go-toml/v2"
+	"gopkg.in/yaml.v3"
+)
+
+// setting is a configurable field, overridden by an environment variable
+// and a flag.
+type setting struct {
+	field reflect.Value
+	env   string
+	flag  string
+	usage string
+	def   string
+}
+
+// settings lists the settings of the nats section and of the section of
+// service.
+func (c *Config) settings(service string) ([]setting, error) {
+	v := reflect.ValueOf(c).Elem()
+	var section reflect.Value
+	switch service {
+	case ServiceAPI:
+		section = v.FieldByName("API")
+	case ServiceHotStorage:
+		section = v.FieldByName("HotStorage")
+	case ServiceArchiver:
+		section = v.FieldByName("Archiver")
+	default:
+		return nil, fmt.Errorf("unknown service %q", service)
+	}
+
+	var settings []setting
+	collect(v.FieldByName("NATS"), &settings)
+	collect(section, &settings)
+	return settings, nil
+}
+
+// collect appends the settings of struct v, descending into nested sections.
+func collect(v reflect.Value, settings *[]setting) {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		f, fv := t.Field(i), v.Field(i)
+		if f.Tag.Get("env") == "" {
+			if f.Type.Kind() == reflect.Struct {
+				collect(fv, settings)
+			}
+			continue
+		}
+		s := setting{field: fv, env: f.Tag.Get("env"), flag: f.Tag.Get("flag"), usage: f.Tag.Get("usage")}
+		if !fv.IsZero() {
+			s.def = fmt.Sprint(fv.Interface())
+		}
+		*settings = append(*settings, s)
+	}
+}
+
+func (s setting) isBool() bool {
+	return s.field.Kind() == reflect.Bool
+}
+
+// help describes the setting in flag usage.
+func (s setting) help() string {
+	if s.def != "" && !s.isBool() {
+		return fmt.Sprintf("%s (default %q, env %s)", s.usage, s.def, s.env)
+	}
+	return fmt.Sprintf("%s (env %s)", s.usage, s.env)
+}
+
+// set parses value into the setting.
+func (s setting) set(value string) error {
+	return parseValue(s.field, value)
+}
+
+// check reports whether value can be parsed, without setting it.
+func (s setting) check(value string) error {
+	return parseValue(reflect.New(s.field.Type()).Elem(), value)
+}
+
+func parseValue(v reflect.Value, value string) error {
+	if u, ok := v.Addr().Interface().(encoding.TextUnmarshaler); ok {
+		return u.UnmarshalText([]byte(value))
+	}
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(value)
+	case reflect.Int, reflect.Int64:
+		n, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return fmt.Errorf("%q is not an integer", value)
+		}
+		v.SetInt(n)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return fmt.Errorf("%q is not a boolean", value)
+		}
+		v.SetBool(b)
+	default:
+		return fmt.Errorf("unsupported setting type %s", v.Type())
+	}
+	return nil
+}
+
+// loadFile reads a YAML or TOML file, chosen by its extension, over c.
+// Unknown keys are rejected so that misspelt settings are not ignored.
+func (c *Config) loadFile(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read config file: %w", err)
+	}
+
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		dec := yaml.NewDecoder(bytes.NewReader(data))
+		dec.KnownFields(true)
+		if err := dec.Decode(c); err != nil && !errors.Is(err, io.EOF) {
+			return fmt.Errorf("failed to parse config file %s: %w", path, err)
+		}
+	case ".toml":
+		dec := toml.NewDecoder(bytes.NewReader(data))
+		dec.DisallowUnknownFields()
+		if err := dec.Decode(c); err != nil {
+			var strict *toml.StrictMissingError
+			if errors.As(err, &strict) {
+				return fmt.Errorf("failed to parse config file %s: %s", path, strict.String())
+			}
+			return fmt.Errorf("failed to parse config file %s: %w", path, err)
+		}
+	default:
+		return fmt.Errorf("config file %s must end in .yaml, .yml or .toml", path)
+	}
+	return nil
+}
@@ -0,0 +1,300 @@
+package elastic
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"log-beacon/internal/compress"
+	"log-beacon/internal/config"
+	"log-beacon/internal/model"
+	"log-beacon/internal/pipeline"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// maxRequestSize bounds the size of a decoded bulk request.
+const maxRequestSize = 16 << 20
+
+// compatibleVersion is the Elasticsearch version reported to shippers that
+// check the cluster version before sending.
+const compatibleVersion = "8.11.0"
+
+// Config maps document fields onto log entry fields. Nested fields may be
+// given as dotted paths, e.g. "log.level" matches {"log":{"level":"info"}}.
+type Config struct {
+	MessageField   string
+	LevelField     string
+	TimestampField string
+}
+
+// DefaultConfig returns field names matching Filebeat and Fluent Bit defaults.
+func DefaultConfig() Config {
+	return Config{
+		MessageField:   config.DefaultESMessageField,
+		LevelField:     config.DefaultESLevelField,
+		TimestampField: config.DefaultESTimestampField,
+	}
+}
+
+// BulkHandler accepts documents sent to the Elasticsearch _bulk API.
+type BulkHandler struct {
+	ingest pipeline.IngestFunc
+	cfg    Config
+}
+
+// NewBulkHandler creates a handler that converts bulk documents using cfg and
+// hands them to ingest. Empty field names in cfg fall back to the defaults.
+func NewBulkHandler(ingest pipeline.IngestFunc, cfg Config) *BulkHandler {
+	defaults := DefaultConfig()
+	if cfg.MessageField == "" {
+		cfg.MessageField = defaults.MessageField
+	}
+	if cfg.LevelField == "" {
+		cfg.LevelField = defaults.LevelField
+	}
+	if cfg.TimestampField == "" {
+		cfg.TimestampField = defaults.TimestampField
+	}
+	return &BulkHandler{ingest: ingest, cfg: cfg}
+}
+
+// bulkAction is the metadata line preceding a document.
+type bulkAction struct {
+	Index string `json:"_index"`
+	ID    string `json:"_id"`
+}
+
+// HandleInfo answers the cluster info request shippers send to detect the
+// Elasticsearch version.
+func (h *BulkHandler) HandleInfo(c *gin.Context) {
+	c.Header("X-Elastic-Product", "Elasticsearch")
+	c.JSON(http.StatusOK, gin.H{
+		"name":         "log-beacon",
+		"cluster_name": "log-beacon",
+		"version": gin.H{
+			"number":                              compatibleVersion,
+			"build_flavor":                        "default",
+			"minimum_wire_compatibility_version":  "7.17.0",
+			"minimum_index_compatibility_version": "7.0.0",
+		},
+		"tagline": "You Know, for Search",
+	})
+}
+
+// HandleBulk implements POST /_bulk and POST /{index}/_bulk. Index and create
+// actions are ingested; other actions are reported as failed items.
+func (h *BulkHandler) HandleBulk(c *gin.Context) {
+	start := time.Now()
+	c.Header("X-Elastic-Product", "Elasticsearch")
+
+	body, err := compress.ReadLimited(c.Request.Body, maxRequestSize)
+	if errors.Is(err, compress.ErrTooLarge) {
+		c.JSON(http.StatusRequestEntityTooLarge, errorResponse(http.StatusRequestEntityTooLarge, "content_too_long_exception", fmt.Sprintf("request body exceeds %d bytes", maxRequestSize)))
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusBadRequest, errorResponse(http.StatusBadRequest, "parse_exception", err.Error()))
+		return
+	}
+
+	items := []gin.H{}
+	hasErrors := false
+	lines := bufio.NewScanner(bytes.NewReader(body))
+	lines.Buffer(make([]byte, 64*1024), maxRequestSize)
+	for lines.Scan() {
+		line := bytes.TrimSpace(lines.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+
+		var action map[string]bulkAction
+		if err := json.Unmarshal(line, &action); err != nil || len(action) != 1 {
+			c.JSON(http.StatusBadRequest, errorResponse(http.StatusBadRequest, "illegal_argument_exception", "Malformed action/metadata line"))
+			return
+		}
+		var name string
+		var meta bulkAction
+		for n, m := range action {
+			name, meta = n, m
+		}
+		if meta.Index == "" {
+			meta.Index = c.Param("index")
+		}
+
+		// Every action except delete is followed by a source line.
+		var source []byte
+		if name != "delete" {
+			if !lines.Scan() {
+				c.JSON(http.StatusBadRequest, errorResponse(http.StatusBadRequest, "illegal_argument_exception", "The bulk request must be terminated by a newline"))
+				return
+			}
+			source = lines.Bytes()
+		}
+
+		status, failure := h.index(name, meta, source)
+		if meta.ID == "" {
+			meta.ID = uuid.NewString()
+		}
+		item := gin.H{"_index": meta.Index, "_id": meta.ID, "status": status}
+		if failure != nil {
+			item["error"] = failure
+			hasErrors = true
+		} else {
+			item["result"] = "created"
+			item["_version"] = 1
+		}
+		items = append(items, gin.H{name: item})
+	}
+	if err := lines.Err(); err != nil {
+		c.JSON(http.StatusBadRequest, errorResponse(http.StatusBadRequest, "parse_exception", err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"took":   time.Since(start).Milliseconds(),
+		"errors": hasErrors,
+		"items":  items,
+	})
+}
+
+// index ingests a single bulk document and returns the item status and, on
+// failure, an Elasticsearch-style error object.
+func (h *BulkHandler) index(action string, meta bulkAction, source []byte) (int, gin.H) {
+	if action != "index" && action != "create" {
+		return http.StatusBadRequest, gin.H{"type": "illegal_argument_exception", "reason": fmt.Sprintf("action [%s] is not supported", action)}
+	}
+
+	logEntry, err := h.toLog(source)
+	if err != nil {
+		return http.StatusBadRequest, gin.H{"type": "document_parsing_exception", "reason": err.Error()}
+	}
+	if meta.Index != "" {
+		logEntry.Labels["index"] = meta.Index
+	}
+
+	err = h.ingest(logEntry)
+	var rejection *pipeline.RejectionError
+	switch {
+	case err == nil, errors.Is(err, pipeline.ErrDropped):
+		return http.StatusCreated, nil
+	case errors.As(err, &rejection):
+		return http.StatusBadRequest, gin.H{"type": "document_parsing_exception", "reason": rejection.Error()}
+	default:
+		// Shippers retry items that fail with 503.
+		log.Printf("Error publishing bulk document: %v", err)
+		return http.StatusServiceUnavailable, gin.H{"type": "unavailable_shards_exception", "reason": err.Error()}
+	}
+}
+
+// toLog maps a document onto a log entry. The configured message, level and
+// timestamp fields are taken out of the document and every remaining field is
+// flattened into labels the same way model.Log.UnmarshalJSON does.
+func (h *BulkHandler) toLog(source []byte) (model.Log, error) {
+	var doc map[string]interface{}
+	decoder := json.NewDecoder(bytes.NewReader(source))
+	decoder.UseNumber()
+	if err := decoder.Decode(&doc); err != nil {
+		return model.Log{}, fmt.Errorf("failed to parse document: %w", err)
+	}
+	if doc == nil {
+		return model.Log{}, fmt.Errorf("document must be a JSON object")
+	}
+
+	logEntry := model.Log{Labels: make(map[string]string)}
+	if v, ok := take(doc, h.cfg.MessageField); ok {
+		logEntry.Message = stringValue(v)
+	}
+	if v, ok := take(doc, h.cfg.LevelField); ok {
+		logEntry.Level = stringValue(v)
+	}
+	if v, ok := take(doc, h.cfg.TimestampField); ok {
+		ts, err := parseTimestamp(v)
+		if err != nil {
+			return model.Log{}, fmt.Errorf("failed to parse field [%s]: %w", h.cfg.TimestampField, err)
+		}
+		logEntry.Timestamp = ts
+	}
+
+	for key, value := range doc {
+		if nested, ok := value.(map[string]interface{}); ok && key == "labels" {
+			for nestedKey, nestedValue := range nested {
+				model.Flatten(logEntry.Labels, nestedKey, nestedValue)
+			}
+			continue
+		}
+		model.Flatten(logEntry.Labels, key, value)
+	}
+	return logEntry, nil
+}
+
+// take removes and returns the value at a field name or dotted path.
+func take(doc map[string]interface{}, field string) (interface{}, bool) {
+	if v, ok := doc[field]; ok {
+		delete(doc, field)
+		return v, true
+	}
+	head, rest, found := strings.Cut(field, ".")
+	if !found {
+		return nil, false
+	}
+	nested, ok := doc[head].(map[string]interface{})
+	if !ok {
+		return nil, false
+	}
+	v, ok := take(nested, rest)
+	if ok && len(nested) == 0 {
+		delete(doc, head)
+	}
+	return v, ok
+}
+
+func stringValue(v interface{}) string {
+	switch s := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return s
+	default:
+		return fmt.Sprintf("%v", s)
+	}
+}
+
+// parseTimestamp accepts RFC 3339 strings and epoch milliseconds.
+func parseTimestamp(v interface{}) (time.Time, error) {
+	switch ts := v.(type) {
+	case string:
+		if t, err := time.Parse(time.RFC3339Nano, ts); err == nil {
+			return t.UTC(), nil
+		}
+		if millis, err := strconv.ParseInt(ts, 10, 64); err == nil {
+			return time.UnixMilli(millis).UTC(), nil
+		}
+		return time.Time{}, fmt.Errorf("unsupported timestamp %q", ts)
+	case json.Number:
+		millis, err := ts.Float64()
+		if err != nil {
+			return time.Time{}, err
+		}
+		return time.UnixMicro(int64(millis * 1000)).UTC(), nil
+	default:
+		return time.Time{}, fmt.Errorf("unsupported timestamp %v", v)
+	}
+}
+
+// errorResponse builds an Elasticsearch-style request error.
+func errorResponse(status int, errorType, reason string) gin.H {
+	cause := gin.H{"type": errorType, "reason": reason}
+	return gin.H{
+		"error":  gin.H{"root_cause": []gin.H{cause}, "type": errorType, "reason": reason},
+		"status": status,
+	}
+}
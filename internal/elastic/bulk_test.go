@@ -0,0 +1,190 @@
+package elastic
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"log-beacon/internal/model"
+	"log-beacon/internal/pipeline"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type recorder struct {
+	logs   []model.Log
+	reject string
+	err    error
+}
+
+func (r *recorder) ingest(logEntry model.Log) error {
+	if r.err != nil {
+		return r.err
+	}
+	if r.reject != "" && logEntry.Message == r.reject {
+		return &pipeline.RejectionError{Reasons: []string{"message rejected"}}
+	}
+	r.logs = append(r.logs, logEntry)
+	return nil
+}
+
+func newTestRouter(r *recorder, cfg Config) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	h := NewBulkHandler(r.ingest, cfg)
+	router.GET("/es", h.HandleInfo)
+	router.POST("/es/_bulk", h.HandleBulk)
+	router.POST("/es/:index/_bulk", h.HandleBulk)
+	return router
+}
+
+type bulkResponse struct {
+	Errors bool                                `json:"errors"`
+	Items  []map[string]map[string]interface{} `json:"items"`
+}
+
+func postBulk(t *testing.T, router *gin.Engine, path, body string) (*httptest.ResponseRecorder, bulkResponse) {
+	t.Helper()
+	req := httptest.NewRequest(http.MethodPost, path, strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/x-ndjson")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	var resp bulkResponse
+	if w.Code == http.StatusOK {
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	}
+	return w, resp
+}
+
+func TestHandleInfo(t *testing.T) {
+	router := newTestRouter(&recorder{}, DefaultConfig())
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/es", nil))
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "Elasticsearch", w.Header().Get("X-Elastic-Product"))
+	assert.Contains(t, w.Body.String(), `"number":"8.11.0"`)
+}
+
+func TestHandleBulk(t *testing.T) {
+	rec := &recorder{}
+	router := newTestRouter(rec, DefaultConfig())
+
+	body := `{"index":{"_index":"app-logs","_id":"1"}}
+{"@timestamp":"2024-05-01T12:00:00.123Z","level":"error","message":"payment failed","service":"checkout","http":{"status":502}}
+{"create":{}}
+{"message":"request served","@timestamp":1714564800000,"labels":{"region":"eu"}}
+`
+	w, resp := postBulk(t, router, "/es/fluentbit/_bulk", body)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.False(t, resp.Errors)
+	require.Len(t, resp.Items, 2)
+	assert.Equal(t, "app-logs", resp.Items[0]["index"]["_index"])
+	assert.Equal(t, "1", resp.Items[0]["index"]["_id"])
+	assert.EqualValues(t, 201, resp.Items[0]["index"]["status"])
+	assert.Equal(t, "created", resp.Items[0]["index"]["result"])
+	assert.Equal(t, "fluentbit", resp.Items[1]["create"]["_index"])
+	assert.NotEmpty(t, resp.Items[1]["create"]["_id"])
+
+	require.Len(t, rec.logs, 2)
+	assert.Equal(t, model.Log{
+		Timestamp: time.Date(2024, 5, 1, 12, 0, 0, 123000000, time.UTC),
+		Level:     "error",
+		Message:   "payment failed",
+		Labels:    map[string]string{"service": "checkout", "http.status": "502", "index": "app-logs"},
+	}, rec.logs[0])
+	assert.Equal(t, model.Log{
+		Timestamp: time.UnixMilli(1714564800000).UTC(),
+		Message:   "request served",
+		Labels:    map[string]string{"region": "eu", "index": "fluentbit"},
+	}, rec.logs[1])
+}
+
+func TestHandleBulk_ConfiguredFields(t *testing.T) {
+	rec := &recorder{}
+	router := newTestRouter(rec, Config{MessageField: "msg", LevelField: "log.level", TimestampField: "time"})
+
+	body := `{"index":{}}
+{"time":"2024-05-01T12:00:00Z","msg":"slow query","log":{"level":"warn","logger":"db"},"message":"kept as label"}
+`
+	w, _ := postBulk(t, router, "/es/_bulk", body)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	require.Len(t, rec.logs, 1)
+	assert.Equal(t, model.Log{
+		Timestamp: time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC),
+		Level:     "warn",
+		Message:   "slow query",
+		Labels:    map[string]string{"log.logger": "db", "message": "kept as label"},
+	}, rec.logs[0])
+}
+
+func TestHandleBulk_ItemErrors(t *testing.T) {
+	rec := &recorder{reject: "too noisy"}
+	router := newTestRouter(rec, DefaultConfig())
+
+	body := `{"index":{"_id":"a"}}
+{"message":"ok"}
+{"index":{"_id":"b"}}
+{"message":"too noisy"}
+{"index":{"_id":"c"}}
+{"message":"bad time","@timestamp":"yesterday"}
+{"delete":{"_id":"d"}}
+{"update":{"_id":"e"}}
+{"doc":{"message":"changed"}}
+`
+	w, resp := postBulk(t, router, "/es/_bulk", body)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.True(t, resp.Errors)
+	require.Len(t, resp.Items, 5)
+	assert.EqualValues(t, 201, resp.Items[0]["index"]["status"])
+	assert.EqualValues(t, 400, resp.Items[1]["index"]["status"])
+	assert.EqualValues(t, 400, resp.Items[2]["index"]["status"])
+	assert.EqualValues(t, 400, resp.Items[3]["delete"]["status"])
+	assert.EqualValues(t, 400, resp.Items[4]["update"]["status"])
+	assert.NotNil(t, resp.Items[1]["index"]["error"])
+	require.Len(t, rec.logs, 1)
+	assert.Equal(t, "ok", rec.logs[0].Message)
+}
+
+func TestHandleBulk_PublishFailure(t *testing.T) {
+	router := newTestRouter(&recorder{err: io.ErrUnexpectedEOF}, DefaultConfig())
+
+	w, resp := postBulk(t, router, "/es/_bulk", "{\"index\":{}}\n{\"message\":\"ok\"}\n")
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.True(t, resp.Errors)
+	require.Len(t, resp.Items, 1)
+	assert.EqualValues(t, 503, resp.Items[0]["index"]["status"])
+}
+
+func TestHandleBulk_MalformedRequest(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+	}{
+		{"invalid action line", "not json\n{\"message\":\"ok\"}\n"},
+		{"multiple actions on one line", "{\"index\":{},\"create\":{}}\n{\"message\":\"ok\"}\n"},
+		{"missing source line", "{\"index\":{}}\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			router := newTestRouter(&recorder{}, DefaultConfig())
+			w, _ := postBulk(t, router, "/es/_bulk", tt.body)
+
+			assert.Equal(t, http.StatusBadRequest, w.Code)
+			assert.Contains(t, w.Body.String(), `"status":400`)
+		})
+	}
+}
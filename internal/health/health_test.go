@@ -0,0 +1,104 @@
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func ok(ctx context.Context) error { return nil }
+
+func serve(t *testing.T, h http.Handler) (int, Report) {
+	t.Helper()
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+	var report Report
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &report))
+	return w.Code, report
+}
+
+func TestChecker(t *testing.T) {
+	t.Run("no checks", func(t *testing.T) {
+		code, report := serve(t, NewChecker(0))
+		assert.Equal(t, http.StatusOK, code)
+		assert.Equal(t, StatusOK, report.Status)
+		assert.Empty(t, report.Checks)
+	})
+
+	t.Run("all passing", func(t *testing.T) {
+		c := NewChecker(0)
+		c.Add("nats", ok)
+		c.Add("postgres", ok)
+
+		code, report := serve(t, c)
+		assert.Equal(t, http.StatusOK, code)
+		assert.Equal(t, StatusOK, report.Status)
+		require.Len(t, report.Checks, 2)
+		assert.Equal(t, "nats", report.Checks[0].Name)
+		assert.Equal(t, "postgres", report.Checks[1].Name)
+		for _, r := range report.Checks {
+			assert.Equal(t, StatusOK, r.Status)
+			assert.Empty(t, r.Error)
+		}
+	})
+
+	t.Run("one failing", func(t *testing.T) {
+		c := NewChecker(0)
+		c.Add("nats", ok)
+		c.Add("postgres", func(ctx context.Context) error { return errors.New("connection refused") })
+
+		code, report := serve(t, c)
+		assert.Equal(t, http.StatusServiceUnavailable, code)
+		assert.Equal(t, StatusFail, report.Status)
+		assert.Equal(t, StatusOK, report.Checks[0].Status)
+		assert.Equal(t, StatusFail, report.Checks[1].Status)
+		assert.Equal(t, "connection refused", report.Checks[1].Error)
+	})
+
+	t.Run("timeout", func(t *testing.T) {
+		c := NewChecker(20 * time.Millisecond)
+		block := make(chan struct{})
+		defer close(block)
+		// The check ignores its context.
+		c.Add("minio", func(ctx context.Context) error {
+			<-block
+			return nil
+		})
+
+		report := c.Run(context.Background())
+		assert.Equal(t, StatusFail, report.Status)
+		assert.Contains(t, report.Checks[0].Error, "timed out")
+		assert.GreaterOrEqual(t, report.Checks[0].LatencyMS, 20.0)
+	})
+}
+
+func TestLive(t *testing.T) {
+	w := httptest.NewRecorder()
+	Live(w, httptest.NewRequest(http.MethodGet, "/livez", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"status":"ok"}`, w.Body.String())
+}
+
+func TestHTTP(t *testing.T) {
+	status := http.StatusOK
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(status)
+	}))
+	defer ts.Close()
+
+	check := HTTP(nil, ts.URL)
+	assert.NoError(t, check(context.Background()))
+
+	status = http.StatusServiceUnavailable
+	assert.EqualError(t, check(context.Background()), "unexpected status 503 Service Unavailable")
+
+	ts.Close()
+	assert.Error(t, check(context.Background()))
+}
@@ -0,0 +1,266 @@
+// Package logmetrics derives Prometheus metrics from the log stream.
+//
+// Rules count the entries matching a query or observe a numeric label in a
+// histogram, optionally split by the values of some labels. The number of
+// series each rule may create is bounded, so that a label with unbounded
+// values, such as a request ID, cannot exhaust memory or the scraper. A
+// Registry is a prometheus.Collector of the resulting metrics.
+package logmetrics
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+
+	"log-beacon/internal/alert"
+	"log-beacon/internal/model"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Metric types.
+const (
+	TypeCounter   = "counter"
+	TypeHistogram = "histogram"
+)
+
+// DefaultMaxSeries is the default number of series per metric.
+const DefaultMaxSeries = 1000
+
+// DefaultBuckets are the default histogram bucket upper bounds.
+var DefaultBuckets = prometheus.DefBuckets
+
+var (
+	metricNameRe = regexp.MustCompile(`^[a-zA-Z_:][a-zA-Z0-9_:]*$`)
+	invalidLabel = regexp.MustCompile(`[^a-zA-Z0-9_]`)
+)
+
+// Config lists the metric rules.
+type Config struct {
+	// MaxSeries bounds the label combinations of each metric. Entries that
+	// would create further series are not counted. Default 1000.
+	MaxSeries int    `json:"max_series"`
+	Rules     []Rule `json:"rules"`
+}
+
+// Rule defines a metric derived from the entries matching Query, in the
+// query syntax of alert rules.
+type Rule struct {
+	Name string `json:"name"`
+	Help string `json:"help"`
+	// Type is counter, counting matching entries, or histogram, observing
+	// the numeric value of ValueLabel.
+	Type  string `json:"type"`
+	Query string `json:"query"`
+	// ValueLabel names the label holding the value observed by histograms,
+	// e.g. http.duration_seconds. Entries without a numeric value are skipped.
+	ValueLabel string    `json:"value_label,omitempty"`
+	Buckets    []float64 `json:"buckets,omitempty"`
+	// GroupBy lists the labels that split the metric into series; "level"
+	// is the entry level. Label names that are not valid in Prometheus, such
+	// as http.status, are exported with underscores, e.g. http_status.
+	GroupBy []string `json:"group_by,omitempty"`
+}
+
+// LoadConfig reads a JSON metric rules file.
+func LoadConfig(path string) (Config, error) {
+	var cfg Config
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return cfg, fmt.Errorf("failed to read log metrics config: %w", err)
+	}
+	if err := json.Unmarshal(data, &cfg); err != nil {
+		return cfg, fmt.Errorf("failed to parse log metrics config: %w", err)
+	}
+	return cfg, nil
+}
+
+// Registry evaluates the metric rules against log entries and collects the
+// resulting series. It implements prometheus.Collector.
+type Registry struct {
+	maxSeries int
+	// dropped counts, per metric, the entries not recorded because the
+	// metric reached its series limit.
+	dropped *prometheus.CounterVec
+
+	mu      sync.Mutex
+	metrics []*metric
+}
+
+type metric struct {
+	Rule
+	query      *alert.Query
+	labelNames []string
+	// series holds the label values recorded so far, bounding those of vec.
+	series map[string]bool
+	// vec is the *prometheus.CounterVec or *prometheus.HistogramVec of the rule.
+	vec prometheus.Collector
+}
+
+// New validates the rules and creates a registry for them.
+func New(cfg Config) (*Registry, error) {
+	if cfg.MaxSeries <= 0 {
+		cfg.MaxSeries = DefaultMaxSeries
+	}
+	r := &Registry{
+		maxSeries: cfg.MaxSeries,
+		dropped: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "beacon_log_metrics_dropped_total",
+			Help: "Log entries not recorded in a derived metric because it reached its series limit.",
+		}, []string{"metric"}),
+	}
+	names := make(map[string]bool)
+	for _, rule := range cfg.Rules {
+		m, err := newMetric(rule)
+		if err != nil {
+			return nil, err
+		}
+		if names[m.Name] {
+			return nil, fmt.Errorf("duplicate metric %q", m.Name)
+		}
+		names[m.Name] = true
+		r.metrics = append(r.metrics, m)
+		r.dropped.WithLabelValues(m.Name)
+	}
+	return r, nil
+}
+
+func newMetric(rule Rule) (*metric, error) {
+	if !metricNameRe.MatchString(rule.Name) {
+		return nil, fmt.Errorf("invalid metric name %q", rule.Name)
+	}
+	q, err := alert.ParseQuery(rule.Query)
+	if err != nil {
+		return nil, fmt.Errorf("metric %q: %w", rule.Name, err)
+	}
+
+	switch rule.Type {
+	case TypeCounter:
+		if rule.ValueLabel != "" || len(rule.Buckets) > 0 {
+			return nil, fmt.Errorf("metric %q: value_label and buckets only apply to histograms", rule.Name)
+		}
+	case TypeHistogram:
+		if rule.ValueLabel == "" {
+			return nil, fmt.Errorf("metric %q: histograms require a value_label", rule.Name)
+		}
+		if len(rule.Buckets) == 0 {
+			rule.Buckets = DefaultBuckets
+		}
+		for i := 1; i < len(rule.Buckets); i++ {
+			if rule.Buckets[i] <= rule.Buckets[i-1] {
+				return nil, fmt.Errorf("metric %q: buckets must be in increasing order", rule.Name)
+			}
+		}
+	default:
+		return nil, fmt.Errorf("metric %q: unknown type %q, expected counter or histogram", rule.Name, rule.Type)
+	}
+
+	m := &metric{Rule: rule, query: q, series: make(map[string]bool)}
+	seen := make(map[string]bool)
+	for _, label := range rule.GroupBy {
+		name := invalidLabel.ReplaceAllString(label, "_")
+		if label == "" || name == "le" || strings.HasPrefix(name, "__") || name[0] >= '0' && name[0] <= '9' {
+			return nil, fmt.Errorf("metric %q: invalid group_by label %q", rule.Name, label)
+		}
+		if seen[name] {
+			return nil, fmt.Errorf("metric %q: duplicate group_by label %q", rule.Name, name)
+		}
+		seen[name] = true
+		m.labelNames = append(m.labelNames, name)
+	}
+
+	if rule.Help == "" {
+		rule.Help = fmt.Sprintf("Logs matching %q.", rule.Query)
+	}
+	if rule.Type == TypeCounter {
+		m.vec = prometheus.NewCounterVec(prometheus.CounterOpts{Name: rule.Name, Help: rule.Help}, m.labelNames)
+	} else {
+		m.vec = prometheus.NewHistogramVec(prometheus.HistogramOpts{Name: rule.Name, Help: rule.Help, Buckets: rule.Buckets}, m.labelNames)
+	}
+	return m, nil
+}
+
+// Describe implements prometheus.Collector.
+func (r *Registry) Describe(ch chan<- *prometheus.Desc) {
+	for _, m := range r.metrics {
+		m.vec.Describe(ch)
+	}
+	r.dropped.Describe(ch)
+}
+
+// Collect implements prometheus.Collector.
+func (r *Registry) Collect(ch chan<- prometheus.Metric) {
+	for _, m := range r.metrics {
+		m.vec.Collect(ch)
+	}
+	r.dropped.Collect(ch)
+}
+
+// Observe records an entry in the metrics whose query it matches.
+func (r *Registry) Observe(l model.Log) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, m := range r.metrics {
+		if m.query.Match(l) {
+			r.observe(m, l)
+		}
+	}
+}
+
+func (r *Registry) observe(m *metric, l model.Log) {
+	var value float64
+	if m.Type == TypeHistogram {
+		v, err := strconv.ParseFloat(l.Labels[m.ValueLabel], 64)
+		if err != nil || math.IsNaN(v) {
+			return
+		}
+		value = v
+	}
+
+	values := make([]string, len(m.GroupBy))
+	for i, label := range m.GroupBy {
+		value := l.Labels[label]
+		if label == "level" {
+			value = l.Level
+		}
+		// Prometheus label values must be valid UTF-8.
+		values[i] = strings.ToValidUTF8(value, "\uFFFD")
+	}
+	key := strings.Join(values, "\xff")
+	if !m.series[key] {
+		if len(m.series) >= r.maxSeries {
+			r.dropped.WithLabelValues(m.Name).Inc()
+			return
+		}
+		m.series[key] = true
+	}
+
+	switch vec := m.vec.(type) {
+	case *prometheus.CounterVec:
+		vec.WithLabelValues(values...).Inc()
+	case *prometheus.HistogramVec:
+		vec.WithLabelValues(values...).Observe(value)
+	}
+}
+
+// Run records the entries of events until ctx ends or the stream closes.
+func (r *Registry) Run(ctx context.Context, events <-chan model.TailEvent) error {
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case event, ok := <-events:
+			if !ok {
+				return errors.New("log stream closed")
+			}
+			r.Observe(event.Log)
+		}
+	}
+}
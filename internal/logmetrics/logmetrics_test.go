@@ -0,0 +1,158 @@
+package logmetrics
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"log-beacon/internal/model"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// assertMetric checks the registry's output about one metric against the
+// expected text exposition.
+func assertMetric(t *testing.T, r *Registry, name, expected string) {
+	t.Helper()
+	assert.NoError(t, testutil.CollectAndCompare(r, strings.NewReader(expected+"\n"), name))
+}
+
+func TestRegistry_Counter(t *testing.T) {
+	r, err := New(Config{Rules: []Rule{{
+		Name:    "beacon_errors_total",
+		Help:    "Error logs by service.",
+		Type:    TypeCounter,
+		Query:   "level:error",
+		GroupBy: []string{"service", "http.status"},
+	}}})
+	require.NoError(t, err)
+
+	r.Observe(model.Log{Level: "error", Labels: map[string]string{"service": "api", "http.status": "500"}})
+	r.Observe(model.Log{Level: "error", Labels: map[string]string{"service": "api", "http.status": "500"}})
+	r.Observe(model.Log{Level: "error", Labels: map[string]string{"service": "we\"ird\n"}})
+	r.Observe(model.Log{Level: "error", Labels: map[string]string{"service": "\xff"}})
+	r.Observe(model.Log{Level: "info", Labels: map[string]string{"service": "api"}})
+
+	assertMetric(t, r, "beacon_errors_total", `# HELP beacon_errors_total Error logs by service.
+# TYPE beacon_errors_total counter
+beacon_errors_total{http_status="",service="we\"ird\n"} 1
+beacon_errors_total{http_status="",service="�"} 1
+beacon_errors_total{http_status="500",service="api"} 2`)
+}
+
+func TestRegistry_Histogram(t *testing.T) {
+	r, err := New(Config{Rules: []Rule{{
+		Name:       "http_request_duration_seconds",
+		Type:       TypeHistogram,
+		Query:      "service:api",
+		ValueLabel: "duration",
+		Buckets:    []float64{0.1, 0.5, 1},
+		GroupBy:    []string{"level"},
+	}}})
+	require.NoError(t, err)
+
+	for _, d := range []string{"0.05", "0.1", "0.3", "2", "not a number", "", "NaN"} {
+		r.Observe(model.Log{Level: "info", Labels: map[string]string{"service": "api", "duration": d}})
+	}
+
+	assertMetric(t, r, "http_request_duration_seconds", `# HELP http_request_duration_seconds Logs matching "service:api".
+# TYPE http_request_duration_seconds histogram
+http_request_duration_seconds_bucket{level="info",le="0.1"} 2
+http_request_duration_seconds_bucket{level="info",le="0.5"} 3
+http_request_duration_seconds_bucket{level="info",le="1"} 3
+http_request_duration_seconds_bucket{level="info",le="+Inf"} 4
+http_request_duration_seconds_sum{level="info"} 2.45
+http_request_duration_seconds_count{level="info"} 4`)
+}
+
+func TestRegistry_MaxSeries(t *testing.T) {
+	r, err := New(Config{MaxSeries: 2, Rules: []Rule{{
+		Name:    "requests_total",
+		Type:    TypeCounter,
+		Query:   "*",
+		GroupBy: []string{"request_id"},
+	}}})
+	require.NoError(t, err)
+
+	for i := 0; i < 5; i++ {
+		r.Observe(model.Log{Labels: map[string]string{"request_id": fmt.Sprint(i)}})
+	}
+	r.Observe(model.Log{Labels: map[string]string{"request_id": "0"}})
+
+	assertMetric(t, r, "requests_total", `# HELP requests_total Logs matching "*".
+# TYPE requests_total counter
+requests_total{request_id="0"} 2
+requests_total{request_id="1"} 1`)
+	assertMetric(t, r, "beacon_log_metrics_dropped_total", `# HELP beacon_log_metrics_dropped_total Log entries not recorded in a derived metric because it reached its series limit.
+# TYPE beacon_log_metrics_dropped_total counter
+beacon_log_metrics_dropped_total{metric="requests_total"} 3`)
+}
+
+func TestRegistry_Run(t *testing.T) {
+	r, err := New(Config{Rules: []Rule{{Name: "logs_total", Type: TypeCounter, Query: "*"}}})
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	events := make(chan model.TailEvent)
+	done := make(chan error)
+	go func() { done <- r.Run(ctx, events) }()
+	events <- model.TailEvent{Seq: 1, Log: model.Log{Message: "one"}}
+	events <- model.TailEvent{Seq: 2, Log: model.Log{Message: "two"}}
+
+	assert.Eventually(t, func() bool {
+		return testutil.CollectAndCompare(r, strings.NewReader("# HELP logs_total Logs matching \"*\".\n# TYPE logs_total counter\nlogs_total 2\n"), "logs_total") == nil
+	}, time.Second, 10*time.Millisecond)
+
+	cancel()
+	assert.ErrorIs(t, <-done, context.Canceled)
+}
+
+func TestNew_InvalidRules(t *testing.T) {
+	tests := []struct {
+		name string
+		rule Rule
+	}{
+		{name: "Invalid name", rule: Rule{Name: "http-errors", Type: TypeCounter, Query: "*"}},
+		{name: "Unknown type", rule: Rule{Name: "m", Type: "gauge", Query: "*"}},
+		{name: "Invalid query", rule: Rule{Name: "m", Type: TypeCounter}},
+		{name: "Query with OR", rule: Rule{Name: "m", Type: TypeCounter, Query: "(level:error OR level:warn)"}},
+		{name: "Counter with value label", rule: Rule{Name: "m", Type: TypeCounter, Query: "*", ValueLabel: "v"}},
+		{name: "Histogram without value label", rule: Rule{Name: "m", Type: TypeHistogram, Query: "*"}},
+		{name: "Unordered buckets", rule: Rule{Name: "m", Type: TypeHistogram, Query: "*", ValueLabel: "v", Buckets: []float64{1, 0.5}}},
+		{name: "Reserved label", rule: Rule{Name: "m", Type: TypeCounter, Query: "*", GroupBy: []string{"le"}}},
+		{name: "Colliding labels", rule: Rule{Name: "m", Type: TypeCounter, Query: "*", GroupBy: []string{"http.status", "http_status"}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := New(Config{Rules: []Rule{tt.rule}})
+			assert.Error(t, err)
+		})
+	}
+
+	_, err := New(Config{Rules: []Rule{
+		{Name: "m", Type: TypeCounter, Query: "*"},
+		{Name: "m", Type: TypeCounter, Query: "level:error"},
+	}})
+	assert.Error(t, err, "duplicate names")
+}
+
+func TestLoadConfig(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "metrics.json")
+	require.NoError(t, os.WriteFile(path, []byte(`{
+		"max_series": 50,
+		"rules": [{"name": "errors_total", "type": "counter", "query": "level:error", "group_by": ["service"]}]
+	}`), 0o644))
+
+	cfg, err := LoadConfig(path)
+	require.NoError(t, err)
+	assert.Equal(t, 50, cfg.MaxSeries)
+	require.Len(t, cfg.Rules, 1)
+	assert.Equal(t, []string{"service"}, cfg.Rules[0].GroupBy)
+}
@@ -0,0 +1,84 @@
+package loki
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// jsonRequest accepts the current JSON push format
+// ({"stream": {...}, "values": [["<unix ns>", "<line>", {metadata}]]})
+// as well as the legacy one ({"labels": "{...}", "entries": [{"ts", "line"}]}).
+type jsonRequest struct {
+	Streams []struct {
+		Stream  map[string]string   `json:"stream"`
+		Values  [][]json.RawMessage `json:"values"`
+		Labels  string              `json:"labels"`
+		Entries []struct {
+			Timestamp time.Time `json:"ts"`
+			Line      string    `json:"line"`
+		} `json:"entries"`
+	} `json:"streams"`
+}
+
+// UnmarshalJSON decodes a JSON-encoded PushRequest.
+func (r *PushRequest) UnmarshalJSON(data []byte) error {
+	var req jsonRequest
+	if err := json.Unmarshal(data, &req); err != nil {
+		return err
+	}
+
+	for _, js := range req.Streams {
+		stream := Stream{Labels: js.Stream}
+		if js.Labels != "" {
+			labels, err := ParseLabels(js.Labels)
+			if err != nil {
+				return err
+			}
+			stream.Labels = labels
+		}
+
+		for _, value := range js.Values {
+			entry, err := parseJSONValue(value)
+			if err != nil {
+				return err
+			}
+			stream.Entries = append(stream.Entries, entry)
+		}
+		for _, je := range js.Entries {
+			stream.Entries = append(stream.Entries, Entry{Timestamp: je.Timestamp.UTC(), Line: je.Line})
+		}
+		r.Streams = append(r.Streams, stream)
+	}
+	return nil
+}
+
+// parseJSONValue decodes a ["<unix ns>", "<line>", {metadata}] tuple.
+func parseJSONValue(value []json.RawMessage) (Entry, error) {
+	var entry Entry
+	if len(value) < 2 || len(value) > 3 {
+		return entry, fmt.Errorf("invalid stream value: expected [timestamp, line] or [timestamp, line, metadata]")
+	}
+
+	var ts string
+	if err := json.Unmarshal(value[0], &ts); err != nil {
+		return entry, fmt.Errorf("invalid timestamp: %w", err)
+	}
+	nanos, err := strconv.ParseInt(ts, 10, 64)
+	if err != nil {
+		return entry, fmt.Errorf("invalid timestamp %q", ts)
+	}
+	entry.Timestamp = time.Unix(0, nanos).UTC()
+
+	if err := json.Unmarshal(value[1], &entry.Line); err != nil {
+		return entry, fmt.Errorf("invalid line: %w", err)
+	}
+
+	if len(value) == 3 {
+		if err := json.Unmarshal(value[2], &entry.StructuredMetadata); err != nil {
+			return entry, fmt.Errorf("invalid structured metadata: %w", err)
+		}
+	}
+	return entry, nil
+}
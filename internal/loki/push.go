@@ -0,0 +1,113 @@
+package loki
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/grafana/loki/pkg/push"
+)
+
+// PushRequest is the payload of a Loki push as sent by Promtail, Grafana
+// Agent and the Docker driver, with the stream labels parsed.
+type PushRequest struct {
+	Streams []Stream
+}
+
+// Stream is a set of entries sharing the same labels.
+type Stream struct {
+	Labels  map[string]string
+	Entries []Entry
+}
+
+// Entry is a single log line.
+type Entry struct {
+	Timestamp          time.Time
+	Line               string
+	StructuredMetadata map[string]string
+}
+
+// UnmarshalProto decodes an uncompressed protobuf-encoded push request
+// (logproto.PushRequest).
+func (r *PushRequest) UnmarshalProto(b []byte) error {
+	var req push.PushRequest
+	if err := req.Unmarshal(b); err != nil {
+		return err
+	}
+
+	for _, ps := range req.Streams {
+		labels, err := ParseLabels(ps.Labels)
+		if err != nil {
+			return err
+		}
+		stream := Stream{Labels: labels, Entries: make([]Entry, 0, len(ps.Entries))}
+		for _, pe := range ps.Entries {
+			entry := Entry{Timestamp: pe.Timestamp.UTC(), Line: pe.Line}
+			for _, pair := range pe.StructuredMetadata {
+				if pair.Name == "" {
+					continue
+				}
+				if entry.StructuredMetadata == nil {
+					entry.StructuredMetadata = make(map[string]string, len(pe.StructuredMetadata))
+				}
+				entry.StructuredMetadata[pair.Name] = pair.Value
+			}
+			stream.Entries = append(stream.Entries, entry)
+		}
+		r.Streams = append(r.Streams, stream)
+	}
+	return nil
+}
+
+// ParseLabels parses a Prometheus-style label set such as
+// `{app="api", env="prod"}`. Values use Go string escaping.
+func ParseLabels(s string) (map[string]string, error) {
+	labels := make(map[string]string)
+	rest := strings.TrimSpace(s)
+	if len(rest) < 2 || rest[0] != '{' || rest[len(rest)-1] != '}' {
+		return nil, fmt.Errorf("invalid stream labels %q", s)
+	}
+	rest = strings.TrimSpace(rest[1 : len(rest)-1])
+
+	for rest != "" {
+		i := 0
+		for i < len(rest) && isLabelNameChar(rest[i], i == 0) {
+			i++
+		}
+		if i == 0 {
+			return nil, fmt.Errorf("invalid stream labels %q: expected label name", s)
+		}
+		name := rest[:i]
+		rest = strings.TrimSpace(rest[i:])
+
+		if rest == "" || rest[0] != '=' {
+			return nil, fmt.Errorf("invalid stream labels %q: expected '=' after %s", s, name)
+		}
+		rest = strings.TrimSpace(rest[1:])
+
+		quoted, err := strconv.QuotedPrefix(rest)
+		if err != nil || quoted[0] != '"' {
+			return nil, fmt.Errorf("invalid stream labels %q: expected quoted value for %s", s, name)
+		}
+		value, err := strconv.Unquote(quoted)
+		if err != nil {
+			return nil, fmt.Errorf("invalid stream labels %q: %w", s, err)
+		}
+		labels[name] = value
+		rest = strings.TrimSpace(rest[len(quoted):])
+
+		if rest == "" {
+			break
+		}
+		if rest[0] != ',' {
+			return nil, fmt.Errorf("invalid stream labels %q: expected ',' after %s", s, name)
+		}
+		rest = strings.TrimSpace(rest[1:])
+	}
+	return labels, nil
+}
+
+func isLabelNameChar(c byte, first bool) bool {
+	return c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || (!first && c >= '0' && c <= '9')
+}
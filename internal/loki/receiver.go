@@ -0,0 +1,127 @@
+package loki
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+
+	"log-beacon/internal/compress"
+	"log-beacon/internal/model"
+	"log-beacon/internal/pipeline"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang/snappy"
+)
+
+// maxRequestSize bounds the size of a decoded push request.
+const maxRequestSize = 16 << 20
+
+// levelLabel is the stream label promoted to the log level.
+const levelLabel = "level"
+
+// Receiver accepts pushes in the Loki push API format.
+type Receiver struct {
+	ingest pipeline.IngestFunc
+}
+
+// NewReceiver creates a receiver that hands every entry to ingest.
+func NewReceiver(ingest pipeline.IngestFunc) *Receiver {
+	return &Receiver{ingest: ingest}
+}
+
+// ToLogs converts every entry in the request into a log entry. Stream labels
+// and structured metadata become labels; a "level" label becomes the log level.
+func ToLogs(req *PushRequest) []model.Log {
+	var logs []model.Log
+	for _, stream := range req.Streams {
+		for _, entry := range stream.Entries {
+			logEntry := model.Log{
+				Timestamp: entry.Timestamp,
+				Message:   entry.Line,
+				Labels:    make(map[string]string, len(stream.Labels)+len(entry.StructuredMetadata)),
+			}
+			for k, v := range stream.Labels {
+				logEntry.Labels[k] = v
+			}
+			for k, v := range entry.StructuredMetadata {
+				logEntry.Labels[k] = v
+			}
+			if level, ok := logEntry.Labels[levelLabel]; ok {
+				logEntry.Level = level
+				delete(logEntry.Labels, levelLabel)
+			}
+			logs = append(logs, logEntry)
+		}
+	}
+	return logs
+}
+
+// HandlePush implements POST /loki/api/v1/push. Protobuf payloads are snappy
+// compressed as sent by Promtail; JSON payloads are plain JSON.
+func (r *Receiver) HandlePush(c *gin.Context) {
+	body, err := compress.ReadLimited(c.Request.Body, maxRequestSize)
+	if errors.Is(err, compress.ErrTooLarge) {
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": fmt.Sprintf("request body exceeds %d bytes", maxRequestSize)})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var req PushRequest
+	if strings.HasPrefix(c.ContentType(), "application/json") {
+		err = json.Unmarshal(body, &req)
+	} else {
+		err = decodeProto(body, &req)
+	}
+	if errors.Is(err, compress.ErrTooLarge) {
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": fmt.Sprintf("request body exceeds %d bytes once decoded", maxRequestSize)})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("invalid push payload: %v", err)})
+		return
+	}
+
+	result, err := pipeline.IngestBatch(r.ingest, ToLogs(&req))
+	if err != nil {
+		log.Printf("Error publishing Loki push: %v", err)
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Failed to process logs"})
+		return
+	}
+
+	// Like Loki, accept the valid entries but report rejected ones with a 400
+	// so that clients do not retry them.
+	if len(result.Rejected) > 0 {
+		reasons := make([]string, 0, len(result.Rejected))
+		for _, rejection := range result.Rejected {
+			reasons = append(reasons, rejection.Err.Error())
+		}
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   fmt.Sprintf("%d entries rejected", len(reasons)),
+			"reasons": reasons,
+		})
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// decodeProto snappy-decodes and unmarshals a protobuf push request.
+func decodeProto(body []byte, req *PushRequest) error {
+	size, err := snappy.DecodedLen(body)
+	if err != nil {
+		return fmt.Errorf("invalid snappy body: %w", err)
+	}
+	if size > maxRequestSize {
+		return compress.ErrTooLarge
+	}
+	decoded, err := snappy.Decode(nil, body)
+	if err != nil {
+		return fmt.Errorf("invalid snappy body: %w", err)
+	}
+	return req.UnmarshalProto(decoded)
+}
@@ -0,0 +1,182 @@
+package loki
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"log-beacon/internal/model"
+	"log-beacon/internal/pipeline"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang/snappy"
+	"github.com/grafana/loki/pkg/push"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// samplePushRequest builds a snappy-compressed protobuf push with one stream.
+func samplePushRequest(labels string, lines ...string) []byte {
+	stream := push.Stream{Labels: labels}
+	for i, line := range lines {
+		stream.Entries = append(stream.Entries, push.Entry{
+			Timestamp:          time.Date(2024, 5, 1, 12, 0, i, 500, time.UTC),
+			Line:               line,
+			StructuredMetadata: push.LabelsAdapter{{Name: "trace_id", Value: "abc123"}},
+		})
+	}
+	req := push.PushRequest{Streams: []push.Stream{stream}}
+	data, err := req.Marshal()
+	if err != nil {
+		panic(err)
+	}
+	return snappy.Encode(nil, data)
+}
+
+type recorder struct {
+	logs   []model.Log
+	reject string
+	err    error
+}
+
+func (r *recorder) ingest(logEntry model.Log) error {
+	if r.err != nil {
+		return r.err
+	}
+	if r.reject != "" && logEntry.Message == r.reject {
+		return &pipeline.RejectionError{Reasons: []string{"message rejected"}}
+	}
+	r.logs = append(r.logs, logEntry)
+	return nil
+}
+
+func newTestRouter(r *recorder) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.POST("/loki/api/v1/push", NewReceiver(r.ingest).HandlePush)
+	return router
+}
+
+func TestParseLabels(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		want    map[string]string
+		wantErr bool
+	}{
+		{"empty", "{}", map[string]string{}, false},
+		{"single", `{app="api"}`, map[string]string{"app": "api"}, false},
+		{"multiple with spaces", `{ app = "api", env="prod" }`, map[string]string{"app": "api", "env": "prod"}, false},
+		{"escaped value", `{path="C:\\logs", msg="say \"hi\""}`, map[string]string{"path": `C:\logs`, "msg": `say "hi"`}, false},
+		{"missing braces", `app="api"`, nil, true},
+		{"unquoted value", `{app=api}`, nil, true},
+		{"missing comma", `{app="api" env="prod"}`, nil, true},
+		{"invalid name", `{1app="api"}`, nil, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseLabels(tt.input)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestHandlePush_Protobuf(t *testing.T) {
+	rec := &recorder{}
+	router := newTestRouter(rec)
+
+	req := httptest.NewRequest(http.MethodPost, "/loki/api/v1/push", bytes.NewReader(samplePushRequest(`{job="varlogs", level="warn"}`, "disk almost full", "disk full")))
+	req.Header.Set("Content-Type", "application/x-protobuf")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusNoContent, w.Code)
+	require.Len(t, rec.logs, 2)
+	assert.Equal(t, model.Log{
+		Timestamp: time.Date(2024, 5, 1, 12, 0, 0, 500, time.UTC),
+		Level:     "warn",
+		Message:   "disk almost full",
+		Labels:    map[string]string{"job": "varlogs", "trace_id": "abc123"},
+	}, rec.logs[0])
+	assert.Equal(t, "disk full", rec.logs[1].Message)
+	assert.Equal(t, time.Date(2024, 5, 1, 12, 0, 1, 500, time.UTC), rec.logs[1].Timestamp)
+}
+
+func TestHandlePush_JSON(t *testing.T) {
+	rec := &recorder{}
+	router := newTestRouter(rec)
+
+	body := `{"streams":[
+		{"stream":{"app":"api"},"values":[["1714564800000000000","request served"],["1714564801000000000","request failed",{"user":"alice"}]]},
+		{"labels":"{app=\"worker\"}","entries":[{"ts":"2024-05-01T12:00:02Z","line":"job done"}]}
+	]}`
+	req := httptest.NewRequest(http.MethodPost, "/loki/api/v1/push", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusNoContent, w.Code)
+	require.Len(t, rec.logs, 3)
+	assert.Equal(t, "request served", rec.logs[0].Message)
+	assert.Equal(t, time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC), rec.logs[0].Timestamp)
+	assert.Equal(t, map[string]string{"app": "api"}, rec.logs[0].Labels)
+	assert.Equal(t, map[string]string{"app": "api", "user": "alice"}, rec.logs[1].Labels)
+	assert.Equal(t, "job done", rec.logs[2].Message)
+	assert.Equal(t, map[string]string{"app": "worker"}, rec.logs[2].Labels)
+}
+
+func TestHandlePush_Rejected(t *testing.T) {
+	rec := &recorder{reject: "disk full"}
+	router := newTestRouter(rec)
+
+	req := httptest.NewRequest(http.MethodPost, "/loki/api/v1/push", bytes.NewReader(samplePushRequest(`{job="varlogs"}`, "disk almost full", "disk full")))
+	req.Header.Set("Content-Type", "application/x-protobuf")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.JSONEq(t, `{"error":"1 entries rejected","reasons":["log entry rejected: message rejected"]}`, w.Body.String())
+	assert.Len(t, rec.logs, 1)
+}
+
+func TestHandlePush_Errors(t *testing.T) {
+	tests := []struct {
+		name        string
+		contentType string
+		body        []byte
+		ingestErr   error
+		wantStatus  int
+	}{
+		{"not snappy", "application/x-protobuf", []byte("plain text"), nil, http.StatusBadRequest},
+		{"malformed protobuf", "application/x-protobuf", snappy.Encode(nil, []byte{0xff, 0xff, 0xff}), nil, http.StatusBadRequest},
+		{"bad stream labels", "application/x-protobuf", samplePushRequest(`job="varlogs"`, "line"), nil, http.StatusBadRequest},
+		{"malformed json", "application/json", []byte("{"), nil, http.StatusBadRequest},
+		{"oversized body", "application/json", bytes.Repeat([]byte(" "), maxRequestSize+1), nil, http.StatusRequestEntityTooLarge},
+		{"oversized once decoded", "application/x-protobuf", snappy.Encode(nil, make([]byte, maxRequestSize+1)), nil, http.StatusRequestEntityTooLarge},
+		{"bad json timestamp", "application/json", []byte(`{"streams":[{"stream":{},"values":[["yesterday","line"]]}]}`), nil, http.StatusBadRequest},
+		{"publish failure", "application/x-protobuf", samplePushRequest(`{job="varlogs"}`, "line"), io.ErrUnexpectedEOF, http.StatusServiceUnavailable},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			router := newTestRouter(&recorder{err: tt.ingestErr})
+
+			req := httptest.NewRequest(http.MethodPost, "/loki/api/v1/push", bytes.NewReader(tt.body))
+			req.Header.Set("Content-Type", tt.contentType)
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.wantStatus, w.Code)
+		})
+	}
+}
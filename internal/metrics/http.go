@@ -0,0 +1,38 @@
+package metrics
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+var httpRequestDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+	Name: "beacon_http_request_duration_seconds",
+	Help: "Latency of HTTP requests by route, method and status code.",
+}, []string{"route", "method", "code"})
+
+// Middleware measures the latency of the requests to a gin router. Routes
+// in skip, such as long-lived streaming endpoints, are not measured.
+func Middleware(skip ...string) gin.HandlerFunc {
+	skipped := make(map[string]bool, len(skip))
+	for _, route := range skip {
+		skipped[route] = true
+	}
+	return func(c *gin.Context) {
+		route := c.FullPath()
+		if skipped[route] {
+			c.Next()
+			return
+		}
+		if route == "" {
+			// Keep unknown paths from creating a series each.
+			route = "unmatched"
+		}
+		start := time.Now()
+		c.Next()
+		httpRequestDuration.WithLabelValues(route, c.Request.Method, strconv.Itoa(c.Writer.Status())).Observe(time.Since(start).Seconds())
+	}
+}
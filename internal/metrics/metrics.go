@@ -0,0 +1,35 @@
+// Package metrics holds the Prometheus helpers shared by the services. The
+// metrics themselves are created with client_golang, usually as promauto
+// package variables, and served by promhttp at /metrics.
+package metrics
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// GaugeFunc is a gauge whose value is read when the metrics are scraped,
+// such as the size of a database. Unlike prometheus.GaugeFunc, its function
+// may fail, in which case the metric is left out of the scrape.
+type GaugeFunc struct {
+	desc *prometheus.Desc
+	fn   func() (float64, error)
+}
+
+// NewGaugeFunc creates a gauge func, to be registered by the caller.
+func NewGaugeFunc(name, help string, fn func() (float64, error)) *GaugeFunc {
+	return &GaugeFunc{desc: prometheus.NewDesc(name, help, nil, nil), fn: fn}
+}
+
+// Describe implements prometheus.Collector.
+func (g *GaugeFunc) Describe(ch chan<- *prometheus.Desc) {
+	ch <- g.desc
+}
+
+// Collect implements prometheus.Collector.
+func (g *GaugeFunc) Collect(ch chan<- prometheus.Metric) {
+	v, err := g.fn()
+	if err != nil {
+		return
+	}
+	ch <- prometheus.MustNewConstMetric(g.desc, prometheus.GaugeValue, v)
+}
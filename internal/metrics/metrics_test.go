@@ -0,0 +1,51 @@
+package metrics
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGaugeFunc(t *testing.T) {
+	var err error
+	g := NewGaugeFunc("db_size_bytes", "Size of the database.", func() (float64, error) { return 42, err })
+
+	assert.NoError(t, testutil.CollectAndCompare(g, strings.NewReader(`# HELP db_size_bytes Size of the database.
+# TYPE db_size_bytes gauge
+db_size_bytes 42
+`)))
+
+	err = errors.New("closed")
+	assert.Equal(t, 0, testutil.CollectAndCount(g), "a failing gauge is left out")
+}
+
+func TestMiddleware(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(Middleware("/stream"))
+	router.GET("/items/:id", func(c *gin.Context) { c.Status(http.StatusNoContent) })
+	router.GET("/stream", func(c *gin.Context) { c.Status(http.StatusOK) })
+
+	count := func(route, code string) uint64 {
+		var m dto.Metric
+		require.NoError(t, httpRequestDuration.WithLabelValues(route, http.MethodGet, code).(prometheus.Metric).Write(&m))
+		return m.GetHistogram().GetSampleCount()
+	}
+	before := count("/items/:id", "204")
+	for _, path := range []string{"/items/1", "/items/2", "/stream", "/missing"} {
+		router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, path, nil))
+	}
+
+	assert.Equal(t, before+2, count("/items/:id", "204"))
+	assert.Equal(t, uint64(0), count("/stream", "200"))
+	assert.Equal(t, uint64(1), count("unmatched", "404"))
+}
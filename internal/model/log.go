@@ -1,8 +1,10 @@
 package model
 
 import (
+	"bytes"
 	"encoding/json"
 	"fmt"
+	"strconv"
 	"time"
 )
 
@@ -17,11 +19,15 @@ type Log struct {
 }
 
 // UnmarshalJSON implements custom unmarshalling to capture top-level fields into Labels.
+// Nested objects and arrays are flattened into dotted label keys, e.g.
+// {"http":{"status":500}} becomes the label "http.status" = "500".
 func (l *Log) UnmarshalJSON(data []byte) error {
-	// 1. Unmarshal into a temporary struct to get known fields
+	// 1. Unmarshal into a temporary struct to get known fields.
+	// Labels is shadowed so that nested label values can be flattened below.
 	type Alias Log
 	aux := &struct {
 		*Alias
+		Labels json.RawMessage `json:"labels"`
 	}{
 		Alias: (*Alias)(l),
 	}
@@ -29,9 +35,11 @@ func (l *Log) UnmarshalJSON(data []byte) error {
 		return err
 	}
 
-	// 2. Unmarshal into a map to get all fields
+	// 2. Unmarshal into a map to get all fields, keeping numbers verbatim
 	var allFields map[string]interface{}
-	if err := json.Unmarshal(data, &allFields); err != nil {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	if err := decoder.Decode(&allFields); err != nil {
 		return err
 	}
 
@@ -43,13 +51,43 @@ func (l *Log) UnmarshalJSON(data []byte) error {
 	// 4. Iterate over all fields and add unknown ones to Labels
 	for key, value := range allFields {
 		switch key {
-		case "timestamp", "level", "message", "labels":
+		case "timestamp", "level", "message":
 			continue
+		case "labels":
+			nested, ok := value.(map[string]interface{})
+			if !ok {
+				if value != nil {
+					return fmt.Errorf("labels must be a JSON object")
+				}
+				continue
+			}
+			for nestedKey, nestedValue := range nested {
+				Flatten(l.Labels, nestedKey, nestedValue)
+			}
 		default:
-			// Convert value to string
-			l.Labels[key] = fmt.Sprintf("%v", value)
+			Flatten(l.Labels, key, value)
 		}
 	}
 
 	return nil
 }
+
+// Flatten stores a decoded JSON value under key in labels, recursing into objects
+// and arrays so that every leaf ends up under a dotted key. Null values are skipped.
+func Flatten(labels map[string]string, key string, value interface{}) {
+	switch v := value.(type) {
+	case nil:
+		return
+	case map[string]interface{}:
+		for nestedKey, nestedValue := range v {
+			Flatten(labels, key+"."+nestedKey, nestedValue)
+		}
+	case []interface{}:
+		for i, item := range v {
+			Flatten(labels, key+"."+strconv.Itoa(i), item)
+		}
+	default:
+		// Convert value to string
+		labels[key] = fmt.Sprintf("%v", v)
+	}
+}
@@ -52,6 +52,24 @@ func TestLogUnmarshalJSON(t *testing.T) {
 				Labels:  map[string]string{"env": "prod", "service": "auth"},
 			},
 		},
+		{
+			name: "Nested objects are flattened",
+			json: `{"level":"info", "message":"test", "http":{"status":500, "route":{"path":"/login"}}, "labels":{"k8s":{"pod":"api-1"}}}`,
+			expected: Log{
+				Level:   "info",
+				Message: "test",
+				Labels:  map[string]string{"http.status": "500", "http.route.path": "/login", "k8s.pod": "api-1"},
+			},
+		},
+		{
+			name: "Arrays, large numbers and nulls",
+			json: `{"level":"info", "message":"test", "tags":["a","b"], "bytes":12345678901, "user":null}`,
+			expected: Log{
+				Level:   "info",
+				Message: "test",
+				Labels:  map[string]string{"tags.0": "a", "tags.1": "b", "bytes": "12345678901"},
+			},
+		},
 	}
 
 	for _, tt := range tests {
@@ -72,3 +90,23 @@ func TestLogUnmarshalJSON(t *testing.T) {
 		})
 	}
 }
+
+func TestTailEventJSON(t *testing.T) {
+	event := TailEvent{
+		Seq: 42,
+		Log: Log{
+			Timestamp: time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC),
+			Level:     "error",
+			Message:   "boom",
+			Labels:    map[string]string{"service": "api"},
+		},
+	}
+
+	data, err := json.Marshal(event)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"seq":42,"timestamp":"2024-05-01T12:00:00Z","level":"error","message":"boom","labels":{"service":"api"}}`, string(data))
+
+	var decoded TailEvent
+	require.NoError(t, json.Unmarshal(data, &decoded))
+	assert.Equal(t, event, decoded)
+}
@@ -0,0 +1,29 @@
+package model
+
+import "encoding/json"
+
+// TailEvent is a log entry streamed by the live tail together with its
+// sequence number in the log stream. It is encoded as the log entry with an
+// additional "seq" field; a client that reconnects resumes after the last
+// sequence number it received.
+type TailEvent struct {
+	Seq uint64 `json:"seq"`
+	Log
+}
+
+// UnmarshalJSON decodes the log entry and its sequence number, keeping "seq"
+// out of the labels.
+func (e *TailEvent) UnmarshalJSON(data []byte) error {
+	var aux struct {
+		Seq uint64 `json:"seq"`
+	}
+	if err := json.Unmarshal(data, &aux); err != nil {
+		return err
+	}
+	if err := json.Unmarshal(data, &e.Log); err != nil {
+		return err
+	}
+	delete(e.Log.Labels, "seq")
+	e.Seq = aux.Seq
+	return nil
+}
@@ -0,0 +1,130 @@
+package notify
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net"
+	"net/smtp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// SMTPConfig configures an email channel.
+type SMTPConfig struct {
+	Host string `json:"host"`
+	// Port defaults to 587.
+	Port int `json:"port"`
+	// Username and Password enable PLAIN authentication, which requires
+	// STARTTLS unless the server is on localhost.
+	Username string   `json:"username,omitempty"`
+	Password string   `json:"password,omitempty"`
+	From     string   `json:"from"`
+	To       []string `json:"to"`
+}
+
+// Email sends plain text notifications over SMTP, upgrading the connection
+// with STARTTLS when the server offers it.
+type Email struct {
+	cfg SMTPConfig
+}
+
+// NewEmail creates an email channel.
+func NewEmail(cfg SMTPConfig) (*Email, error) {
+	if cfg.Host == "" {
+		return nil, fmt.Errorf("smtp host is required")
+	}
+	if cfg.Port == 0 {
+		cfg.Port = 587
+	}
+	if cfg.From == "" {
+		return nil, fmt.Errorf("smtp from address is required")
+	}
+	if len(cfg.To) == 0 {
+		return nil, fmt.Errorf("at least one recipient is required")
+	}
+	for _, addr := range append([]string{cfg.From}, cfg.To...) {
+		if strings.ContainsAny(addr, "\r\n") {
+			return nil, fmt.Errorf("invalid address %q", addr)
+		}
+	}
+	return &Email{cfg: cfg}, nil
+}
+
+// Send implements Channel.
+func (e *Email) Send(ctx context.Context, msg Message) error {
+	addr := net.JoinHostPort(e.cfg.Host, strconv.Itoa(e.cfg.Port))
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return err
+	}
+	// The SMTP client has no context support, so the deadline bounds the whole exchange.
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+
+	client, err := smtp.NewClient(conn, e.cfg.Host)
+	if err != nil {
+		conn.Close()
+		return err
+	}
+	defer client.Close()
+
+	if ok, _ := client.Extension("STARTTLS"); ok {
+		if err := client.StartTLS(&tls.Config{ServerName: e.cfg.Host}); err != nil {
+			return err
+		}
+	}
+	if e.cfg.Username != "" {
+		if err := client.Auth(smtp.PlainAuth("", e.cfg.Username, e.cfg.Password, e.cfg.Host)); err != nil {
+			return &permanentError{err}
+		}
+	}
+
+	if err := client.Mail(e.cfg.From); err != nil {
+		return err
+	}
+	for _, to := range e.cfg.To {
+		if err := client.Rcpt(to); err != nil {
+			return err
+		}
+	}
+	w, err := client.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(e.message(msg)); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+	return client.Quit()
+}
+
+func (e *Email) message(msg Message) []byte {
+	var b strings.Builder
+	fmt.Fprintf(&b, "From: %s\r\n", e.cfg.From)
+	fmt.Fprintf(&b, "To: %s\r\n", strings.Join(e.cfg.To, ", "))
+	fmt.Fprintf(&b, "Subject: %s\r\n", strings.NewReplacer("\r", " ", "\n", " ").Replace(msg.Summary))
+	fmt.Fprintf(&b, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	b.WriteString("MIME-Version: 1.0\r\n")
+	b.WriteString("Content-Type: text/plain; charset=utf-8\r\n")
+	b.WriteString("\r\n")
+
+	fmt.Fprintf(&b, "%s\r\n\r\n", msg.Summary)
+	fmt.Fprintf(&b, "Rule:      %s\r\n", msg.RuleName)
+	if msg.Group != "" {
+		fmt.Fprintf(&b, "Group:     %s\r\n", msg.Group)
+	}
+	fmt.Fprintf(&b, "State:     %s\r\n", msg.State)
+	fmt.Fprintf(&b, "Matches:   %d\r\n", msg.Count)
+	fmt.Fprintf(&b, "Threshold: %d\r\n", msg.Threshold)
+	fmt.Fprintf(&b, "Started:   %s\r\n", msg.StartsAt.Format(time.RFC3339))
+	if msg.ResolvedAt != nil {
+		fmt.Fprintf(&b, "Resolved:  %s\r\n", msg.ResolvedAt.Format(time.RFC3339))
+	}
+	return []byte(b.String())
+}
@@ -0,0 +1,136 @@
+package notify
+
+import (
+	"bufio"
+	"context"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// smtpServer is a minimal SMTP stand-in that accepts one message per
+// connection and hands over the commands and message data it receives.
+type smtpServer struct {
+	ln       net.Listener
+	commands chan []string
+	messages chan string
+}
+
+func newSMTPServer(t *testing.T) *smtpServer {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	s := &smtpServer{ln: ln, commands: make(chan []string, 1), messages: make(chan string, 1)}
+	t.Cleanup(func() { ln.Close() })
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go s.serve(conn)
+		}
+	}()
+	return s
+}
+
+func (s *smtpServer) port() int {
+	return s.ln.Addr().(*net.TCPAddr).Port
+}
+
+func (s *smtpServer) serve(conn net.Conn) {
+	defer conn.Close()
+	r := bufio.NewReader(conn)
+	reply := func(line string) { conn.Write([]byte(line + "\r\n")) }
+
+	var commands []string
+	reply("220 localhost ESMTP")
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return
+		}
+		line = strings.TrimRight(line, "\r\n")
+		verb := strings.ToUpper(strings.SplitN(line, " ", 2)[0])
+		commands = append(commands, line)
+		switch verb {
+		case "EHLO", "HELO":
+			reply("250 localhost")
+		case "MAIL", "RCPT":
+			reply("250 OK")
+		case "DATA":
+			reply("354 End data with <CR><LF>.<CR><LF>")
+			var data strings.Builder
+			for {
+				l, err := r.ReadString('\n')
+				if err != nil {
+					return
+				}
+				if l == ".\r\n" {
+					break
+				}
+				data.WriteString(l)
+			}
+			s.messages <- data.String()
+			reply("250 OK")
+		case "QUIT":
+			s.commands <- commands
+			reply("221 Bye")
+			return
+		default:
+			reply("502 Command not implemented")
+		}
+	}
+}
+
+func TestEmail_Send(t *testing.T) {
+	srv := newSMTPServer(t)
+	e, err := NewEmail(SMTPConfig{
+		Host: "127.0.0.1",
+		Port: srv.port(),
+		From: "beacon@example.com",
+		To:   []string{"oncall@example.com", "team@example.com"},
+	})
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	require.NoError(t, e.Send(ctx, testMessage()))
+
+	msg := <-srv.messages
+	assert.Contains(t, msg, "From: beacon@example.com\r\n")
+	assert.Contains(t, msg, "To: oncall@example.com, team@example.com\r\n")
+	assert.Contains(t, msg, "Subject: [FIRING] payment errors (eu-west-1): 62 matches, threshold 50\r\n")
+	assert.Contains(t, msg, "Group:     eu-west-1\r\n")
+
+	commands := <-srv.commands
+	assert.Contains(t, commands, "MAIL FROM:<beacon@example.com>")
+	assert.Contains(t, commands, "RCPT TO:<oncall@example.com>")
+	assert.Contains(t, commands, "RCPT TO:<team@example.com>")
+}
+
+func TestEmail_Unreachable(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	port := ln.Addr().(*net.TCPAddr).Port
+	ln.Close()
+
+	e, err := NewEmail(SMTPConfig{Host: "127.0.0.1", Port: port, From: "a@example.com", To: []string{"b@example.com"}})
+	require.NoError(t, err)
+	assert.Error(t, e.Send(context.Background(), testMessage()))
+}
+
+func TestNewEmail_Invalid(t *testing.T) {
+	_, err := NewEmail(SMTPConfig{From: "a@example.com", To: []string{"b@example.com"}})
+	assert.Error(t, err)
+	_, err = NewEmail(SMTPConfig{Host: "localhost", To: []string{"b@example.com"}})
+	assert.Error(t, err)
+	_, err = NewEmail(SMTPConfig{Host: "localhost", From: "a@example.com"})
+	assert.Error(t, err)
+	_, err = NewEmail(SMTPConfig{Host: "localhost", From: "a@example.com", To: []string{"b@example.com\r\nBcc: c@example.com"}})
+	assert.Error(t, err)
+}
@@ -0,0 +1,389 @@
+// Package notify delivers alert notifications through webhooks, Slack and
+// Teams incoming webhooks and email.
+//
+// A Dispatcher queues notifications per channel and sends them from one
+// background goroutine per channel, so that a slow or unreachable channel
+// holds up neither rule evaluation nor the other channels. Failed sends are
+// retried with exponential backoff and every delivery is recorded with its
+// outcome.
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"log-beacon/internal/alert"
+)
+
+// Channel types.
+const (
+	TypeWebhook = "webhook"
+	TypeSlack   = "slack"
+	TypeTeams   = "teams"
+	TypeEmail   = "email"
+)
+
+var (
+	// ErrClosed is returned when notifying through a closed Dispatcher.
+	ErrClosed = errors.New("notifier closed")
+	// ErrQueueFull is returned when the notification queue has no room.
+	ErrQueueFull = errors.New("notification queue full")
+	// ErrUnknownChannel is returned by Test for channel names that are not configured.
+	ErrUnknownChannel = errors.New("unknown notification channel")
+)
+
+// Config lists the notification channels and how failed sends are retried.
+type Config struct {
+	Channels []ChannelConfig `json:"channels"`
+	// Retries is the number of extra attempts for a failed send. Default 3;
+	// a negative value disables retries.
+	Retries int `json:"retries"`
+	// Backoff is the delay before the first retry; it doubles on every retry. Default 1s.
+	Backoff alert.Duration `json:"backoff"`
+	// QueueSize is the number of notifications waiting to be sent through a
+	// channel before further ones are refused for it. Default 1000.
+	QueueSize int `json:"queue_size"`
+}
+
+// ChannelConfig configures one notification channel. URL, Secret and the
+// SMTP credentials may reference environment variables as $NAME or ${NAME},
+// keeping secrets out of the file.
+type ChannelConfig struct {
+	Name string `json:"name"`
+	// Type is webhook, slack, teams or email.
+	Type string `json:"type"`
+	// URL is the endpoint of webhook, slack and teams channels.
+	URL string `json:"url,omitempty"`
+	// Headers are added to webhook requests.
+	Headers map[string]string `json:"headers,omitempty"`
+	// Template renders the JSON body of webhook requests with text/template.
+	// It receives a Message; the json function encodes a value as JSON. The
+	// default body holds the status, summary and alert.
+	Template string `json:"template,omitempty"`
+	// Secret signs webhook requests with HMAC-SHA256.
+	Secret string      `json:"secret,omitempty"`
+	SMTP   *SMTPConfig `json:"smtp,omitempty"`
+}
+
+// Message is the data rendered into notifications.
+type Message struct {
+	alert.Alert
+	// Summary is a one-line description such as
+	// "[FIRING] payment errors (eu-west-1): 62 matches, threshold 50".
+	Summary string `json:"summary"`
+}
+
+func newMessage(a alert.Alert) Message {
+	name := a.RuleName
+	if a.Group != "" {
+		name += " (" + a.Group + ")"
+	}
+	status := "FIRING"
+	if a.State == alert.StateResolved {
+		status = "RESOLVED"
+	}
+	return Message{
+		Alert:   a,
+		Summary: fmt.Sprintf("[%s] %s: %d matches, threshold %d", status, name, a.Count, a.Threshold),
+	}
+}
+
+// Channel sends notifications to one destination.
+type Channel interface {
+	Send(ctx context.Context, msg Message) error
+}
+
+// permanentError marks failures that retrying cannot fix, such as a
+// rejected request.
+type permanentError struct {
+	err error
+}
+
+func (e *permanentError) Error() string { return e.err.Error() }
+func (e *permanentError) Unwrap() error { return e.err }
+
+// History records deliveries. It is implemented by alert.Store.
+type History interface {
+	SaveDelivery(delivery *alert.Delivery) error
+}
+
+// ChannelInfo describes a configured channel.
+type ChannelInfo struct {
+	Name string `json:"name"`
+	Type string `json:"type"`
+}
+
+type namedChannel struct {
+	ChannelInfo
+	Channel
+	// queue holds the notifications waiting to be sent through the channel.
+	queue chan alert.Alert
+}
+
+// Dispatcher sends alert notifications through every configured channel.
+// It implements alert.Notifier.
+type Dispatcher struct {
+	channels []namedChannel
+	history  History
+	retries  int
+	backoff  time.Duration
+	// sleep waits between attempts. It is replaced in tests.
+	sleep func(ctx context.Context, d time.Duration) error
+
+	mu     sync.RWMutex
+	closed bool
+
+	// ctx is cancelled by Close to abandon pending retries.
+	ctx    context.Context
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+// LoadConfig reads a JSON notification configuration file.
+func LoadConfig(path string) (Config, error) {
+	var cfg Config
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return cfg, fmt.Errorf("failed to read notification config: %w", err)
+	}
+	if err := json.Unmarshal(data, &cfg); err != nil {
+		return cfg, fmt.Errorf("failed to parse notification config: %w", err)
+	}
+	return cfg, nil
+}
+
+// New validates the configuration, builds its channels and starts sending.
+// Deliveries are recorded in history.
+func New(cfg Config, history History) (*Dispatcher, error) {
+	if cfg.Retries == 0 {
+		cfg.Retries = 3
+	} else if cfg.Retries < 0 {
+		cfg.Retries = 0
+	}
+	if cfg.Backoff <= 0 {
+		cfg.Backoff = alert.Duration(time.Second)
+	}
+	if cfg.QueueSize <= 0 {
+		cfg.QueueSize = 1000
+	}
+
+	d := &Dispatcher{
+		history: history,
+		retries: cfg.Retries,
+		backoff: time.Duration(cfg.Backoff),
+		sleep:   sleep,
+		done:    make(chan struct{}),
+	}
+	names := make(map[string]bool)
+	for _, cc := range cfg.Channels {
+		if cc.Name == "" {
+			return nil, fmt.Errorf("notification channel name is required")
+		}
+		if names[cc.Name] {
+			return nil, fmt.Errorf("duplicate notification channel %q", cc.Name)
+		}
+		names[cc.Name] = true
+
+		ch, err := newChannel(expandSecrets(cc))
+		if err != nil {
+			return nil, fmt.Errorf("notification channel %q: %w", cc.Name, err)
+		}
+		d.channels = append(d.channels, namedChannel{
+			ChannelInfo: ChannelInfo{Name: cc.Name, Type: cc.Type},
+			Channel:     ch,
+			queue:       make(chan alert.Alert, cfg.QueueSize),
+		})
+	}
+
+	d.ctx, d.cancel = context.WithCancel(context.Background())
+	var workers sync.WaitGroup
+	for _, ch := range d.channels {
+		workers.Add(1)
+		go func(ch namedChannel) {
+			defer workers.Done()
+			d.run(ch)
+		}(ch)
+	}
+	go func() {
+		workers.Wait()
+		close(d.done)
+	}()
+	return d, nil
+}
+
+func newChannel(cc ChannelConfig) (Channel, error) {
+	switch cc.Type {
+	case TypeWebhook:
+		return NewWebhook(cc.URL, cc.Template, cc.Secret, cc.Headers)
+	case TypeSlack:
+		return NewSlack(cc.URL)
+	case TypeTeams:
+		return NewTeams(cc.URL)
+	case TypeEmail:
+		if cc.SMTP == nil {
+			return nil, fmt.Errorf("smtp settings are required")
+		}
+		return NewEmail(*cc.SMTP)
+	default:
+		return nil, fmt.Errorf("unknown type %q, expected webhook, slack, teams or email", cc.Type)
+	}
+}
+
+func expandSecrets(cc ChannelConfig) ChannelConfig {
+	cc.URL = os.ExpandEnv(cc.URL)
+	cc.Secret = os.ExpandEnv(cc.Secret)
+	if cc.SMTP != nil {
+		smtp := *cc.SMTP
+		smtp.Username = os.ExpandEnv(smtp.Username)
+		smtp.Password = os.ExpandEnv(smtp.Password)
+		cc.SMTP = &smtp
+	}
+	return cc
+}
+
+// Channels describes the configured channels.
+func (d *Dispatcher) Channels() []ChannelInfo {
+	infos := make([]ChannelInfo, 0, len(d.channels))
+	for _, ch := range d.channels {
+		infos = append(infos, ch.ChannelInfo)
+	}
+	return infos
+}
+
+// Notify logs the alert and queues it for every channel. It implements
+// alert.Notifier and never blocks: channels whose queue is full skip the
+// alert, and are named in the returned ErrQueueFull.
+func (d *Dispatcher) Notify(ctx context.Context, a alert.Alert) error {
+	alert.LogNotifier{}.Notify(ctx, a)
+	if len(d.channels) == 0 {
+		return nil
+	}
+
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	if d.closed {
+		return ErrClosed
+	}
+	var full []string
+	for _, ch := range d.channels {
+		select {
+		case ch.queue <- a:
+		default:
+			full = append(full, ch.Name)
+		}
+	}
+	if len(full) > 0 {
+		return fmt.Errorf("%w: %s", ErrQueueFull, strings.Join(full, ", "))
+	}
+	return nil
+}
+
+// Test sends a sample notification through the named channel once, without
+// retrying or recording it, and returns the outcome.
+func (d *Dispatcher) Test(ctx context.Context, name string) error {
+	for _, ch := range d.channels {
+		if ch.Name == name {
+			now := time.Now().UTC()
+			return ch.Send(ctx, newMessage(alert.Alert{
+				RuleName:  "Test notification",
+				State:     alert.StateFiring,
+				Count:     1,
+				Threshold: 0,
+				StartsAt:  now,
+			}))
+		}
+	}
+	return ErrUnknownChannel
+}
+
+// Close stops accepting notifications and waits until the queued ones are
+// sent. If ctx expires first, pending retries are abandoned.
+func (d *Dispatcher) Close(ctx context.Context) error {
+	d.mu.Lock()
+	if !d.closed {
+		d.closed = true
+		for _, ch := range d.channels {
+			close(ch.queue)
+		}
+	}
+	d.mu.Unlock()
+
+	select {
+	case <-d.done:
+		return nil
+	case <-ctx.Done():
+		d.cancel()
+		<-d.done
+		return ctx.Err()
+	}
+}
+
+// run delivers the notifications queued for one channel, in order, until
+// the queue is closed.
+func (d *Dispatcher) run(ch namedChannel) {
+	for a := range ch.queue {
+		d.deliver(ch, a)
+	}
+}
+
+// deliver sends a notification through one channel, retrying failures, and
+// records the outcome.
+func (d *Dispatcher) deliver(ch namedChannel, a alert.Alert) {
+	msg := newMessage(a)
+	delivery := alert.Delivery{
+		AlertID:    a.ID,
+		RuleName:   a.RuleName,
+		AlertState: a.State,
+		Channel:    ch.Name,
+		Status:     alert.DeliverySent,
+		CreatedAt:  time.Now().UTC(),
+	}
+
+	backoff := d.backoff
+	var err error
+	for attempt := 0; attempt <= d.retries; attempt++ {
+		if attempt > 0 {
+			if d.sleep(d.ctx, backoff) != nil {
+				break
+			}
+			backoff *= 2
+		}
+		delivery.Attempts++
+		ctx, cancel := context.WithTimeout(d.ctx, 30*time.Second)
+		err = ch.Send(ctx, msg)
+		cancel()
+		var permanent *permanentError
+		if err == nil || errors.As(err, &permanent) {
+			break
+		}
+	}
+	if err != nil {
+		log.Printf("Failed to notify %s of alert %q after %d attempts: %v", ch.Name, a.RuleName, delivery.Attempts, err)
+		delivery.Status = alert.DeliveryFailed
+		delivery.Error = err.Error()
+	}
+
+	if d.history != nil {
+		if err := d.history.SaveDelivery(&delivery); err != nil {
+			log.Printf("Failed to record notification delivery: %v", err)
+		}
+	}
+}
+
+func sleep(ctx context.Context, d time.Duration) error {
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-t.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
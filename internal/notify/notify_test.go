@@ -0,0 +1,194 @@
+package notify
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"log-beacon/internal/alert"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// noSleep makes retries immediate.
+func noSleep(ctx context.Context, d time.Duration) error { return ctx.Err() }
+
+func TestDispatcher_DeliversAndRecords(t *testing.T) {
+	ok, okSrv := newReceiver(t, http.StatusOK)
+	_, failSrv := newReceiver(t, http.StatusBadGateway)
+	_, rejectSrv := newReceiver(t, http.StatusUnauthorized)
+	store := alert.NewMemoryStore()
+
+	d, err := New(Config{
+		Channels: []ChannelConfig{
+			{Name: "hook", Type: TypeWebhook, URL: okSrv.URL},
+			{Name: "down", Type: TypeSlack, URL: failSrv.URL},
+			{Name: "rejected", Type: TypeTeams, URL: rejectSrv.URL},
+		},
+		Retries: 2,
+	}, store)
+	require.NoError(t, err)
+	d.sleep = noSleep
+
+	a := testMessage().Alert
+	require.NoError(t, d.Notify(context.Background(), a))
+	require.NoError(t, d.Close(context.Background()))
+	assert.Len(t, ok.bodies, 1)
+
+	deliveries, err := store.ListDeliveries(a.ID, 0)
+	require.NoError(t, err)
+	byChannel := map[string]alert.Delivery{}
+	for _, dl := range deliveries {
+		byChannel[dl.Channel] = dl
+	}
+	require.Len(t, byChannel, 3)
+
+	assert.Equal(t, alert.DeliverySent, byChannel["hook"].Status)
+	assert.Equal(t, 1, byChannel["hook"].Attempts)
+	assert.Equal(t, "payment errors", byChannel["hook"].RuleName)
+
+	// Server errors are retried, rejected requests are not.
+	assert.Equal(t, alert.DeliveryFailed, byChannel["down"].Status)
+	assert.Equal(t, 3, byChannel["down"].Attempts)
+	assert.Contains(t, byChannel["down"].Error, "502")
+	assert.Equal(t, alert.DeliveryFailed, byChannel["rejected"].Status)
+	assert.Equal(t, 1, byChannel["rejected"].Attempts)
+
+	assert.ErrorIs(t, d.Notify(context.Background(), a), ErrClosed)
+}
+
+func TestDispatcher_RetrySucceeds(t *testing.T) {
+	r, srv := newReceiver(t, http.StatusServiceUnavailable)
+	store := alert.NewMemoryStore()
+	d, err := New(Config{Channels: []ChannelConfig{{Name: "hook", Type: TypeWebhook, URL: srv.URL}}}, store)
+	require.NoError(t, err)
+	d.sleep = func(ctx context.Context, _ time.Duration) error {
+		r.status = http.StatusOK
+		return nil
+	}
+
+	require.NoError(t, d.Notify(context.Background(), testMessage().Alert))
+	require.NoError(t, d.Close(context.Background()))
+
+	deliveries, err := store.ListDeliveries(0, 0)
+	require.NoError(t, err)
+	require.Len(t, deliveries, 1)
+	assert.Equal(t, alert.DeliverySent, deliveries[0].Status)
+	assert.Equal(t, 2, deliveries[0].Attempts)
+}
+
+func TestDispatcher_SlowChannel(t *testing.T) {
+	fast, fastSrv := newReceiver(t, http.StatusOK)
+	started := make(chan struct{}, 1)
+	release := make(chan struct{})
+	slowSrv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		started <- struct{}{}
+		<-release
+	}))
+	defer slowSrv.Close()
+	unblock := sync.OnceFunc(func() { close(release) })
+	defer unblock()
+
+	d, err := New(Config{
+		Channels: []ChannelConfig{
+			{Name: "slow", Type: TypeWebhook, URL: slowSrv.URL},
+			{Name: "fast", Type: TypeWebhook, URL: fastSrv.URL},
+		},
+		QueueSize: 1,
+	}, nil)
+	require.NoError(t, err)
+	delivered := func() {
+		t.Helper()
+		select {
+		case <-fast.bodies:
+		case <-time.After(2 * time.Second):
+			t.Fatal("fast channel was held up by the slow one")
+		}
+	}
+
+	a := testMessage().Alert
+	require.NoError(t, d.Notify(context.Background(), a))
+	<-started
+	delivered()
+
+	// The fast channel keeps delivering while the slow one is stuck, and only
+	// the slow channel runs out of queue.
+	require.NoError(t, d.Notify(context.Background(), a))
+	delivered()
+	err = d.Notify(context.Background(), a)
+	assert.ErrorIs(t, err, ErrQueueFull)
+	assert.EqualError(t, err, "notification queue full: slow")
+	delivered()
+
+	unblock()
+	require.NoError(t, d.Close(context.Background()))
+}
+
+func TestDispatcher_Test(t *testing.T) {
+	r, srv := newReceiver(t, http.StatusOK)
+	store := alert.NewMemoryStore()
+	d, err := New(Config{Channels: []ChannelConfig{{Name: "ops", Type: TypeSlack, URL: srv.URL}}}, store)
+	require.NoError(t, err)
+	defer d.Close(context.Background())
+
+	assert.Equal(t, []ChannelInfo{{Name: "ops", Type: TypeSlack}}, d.Channels())
+	require.NoError(t, d.Test(context.Background(), "ops"))
+	assert.Contains(t, string(<-r.bodies), "Test notification")
+	assert.ErrorIs(t, d.Test(context.Background(), "missing"), ErrUnknownChannel)
+
+	// Test sends are not part of the history.
+	deliveries, err := store.ListDeliveries(0, 0)
+	require.NoError(t, err)
+	assert.Empty(t, deliveries)
+}
+
+func TestNew_InvalidConfig(t *testing.T) {
+	tests := []struct {
+		name     string
+		channels []ChannelConfig
+	}{
+		{name: "Missing name", channels: []ChannelConfig{{Type: TypeSlack, URL: "http://example.com"}}},
+		{name: "Duplicate name", channels: []ChannelConfig{
+			{Name: "a", Type: TypeSlack, URL: "http://example.com"},
+			{Name: "a", Type: TypeTeams, URL: "http://example.com"},
+		}},
+		{name: "Unknown type", channels: []ChannelConfig{{Name: "a", Type: "pager", URL: "http://example.com"}}},
+		{name: "Missing URL", channels: []ChannelConfig{{Name: "a", Type: TypeWebhook}}},
+		{name: "Missing SMTP settings", channels: []ChannelConfig{{Name: "a", Type: TypeEmail}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := New(Config{Channels: tt.channels}, nil)
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestLoadConfig(t *testing.T) {
+	t.Setenv("TEST_WEBHOOK_SECRET", "s3cret")
+	path := filepath.Join(t.TempDir(), "channels.json")
+	require.NoError(t, os.WriteFile(path, []byte(`{
+		"retries": 5,
+		"backoff": "2s",
+		"channels": [
+			{"name": "hook", "type": "webhook", "url": "http://example.com/hook", "secret": "${TEST_WEBHOOK_SECRET}"}
+		]
+	}`), 0o644))
+
+	cfg, err := LoadConfig(path)
+	require.NoError(t, err)
+	assert.Equal(t, 5, cfg.Retries)
+	assert.Equal(t, alert.Duration(2*time.Second), cfg.Backoff)
+	require.Len(t, cfg.Channels, 1)
+	assert.Equal(t, "s3cret", expandSecrets(cfg.Channels[0]).Secret)
+
+	_, err = LoadConfig(filepath.Join(t.TempDir(), "missing.json"))
+	assert.Error(t, err)
+}
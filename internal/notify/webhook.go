@@ -0,0 +1,206 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"text/template"
+	"time"
+
+	"log-beacon/internal/alert"
+)
+
+// Headers of signed webhook requests. The signature is
+// "sha256=" + hex(HMAC-SHA256(secret, timestamp + "." + body)).
+const (
+	SignatureHeader = "X-Beacon-Signature"
+	TimestampHeader = "X-Beacon-Timestamp"
+)
+
+const defaultTemplate = `{"status":{{json .State}},"summary":{{json .Summary}},"alert":{{json .Alert}}}`
+
+var templateFuncs = template.FuncMap{
+	"json": func(v any) (string, error) {
+		b, err := json.Marshal(v)
+		return string(b), err
+	},
+}
+
+// Webhook posts a JSON body rendered from a template, optionally signed.
+type Webhook struct {
+	url      string
+	template *template.Template
+	secret   string
+	headers  map[string]string
+	client   *http.Client
+}
+
+// NewWebhook creates a webhook channel. An empty tmpl uses the default body.
+func NewWebhook(rawURL, tmpl, secret string, headers map[string]string) (*Webhook, error) {
+	if err := validateURL(rawURL); err != nil {
+		return nil, err
+	}
+	if tmpl == "" {
+		tmpl = defaultTemplate
+	}
+	t, err := template.New("webhook").Funcs(templateFuncs).Option("missingkey=error").Parse(tmpl)
+	if err != nil {
+		return nil, fmt.Errorf("invalid template: %w", err)
+	}
+
+	w := &Webhook{url: rawURL, template: t, secret: secret, headers: headers, client: &http.Client{}}
+	// Catch templates that do not produce JSON now rather than on the first alert.
+	if _, err := w.render(newMessage(alert.Alert{RuleName: "test", State: alert.StateFiring, StartsAt: time.Now()})); err != nil {
+		return nil, err
+	}
+	return w, nil
+}
+
+func (w *Webhook) render(msg Message) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := w.template.Execute(&buf, msg); err != nil {
+		return nil, fmt.Errorf("failed to render template: %w", err)
+	}
+	if !json.Valid(buf.Bytes()) {
+		return nil, fmt.Errorf("template does not render valid JSON")
+	}
+	return buf.Bytes(), nil
+}
+
+// Send implements Channel.
+func (w *Webhook) Send(ctx context.Context, msg Message) error {
+	body, err := w.render(msg)
+	if err != nil {
+		return &permanentError{err}
+	}
+	headers := make(map[string]string, len(w.headers)+2)
+	for k, v := range w.headers {
+		headers[k] = v
+	}
+	if w.secret != "" {
+		ts := strconv.FormatInt(time.Now().Unix(), 10)
+		headers[TimestampHeader] = ts
+		headers[SignatureHeader] = Sign(w.secret, ts, body)
+	}
+	return post(ctx, w.client, w.url, body, headers)
+}
+
+// Sign returns the signature of a webhook body sent at the given Unix
+// timestamp, for receivers to compare with the X-Beacon-Signature header.
+func Sign(secret, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Slack posts to a Slack incoming webhook. Mattermost and Rocket.Chat accept
+// the same format.
+type Slack struct {
+	url    string
+	client *http.Client
+}
+
+// NewSlack creates a Slack channel.
+func NewSlack(rawURL string) (*Slack, error) {
+	if err := validateURL(rawURL); err != nil {
+		return nil, err
+	}
+	return &Slack{url: rawURL, client: &http.Client{}}, nil
+}
+
+// Send implements Channel.
+func (s *Slack) Send(ctx context.Context, msg Message) error {
+	body, err := json.Marshal(map[string]string{"text": msg.Summary})
+	if err != nil {
+		return &permanentError{err}
+	}
+	return post(ctx, s.client, s.url, body, nil)
+}
+
+// Teams posts a message card to a Microsoft Teams incoming webhook.
+type Teams struct {
+	url    string
+	client *http.Client
+}
+
+// NewTeams creates a Teams channel.
+func NewTeams(rawURL string) (*Teams, error) {
+	if err := validateURL(rawURL); err != nil {
+		return nil, err
+	}
+	return &Teams{url: rawURL, client: &http.Client{}}, nil
+}
+
+// Send implements Channel.
+func (t *Teams) Send(ctx context.Context, msg Message) error {
+	color := "D32F2F"
+	if msg.State == alert.StateResolved {
+		color = "388E3C"
+	}
+	text := fmt.Sprintf("Rule **%s** matched %d logs (threshold %d) since %s.",
+		msg.RuleName, msg.Count, msg.Threshold, msg.StartsAt.Format(time.RFC3339))
+	if msg.Group != "" {
+		text += " Group: " + msg.Group + "."
+	}
+	body, err := json.Marshal(map[string]string{
+		"@type":      "MessageCard",
+		"@context":   "https://schema.org/extensions",
+		"summary":    msg.Summary,
+		"themeColor": color,
+		"title":      msg.Summary,
+		"text":       text,
+	})
+	if err != nil {
+		return &permanentError{err}
+	}
+	return post(ctx, t.client, t.url, body, nil)
+}
+
+func validateURL(rawURL string) error {
+	u, err := url.Parse(rawURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("url must be an http or https URL")
+	}
+	return nil
+}
+
+// post sends a JSON body. Client errors other than timeouts and rate limits
+// are permanent.
+func post(ctx context.Context, client *http.Client, url string, body []byte, headers map[string]string) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return &permanentError{err}
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "log-beacon")
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return nil
+	}
+	err = fmt.Errorf("unexpected status %s", resp.Status)
+	if resp.StatusCode >= 400 && resp.StatusCode < 500 &&
+		resp.StatusCode != http.StatusRequestTimeout && resp.StatusCode != http.StatusTooManyRequests {
+		return &permanentError{err}
+	}
+	return err
+}
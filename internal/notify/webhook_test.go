@@ -0,0 +1,137 @@
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"log-beacon/internal/alert"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// receiver records the requests it receives and answers with status.
+type receiver struct {
+	status  int
+	bodies  chan []byte
+	headers chan http.Header
+}
+
+func newReceiver(t *testing.T, status int) (*receiver, *httptest.Server) {
+	t.Helper()
+	r := &receiver{status: status, bodies: make(chan []byte, 10), headers: make(chan http.Header, 10)}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		body, _ := io.ReadAll(req.Body)
+		r.bodies <- body
+		r.headers <- req.Header
+		w.WriteHeader(r.status)
+	}))
+	t.Cleanup(srv.Close)
+	return r, srv
+}
+
+func testMessage() Message {
+	return newMessage(alert.Alert{
+		ID:        7,
+		RuleName:  "payment errors",
+		Group:     "eu-west-1",
+		State:     alert.StateFiring,
+		Count:     62,
+		Threshold: 50,
+		StartsAt:  time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC),
+	})
+}
+
+func TestWebhook_DefaultBody(t *testing.T) {
+	r, srv := newReceiver(t, http.StatusOK)
+	w, err := NewWebhook(srv.URL, "", "", map[string]string{"Authorization": "Bearer token"})
+	require.NoError(t, err)
+
+	require.NoError(t, w.Send(context.Background(), testMessage()))
+
+	var body struct {
+		Status  string      `json:"status"`
+		Summary string      `json:"summary"`
+		Alert   alert.Alert `json:"alert"`
+	}
+	require.NoError(t, json.Unmarshal(<-r.bodies, &body))
+	assert.Equal(t, "firing", body.Status)
+	assert.Equal(t, "[FIRING] payment errors (eu-west-1): 62 matches, threshold 50", body.Summary)
+	assert.Equal(t, int64(7), body.Alert.ID)
+	headers := <-r.headers
+	assert.Equal(t, "Bearer token", headers.Get("Authorization"))
+	assert.Empty(t, headers.Get(SignatureHeader))
+}
+
+func TestWebhook_TemplateAndSignature(t *testing.T) {
+	r, srv := newReceiver(t, http.StatusNoContent)
+	w, err := NewWebhook(srv.URL, `{"text":{{json .Summary}},"rule":{{json .RuleName}},"count":{{.Count}}}`, "s3cret", nil)
+	require.NoError(t, err)
+
+	require.NoError(t, w.Send(context.Background(), testMessage()))
+
+	body := <-r.bodies
+	assert.JSONEq(t, `{"text":"[FIRING] payment errors (eu-west-1): 62 matches, threshold 50","rule":"payment errors","count":62}`, string(body))
+	headers := <-r.headers
+	ts := headers.Get(TimestampHeader)
+	require.NotEmpty(t, ts)
+	assert.Equal(t, Sign("s3cret", ts, body), headers.Get(SignatureHeader))
+}
+
+func TestNewWebhook_Invalid(t *testing.T) {
+	_, err := NewWebhook("ftp://example.com", "", "", nil)
+	assert.Error(t, err)
+	_, err = NewWebhook("http://example.com", `{{.Missing`, "", nil)
+	assert.Error(t, err)
+	_, err = NewWebhook("http://example.com", `text: {{.Summary}}`, "", nil)
+	assert.ErrorContains(t, err, "valid JSON")
+}
+
+func TestWebhook_Errors(t *testing.T) {
+	var permanent *permanentError
+
+	_, srv := newReceiver(t, http.StatusBadRequest)
+	w, err := NewWebhook(srv.URL, "", "", nil)
+	require.NoError(t, err)
+	err = w.Send(context.Background(), testMessage())
+	assert.ErrorAs(t, err, &permanent)
+
+	_, srv = newReceiver(t, http.StatusServiceUnavailable)
+	w, err = NewWebhook(srv.URL, "", "", nil)
+	require.NoError(t, err)
+	err = w.Send(context.Background(), testMessage())
+	require.Error(t, err)
+	assert.False(t, errors.As(err, &permanent), "server errors are retried")
+}
+
+func TestSlack(t *testing.T) {
+	r, srv := newReceiver(t, http.StatusOK)
+	s, err := NewSlack(srv.URL)
+	require.NoError(t, err)
+
+	require.NoError(t, s.Send(context.Background(), testMessage()))
+	assert.JSONEq(t, `{"text":"[FIRING] payment errors (eu-west-1): 62 matches, threshold 50"}`, string(<-r.bodies))
+}
+
+func TestTeams(t *testing.T) {
+	r, srv := newReceiver(t, http.StatusOK)
+	tm, err := NewTeams(srv.URL)
+	require.NoError(t, err)
+
+	msg := testMessage()
+	msg.State = alert.StateResolved
+	require.NoError(t, tm.Send(context.Background(), msg))
+
+	var card map[string]string
+	require.NoError(t, json.Unmarshal(<-r.bodies, &card))
+	assert.Equal(t, "MessageCard", card["@type"])
+	assert.Equal(t, "388E3C", card["themeColor"])
+	assert.Equal(t, msg.Summary, card["summary"])
+	assert.Contains(t, card["text"], "eu-west-1")
+}
@@ -0,0 +1,157 @@
+package otlp
+
+import (
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"strconv"
+	"strings"
+	"time"
+
+	"log-beacon/internal/model"
+
+	collogspb "go.opentelemetry.io/proto/otlp/collector/logs/v1"
+	commonpb "go.opentelemetry.io/proto/otlp/common/v1"
+)
+
+// severityLevels maps OTLP severity number ranges (1-4, 5-8, ...) onto Log Beacon levels.
+var severityLevels = []string{"trace", "debug", "info", "warn", "error", "fatal"}
+
+// ToLogs converts every log record in the request into a log entry.
+// Resource attributes, the instrumentation scope, record attributes and the
+// trace context become labels; record attributes win over resource attributes.
+func ToLogs(req *collogspb.ExportLogsServiceRequest) []model.Log {
+	var logs []model.Log
+	for _, rl := range req.GetResourceLogs() {
+		for _, sl := range rl.GetScopeLogs() {
+			for _, rec := range sl.GetLogRecords() {
+				logEntry := model.Log{Labels: make(map[string]string)}
+
+				for _, kv := range rl.GetResource().GetAttributes() {
+					flattenAnyValue(logEntry.Labels, kv.GetKey(), kv.GetValue())
+				}
+				if name := sl.GetScope().GetName(); name != "" {
+					logEntry.Labels["otel.scope.name"] = name
+				}
+				if version := sl.GetScope().GetVersion(); version != "" {
+					logEntry.Labels["otel.scope.version"] = version
+				}
+				for _, kv := range rec.GetAttributes() {
+					flattenAnyValue(logEntry.Labels, kv.GetKey(), kv.GetValue())
+				}
+				if len(rec.GetTraceId()) > 0 {
+					logEntry.Labels["trace_id"] = hex.EncodeToString(rec.GetTraceId())
+				}
+				if len(rec.GetSpanId()) > 0 {
+					logEntry.Labels["span_id"] = hex.EncodeToString(rec.GetSpanId())
+				}
+
+				switch {
+				case rec.GetTimeUnixNano() > 0:
+					logEntry.Timestamp = time.Unix(0, int64(rec.GetTimeUnixNano())).UTC()
+				case rec.GetObservedTimeUnixNano() > 0:
+					logEntry.Timestamp = time.Unix(0, int64(rec.GetObservedTimeUnixNano())).UTC()
+				}
+
+				logEntry.Level = severityLevel(int32(rec.GetSeverityNumber()), rec.GetSeverityText())
+
+				if body := rec.GetBody(); body != nil {
+					logEntry.Message = bodyString(body)
+					if body.GetKvlistValue() != nil {
+						flattenAnyValue(logEntry.Labels, "body", body)
+					}
+				}
+
+				logs = append(logs, logEntry)
+			}
+		}
+	}
+	return logs
+}
+
+// severityLevel maps a severity number onto a level, falling back to the
+// severity text when the number is unset.
+func severityLevel(number int32, text string) string {
+	if number >= 1 && number <= 24 {
+		return severityLevels[(number-1)/4]
+	}
+	return strings.ToLower(text)
+}
+
+// bodyString renders a log body as the log message. Structured bodies are rendered as JSON.
+func bodyString(v *commonpb.AnyValue) string {
+	switch v.GetValue().(type) {
+	case *commonpb.AnyValue_ArrayValue, *commonpb.AnyValue_KvlistValue:
+		data, err := json.Marshal(toInterface(v))
+		if err != nil {
+			return ""
+		}
+		return string(data)
+	}
+	return scalarString(v)
+}
+
+// flattenAnyValue stores v under key in labels, using dotted keys for map
+// entries and array elements like model.Flatten does for JSON.
+func flattenAnyValue(labels map[string]string, key string, v *commonpb.AnyValue) {
+	if v.GetValue() == nil || key == "" {
+		return
+	}
+	switch val := v.GetValue().(type) {
+	case *commonpb.AnyValue_KvlistValue:
+		for _, kv := range val.KvlistValue.GetValues() {
+			flattenAnyValue(labels, key+"."+kv.GetKey(), kv.GetValue())
+		}
+	case *commonpb.AnyValue_ArrayValue:
+		for i, item := range val.ArrayValue.GetValues() {
+			flattenAnyValue(labels, key+"."+strconv.Itoa(i), item)
+		}
+	default:
+		labels[key] = scalarString(v)
+	}
+}
+
+func scalarString(v *commonpb.AnyValue) string {
+	switch val := v.GetValue().(type) {
+	case *commonpb.AnyValue_StringValue:
+		return val.StringValue
+	case *commonpb.AnyValue_BoolValue:
+		return strconv.FormatBool(val.BoolValue)
+	case *commonpb.AnyValue_IntValue:
+		return strconv.FormatInt(val.IntValue, 10)
+	case *commonpb.AnyValue_DoubleValue:
+		return strconv.FormatFloat(val.DoubleValue, 'f', -1, 64)
+	case *commonpb.AnyValue_BytesValue:
+		return base64.StdEncoding.EncodeToString(val.BytesValue)
+	}
+	return ""
+}
+
+// toInterface converts a value into plain Go types for JSON encoding.
+func toInterface(v *commonpb.AnyValue) interface{} {
+	switch val := v.GetValue().(type) {
+	case *commonpb.AnyValue_KvlistValue:
+		m := make(map[string]interface{}, len(val.KvlistValue.GetValues()))
+		for _, kv := range val.KvlistValue.GetValues() {
+			m[kv.GetKey()] = toInterface(kv.GetValue())
+		}
+		return m
+	case *commonpb.AnyValue_ArrayValue:
+		items := make([]interface{}, 0, len(val.ArrayValue.GetValues()))
+		for _, item := range val.ArrayValue.GetValues() {
+			items = append(items, toInterface(item))
+		}
+		return items
+	case *commonpb.AnyValue_StringValue:
+		return val.StringValue
+	case *commonpb.AnyValue_BoolValue:
+		return val.BoolValue
+	case *commonpb.AnyValue_IntValue:
+		return val.IntValue
+	case *commonpb.AnyValue_DoubleValue:
+		return val.DoubleValue
+	case *commonpb.AnyValue_BytesValue:
+		return val.BytesValue
+	}
+	return nil
+}
@@ -0,0 +1,61 @@
+package otlp
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+
+	collogspb "go.opentelemetry.io/proto/otlp/collector/logs/v1"
+	"google.golang.org/protobuf/encoding/protojson"
+)
+
+// unmarshalJSON decodes an OTLP/JSON encoded export. OTLP/JSON follows the
+// protobuf JSON mapping, except that trace and span IDs are hex strings
+// rather than base64, so they are re-encoded before protojson decodes the
+// request. Unknown fields are ignored for compatibility with newer clients.
+func unmarshalJSON(data []byte, req *collogspb.ExportLogsServiceRequest) error {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var raw map[string]interface{}
+	if err := dec.Decode(&raw); err != nil {
+		return err
+	}
+
+	for _, rl := range objects(raw["resourceLogs"]) {
+		for _, sl := range objects(rl["scopeLogs"]) {
+			for _, rec := range objects(sl["logRecords"]) {
+				for _, field := range []string{"traceId", "spanId"} {
+					id, ok := rec[field].(string)
+					if !ok || id == "" {
+						continue
+					}
+					b, err := hex.DecodeString(id)
+					if err != nil {
+						return fmt.Errorf("invalid %s: %w", field, err)
+					}
+					rec[field] = base64.StdEncoding.EncodeToString(b)
+				}
+			}
+		}
+	}
+
+	data, err := json.Marshal(raw)
+	if err != nil {
+		return err
+	}
+	return protojson.UnmarshalOptions{DiscardUnknown: true}.Unmarshal(data, req)
+}
+
+// objects returns the JSON objects in v, which is expected to be an array.
+func objects(v interface{}) []map[string]interface{} {
+	items, _ := v.([]interface{})
+	objs := make([]map[string]interface{}, 0, len(items))
+	for _, item := range items {
+		if obj, ok := item.(map[string]interface{}); ok {
+			objs = append(objs, obj)
+		}
+	}
+	return objs
+}
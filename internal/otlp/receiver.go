@@ -0,0 +1,125 @@
+package otlp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+
+	"log-beacon/internal/compress"
+	"log-beacon/internal/pipeline"
+
+	"github.com/gin-gonic/gin"
+	collogspb "go.opentelemetry.io/proto/otlp/collector/logs/v1"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	_ "google.golang.org/grpc/encoding/gzip" // Accept gzip-compressed gRPC exports.
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+)
+
+// maxRequestSize bounds the size of a decoded export request.
+const maxRequestSize = 16 << 20
+
+// Receiver accepts OTLP log exports over HTTP (protobuf and JSON) and gRPC.
+type Receiver struct {
+	collogspb.UnimplementedLogsServiceServer
+
+	ingest pipeline.IngestFunc
+}
+
+// NewReceiver creates a receiver that hands every log record to ingest.
+func NewReceiver(ingest pipeline.IngestFunc) *Receiver {
+	return &Receiver{ingest: ingest}
+}
+
+// export ingests every record in the request. Records refused by the ingest
+// pipeline are counted as rejected; any other error aborts the export so that
+// the client retries.
+func (r *Receiver) export(req *collogspb.ExportLogsServiceRequest) (*collogspb.ExportLogsServiceResponse, error) {
+	result, err := pipeline.IngestBatch(r.ingest, ToLogs(req))
+	if err != nil {
+		return nil, err
+	}
+	resp := &collogspb.ExportLogsServiceResponse{}
+	if len(result.Rejected) > 0 {
+		resp.PartialSuccess = &collogspb.ExportLogsPartialSuccess{
+			RejectedLogRecords: int64(len(result.Rejected)),
+			ErrorMessage:       result.Rejected[0].Err.Error(),
+		}
+	}
+	return resp, nil
+}
+
+// HandleHTTP implements OTLP/HTTP for logs with protobuf or JSON payloads.
+func (r *Receiver) HandleHTTP(c *gin.Context) {
+	isJSON := strings.HasPrefix(c.ContentType(), "application/json")
+	if !isJSON && c.ContentType() != "application/x-protobuf" {
+		c.JSON(http.StatusUnsupportedMediaType, gin.H{"error": "Content-Type must be application/x-protobuf or application/json"})
+		return
+	}
+
+	body, err := compress.ReadLimited(c.Request.Body, maxRequestSize)
+	if errors.Is(err, compress.ErrTooLarge) {
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": fmt.Sprintf("request body exceeds %d bytes", maxRequestSize)})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	req := &collogspb.ExportLogsServiceRequest{}
+	if isJSON {
+		err = unmarshalJSON(body, req)
+	} else {
+		err = proto.Unmarshal(body, req)
+	}
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("invalid OTLP payload: %v", err)})
+		return
+	}
+
+	resp, err := r.export(req)
+	if err != nil {
+		log.Printf("Error publishing OTLP logs: %v", err)
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Failed to process logs"})
+		return
+	}
+
+	var data []byte
+	contentType := "application/x-protobuf"
+	if isJSON {
+		data, err = protojson.Marshal(resp)
+		contentType = "application/json"
+	} else {
+		data, err = proto.Marshal(resp)
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to encode response"})
+		return
+	}
+	c.Data(http.StatusOK, contentType, data)
+}
+
+// Export implements the OTLP logs gRPC service.
+func (r *Receiver) Export(ctx context.Context, req *collogspb.ExportLogsServiceRequest) (*collogspb.ExportLogsServiceResponse, error) {
+	resp, err := r.export(req)
+	if err != nil {
+		log.Printf("Error publishing OTLP logs: %v", err)
+		return nil, status.Error(codes.Unavailable, "failed to process logs")
+	}
+	return resp, nil
+}
+
+// NewGRPCServer creates a gRPC server serving the OTLP logs service of the
+// receiver. Exports may be gzip compressed.
+func (r *Receiver) NewGRPCServer(opts ...grpc.ServerOption) *grpc.Server {
+	opts = append([]grpc.ServerOption{grpc.MaxRecvMsgSize(maxRequestSize)}, opts...)
+	s := grpc.NewServer(opts...)
+	collogspb.RegisterLogsServiceServer(s, r)
+	return s
+}
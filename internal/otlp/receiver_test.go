@@ -0,0 +1,291 @@
+package otlp
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"log-beacon/internal/model"
+	"log-beacon/internal/pipeline"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	collogspb "go.opentelemetry.io/proto/otlp/collector/logs/v1"
+	commonpb "go.opentelemetry.io/proto/otlp/common/v1"
+	logspb "go.opentelemetry.io/proto/otlp/logs/v1"
+	resourcepb "go.opentelemetry.io/proto/otlp/resource/v1"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/encoding/gzip"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+)
+
+func stringValue(s string) *commonpb.AnyValue {
+	return &commonpb.AnyValue{Value: &commonpb.AnyValue_StringValue{StringValue: s}}
+}
+
+// sampleRequest builds an export with one resource, one scope and the given record bodies.
+func sampleRequest(bodies ...string) *collogspb.ExportLogsServiceRequest {
+	scopeLogs := &logspb.ScopeLogs{Scope: &commonpb.InstrumentationScope{Name: "app/logger", Version: "1.2.0"}}
+	for _, body := range bodies {
+		scopeLogs.LogRecords = append(scopeLogs.LogRecords, &logspb.LogRecord{
+			TimeUnixNano:   uint64(time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC).UnixNano()),
+			SeverityNumber: logspb.SeverityNumber_SEVERITY_NUMBER_ERROR,
+			Body:           stringValue(body),
+			Attributes:     []*commonpb.KeyValue{{Key: "http.method", Value: stringValue("GET")}},
+			TraceId:        []byte{0x5b, 0x8e, 0xff, 0xf7, 0x98, 0x03, 0x81, 0x03, 0xd2, 0x69, 0xb6, 0x33, 0x81, 0x3f, 0xc6, 0x0c},
+			SpanId:         []byte{0xee, 0xe1, 0x9b, 0x7e, 0xc3, 0xc1, 0xb1, 0x74},
+		})
+	}
+	return &collogspb.ExportLogsServiceRequest{ResourceLogs: []*logspb.ResourceLogs{{
+		Resource:  &resourcepb.Resource{Attributes: []*commonpb.KeyValue{{Key: "service.name", Value: stringValue("checkout")}}},
+		ScopeLogs: []*logspb.ScopeLogs{scopeLogs},
+	}}}
+}
+
+// sampleProtoRequest encodes sampleRequest as protobuf.
+func sampleProtoRequest(bodies ...string) []byte {
+	data, err := proto.Marshal(sampleRequest(bodies...))
+	if err != nil {
+		panic(err)
+	}
+	return data
+}
+
+const sampleJSONRequest = `{
+  "resourceLogs": [{
+    "resource": {"attributes": [{"key": "service.name", "value": {"stringValue": "checkout"}}]},
+    "scopeLogs": [{
+      "scope": {"name": "app/logger", "version": "1.2.0"},
+      "logRecords": [{
+        "timeUnixNano": "1714564800000000000",
+        "severityNumber": "SEVERITY_NUMBER_ERROR",
+        "body": {"stringValue": "payment failed"},
+        "attributes": [
+          {"key": "http.method", "value": {"stringValue": "GET"}},
+          {"key": "retry", "value": {"intValue": "3"}},
+          {"key": "cart", "value": {"kvlistValue": {"values": [{"key": "items", "value": {"intValue": 2}}]}}}
+        ],
+        "traceId": "5b8efff798038103d269b633813fc60c",
+        "spanId": "eee19b7ec3c1b174"
+      }]
+    }]
+  }]
+}`
+
+// recorder collects ingested log entries, failing the ones whose message matches reject.
+type recorder struct {
+	logs   []model.Log
+	reject string
+	err    error
+}
+
+func (r *recorder) ingest(logEntry model.Log) error {
+	if r.err != nil {
+		return r.err
+	}
+	if r.reject != "" && logEntry.Message == r.reject {
+		return &pipeline.RejectionError{Reasons: []string{"message rejected"}}
+	}
+	r.logs = append(r.logs, logEntry)
+	return nil
+}
+
+func newTestRouter(r *recorder) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.POST("/otlp/v1/logs", NewReceiver(r.ingest).HandleHTTP)
+	return router
+}
+
+func assertSampleLog(t *testing.T, logEntry model.Log, message string) {
+	t.Helper()
+	assert.Equal(t, message, logEntry.Message)
+	assert.Equal(t, "error", logEntry.Level)
+	assert.Equal(t, time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC), logEntry.Timestamp)
+	assert.Equal(t, "checkout", logEntry.Labels["service.name"])
+	assert.Equal(t, "app/logger", logEntry.Labels["otel.scope.name"])
+	assert.Equal(t, "1.2.0", logEntry.Labels["otel.scope.version"])
+	assert.Equal(t, "GET", logEntry.Labels["http.method"])
+	assert.Equal(t, "5b8efff798038103d269b633813fc60c", logEntry.Labels["trace_id"])
+	assert.Equal(t, "eee19b7ec3c1b174", logEntry.Labels["span_id"])
+}
+
+func TestHandleHTTP_Protobuf(t *testing.T) {
+	rec := &recorder{}
+	router := newTestRouter(rec)
+
+	req := httptest.NewRequest(http.MethodPost, "/otlp/v1/logs", bytes.NewReader(sampleProtoRequest("payment failed", "card declined")))
+	req.Header.Set("Content-Type", "application/x-protobuf")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "application/x-protobuf", w.Header().Get("Content-Type"))
+	assert.Empty(t, w.Body.Bytes())
+	require.Len(t, rec.logs, 2)
+	assertSampleLog(t, rec.logs[0], "payment failed")
+	assertSampleLog(t, rec.logs[1], "card declined")
+}
+
+func TestHandleHTTP_JSON(t *testing.T) {
+	rec := &recorder{}
+	router := newTestRouter(rec)
+
+	req := httptest.NewRequest(http.MethodPost, "/otlp/v1/logs", strings.NewReader(sampleJSONRequest))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{}`, w.Body.String())
+	require.Len(t, rec.logs, 1)
+	assertSampleLog(t, rec.logs[0], "payment failed")
+	assert.Equal(t, "3", rec.logs[0].Labels["retry"])
+	assert.Equal(t, "2", rec.logs[0].Labels["cart.items"])
+}
+
+func TestHandleHTTP_PartialSuccess(t *testing.T) {
+	t.Run("json", func(t *testing.T) {
+		rec := &recorder{reject: "payment failed"}
+		router := newTestRouter(rec)
+
+		req := httptest.NewRequest(http.MethodPost, "/otlp/v1/logs", strings.NewReader(sampleJSONRequest))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.JSONEq(t, `{"partialSuccess":{"rejectedLogRecords":"1","errorMessage":"log entry rejected: message rejected"}}`, w.Body.String())
+		assert.Empty(t, rec.logs)
+	})
+
+	t.Run("protobuf", func(t *testing.T) {
+		rec := &recorder{reject: "card declined"}
+		router := newTestRouter(rec)
+
+		req := httptest.NewRequest(http.MethodPost, "/otlp/v1/logs", bytes.NewReader(sampleProtoRequest("payment failed", "card declined")))
+		req.Header.Set("Content-Type", "application/x-protobuf")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		var resp collogspb.ExportLogsServiceResponse
+		require.NoError(t, proto.Unmarshal(w.Body.Bytes(), &resp))
+		assert.Equal(t, int64(1), resp.GetPartialSuccess().GetRejectedLogRecords())
+		assert.Equal(t, "log entry rejected: message rejected", resp.GetPartialSuccess().GetErrorMessage())
+		assert.Len(t, rec.logs, 1)
+	})
+}
+
+func TestHandleHTTP_Errors(t *testing.T) {
+	tests := []struct {
+		name        string
+		contentType string
+		body        string
+		ingestErr   error
+		wantStatus  int
+	}{
+		{"unsupported content type", "text/plain", "hello", nil, http.StatusUnsupportedMediaType},
+		{"malformed protobuf", "application/x-protobuf", "\xff\xff\xff", nil, http.StatusBadRequest},
+		{"malformed json", "application/json", "{", nil, http.StatusBadRequest},
+		{"invalid trace id", "application/json", `{"resourceLogs":[{"scopeLogs":[{"logRecords":[{"traceId":"xyz"}]}]}]}`, nil, http.StatusBadRequest},
+		{"publish failure", "application/json", sampleJSONRequest, io.ErrUnexpectedEOF, http.StatusServiceUnavailable},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			router := newTestRouter(&recorder{err: tt.ingestErr})
+
+			req := httptest.NewRequest(http.MethodPost, "/otlp/v1/logs", strings.NewReader(tt.body))
+			req.Header.Set("Content-Type", tt.contentType)
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.wantStatus, w.Code)
+		})
+	}
+}
+
+func TestToLogs_SeverityAndBody(t *testing.T) {
+	req := &collogspb.ExportLogsServiceRequest{ResourceLogs: []*logspb.ResourceLogs{{
+		ScopeLogs: []*logspb.ScopeLogs{{LogRecords: []*logspb.LogRecord{
+			{SeverityNumber: logspb.SeverityNumber_SEVERITY_NUMBER_WARN, Body: stringValue("disk almost full")},
+			{SeverityText: "NOTICE", ObservedTimeUnixNano: 1_000_000_000},
+			{Body: &commonpb.AnyValue{Value: &commonpb.AnyValue_KvlistValue{KvlistValue: &commonpb.KeyValueList{
+				Values: []*commonpb.KeyValue{{Key: "user", Value: stringValue("alice")}},
+			}}}},
+		}}},
+	}}}
+
+	logs := ToLogs(req)
+	require.Len(t, logs, 3)
+
+	assert.Equal(t, "warn", logs[0].Level)
+	assert.Equal(t, "disk almost full", logs[0].Message)
+	assert.True(t, logs[0].Timestamp.IsZero())
+
+	assert.Equal(t, "notice", logs[1].Level)
+	assert.Equal(t, time.Unix(1, 0).UTC(), logs[1].Timestamp)
+
+	assert.Equal(t, `{"user":"alice"}`, logs[2].Message)
+	assert.Equal(t, "alice", logs[2].Labels["body.user"])
+}
+
+func TestGRPC(t *testing.T) {
+	rec := &recorder{}
+	server := NewReceiver(rec.ingest).NewGRPCServer()
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	go server.Serve(lis)
+	defer server.Stop()
+
+	conn, err := grpc.NewClient(lis.Addr().String(), grpc.WithTransportCredentials(insecure.NewCredentials()))
+	require.NoError(t, err)
+	defer conn.Close()
+	client := collogspb.NewLogsServiceClient(conn)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	t.Run("export", func(t *testing.T) {
+		resp, err := client.Export(ctx, sampleRequest("payment failed"))
+		require.NoError(t, err)
+		assert.Nil(t, resp.GetPartialSuccess())
+		require.Len(t, rec.logs, 1)
+		assertSampleLog(t, rec.logs[0], "payment failed")
+	})
+
+	t.Run("gzip", func(t *testing.T) {
+		rec.logs = nil
+		_, err := client.Export(ctx, sampleRequest("card declined"), grpc.UseCompressor(gzip.Name))
+		require.NoError(t, err)
+		require.Len(t, rec.logs, 1)
+		assertSampleLog(t, rec.logs[0], "card declined")
+	})
+
+	t.Run("partial success", func(t *testing.T) {
+		rec.logs, rec.reject = nil, "payment failed"
+		defer func() { rec.reject = "" }()
+		resp, err := client.Export(ctx, sampleRequest("payment failed", "card declined"))
+		require.NoError(t, err)
+		assert.Equal(t, int64(1), resp.GetPartialSuccess().GetRejectedLogRecords())
+		assert.Len(t, rec.logs, 1)
+	})
+
+	t.Run("publish failure", func(t *testing.T) {
+		rec.err = io.ErrUnexpectedEOF
+		defer func() { rec.err = nil }()
+		_, err := client.Export(ctx, sampleRequest("payment failed"))
+		assert.Equal(t, codes.Unavailable, status.Code(err))
+	})
+}
@@ -0,0 +1,74 @@
+package pipeline
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+)
+
+// Config describes the processing stages applied to ingested logs.
+type Config struct {
+	Parse     ParseConfig     `json:"parse"`
+	Redact    RedactConfig    `json:"redact"`
+	Normalize NormalizeConfig `json:"normalize"`
+	Sample    SampleConfig    `json:"sample"`
+}
+
+// DefaultConfig returns the configuration used when no pipeline file is provided.
+func DefaultConfig() Config {
+	return Config{
+		Redact:    DefaultRedactConfig(),
+		Normalize: DefaultNormalizeConfig(),
+	}
+}
+
+// LoadConfig reads a JSON pipeline configuration file.
+// Settings missing from the file keep their default values.
+func LoadConfig(path string) (Config, error) {
+	cfg := DefaultConfig()
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return cfg, fmt.Errorf("failed to read pipeline config: %w", err)
+	}
+	if err := json.Unmarshal(data, &cfg); err != nil {
+		return cfg, fmt.Errorf("failed to parse pipeline config: %w", err)
+	}
+	return cfg, nil
+}
+
+// Build creates a pipeline from the configuration.
+// Labels are extracted first so that extracted labels are also redacted and validated,
+// and sampling runs last so that rules see canonical levels.
+func Build(cfg Config) (*Pipeline, error) {
+	var processors []Processor
+
+	extractor, err := NewExtractor(cfg.Parse)
+	if err != nil {
+		return nil, err
+	}
+	processors = append(processors, extractor)
+
+	if cfg.Redact.Enabled {
+		redactor, err := NewRedactor(cfg.Redact)
+		if err != nil {
+			return nil, err
+		}
+		processors = append(processors, redactor)
+	}
+
+	normalizer, err := NewNormalizer(cfg.Normalize)
+	if err != nil {
+		return nil, err
+	}
+	processors = append(processors, normalizer)
+
+	// The sampler is always installed, even without rules, so that rules can be added at runtime.
+	sampler, err := NewSampler(cfg.Sample, normalizer)
+	if err != nil {
+		return nil, err
+	}
+	processors = append(processors, sampler)
+
+	return New(processors...), nil
+}
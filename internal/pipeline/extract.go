@@ -0,0 +1,298 @@
+package pipeline
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+	"sync/atomic"
+	"unicode"
+
+	"log-beacon/internal/model"
+)
+
+// Parse rule types.
+const (
+	ParseRegex    = "regex"
+	ParseKeyValue = "kv"
+	ParseJSON     = "json"
+	ParseGrok     = "grok"
+)
+
+// ParseConfig configures label extraction from unstructured log messages.
+type ParseConfig struct {
+	Rules []ParseRule `json:"rules"`
+	// GrokPatterns adds custom grok patterns or overrides built-in ones.
+	GrokPatterns map[string]string `json:"grok_patterns"`
+}
+
+// ParseRule describes one way of extracting labels from a log message.
+type ParseRule struct {
+	Name string `json:"name"`
+	Type string `json:"type"`
+	// Pattern is the regular expression (named captures) or grok expression.
+	Pattern string `json:"pattern"`
+	// Prefix is prepended to every extracted label key.
+	Prefix string `json:"prefix"`
+	// Overwrite replaces labels that are already present on the entry.
+	Overwrite bool `json:"overwrite"`
+	// LevelFrom names an extracted field whose value becomes the entry's level.
+	LevelFrom string `json:"level_from"`
+	// Stop skips the remaining rules once this rule has matched.
+	Stop bool `json:"stop"`
+	// FieldSplit and ValueSplit control key=value parsing. By default pairs are
+	// separated by whitespace and keys from values by "=".
+	FieldSplit string `json:"field_split"`
+	ValueSplit string `json:"value_split"`
+}
+
+// Extractor applies parse rules to log messages and stores the results as labels.
+type Extractor struct {
+	rules []*compiledParseRule
+}
+
+type compiledParseRule struct {
+	ParseRule
+	extract func(message string) map[string]string
+
+	evaluated atomic.Uint64
+	matched   atomic.Uint64
+	extracted atomic.Uint64
+}
+
+// NewExtractor compiles the parse rules and creates an Extractor.
+func NewExtractor(cfg ParseConfig) (*Extractor, error) {
+	e := &Extractor{}
+	for i, rule := range cfg.Rules {
+		if rule.Name == "" {
+			rule.Name = fmt.Sprintf("%s-%d", rule.Type, i)
+		}
+		extract, err := compileParseRule(rule, cfg.GrokPatterns)
+		if err != nil {
+			return nil, fmt.Errorf("parse rule %q: %w", rule.Name, err)
+		}
+		e.rules = append(e.rules, &compiledParseRule{ParseRule: rule, extract: extract})
+	}
+	return e, nil
+}
+
+func compileParseRule(rule ParseRule, grokPatterns map[string]string) (func(string) map[string]string, error) {
+	switch rule.Type {
+	case ParseRegex:
+		re, err := regexp.Compile(rule.Pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid regex: %w", err)
+		}
+		fields := make(map[string]string)
+		for _, name := range re.SubexpNames() {
+			if name != "" {
+				fields[name] = name
+			}
+		}
+		if len(fields) == 0 {
+			return nil, fmt.Errorf("regex has no named captures")
+		}
+		return regexExtractor(re, fields), nil
+	case ParseGrok:
+		re, fields, err := compileGrok(rule.Pattern, grokPatterns)
+		if err != nil {
+			return nil, err
+		}
+		if len(fields) == 0 {
+			return nil, fmt.Errorf("grok pattern has no named fields")
+		}
+		return regexExtractor(re, fields), nil
+	case ParseKeyValue:
+		valueSplit := rule.ValueSplit
+		if valueSplit == "" {
+			valueSplit = "="
+		}
+		fieldSplit := rule.FieldSplit
+		return func(message string) map[string]string {
+			return parseKeyValues(message, fieldSplit, valueSplit)
+		}, nil
+	case ParseJSON:
+		return parseEmbeddedJSON, nil
+	default:
+		return nil, fmt.Errorf("unknown rule type %q", rule.Type)
+	}
+}
+
+// Process implements the Processor interface.
+func (e *Extractor) Process(logEntry *model.Log) error {
+	for _, rule := range e.rules {
+		rule.evaluated.Add(1)
+		fields := rule.extract(logEntry.Message)
+		if len(fields) == 0 {
+			continue
+		}
+		rule.matched.Add(1)
+
+		if logEntry.Labels == nil {
+			logEntry.Labels = make(map[string]string)
+		}
+		for key, value := range fields {
+			key = rule.Prefix + key
+			if _, exists := logEntry.Labels[key]; exists && !rule.Overwrite {
+				continue
+			}
+			logEntry.Labels[key] = value
+			rule.extracted.Add(1)
+		}
+
+		if level, ok := fields[rule.LevelFrom]; ok && rule.LevelFrom != "" {
+			if logEntry.Level == "" || rule.Overwrite {
+				logEntry.Level = level
+			}
+		}
+
+		if rule.Stop {
+			break
+		}
+	}
+	return nil
+}
+
+// Stats implements the StatsReporter interface.
+func (e *Extractor) Stats() []RuleStats {
+	stats := make([]RuleStats, 0, len(e.rules))
+	for _, rule := range e.rules {
+		stats = append(stats, RuleStats{
+			Stage: "parse",
+			Rule:  rule.Name,
+			Counters: map[string]uint64{
+				"evaluated":        rule.evaluated.Load(),
+				"matched":          rule.matched.Load(),
+				"labels_extracted": rule.extracted.Load(),
+			},
+		})
+	}
+	return stats
+}
+
+// regexExtractor returns the non-empty captures of re, keyed by the label name
+// that fields maps each group name to.
+func regexExtractor(re *regexp.Regexp, fields map[string]string) func(string) map[string]string {
+	return func(message string) map[string]string {
+		match := re.FindStringSubmatch(message)
+		if match == nil {
+			return nil
+		}
+		result := make(map[string]string)
+		for i, group := range re.SubexpNames() {
+			label, ok := fields[group]
+			if !ok || match[i] == "" {
+				continue
+			}
+			result[label] = match[i]
+		}
+		return result
+	}
+}
+
+// parseKeyValues extracts key=value pairs from a message. With an empty
+// fieldSplit, pairs are separated by whitespace and values may be double-quoted.
+func parseKeyValues(message, fieldSplit, valueSplit string) map[string]string {
+	result := make(map[string]string)
+
+	if fieldSplit != "" {
+		for _, pair := range strings.Split(message, fieldSplit) {
+			key, value, ok := strings.Cut(strings.TrimSpace(pair), valueSplit)
+			key = strings.TrimSpace(key)
+			value = strings.Trim(strings.TrimSpace(value), `"`)
+			if ok && isKeyToken(key) && value != "" {
+				result[key] = value
+			}
+		}
+		return result
+	}
+
+	rest := message
+	for {
+		rest = strings.TrimLeftFunc(rest, unicode.IsSpace)
+		if rest == "" {
+			return result
+		}
+
+		// Read the key up to the separator or the next whitespace.
+		end := strings.IndexFunc(rest, unicode.IsSpace)
+		if end < 0 {
+			end = len(rest)
+		}
+		sep := strings.Index(rest[:end], valueSplit)
+		if sep <= 0 {
+			rest = rest[end:]
+			continue
+		}
+		key := rest[:sep]
+		rest = rest[sep+len(valueSplit):]
+
+		var value string
+		if strings.HasPrefix(rest, `"`) {
+			value, rest = readQuoted(rest)
+		} else {
+			end = strings.IndexFunc(rest, unicode.IsSpace)
+			if end < 0 {
+				end = len(rest)
+			}
+			value, rest = rest[:end], rest[end:]
+		}
+		if isKeyToken(key) && value != "" {
+			result[key] = value
+		}
+	}
+}
+
+// readQuoted reads a double-quoted string with backslash escapes from the start of s.
+// It returns the unquoted value and the remainder of s.
+func readQuoted(s string) (string, string) {
+	var b strings.Builder
+	for i := 1; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			if i+1 < len(s) {
+				i++
+				b.WriteByte(s[i])
+			}
+		case '"':
+			return b.String(), s[i+1:]
+		default:
+			b.WriteByte(s[i])
+		}
+	}
+	return b.String(), ""
+}
+
+func isKeyToken(key string) bool {
+	if key == "" {
+		return false
+	}
+	for _, r := range key {
+		if !(unicode.IsLetter(r) || unicode.IsDigit(r) || r == '_' || r == '.' || r == '-') {
+			return false
+		}
+	}
+	return true
+}
+
+// parseEmbeddedJSON decodes the first JSON object found in the message and
+// flattens it into dotted label keys.
+func parseEmbeddedJSON(message string) map[string]string {
+	start := strings.IndexByte(message, '{')
+	if start < 0 {
+		return nil
+	}
+
+	var object map[string]interface{}
+	decoder := json.NewDecoder(strings.NewReader(message[start:]))
+	decoder.UseNumber()
+	if err := decoder.Decode(&object); err != nil {
+		return nil
+	}
+
+	result := make(map[string]string)
+	for key, value := range object {
+		model.Flatten(result, key, value)
+	}
+	return result
+}
@@ -0,0 +1,112 @@
+package pipeline
+
+import (
+	"testing"
+
+	"log-beacon/internal/model"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExtractor_Rules(t *testing.T) {
+	tests := []struct {
+		name    string
+		rule    ParseRule
+		message string
+		want    map[string]string
+	}{
+		{
+			name:    "key value",
+			rule:    ParseRule{Type: ParseKeyValue},
+			message: `user=42 latency=120ms status=500 msg="request failed" ignored`,
+			want:    map[string]string{"user": "42", "latency": "120ms", "status": "500", "msg": "request failed"},
+		},
+		{
+			name:    "key value with custom separators",
+			rule:    ParseRule{Type: ParseKeyValue, FieldSplit: "&", ValueSplit: ":"},
+			message: "a:1&b: 2&broken",
+			want:    map[string]string{"a": "1", "b": "2"},
+		},
+		{
+			name:    "regex named captures",
+			rule:    ParseRule{Type: ParseRegex, Pattern: `took (?P<duration>\d+)ms for (?P<route>\S+)`},
+			message: "request took 87ms for /api/v1/search",
+			want:    map[string]string{"duration": "87", "route": "/api/v1/search"},
+		},
+		{
+			name:    "embedded json",
+			rule:    ParseRule{Type: ParseJSON, Prefix: "ctx."},
+			message: `payment declined {"order":{"id":"o-1"},"amount":12.5}`,
+			want:    map[string]string{"ctx.order.id": "o-1", "ctx.amount": "12.5"},
+		},
+		{
+			name:    "grok",
+			rule:    ParseRule{Type: ParseGrok, Pattern: `%{IPORHOST:client.ip} %{HTTPMETHOD:http.method} %{URIPATHPARAM:http.path} %{NUMBER:http.status}`},
+			message: "10.0.0.7 GET /login?next=/home 302",
+			want:    map[string]string{"client.ip": "10.0.0.7", "http.method": "GET", "http.path": "/login?next=/home", "http.status": "302"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e, err := NewExtractor(ParseConfig{Rules: []ParseRule{tt.rule}})
+			require.NoError(t, err)
+
+			logEntry := model.Log{Message: tt.message}
+			require.NoError(t, e.Process(&logEntry))
+			assert.Equal(t, tt.want, logEntry.Labels)
+		})
+	}
+}
+
+func TestExtractor_OverwriteLevelAndStop(t *testing.T) {
+	e, err := NewExtractor(ParseConfig{
+		Rules: []ParseRule{
+			{Name: "level", Type: ParseGrok, Pattern: `^\[%{LOGLEVEL:severity}\]`, LevelFrom: "severity", Stop: true},
+			{Name: "never", Type: ParseKeyValue},
+		},
+	})
+	require.NoError(t, err)
+
+	logEntry := model.Log{Message: "[ERROR] service=db down", Labels: map[string]string{"severity": "keep"}}
+	require.NoError(t, e.Process(&logEntry))
+
+	assert.Equal(t, "ERROR", logEntry.Level)
+	assert.Equal(t, map[string]string{"severity": "keep"}, logEntry.Labels)
+
+	stats := e.Stats()
+	require.Len(t, stats, 2)
+	assert.Equal(t, RuleStats{Stage: "parse", Rule: "level", Counters: map[string]uint64{"evaluated": 1, "matched": 1, "labels_extracted": 0}}, stats[0])
+	assert.Equal(t, uint64(0), stats[1].Counters["evaluated"])
+}
+
+func TestExtractor_Stats(t *testing.T) {
+	e, err := NewExtractor(ParseConfig{Rules: []ParseRule{{Type: ParseKeyValue}}})
+	require.NoError(t, err)
+
+	for _, msg := range []string{"a=1 b=2", "no pairs here", "c=3"} {
+		logEntry := model.Log{Message: msg}
+		require.NoError(t, e.Process(&logEntry))
+	}
+
+	stats := e.Stats()
+	require.Len(t, stats, 1)
+	assert.Equal(t, "kv-0", stats[0].Rule)
+	assert.Equal(t, map[string]uint64{"evaluated": 3, "matched": 2, "labels_extracted": 3}, stats[0].Counters)
+}
+
+func TestNewExtractor_InvalidRules(t *testing.T) {
+	tests := []ParseRule{
+		{Type: "xml"},
+		{Type: ParseRegex, Pattern: `(unclosed`},
+		{Type: ParseRegex, Pattern: `no captures`},
+		{Type: ParseGrok, Pattern: `%{NOPE:x}`},
+		{Type: ParseGrok, Pattern: `%{LOOP:x}`},
+	}
+
+	for _, rule := range tests {
+		_, err := NewExtractor(ParseConfig{Rules: []ParseRule{rule}, GrokPatterns: map[string]string{"LOOP": "%{LOOP}"}})
+		assert.Error(t, err, "rule %+v", rule)
+	}
+}
@@ -0,0 +1,109 @@
+package pipeline
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+)
+
+// grokPatterns is the built-in grok pattern library.
+// Patterns may reference each other with %{NAME}.
+var grokPatterns = map[string]string{
+	"WORD":              `\b\w+\b`,
+	"NOTSPACE":          `\S+`,
+	"SPACE":             `\s*`,
+	"DATA":              `.*?`,
+	"GREEDYDATA":        `.*`,
+	"INT":               `[+-]?\d+`,
+	"POSINT":            `\b[1-9]\d*\b`,
+	"NUMBER":            `[+-]?(?:\d+(?:\.\d*)?|\.\d+)`,
+	"BASE16NUM":         `(?:0[xX])?[0-9A-Fa-f]+`,
+	"UUID":              `[A-Fa-f0-9]{8}-(?:[A-Fa-f0-9]{4}-){3}[A-Fa-f0-9]{12}`,
+	"IPV4":              `(?:(?:25[0-5]|2[0-4]\d|1?\d?\d)\.){3}(?:25[0-5]|2[0-4]\d|1?\d?\d)`,
+	"IPV6":              `(?:[0-9A-Fa-f]{0,4}:){2,7}[0-9A-Fa-f]{0,4}`,
+	"IP":                `(?:%{IPV6}|%{IPV4})`,
+	"HOSTNAME":          `\b[0-9A-Za-z][0-9A-Za-z\-]{0,62}(?:\.[0-9A-Za-z][0-9A-Za-z\-]{0,62})*\.?\b`,
+	"IPORHOST":          `(?:%{IP}|%{HOSTNAME})`,
+	"USER":              `[a-zA-Z0-9._-]+`,
+	"EMAILADDRESS":      `[a-zA-Z0-9!#$%&'*+\-/=?^_{|}~.]+@%{HOSTNAME}`,
+	"QUOTEDSTRING":      `"(?:[^"\\]|\\.)*"`,
+	"URIPATH":           `(?:/[A-Za-z0-9$.+!*'(){},~:;=@#%&_\-]*)+`,
+	"URIPARAM":          `\?[A-Za-z0-9$.+!*'|(){},~@#%&/=:;_?\-\[\]<>]*`,
+	"URIPATHPARAM":      `%{URIPATH}(?:%{URIPARAM})?`,
+	"HTTPMETHOD":        `\b(?:GET|HEAD|POST|PUT|DELETE|CONNECT|OPTIONS|TRACE|PATCH)\b`,
+	"LOGLEVEL":          `(?i:trace|debug|info|information|notice|warn|warning|error|err|crit|critical|fatal|alert|emerg|panic)`,
+	"DURATION":          `[+-]?\d+(?:\.\d+)?(?:ns|us|µs|ms|s|m|h)`,
+	"YEAR":              `\d{4}`,
+	"MONTHNUM":          `(?:0?[1-9]|1[0-2])`,
+	"MONTHDAY":          `(?:0?[1-9]|[12]\d|3[01])`,
+	"MONTH":             `\b(?:Jan|Feb|Mar|Apr|May|Jun|Jul|Aug|Sep|Oct|Nov|Dec)[a-z]*\b`,
+	"TIME":              `(?:[01]?\d|2[0-3]):[0-5]\d(?::[0-5]\d(?:[.,]\d+)?)?`,
+	"ISO8601_TIMEZONE":  `(?:Z|[+-](?:[01]\d|2[0-3]):?[0-5]\d)`,
+	"TIMESTAMP_ISO8601": `%{YEAR}-%{MONTHNUM}-%{MONTHDAY}[T ]%{TIME}%{ISO8601_TIMEZONE}?`,
+	"HTTPDATE":          `%{MONTHDAY}/%{MONTH}/%{YEAR}:%{TIME} [+-]\d{4}`,
+	"SYSLOGTIMESTAMP":   `%{MONTH} +%{MONTHDAY} %{TIME}`,
+}
+
+var grokReference = regexp.MustCompile(`%\{([A-Z0-9_]+)(?::([A-Za-z0-9_.\-]+))?\}`)
+
+// maxGrokDepth bounds pattern expansion so that self-referencing patterns fail fast.
+const maxGrokDepth = 16
+
+// compileGrok expands a grok expression into a regular expression.
+// It returns the compiled expression and the label name of every capture group,
+// indexed by the group's subexpression name.
+func compileGrok(pattern string, custom map[string]string) (*regexp.Regexp, map[string]string, error) {
+	fields := make(map[string]string)
+	expanded, err := expandGrok(pattern, custom, fields, 0)
+	if err != nil {
+		return nil, nil, err
+	}
+	re, err := regexp.Compile(expanded)
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid grok pattern: %w", err)
+	}
+	return re, fields, nil
+}
+
+func expandGrok(pattern string, custom map[string]string, fields map[string]string, depth int) (string, error) {
+	if depth > maxGrokDepth {
+		return "", fmt.Errorf("grok pattern nesting exceeds %d levels", maxGrokDepth)
+	}
+
+	var expandErr error
+	expanded := grokReference.ReplaceAllStringFunc(pattern, func(ref string) string {
+		if expandErr != nil {
+			return ""
+		}
+		parts := grokReference.FindStringSubmatch(ref)
+		name, field := parts[1], parts[2]
+
+		definition, ok := custom[name]
+		if !ok {
+			definition, ok = grokPatterns[name]
+		}
+		if !ok {
+			expandErr = fmt.Errorf("unknown grok pattern %q", name)
+			return ""
+		}
+
+		inner, err := expandGrok(definition, custom, fields, depth+1)
+		if err != nil {
+			expandErr = err
+			return ""
+		}
+		if field == "" {
+			return "(?:" + inner + ")"
+		}
+
+		// Label names may contain dots, which are not valid in group names,
+		// so groups are numbered and mapped back to the label name.
+		group := "g" + strconv.Itoa(len(fields))
+		fields[group] = field
+		return "(?P<" + group + ">" + inner + ")"
+	})
+	if expandErr != nil {
+		return "", expandErr
+	}
+	return expanded, nil
+}
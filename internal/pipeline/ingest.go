@@ -0,0 +1,49 @@
+package pipeline
+
+import (
+	"errors"
+
+	"log-beacon/internal/model"
+)
+
+// IngestFunc processes and publishes a single log entry. It is implemented
+// by the API server and shared by the receivers of every wire format.
+type IngestFunc func(logEntry model.Log) error
+
+// BatchRejection is an entry of a batch refused by the pipeline.
+type BatchRejection struct {
+	Index int
+	Err   *RejectionError
+}
+
+// BatchResult counts the outcome of ingesting a batch of entries.
+type BatchResult struct {
+	Accepted int
+	Dropped  int
+	Rejected []BatchRejection
+}
+
+// IngestBatch hands every entry to ingest. Entries rejected by the pipeline
+// are reported in the result without failing the batch; any other error
+// aborts the batch so that the client retries it.
+func IngestBatch(ingest IngestFunc, logs []model.Log) (BatchResult, error) {
+	var result BatchResult
+	for i, logEntry := range logs {
+		err := ingest(logEntry)
+		if err == nil {
+			result.Accepted++
+			continue
+		}
+		if errors.Is(err, ErrDropped) {
+			result.Dropped++
+			continue
+		}
+		var rejection *RejectionError
+		if errors.As(err, &rejection) {
+			result.Rejected = append(result.Rejected, BatchRejection{Index: i, Err: rejection})
+			continue
+		}
+		return result, err
+	}
+	return result, nil
+}
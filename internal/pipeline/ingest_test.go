@@ -0,0 +1,42 @@
+package pipeline
+
+import (
+	"errors"
+	"testing"
+
+	"log-beacon/internal/model"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIngestBatch(t *testing.T) {
+	var published []string
+	ingest := func(logEntry model.Log) error {
+		switch logEntry.Message {
+		case "noise":
+			return ErrDropped
+		case "invalid":
+			return &RejectionError{Reasons: []string{"message rejected"}}
+		case "unavailable":
+			return errors.New("nats: no responders")
+		}
+		published = append(published, logEntry.Message)
+		return nil
+	}
+
+	result, err := IngestBatch(ingest, []model.Log{{Message: "a"}, {Message: "noise"}, {Message: "invalid"}, {Message: "b"}})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"a", "b"}, published)
+	assert.Equal(t, 2, result.Accepted)
+	assert.Equal(t, 1, result.Dropped)
+	require.Len(t, result.Rejected, 1)
+	assert.Equal(t, 2, result.Rejected[0].Index)
+	assert.Equal(t, []string{"message rejected"}, result.Rejected[0].Err.Reasons)
+
+	// Any other error stops the batch so that the client retries it.
+	published = nil
+	_, err = IngestBatch(ingest, []model.Log{{Message: "a"}, {Message: "unavailable"}, {Message: "b"}})
+	assert.Error(t, err)
+	assert.Equal(t, []string{"a"}, published)
+}
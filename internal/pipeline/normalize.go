@@ -0,0 +1,206 @@
+package pipeline
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"log-beacon/internal/model"
+)
+
+// CanonicalLevels lists the log levels stored by Log Beacon, from least to most severe.
+var CanonicalLevels = []string{"trace", "debug", "info", "warn", "error", "fatal"}
+
+// NormalizeConfig controls how ingested log entries are validated and normalized.
+// A zero size limit disables that limit.
+type NormalizeConfig struct {
+	// LevelAliases maps lower-cased level spellings to a canonical level.
+	LevelAliases map[string]string `json:"level_aliases"`
+	// DefaultLevel is assigned to entries that arrive without a level.
+	DefaultLevel string `json:"default_level"`
+	// RejectUnknownLevels rejects entries whose level cannot be mapped.
+	// Otherwise the level is kept in lower case.
+	RejectUnknownLevels bool `json:"reject_unknown_levels"`
+	MaxMessageBytes     int  `json:"max_message_bytes"`
+	MaxLabels           int  `json:"max_labels"`
+	MaxLabelKeyBytes    int  `json:"max_label_key_bytes"`
+	MaxLabelValueBytes  int  `json:"max_label_value_bytes"`
+	// Truncate shortens oversized messages and label values instead of rejecting the entry.
+	Truncate bool `json:"truncate"`
+}
+
+// DefaultNormalizeConfig returns the default normalization settings.
+func DefaultNormalizeConfig() NormalizeConfig {
+	return NormalizeConfig{
+		LevelAliases: map[string]string{
+			"t": "trace", "trc": "trace", "trace": "trace",
+			"d": "debug", "dbg": "debug", "debug": "debug",
+			"i": "info", "inf": "info", "info": "info", "information": "info", "informational": "info", "notice": "info",
+			"w": "warn", "wrn": "warn", "warn": "warn", "warning": "warn",
+			"e": "error", "err": "error", "error": "error",
+			"f": "fatal", "fatal": "fatal", "crit": "fatal", "critical": "fatal", "alert": "fatal", "emerg": "fatal", "emergency": "fatal", "panic": "fatal",
+		},
+		DefaultLevel:       "info",
+		MaxMessageBytes:    64 * 1024,
+		MaxLabels:          64,
+		MaxLabelKeyBytes:   128,
+		MaxLabelValueBytes: 1024,
+	}
+}
+
+// Normalizer maps levels onto the canonical set, sanitizes label keys and
+// enforces size limits.
+type Normalizer struct {
+	cfg NormalizeConfig
+}
+
+// NewNormalizer validates the configuration and creates a Normalizer.
+func NewNormalizer(cfg NormalizeConfig) (*Normalizer, error) {
+	if cfg.DefaultLevel != "" && !isCanonicalLevel(cfg.DefaultLevel) {
+		return nil, fmt.Errorf("default level %q is not one of %v", cfg.DefaultLevel, CanonicalLevels)
+	}
+	aliases := make(map[string]string, len(cfg.LevelAliases))
+	for alias, level := range cfg.LevelAliases {
+		if !isCanonicalLevel(level) {
+			return nil, fmt.Errorf("level alias %q maps to unknown level %q", alias, level)
+		}
+		aliases[strings.ToLower(alias)] = level
+	}
+	cfg.LevelAliases = aliases
+
+	if cfg.MaxMessageBytes < 0 || cfg.MaxLabels < 0 || cfg.MaxLabelKeyBytes < 0 || cfg.MaxLabelValueBytes < 0 {
+		return nil, fmt.Errorf("normalize size limits must not be negative")
+	}
+	return &Normalizer{cfg: cfg}, nil
+}
+
+// Process implements the Processor interface.
+func (n *Normalizer) Process(logEntry *model.Log) error {
+	var reasons []string
+
+	level, ok := n.normalizeLevel(logEntry.Level)
+	if !ok {
+		reasons = append(reasons, fmt.Sprintf("unknown level %q", logEntry.Level))
+	}
+	logEntry.Level = level
+
+	if limit := n.cfg.MaxMessageBytes; limit > 0 && len(logEntry.Message) > limit {
+		if n.cfg.Truncate {
+			logEntry.Message = truncateUTF8(logEntry.Message, limit)
+		} else {
+			reasons = append(reasons, fmt.Sprintf("message exceeds %d bytes", limit))
+		}
+	}
+
+	labels, labelReasons := n.normalizeLabels(logEntry.Labels)
+	logEntry.Labels = labels
+	reasons = append(reasons, labelReasons...)
+
+	if len(reasons) > 0 {
+		return &RejectionError{Reasons: reasons}
+	}
+	return nil
+}
+
+// normalizeLevel maps a level onto the canonical set.
+// It reports false when the level is unknown and unknown levels are rejected.
+func (n *Normalizer) normalizeLevel(level string) (string, bool) {
+	if strings.TrimSpace(level) == "" {
+		return n.cfg.DefaultLevel, true
+	}
+	level, ok := n.CanonicalLevel(level)
+	return level, ok || !n.cfg.RejectUnknownLevels
+}
+
+// CanonicalLevel maps a level spelling onto the canonical set through the
+// level aliases. It reports false, with the level in lower case, when the
+// level is unknown.
+func (n *Normalizer) CanonicalLevel(level string) (string, bool) {
+	level = strings.ToLower(strings.TrimSpace(level))
+	if canonical, ok := n.cfg.LevelAliases[level]; ok {
+		return canonical, true
+	}
+	return level, isCanonicalLevel(level)
+}
+
+// normalizeLabels sanitizes label keys and enforces the label limits.
+func (n *Normalizer) normalizeLabels(labels map[string]string) (map[string]string, []string) {
+	if labels == nil {
+		return nil, nil
+	}
+
+	var reasons []string
+	if limit := n.cfg.MaxLabels; limit > 0 && len(labels) > limit {
+		reasons = append(reasons, fmt.Sprintf("entry has %d labels, the limit is %d", len(labels), limit))
+	}
+
+	result := make(map[string]string, len(labels))
+	renamed := make(map[string]string)
+	for key, value := range labels {
+		clean := sanitizeLabelKey(key)
+		if clean == "" {
+			reasons = append(reasons, fmt.Sprintf("label key %q is empty after sanitization", key))
+			continue
+		}
+		if limit := n.cfg.MaxLabelKeyBytes; limit > 0 && len(clean) > limit {
+			reasons = append(reasons, fmt.Sprintf("label key %q exceeds %d bytes", truncateUTF8(clean, 32), limit))
+			continue
+		}
+		if limit := n.cfg.MaxLabelValueBytes; limit > 0 && len(value) > limit {
+			if n.cfg.Truncate {
+				value = truncateUTF8(value, limit)
+			} else {
+				reasons = append(reasons, fmt.Sprintf("value of label %q exceeds %d bytes", clean, limit))
+				continue
+			}
+		}
+		if clean == key {
+			result[clean] = value
+		} else {
+			renamed[clean] = value
+		}
+	}
+
+	// Keys that were already clean win over sanitized keys that collide with them.
+	for key, value := range renamed {
+		if _, exists := result[key]; !exists {
+			result[key] = value
+		}
+	}
+	return result, reasons
+}
+
+// sanitizeLabelKey replaces characters that are not safe in query field names
+// with underscores and trims leading and trailing separators.
+func sanitizeLabelKey(key string) string {
+	var b strings.Builder
+	for _, r := range strings.TrimSpace(key) {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '_', r == '.', r == '-':
+			b.WriteRune(r)
+		default:
+			b.WriteByte('_')
+		}
+	}
+	return strings.Trim(b.String(), ".-")
+}
+
+// truncateUTF8 shortens s to at most limit bytes without splitting a rune.
+func truncateUTF8(s string, limit int) string {
+	if len(s) <= limit {
+		return s
+	}
+	for limit > 0 && !utf8.RuneStart(s[limit]) {
+		limit--
+	}
+	return s[:limit]
+}
+
+func isCanonicalLevel(level string) bool {
+	for _, l := range CanonicalLevels {
+		if l == level {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,130 @@
+package pipeline
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"log-beacon/internal/model"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNormalizer_Levels(t *testing.T) {
+	n, err := NewNormalizer(DefaultNormalizeConfig())
+	require.NoError(t, err)
+
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{"WARN", "warn"},
+		{"warning", "warn"},
+		{"W", "warn"},
+		{" Error ", "error"},
+		{"CRITICAL", "fatal"},
+		{"", "info"},
+		{"verbose", "verbose"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			logEntry := model.Log{Level: tt.input}
+			require.NoError(t, n.Process(&logEntry))
+			assert.Equal(t, tt.want, logEntry.Level)
+		})
+	}
+}
+
+func TestNormalizer_RejectUnknownLevel(t *testing.T) {
+	cfg := DefaultNormalizeConfig()
+	cfg.RejectUnknownLevels = true
+	n, err := NewNormalizer(cfg)
+	require.NoError(t, err)
+
+	logEntry := model.Log{Level: "verbose", Message: "test"}
+	err = n.Process(&logEntry)
+
+	var rejection *RejectionError
+	require.True(t, errors.As(err, &rejection))
+	assert.Equal(t, []string{`unknown level "verbose"`}, rejection.Reasons)
+}
+
+func TestNormalizer_SizeLimits(t *testing.T) {
+	cfg := DefaultNormalizeConfig()
+	cfg.MaxMessageBytes = 10
+	cfg.MaxLabels = 2
+	cfg.MaxLabelValueBytes = 4
+
+	t.Run("rejects oversized entries", func(t *testing.T) {
+		n, err := NewNormalizer(cfg)
+		require.NoError(t, err)
+
+		logEntry := model.Log{
+			Level:   "info",
+			Message: strings.Repeat("x", 11),
+			Labels:  map[string]string{"a": "1", "b": "too long", "c": "3"},
+		}
+		err = n.Process(&logEntry)
+
+		var rejection *RejectionError
+		require.True(t, errors.As(err, &rejection))
+		assert.Len(t, rejection.Reasons, 3)
+		assert.Contains(t, rejection.Reasons, "message exceeds 10 bytes")
+		assert.Contains(t, rejection.Reasons, `value of label "b" exceeds 4 bytes`)
+	})
+
+	t.Run("truncates when configured", func(t *testing.T) {
+		truncating := cfg
+		truncating.Truncate = true
+		n, err := NewNormalizer(truncating)
+		require.NoError(t, err)
+
+		logEntry := model.Log{
+			Level:   "info",
+			Message: "héllo wörld!",
+			Labels:  map[string]string{"b": "too long"},
+		}
+		require.NoError(t, n.Process(&logEntry))
+		assert.Equal(t, "héllo wö", logEntry.Message)
+		assert.Equal(t, "too ", logEntry.Labels["b"])
+	})
+}
+
+func TestNormalizer_SanitizesLabelKeys(t *testing.T) {
+	n, err := NewNormalizer(DefaultNormalizeConfig())
+	require.NoError(t, err)
+
+	logEntry := model.Log{
+		Level: "info",
+		Labels: map[string]string{
+			"user id":     "42",
+			"k8s.pod":     "api-1",
+			"service":     "auth",
+			"service ":    "ignored",
+			".trace-id.":  "abc",
+			"weird/key=1": "x",
+		},
+	}
+	require.NoError(t, n.Process(&logEntry))
+	assert.Equal(t, map[string]string{
+		"user_id":     "42",
+		"k8s.pod":     "api-1",
+		"service":     "auth",
+		"trace-id":    "abc",
+		"weird_key_1": "x",
+	}, logEntry.Labels)
+}
+
+func TestNewNormalizer_InvalidConfig(t *testing.T) {
+	cfg := DefaultNormalizeConfig()
+	cfg.LevelAliases = map[string]string{"sev1": "urgent"}
+	_, err := NewNormalizer(cfg)
+	assert.Error(t, err)
+
+	cfg = DefaultNormalizeConfig()
+	cfg.DefaultLevel = "loud"
+	_, err = NewNormalizer(cfg)
+	assert.Error(t, err)
+}
@@ -0,0 +1,77 @@
+package pipeline
+
+import (
+	"strings"
+
+	"log-beacon/internal/model"
+)
+
+// Processor defines a single stage that inspects or transforms a log entry
+// before it is published.
+type Processor interface {
+	Process(logEntry *model.Log) error
+}
+
+// RejectionError is returned by a processor when a log entry must not be accepted.
+// Reasons are reported back to the client.
+type RejectionError struct {
+	Reasons []string
+}
+
+// Error implements the error interface.
+func (e *RejectionError) Error() string {
+	return "log entry rejected: " + strings.Join(e.Reasons, "; ")
+}
+
+// RuleStats reports the counters kept for a single rule of a pipeline stage.
+type RuleStats struct {
+	Stage    string            `json:"stage"`
+	Rule     string            `json:"rule"`
+	Counters map[string]uint64 `json:"counters"`
+}
+
+// StatsReporter is implemented by processors that keep per-rule statistics.
+type StatsReporter interface {
+	Stats() []RuleStats
+}
+
+// Pipeline runs a chain of processors in order.
+type Pipeline struct {
+	processors []Processor
+}
+
+// New creates a pipeline from the given processors.
+func New(processors ...Processor) *Pipeline {
+	return &Pipeline{processors: processors}
+}
+
+// Process runs every processor against the log entry, stopping at the first error.
+func (p *Pipeline) Process(logEntry *model.Log) error {
+	for _, proc := range p.processors {
+		if err := proc.Process(logEntry); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Sampler returns the pipeline's sampler, or nil if it has none.
+func (p *Pipeline) Sampler() *Sampler {
+	for _, proc := range p.processors {
+		if sampler, ok := proc.(*Sampler); ok {
+			return sampler
+		}
+	}
+	return nil
+}
+
+// Stats collects the per-rule statistics of every processor that keeps them.
+func (p *Pipeline) Stats() []RuleStats {
+	stats := []RuleStats{}
+	for _, proc := range p.processors {
+		if reporter, ok := proc.(StatsReporter); ok {
+			stats = append(stats, reporter.Stats()...)
+		}
+	}
+	return stats
+}
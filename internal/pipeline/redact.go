@@ -0,0 +1,288 @@
+package pipeline
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync/atomic"
+
+	"log-beacon/internal/model"
+)
+
+// Redaction actions.
+const (
+	RedactMask = "mask"
+	RedactHash = "hash"
+	RedactDrop = "drop"
+)
+
+// MessageField names the log message in RedactConfig.Fields.
+const MessageField = "message"
+
+// RedactConfig controls removal of sensitive data from log entries.
+type RedactConfig struct {
+	Enabled bool `json:"enabled"`
+	// BuiltIns selects built-in patterns by name. Nil enables all of them.
+	BuiltIns []string `json:"builtins"`
+	// Patterns adds custom patterns.
+	Patterns []RedactPattern `json:"patterns"`
+	// SensitiveKeys lists label key fragments whose values are always redacted.
+	SensitiveKeys []string `json:"sensitive_keys"`
+	// Fields sets the action per field: "message" or a label key.
+	Fields map[string]string `json:"fields"`
+	// DefaultAction applies to fields not listed in Fields.
+	DefaultAction string `json:"default_action"`
+	// HashSalt is mixed into hashed values so they cannot be looked up in precomputed tables.
+	HashSalt string `json:"hash_salt"`
+	// CountLabel receives the number of redactions made on an entry.
+	CountLabel string `json:"count_label"`
+}
+
+// RedactPattern is a named regular expression matching sensitive data.
+// If the expression has a capture group named "secret", only that group is redacted.
+type RedactPattern struct {
+	Name    string `json:"name"`
+	Pattern string `json:"pattern"`
+}
+
+// builtInRedactPatterns are the patterns available through RedactConfig.BuiltIns.
+var builtInRedactPatterns = []RedactPattern{
+	{Name: "email", Pattern: `[A-Za-z0-9._%+\-]+@[A-Za-z0-9.\-]+\.[A-Za-z]{2,}`},
+	{Name: "credit_card", Pattern: `\b(?:\d[ \-]?){12,18}\d\b`},
+	{Name: "bearer_token", Pattern: `(?i)\bbearer\s+(?P<secret>[A-Za-z0-9\-._~+/]+=*)`},
+	{Name: "jwt", Pattern: `\beyJ[A-Za-z0-9_\-]+\.[A-Za-z0-9_\-]+\.[A-Za-z0-9_\-]+`},
+	{Name: "password", Pattern: `(?i)\b(?:password|passwd|pwd|secret)\s*[=:]\s*(?P<secret>"[^"]*"|\S+)`},
+	{Name: "aws_access_key", Pattern: `\bAKIA[0-9A-Z]{16}\b`},
+}
+
+// DefaultRedactConfig returns the default redaction settings.
+func DefaultRedactConfig() RedactConfig {
+	return RedactConfig{
+		Enabled:       true,
+		SensitiveKeys: []string{"password", "passwd", "secret", "token", "api_key", "apikey", "authorization", "cookie"},
+		DefaultAction: RedactMask,
+		CountLabel:    "redacted_count",
+	}
+}
+
+// Redactor replaces sensitive data in messages and labels.
+type Redactor struct {
+	cfg      RedactConfig
+	patterns []*compiledRedactPattern
+	keyHits  atomic.Uint64
+}
+
+type compiledRedactPattern struct {
+	name   string
+	re     *regexp.Regexp
+	secret int
+	// validate optionally rejects false positives.
+	validate func(match string) bool
+	hits     atomic.Uint64
+}
+
+// NewRedactor compiles the redaction patterns and creates a Redactor.
+func NewRedactor(cfg RedactConfig) (*Redactor, error) {
+	if cfg.DefaultAction == "" {
+		cfg.DefaultAction = RedactMask
+	}
+	if !isRedactAction(cfg.DefaultAction) {
+		return nil, fmt.Errorf("unknown redaction action %q", cfg.DefaultAction)
+	}
+	for field, action := range cfg.Fields {
+		if !isRedactAction(action) {
+			return nil, fmt.Errorf("unknown redaction action %q for field %q", action, field)
+		}
+	}
+	sensitiveKeys := make([]string, len(cfg.SensitiveKeys))
+	for i, key := range cfg.SensitiveKeys {
+		sensitiveKeys[i] = strings.ToLower(key)
+	}
+	cfg.SensitiveKeys = sensitiveKeys
+
+	r := &Redactor{cfg: cfg}
+
+	selected := append([]RedactPattern(nil), builtInRedactPatterns...)
+	if cfg.BuiltIns != nil {
+		selected = nil
+		for _, name := range cfg.BuiltIns {
+			pattern, ok := findBuiltInRedactPattern(name)
+			if !ok {
+				return nil, fmt.Errorf("unknown built-in redaction pattern %q", name)
+			}
+			selected = append(selected, pattern)
+		}
+	}
+
+	for _, pattern := range append(selected, cfg.Patterns...) {
+		re, err := regexp.Compile(pattern.Pattern)
+		if err != nil {
+			return nil, fmt.Errorf("redaction pattern %q: %w", pattern.Name, err)
+		}
+		compiled := &compiledRedactPattern{name: pattern.Name, re: re, secret: re.SubexpIndex("secret")}
+		if pattern.Name == "credit_card" {
+			compiled.validate = luhnValid
+		}
+		r.patterns = append(r.patterns, compiled)
+	}
+	return r, nil
+}
+
+// Process implements the Processor interface.
+func (r *Redactor) Process(logEntry *model.Log) error {
+	count := 0
+
+	var n int
+	logEntry.Message, n = r.redactText(logEntry.Message, r.actionFor(MessageField))
+	count += n
+
+	for key, value := range logEntry.Labels {
+		action := r.actionFor(key)
+		if r.isSensitiveKey(key) {
+			r.keyHits.Add(1)
+			count++
+			if action == RedactDrop {
+				delete(logEntry.Labels, key)
+			} else {
+				logEntry.Labels[key] = r.replacement("sensitive_key", value, action)
+			}
+			continue
+		}
+
+		redacted, n := r.redactText(value, action)
+		if n == 0 {
+			continue
+		}
+		count += n
+		if action == RedactDrop {
+			delete(logEntry.Labels, key)
+		} else {
+			logEntry.Labels[key] = redacted
+		}
+	}
+
+	if count > 0 && r.cfg.CountLabel != "" {
+		if logEntry.Labels == nil {
+			logEntry.Labels = make(map[string]string)
+		}
+		logEntry.Labels[r.cfg.CountLabel] = strconv.Itoa(count)
+	}
+	return nil
+}
+
+// Stats implements the StatsReporter interface.
+func (r *Redactor) Stats() []RuleStats {
+	stats := make([]RuleStats, 0, len(r.patterns)+1)
+	for _, pattern := range r.patterns {
+		stats = append(stats, RuleStats{
+			Stage:    "redact",
+			Rule:     pattern.name,
+			Counters: map[string]uint64{"redactions": pattern.hits.Load()},
+		})
+	}
+	stats = append(stats, RuleStats{
+		Stage:    "redact",
+		Rule:     "sensitive_keys",
+		Counters: map[string]uint64{"redactions": r.keyHits.Load()},
+	})
+	return stats
+}
+
+// redactText applies every pattern to text and returns the result and the
+// number of redactions made. With the drop action matches are removed.
+func (r *Redactor) redactText(text, action string) (string, int) {
+	count := 0
+	for _, pattern := range r.patterns {
+		matches := pattern.re.FindAllStringSubmatchIndex(text, -1)
+		if matches == nil {
+			continue
+		}
+
+		var b strings.Builder
+		last := 0
+		for _, m := range matches {
+			start, end := m[0], m[1]
+			if pattern.secret > 0 && m[2*pattern.secret] >= 0 {
+				start, end = m[2*pattern.secret], m[2*pattern.secret+1]
+			}
+			if pattern.validate != nil && !pattern.validate(text[start:end]) {
+				continue
+			}
+			b.WriteString(text[last:start])
+			if action != RedactDrop {
+				b.WriteString(r.replacement(pattern.name, text[start:end], action))
+			}
+			last = end
+			count++
+			pattern.hits.Add(1)
+		}
+		b.WriteString(text[last:])
+		text = b.String()
+	}
+	return text, count
+}
+
+// replacement returns the text that stands in for a redacted value.
+func (r *Redactor) replacement(name, value, action string) string {
+	if action == RedactHash {
+		sum := sha256.Sum256([]byte(r.cfg.HashSalt + value))
+		return "[HASH:" + hex.EncodeToString(sum[:8]) + "]"
+	}
+	return "[REDACTED:" + name + "]"
+}
+
+func (r *Redactor) actionFor(field string) string {
+	if action, ok := r.cfg.Fields[field]; ok {
+		return action
+	}
+	return r.cfg.DefaultAction
+}
+
+func (r *Redactor) isSensitiveKey(key string) bool {
+	key = strings.ToLower(key)
+	for _, fragment := range r.cfg.SensitiveKeys {
+		if strings.Contains(key, fragment) {
+			return true
+		}
+	}
+	return false
+}
+
+func findBuiltInRedactPattern(name string) (RedactPattern, bool) {
+	for _, pattern := range builtInRedactPatterns {
+		if pattern.Name == name {
+			return pattern, true
+		}
+	}
+	return RedactPattern{}, false
+}
+
+func isRedactAction(action string) bool {
+	return action == RedactMask || action == RedactHash || action == RedactDrop
+}
+
+// luhnValid reports whether the digits in s pass the Luhn checksum used by card numbers.
+func luhnValid(s string) bool {
+	sum, digits := 0, 0
+	double := false
+	for i := len(s) - 1; i >= 0; i-- {
+		c := s[i]
+		if c < '0' || c > '9' {
+			continue
+		}
+		d := int(c - '0')
+		if double {
+			d *= 2
+			if d > 9 {
+				d -= 9
+			}
+		}
+		sum += d
+		digits++
+		double = !double
+	}
+	return digits >= 13 && sum%10 == 0
+}
@@ -0,0 +1,108 @@
+package pipeline
+
+import (
+	"strings"
+	"testing"
+
+	"log-beacon/internal/model"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRedactor_BuiltInPatterns(t *testing.T) {
+	r, err := NewRedactor(DefaultRedactConfig())
+	require.NoError(t, err)
+
+	tests := []struct {
+		name    string
+		message string
+		want    string
+	}{
+		{"email", "signup from jane.doe@example.com done", "signup from [REDACTED:email] done"},
+		{"valid card", "charged 4111 1111 1111 1111 ok", "charged [REDACTED:credit_card] ok"},
+		{"invalid luhn is kept", "order 1234567812345678 shipped", "order 1234567812345678 shipped"},
+		{"bearer token", "Authorization: Bearer abc.def-123", "Authorization: Bearer [REDACTED:bearer_token]"},
+		{"password", `login password="hunter2" user=bob`, `login password=[REDACTED:password] user=bob`},
+		{"nothing sensitive", "all good", "all good"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			logEntry := model.Log{Message: tt.message}
+			require.NoError(t, r.Process(&logEntry))
+			assert.Equal(t, tt.want, logEntry.Message)
+		})
+	}
+}
+
+func TestRedactor_FieldActionsAndCount(t *testing.T) {
+	cfg := DefaultRedactConfig()
+	cfg.Fields = map[string]string{
+		MessageField: RedactDrop,
+		"user":       RedactHash,
+		"contact":    RedactDrop,
+	}
+	cfg.Patterns = []RedactPattern{{Name: "ssn", Pattern: `\b\d{3}-\d{2}-\d{4}\b`}}
+	r, err := NewRedactor(cfg)
+	require.NoError(t, err)
+
+	logEntry := model.Log{
+		Message: "ssn 123-45-6789 on file",
+		Labels: map[string]string{
+			"user":        "bob@example.com",
+			"contact":     "alice@example.com",
+			"db_password": "s3cret",
+			"service":     "billing",
+		},
+	}
+	require.NoError(t, r.Process(&logEntry))
+
+	assert.Equal(t, "ssn  on file", logEntry.Message)
+	assert.True(t, strings.HasPrefix(logEntry.Labels["user"], "[HASH:"))
+	assert.NotContains(t, logEntry.Labels, "contact")
+	assert.Equal(t, "[REDACTED:sensitive_key]", logEntry.Labels["db_password"])
+	assert.Equal(t, "billing", logEntry.Labels["service"])
+	assert.Equal(t, "4", logEntry.Labels["redacted_count"])
+
+	counters := map[string]uint64{}
+	for _, s := range r.Stats() {
+		counters[s.Rule] = s.Counters["redactions"]
+	}
+	assert.Equal(t, uint64(2), counters["email"])
+	assert.Equal(t, uint64(1), counters["ssn"])
+	assert.Equal(t, uint64(1), counters["sensitive_keys"])
+}
+
+func TestRedactor_HashIsStable(t *testing.T) {
+	cfg := DefaultRedactConfig()
+	cfg.DefaultAction = RedactHash
+	cfg.HashSalt = "pepper"
+	r, err := NewRedactor(cfg)
+	require.NoError(t, err)
+
+	first := model.Log{Message: "mail bob@example.com"}
+	second := model.Log{Message: "mail bob@example.com"}
+	require.NoError(t, r.Process(&first))
+	require.NoError(t, r.Process(&second))
+
+	assert.Equal(t, first.Message, second.Message)
+	assert.NotContains(t, first.Message, "bob@example.com")
+}
+
+func TestNewRedactor_InvalidConfig(t *testing.T) {
+	cfg := DefaultRedactConfig()
+	cfg.DefaultAction = "shred"
+	_, err := NewRedactor(cfg)
+	assert.Error(t, err)
+
+	cfg = DefaultRedactConfig()
+	cfg.BuiltIns = []string{"ssn"}
+	_, err = NewRedactor(cfg)
+	assert.Error(t, err)
+
+	cfg = DefaultRedactConfig()
+	cfg.Patterns = []RedactPattern{{Name: "bad", Pattern: "("}}
+	_, err = NewRedactor(cfg)
+	assert.Error(t, err)
+}
@@ -0,0 +1,219 @@
+package pipeline
+
+import (
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"sync"
+	"sync/atomic"
+
+	"log-beacon/internal/model"
+
+	"golang.org/x/time/rate"
+)
+
+// ErrDropped is returned when a log entry is intentionally discarded by a sampling rule.
+var ErrDropped = errors.New("log entry dropped by sampling rule")
+
+// Sampling actions.
+const (
+	SampleKeep      = "keep"
+	SampleDrop      = "drop"
+	SampleRandom    = "sample"
+	SampleRateLimit = "rate_limit"
+)
+
+// SampleConfig holds the sampling rules. The first matching rule decides
+// the fate of an entry; entries matching no rule are kept.
+type SampleConfig struct {
+	Rules []SampleRule `json:"rules"`
+}
+
+// SampleRule matches entries by level and labels and keeps, drops, samples
+// or rate limits them.
+type SampleRule struct {
+	Name string `json:"name"`
+	// Levels matches any of the listed levels. Empty matches every level.
+	Levels []string `json:"levels,omitempty"`
+	// Labels must all be present with the given value; "*" matches any value.
+	Labels map[string]string `json:"labels,omitempty"`
+	Action string            `json:"action"`
+	// Rate is the fraction of matching entries kept by the "sample" action.
+	Rate float64 `json:"rate,omitempty"`
+	// PerSecond and Burst configure the "rate_limit" action.
+	PerSecond float64 `json:"per_second,omitempty"`
+	Burst     int     `json:"burst,omitempty"`
+}
+
+// Sampler drops or samples entries according to rules that can be replaced at runtime.
+type Sampler struct {
+	// levels maps the levels of rules onto the canonical levels of entries.
+	levels *Normalizer
+
+	mu    sync.RWMutex
+	rules []*compiledSampleRule
+	// random returns a number in [0, 1). It is replaced in tests.
+	random func() float64
+}
+
+type compiledSampleRule struct {
+	SampleRule
+	limiter *rate.Limiter
+
+	matched atomic.Uint64
+	kept    atomic.Uint64
+	dropped atomic.Uint64
+}
+
+// NewSampler validates the rules and creates a Sampler. Rule levels are
+// mapped onto canonical levels with the level aliases of normalizer, the
+// same way entry levels are; a nil normalizer uses the default aliases.
+func NewSampler(cfg SampleConfig, normalizer *Normalizer) (*Sampler, error) {
+	if normalizer == nil {
+		var err error
+		if normalizer, err = NewNormalizer(DefaultNormalizeConfig()); err != nil {
+			return nil, err
+		}
+	}
+	s := &Sampler{levels: normalizer, random: rand.Float64}
+	if err := s.SetRules(cfg.Rules); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// SetRules validates and atomically replaces the sampling rules. Rule
+// levels are stored in their canonical form; unknown levels are rejected.
+// Counters start from zero for the new rules.
+func (s *Sampler) SetRules(rules []SampleRule) error {
+	compiled := make([]*compiledSampleRule, 0, len(rules))
+	for i, rule := range rules {
+		if rule.Name == "" {
+			rule.Name = fmt.Sprintf("%s-%d", rule.Action, i)
+		}
+		levels := make([]string, 0, len(rule.Levels))
+		for _, level := range rule.Levels {
+			canonical, ok := s.levels.CanonicalLevel(level)
+			if !ok {
+				return fmt.Errorf("sampling rule %q: unknown level %q, expected one of %v or an alias", rule.Name, level, CanonicalLevels)
+			}
+			levels = append(levels, canonical)
+		}
+		if len(levels) > 0 {
+			rule.Levels = levels
+		}
+
+		c := &compiledSampleRule{SampleRule: rule}
+		switch rule.Action {
+		case SampleKeep, SampleDrop:
+		case SampleRandom:
+			if rule.Rate < 0 || rule.Rate > 1 {
+				return fmt.Errorf("sampling rule %q: rate must be between 0 and 1", rule.Name)
+			}
+		case SampleRateLimit:
+			if rule.PerSecond <= 0 {
+				return fmt.Errorf("sampling rule %q: per_second must be positive", rule.Name)
+			}
+			burst := rule.Burst
+			if burst < 1 {
+				burst = 1
+			}
+			c.limiter = rate.NewLimiter(rate.Limit(rule.PerSecond), burst)
+		default:
+			return fmt.Errorf("sampling rule %q: unknown action %q", rule.Name, rule.Action)
+		}
+		compiled = append(compiled, c)
+	}
+
+	s.mu.Lock()
+	s.rules = compiled
+	s.mu.Unlock()
+	return nil
+}
+
+// Rules returns the current sampling rules.
+func (s *Sampler) Rules() []SampleRule {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	rules := make([]SampleRule, 0, len(s.rules))
+	for _, rule := range s.rules {
+		rules = append(rules, rule.SampleRule)
+	}
+	return rules
+}
+
+// Process implements the Processor interface. It returns ErrDropped for
+// entries that should not be published.
+func (s *Sampler) Process(logEntry *model.Log) error {
+	s.mu.RLock()
+	rules := s.rules
+	s.mu.RUnlock()
+
+	for _, rule := range rules {
+		if !rule.matches(logEntry) {
+			continue
+		}
+		rule.matched.Add(1)
+
+		keep := true
+		switch rule.Action {
+		case SampleDrop:
+			keep = false
+		case SampleRandom:
+			keep = s.random() < rule.Rate
+		case SampleRateLimit:
+			keep = rule.limiter.Allow()
+		}
+
+		if !keep {
+			rule.dropped.Add(1)
+			return ErrDropped
+		}
+		rule.kept.Add(1)
+		return nil
+	}
+	return nil
+}
+
+// Stats implements the StatsReporter interface.
+func (s *Sampler) Stats() []RuleStats {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	stats := make([]RuleStats, 0, len(s.rules))
+	for _, rule := range s.rules {
+		stats = append(stats, RuleStats{
+			Stage: "sample",
+			Rule:  rule.Name,
+			Counters: map[string]uint64{
+				"matched": rule.matched.Load(),
+				"kept":    rule.kept.Load(),
+				"dropped": rule.dropped.Load(),
+			},
+		})
+	}
+	return stats
+}
+
+func (r *compiledSampleRule) matches(logEntry *model.Log) bool {
+	if len(r.Levels) > 0 {
+		found := false
+		for _, level := range r.Levels {
+			if level == logEntry.Level {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	for key, want := range r.Labels {
+		got, ok := logEntry.Labels[key]
+		if !ok || (want != "*" && got != want) {
+			return false
+		}
+	}
+	return true
+}
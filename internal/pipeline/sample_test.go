@@ -0,0 +1,110 @@
+package pipeline
+
+import (
+	"testing"
+
+	"log-beacon/internal/model"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSampler_Rules(t *testing.T) {
+	s, err := NewSampler(SampleConfig{Rules: []SampleRule{
+		{Name: "keep-errors", Levels: []string{"error", "fatal"}, Action: SampleKeep},
+		{Name: "drop-chatty-debug", Levels: []string{"debug"}, Labels: map[string]string{"service": "chatty"}, Action: SampleDrop},
+		{Name: "sample-health", Labels: map[string]string{"route": "*"}, Action: SampleRandom, Rate: 0.5},
+	}}, nil)
+	require.NoError(t, err)
+
+	// Deterministic "random" values: first 0.2 (kept), then 0.8 (dropped).
+	values := []float64{0.2, 0.8}
+	s.random = func() float64 {
+		v := values[0]
+		values = values[1:]
+		return v
+	}
+
+	tests := []struct {
+		name    string
+		entry   model.Log
+		dropped bool
+	}{
+		{"error from chatty is kept", model.Log{Level: "error", Labels: map[string]string{"service": "chatty"}}, false},
+		{"debug from chatty is dropped", model.Log{Level: "debug", Labels: map[string]string{"service": "chatty"}}, true},
+		{"debug from other service is kept", model.Log{Level: "debug", Labels: map[string]string{"service": "auth"}}, false},
+		{"sampled and kept", model.Log{Level: "info", Labels: map[string]string{"route": "/health"}}, false},
+		{"sampled and dropped", model.Log{Level: "info", Labels: map[string]string{"route": "/health"}}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := s.Process(&tt.entry)
+			if tt.dropped {
+				assert.ErrorIs(t, err, ErrDropped)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+
+	stats := s.Stats()
+	require.Len(t, stats, 3)
+	assert.Equal(t, map[string]uint64{"matched": 1, "kept": 1, "dropped": 0}, stats[0].Counters)
+	assert.Equal(t, map[string]uint64{"matched": 1, "kept": 0, "dropped": 1}, stats[1].Counters)
+	assert.Equal(t, map[string]uint64{"matched": 2, "kept": 1, "dropped": 1}, stats[2].Counters)
+}
+
+func TestSampler_RateLimit(t *testing.T) {
+	s, err := NewSampler(SampleConfig{Rules: []SampleRule{
+		{Name: "limit", Action: SampleRateLimit, PerSecond: 0.001, Burst: 2},
+	}}, nil)
+	require.NoError(t, err)
+
+	for i := 0; i < 2; i++ {
+		assert.NoError(t, s.Process(&model.Log{Level: "info"}))
+	}
+	assert.ErrorIs(t, s.Process(&model.Log{Level: "info"}), ErrDropped)
+}
+
+func TestSampler_SetRules(t *testing.T) {
+	s, err := NewSampler(SampleConfig{}, nil)
+	require.NoError(t, err)
+	assert.NoError(t, s.Process(&model.Log{Level: "debug"}))
+
+	require.NoError(t, s.SetRules([]SampleRule{{Levels: []string{"debug"}, Action: SampleDrop}}))
+	assert.ErrorIs(t, s.Process(&model.Log{Level: "debug"}), ErrDropped)
+	assert.Equal(t, "drop-0", s.Rules()[0].Name)
+
+	assert.Error(t, s.SetRules([]SampleRule{{Action: "explode"}}))
+	assert.Error(t, s.SetRules([]SampleRule{{Action: SampleRandom, Rate: 2}}))
+	assert.Error(t, s.SetRules([]SampleRule{{Action: SampleRateLimit}}))
+	assert.Error(t, s.SetRules([]SampleRule{{Levels: []string{"verbose"}, Action: SampleDrop}}))
+	// Invalid updates leave the previous rules in place.
+	assert.Len(t, s.Rules(), 1)
+}
+
+func TestSampler_LevelAliases(t *testing.T) {
+	normalizer, err := NewNormalizer(DefaultNormalizeConfig())
+	require.NoError(t, err)
+	s, err := NewSampler(SampleConfig{Rules: []SampleRule{
+		{Name: "drop-noise", Levels: []string{"DEBUG", "Trace", "warning"}, Action: SampleDrop},
+	}}, normalizer)
+	require.NoError(t, err)
+
+	// Rules hold the canonical levels that normalized entries carry.
+	assert.Equal(t, []string{"debug", "trace", "warn"}, s.Rules()[0].Levels)
+	assert.ErrorIs(t, s.Process(&model.Log{Level: "debug"}), ErrDropped)
+	assert.ErrorIs(t, s.Process(&model.Log{Level: "trace"}), ErrDropped)
+	assert.ErrorIs(t, s.Process(&model.Log{Level: "warn"}), ErrDropped)
+	assert.NoError(t, s.Process(&model.Log{Level: "info"}))
+
+	// Custom aliases of the normalizer apply to rules too.
+	cfg := DefaultNormalizeConfig()
+	cfg.LevelAliases["verbose"] = "debug"
+	normalizer, err = NewNormalizer(cfg)
+	require.NoError(t, err)
+	s, err = NewSampler(SampleConfig{Rules: []SampleRule{{Levels: []string{"Verbose"}, Action: SampleDrop}}}, normalizer)
+	require.NoError(t, err)
+	assert.ErrorIs(t, s.Process(&model.Log{Level: "debug"}), ErrDropped)
+}
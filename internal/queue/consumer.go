@@ -0,0 +1,57 @@
+package queue
+
+import (
+	"context"
+	"errors"
+
+	"github.com/nats-io/nats.go"
+)
+
+// SubscribeDurable subscribes handler to the durable push consumer of
+// stream as a member of its queue group, creating the consumer first if
+// needed. Unlike a consumer created by the subscription itself, the consumer
+// outlives Unsubscribe and Drain, so the service resumes where it stopped
+// and the stream keeps logs for it while it is down.
+func SubscribeDurable(js nats.JetStreamContext, stream, subject, durable string, handler nats.MsgHandler) (*nats.Subscription, error) {
+	_, err := js.ConsumerInfo(stream, durable)
+	if errors.Is(err, nats.ErrConsumerNotFound) {
+		_, err = js.AddConsumer(stream, &nats.ConsumerConfig{
+			Durable:        durable,
+			DeliverSubject: nats.NewInbox(),
+			DeliverGroup:   durable,
+			FilterSubject:  subject,
+			AckPolicy:      nats.AckExplicitPolicy,
+		})
+	}
+	if err != nil {
+		return nil, err
+	}
+	return js.QueueSubscribe(subject, durable, handler, nats.Bind(stream, durable), nats.ManualAck())
+}
+
+// Drain stops the subscriptions of nc from receiving messages, waits for
+// their handlers to finish with the messages already delivered, and closes
+// nc. If ctx expires first, nc is closed at once and ctx's error returned;
+// messages left unacknowledged are redelivered to the durable consumer.
+func Drain(ctx context.Context, nc *nats.Conn) error {
+	closed := make(chan struct{})
+	nc.SetClosedHandler(func(*nats.Conn) { close(closed) })
+	if err := nc.Drain(); err != nil {
+		nc.Close()
+		if errors.Is(err, nats.ErrConnectionClosed) {
+			return nil
+		}
+		return err
+	}
+
+	select {
+	case <-closed:
+		if err := nc.LastError(); errors.Is(err, nats.ErrDrainTimeout) {
+			return err
+		}
+		return nil
+	case <-ctx.Done():
+		nc.Close()
+		return ctx.Err()
+	}
+}
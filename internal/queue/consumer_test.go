@@ -0,0 +1,91 @@
+package queue
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/nats-io/nats.go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDrain_FinishesDeliveredMessages(t *testing.T) {
+	s, url := runTestServer(t)
+	defer s.Shutdown()
+	EnsureStream(url)
+
+	nc, err := nats.Connect(url)
+	require.NoError(t, err)
+	js, err := nc.JetStream()
+	require.NoError(t, err)
+
+	const total = 20
+	var handled atomic.Int32
+	received := make(chan struct{}, total)
+	_, err = SubscribeDurable(js, DefaultStream, DefaultSubject, "test-processor", func(msg *nats.Msg) {
+		received <- struct{}{}
+		// A slow handler must still finish once the drain has started.
+		time.Sleep(50 * time.Millisecond)
+		handled.Add(1)
+		msg.Ack()
+	})
+	require.NoError(t, err)
+
+	for i := 0; i < total; i++ {
+		_, err := js.Publish(DefaultSubject, []byte(`{"message":"drain"}`))
+		require.NoError(t, err)
+	}
+	select {
+	case <-received:
+	case <-time.After(2 * time.Second):
+		t.Fatal("no message delivered")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	require.NoError(t, Drain(ctx, nc))
+	assert.True(t, nc.IsClosed())
+
+	// The durable consumer survives the drain with every delivered message
+	// acknowledged, and keeps the rest for the next run.
+	check, err := nats.Connect(url)
+	require.NoError(t, err)
+	defer check.Close()
+	checkJS, err := check.JetStream()
+	require.NoError(t, err)
+	info, err := checkJS.ConsumerInfo(DefaultStream, "test-processor")
+	require.NoError(t, err)
+	assert.Zero(t, info.NumAckPending)
+	assert.Equal(t, uint64(total), uint64(handled.Load())+info.NumPending)
+}
+
+func TestDrain_Timeout(t *testing.T) {
+	s, url := runTestServer(t)
+	defer s.Shutdown()
+	EnsureStream(url)
+
+	nc, err := nats.Connect(url)
+	require.NoError(t, err)
+	js, err := nc.JetStream()
+	require.NoError(t, err)
+
+	release := make(chan struct{})
+	defer close(release)
+	received := make(chan struct{}, 1)
+	_, err = SubscribeDurable(js, DefaultStream, DefaultSubject, "test-processor", func(msg *nats.Msg) {
+		received <- struct{}{}
+		<-release
+	})
+	require.NoError(t, err)
+
+	_, err = js.Publish(DefaultSubject, []byte(`{"message":"stuck"}`))
+	require.NoError(t, err)
+	<-received
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	assert.ErrorIs(t, Drain(ctx, nc), context.DeadlineExceeded)
+	assert.True(t, nc.IsClosed())
+}
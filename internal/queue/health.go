@@ -0,0 +1,38 @@
+package queue
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/nats-io/nats.go"
+)
+
+// CheckStream verifies that nc is connected and that the stream exists.
+func CheckStream(ctx context.Context, nc *nats.Conn, js nats.JetStreamContext, stream string) error {
+	if status := nc.Status(); status != nats.CONNECTED {
+		return fmt.Errorf("nats connection is %s", status)
+	}
+	if _, err := js.StreamInfo(stream, nats.Context(ctx)); err != nil {
+		return fmt.Errorf("stream %s: %w", stream, err)
+	}
+	return nil
+}
+
+// CheckConsumer verifies that the durable consumer of sub still exists on
+// the stream.
+func CheckConsumer(ctx context.Context, sub *nats.Subscription) error {
+	if sub == nil || !sub.IsValid() {
+		return errors.New("not subscribed")
+	}
+	// ConsumerInfo takes no context; it is bounded by the JetStream timeout.
+	if _, err := sub.ConsumerInfo(); err != nil {
+		return fmt.Errorf("consumer: %w", err)
+	}
+	return nil
+}
+
+// Check verifies that the publisher can reach the stream.
+func (p *Publisher) Check(ctx context.Context) error {
+	return CheckStream(ctx, p.conn, p.js, p.stream)
+}
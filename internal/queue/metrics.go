@@ -0,0 +1,74 @@
+package queue
+
+import (
+	"errors"
+
+	"log-beacon/internal/metrics"
+
+	"github.com/nats-io/nats.go"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+var (
+	publishedLogs = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "beacon_published_logs_total",
+		Help: "Logs published to the stream.",
+	})
+	publishErrors = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "beacon_publish_errors_total",
+		Help: "Logs that could not be published to the stream.",
+	})
+	consumedMessages = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "beacon_consumer_messages_total",
+		Help: "Messages handled by the stream consumer, by result: ok, invalid or error.",
+	}, []string{"result"})
+	redeliveries = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "beacon_consumer_redeliveries_total",
+		Help: "Messages delivered to the stream consumer more than once.",
+	})
+)
+
+// Consumer message results.
+const (
+	ResultOK      = "ok"
+	ResultInvalid = "invalid"
+	ResultError   = "error"
+)
+
+// ObserveMessage records how a durable consumer handled a message, and
+// whether the message was a redelivery.
+func ObserveMessage(msg *nats.Msg, result string) {
+	consumedMessages.WithLabelValues(result).Inc()
+	if meta, err := msg.Metadata(); err == nil && meta.NumDelivered > 1 {
+		redeliveries.Inc()
+	}
+}
+
+// RegisterConsumerLag reports in r how far the consumer of sub is behind
+// the stream: the messages not yet delivered and those delivered but not
+// yet acknowledged. The consumer is queried on every scrape.
+func RegisterConsumerLag(r prometheus.Registerer, sub *nats.Subscription) {
+	info := func() (*nats.ConsumerInfo, error) {
+		if sub == nil {
+			return nil, errors.New("not subscribed")
+		}
+		return sub.ConsumerInfo()
+	}
+	r.MustRegister(
+		metrics.NewGaugeFunc("beacon_consumer_pending_messages", "Stream messages not yet delivered to the consumer.", func() (float64, error) {
+			ci, err := info()
+			if err != nil {
+				return 0, err
+			}
+			return float64(ci.NumPending), nil
+		}),
+		metrics.NewGaugeFunc("beacon_consumer_ack_pending_messages", "Messages delivered to the consumer but not yet acknowledged.", func() (float64, error) {
+			ci, err := info()
+			if err != nil {
+				return 0, err
+			}
+			return float64(ci.NumAckPending), nil
+		}),
+	)
+}
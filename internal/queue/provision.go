@@ -2,13 +2,58 @@ package queue
 
 import (
 	"log"
+	"time"
 
 	"github.com/nats-io/nats.go"
 )
 
+// Names of the log stream and of the subject logs are published on, unless
+// configured otherwise.
+const (
+	DefaultStream  = "LOGS"
+	DefaultSubject = "log.events"
+)
+
+// StreamOption customizes the stream configuration applied by EnsureStream.
+// Publishers and subscribers take the same options but only use the stream
+// name and subject.
+type StreamOption func(*nats.StreamConfig)
+
+// streamNames returns the stream name and subject selected by opts.
+func streamNames(opts []StreamOption) (stream, subject string) {
+	cfg := nats.StreamConfig{Name: DefaultStream, Subjects: []string{DefaultSubject}}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	return cfg.Name, cfg.Subjects[0]
+}
+
+// WithReplayWindow switches the stream from interest to time-based retention,
+// so that live tail clients can replay logs when they reconnect: each log is
+// kept for d after the stream stored it, whether or not it was consumed.
+// Logs a consumer has not processed within d are lost too, so the window must
+// outlast any consumer outage. Without it logs are removed as soon as every
+// consumer has acknowledged them.
+func WithReplayWindow(d time.Duration) StreamOption {
+	return func(cfg *nats.StreamConfig) {
+		if d > 0 {
+			cfg.Retention = nats.LimitsPolicy
+			cfg.MaxAge = d
+		}
+	}
+}
+
+// WithStreamName names the stream and the subject it stores.
+func WithStreamName(name, subject string) StreamOption {
+	return func(cfg *nats.StreamConfig) {
+		cfg.Name = name
+		cfg.Subjects = []string{subject}
+	}
+}
+
 // EnsureStream creates a NATS JetStream stream if it doesn't already exist.
 // This function is idempotent, meaning it can be safely run multiple times.
-func EnsureStream(natsURL string) {
+func EnsureStream(natsURL string, opts ...StreamOption) {
 	// Connect to the NATS server.
 	nc, err := nats.Connect(natsURL)
 	if err != nil {
@@ -25,19 +70,22 @@ func EnsureStream(natsURL string) {
 
 	// Define the stream configuration.
 	streamConfig := &nats.StreamConfig{
-		Name:      "LOGS",
-		Subjects:  []string{"log.events"},
+		Name:      DefaultStream,
+		Subjects:  []string{DefaultSubject},
 		Storage:   nats.FileStorage,     // Ensure persistence on disk
 		Retention: nats.InterestPolicy, // Messages are kept as long as there are consumers interested
 	}
+	for _, opt := range opts {
+		opt(streamConfig)
+	}
 
 	// Check if the stream already exists.
-	stream, err := js.StreamInfo("LOGS")
+	stream, err := js.StreamInfo(streamConfig.Name)
 	if err != nil {
 		// If the stream doesn't exist, js.StreamInfo returns an error.
 		// We can check for nats.ErrStreamNotFound, but for simplicity,
 		// we'll just try to create it.
-		log.Println("Stream 'LOGS' not found, creating it...")
+		log.Printf("Stream '%s' not found, creating it...", streamConfig.Name)
 	}
 
 	// If the stream is nil, it means it was not found and needs to be created
@@ -46,13 +94,13 @@ func EnsureStream(natsURL string) {
 		if err != nil {
 			log.Fatalf("Failed to add stream: %v", err)
 		}
-		log.Println("Stream 'LOGS' created.")
+		log.Printf("Stream '%s' created.", streamConfig.Name)
 	} else {
 		// If the stream exists, we update it to ensure the configuration is up to date.
 		_, err = js.UpdateStream(streamConfig)
 		if err != nil {
 			log.Fatalf("Failed to update stream: %v", err)
 		}
-		log.Println("Stream 'LOGS' already exists, configuration updated.")
+		log.Printf("Stream '%s' already exists, configuration updated.", streamConfig.Name)
 	}
 }
@@ -81,4 +81,30 @@ func TestEnsureStream_UpdatesStreamWhenExists(t *testing.T) {
 	assert.NotNil(t, stream)
 	assert.Contains(t, stream.Config.Subjects, "log.events", "Subjects should be updated")
 	assert.NotContains(t, stream.Config.Subjects, "old.subject", "Old subject should be removed")
-}
\ No newline at end of file
+}
+func TestEnsureStream_ReplayWindow(t *testing.T) {
+	s, url := runTestServerForProvision(t)
+	defer s.Shutdown()
+
+	// An existing interest-based stream is switched to time-based retention.
+	EnsureStream(url)
+	EnsureStream(url, WithReplayWindow(time.Hour))
+
+	nc, err := nats.Connect(url)
+	require.NoError(t, err)
+	defer nc.Close()
+	js, err := nc.JetStream()
+	require.NoError(t, err)
+
+	stream, err := js.StreamInfo("LOGS")
+	require.NoError(t, err)
+	assert.Equal(t, nats.LimitsPolicy, stream.Config.Retention)
+	assert.Equal(t, time.Hour, stream.Config.MaxAge)
+
+	// Without a window the stream returns to interest retention.
+	EnsureStream(url)
+	stream, err = js.StreamInfo("LOGS")
+	require.NoError(t, err)
+	assert.Equal(t, nats.InterestPolicy, stream.Config.Retention)
+	assert.Zero(t, stream.Config.MaxAge)
+}
@@ -2,7 +2,9 @@ package queue
 
 import (
 	"encoding/json"
+	"errors"
 	"log"
+	"time"
 
 	"log-beacon/internal/model"
 
@@ -11,12 +13,14 @@ import (
 
 // Publisher handles publishing messages to a NATS stream.
 type Publisher struct {
-	conn *nats.Conn
-	js   nats.JetStreamContext
+	conn    *nats.Conn
+	js      nats.JetStreamContext
+	stream  string
+	subject string
 }
 
 // NewPublisher creates and returns a new NATS publisher.
-func NewPublisher(natsURL string) (*Publisher, error) {
+func NewPublisher(natsURL string, opts ...StreamOption) (*Publisher, error) {
 	nc, err := nats.Connect(natsURL)
 	if err != nil {
 		return nil, err
@@ -28,7 +32,8 @@ func NewPublisher(natsURL string) (*Publisher, error) {
 	}
 
 	log.Println("Publisher successfully connected to NATS.")
-	return &Publisher{conn: nc, js: js}, nil
+	stream, subject := streamNames(opts)
+	return &Publisher{conn: nc, js: js, stream: stream, subject: subject}, nil
 }
 
 // Publish sends a log entry to the NATS stream.
@@ -39,16 +44,25 @@ func (p *Publisher) Publish(logEntry model.Log) error {
 		return err
 	}
 
-	// Publish the message to the "log.events" subject.
-	_, err = p.js.Publish("log.events", data)
+	// Publish the message to the stream's subject.
+	_, err = p.js.Publish(p.subject, data)
 	if err != nil {
+		publishErrors.Inc()
 		return err
 	}
 
+	publishedLogs.Inc()
 	return nil
 }
 
-// Close closes the NATS connection.
+// flushTimeout bounds how long Close waits for pending publishes.
+const flushTimeout = 5 * time.Second
+
+// Close flushes pending publishes to the server and closes the NATS
+// connection.
 func (p *Publisher) Close() {
+	if err := p.conn.FlushTimeout(flushTimeout); err != nil && !errors.Is(err, nats.ErrConnectionClosed) {
+		log.Printf("Failed to flush NATS publishes: %v", err)
+	}
 	p.conn.Close()
 }
@@ -1,6 +1,7 @@
 package queue
 
 import (
+	"context"
 	"encoding/json"
 	"log-beacon/internal/model"
 	"os"
@@ -100,4 +101,21 @@ func TestPublisher_Close(t *testing.T) {
 
 	publisher.Close()
 	assert.True(t, publisher.conn.IsClosed())
-}
\ No newline at end of file
+}
+
+func TestPublisher_Check(t *testing.T) {
+	s, url := runTestServer(t)
+	defer s.Shutdown()
+
+	pub, err := NewPublisher(url)
+	require.NoError(t, err)
+
+	// The stream does not exist yet.
+	assert.Error(t, pub.Check(context.Background()))
+
+	EnsureStream(url)
+	assert.NoError(t, pub.Check(context.Background()))
+
+	pub.Close()
+	assert.Error(t, pub.Check(context.Background()))
+}
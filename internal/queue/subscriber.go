@@ -5,6 +5,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"log"
+	"time"
 
 	"log-beacon/internal/model"
 
@@ -13,14 +14,15 @@ import (
 
 // Subscriber handles subscribing to the NATS stream for live logs.
 type Subscriber struct {
-	conn *nats.Conn
-	js   nats.JetStreamContext
+	conn    *nats.Conn
+	js      nats.JetStreamContext
+	subject string
 }
 
 // NewSubscriber creates and returns a new NATS subscriber.
 // It reuses the existing NATS connection if possible, but for now we'll take the URL.
 // Ideally, we should share the connection, but following the pattern in publisher.go.
-func NewSubscriber(natsURL string) (*Subscriber, error) {
+func NewSubscriber(natsURL string, opts ...StreamOption) (*Subscriber, error) {
 	nc, err := nats.Connect(natsURL)
 	if err != nil {
 		return nil, err
@@ -32,31 +34,43 @@ func NewSubscriber(natsURL string) (*Subscriber, error) {
 	}
 
 	log.Println("Subscriber successfully connected to NATS.")
-	return &Subscriber{conn: nc, js: js}, nil
+	_, subject := streamNames(opts)
+	return &Subscriber{conn: nc, js: js, subject: subject}, nil
 }
 
-// Subscribe returns a channel that streams new log entries.
-// It uses a JetStream consumer with DeliverNew policy to only receive new logs.
-func (s *Subscriber) Subscribe(ctx context.Context) (<-chan model.Log, error) {
-	logChan := make(chan model.Log, 100)
+// Subscribe returns a channel that streams log entries with their stream
+// sequence numbers. By default only new logs are delivered. A non-zero
+// startSeq replays from that sequence number, otherwise a non-zero startTime
+// replays from the first log stored at or after that time. Replay is limited
+// to what the stream still retains.
+func (s *Subscriber) Subscribe(ctx context.Context, startSeq uint64, startTime time.Time) (<-chan model.TailEvent, error) {
+	logChan := make(chan model.TailEvent, 100)
 
-	// Create a unique consumer name for this client to ensure they get their own copy of the stream
-	// or use an ephemeral consumer.
-	// For live tail, we want an ephemeral consumer that only gets new messages.
+	// Each client gets its own ephemeral consumer starting at the requested position.
+	deliver := nats.DeliverNew()
+	switch {
+	case startSeq > 0:
+		deliver = nats.StartSequence(startSeq)
+	case !startTime.IsZero():
+		deliver = nats.StartTime(startTime)
+	}
 
-	sub, err := s.js.Subscribe("log.events", func(msg *nats.Msg) {
-		var logEntry model.Log
-		if err := json.Unmarshal(msg.Data, &logEntry); err != nil {
+	sub, err := s.js.Subscribe(s.subject, func(msg *nats.Msg) {
+		var event model.TailEvent
+		if err := json.Unmarshal(msg.Data, &event.Log); err != nil {
 			log.Printf("Error unmarshalling log entry: %v", err)
 			return
 		}
+		if meta, err := msg.Metadata(); err == nil {
+			event.Seq = meta.Sequence.Stream
+		}
 
 		select {
-		case logChan <- logEntry:
+		case logChan <- event:
 		case <-ctx.Done():
 			// Context cancelled, stop sending
 		}
-	}, nats.DeliverNew())
+	}, deliver)
 
 	if err != nil {
 		return nil, fmt.Errorf("failed to subscribe to NATS: %w", err)
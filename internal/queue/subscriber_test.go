@@ -0,0 +1,91 @@
+package queue
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"log-beacon/internal/model"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func receive(t *testing.T, ch <-chan model.TailEvent) model.TailEvent {
+	t.Helper()
+	select {
+	case event := <-ch:
+		return event
+	case <-time.After(2 * time.Second):
+		t.Fatal("timed out waiting for a log")
+		return model.TailEvent{}
+	}
+}
+
+func TestSubscribe_Replay(t *testing.T) {
+	s, url := runTestServer(t)
+	defer s.Shutdown()
+
+	EnsureStream(url, WithReplayWindow(time.Hour))
+
+	publisher, err := NewPublisher(url)
+	require.NoError(t, err)
+	defer publisher.Close()
+	subscriber, err := NewSubscriber(url)
+	require.NoError(t, err)
+	defer subscriber.Close()
+
+	for _, msg := range []string{"one", "two", "three"} {
+		require.NoError(t, publisher.Publish(model.Log{Timestamp: time.Now(), Message: msg}))
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// Resuming after the first log replays the rest with their sequence numbers.
+	replay, err := subscriber.Subscribe(ctx, 2, time.Time{})
+	require.NoError(t, err)
+	event := receive(t, replay)
+	assert.Equal(t, uint64(2), event.Seq)
+	assert.Equal(t, "two", event.Message)
+	event = receive(t, replay)
+	assert.Equal(t, uint64(3), event.Seq)
+	assert.Equal(t, "three", event.Message)
+
+	// Replaying from a time before the first log returns everything.
+	fromTime, err := subscriber.Subscribe(ctx, 0, time.Now().Add(-time.Minute))
+	require.NoError(t, err)
+	assert.Equal(t, "one", receive(t, fromTime).Message)
+
+	// Without a start position only new logs are delivered.
+	live, err := subscriber.Subscribe(ctx, 0, time.Time{})
+	require.NoError(t, err)
+	require.NoError(t, publisher.Publish(model.Log{Timestamp: time.Now(), Message: "four"}))
+	event = receive(t, live)
+	assert.Equal(t, uint64(4), event.Seq)
+	assert.Equal(t, "four", event.Message)
+}
+
+func TestSubscribe_StreamName(t *testing.T) {
+	s, url := runTestServer(t)
+	defer s.Shutdown()
+
+	names := WithStreamName("AUDIT", "audit.events")
+	EnsureStream(url, names, WithReplayWindow(time.Hour))
+
+	publisher, err := NewPublisher(url, names)
+	require.NoError(t, err)
+	defer publisher.Close()
+	subscriber, err := NewSubscriber(url, names)
+	require.NoError(t, err)
+	defer subscriber.Close()
+
+	require.NoError(t, publisher.Check(context.Background()))
+	require.NoError(t, publisher.Publish(model.Log{Timestamp: time.Now(), Message: "audited"}))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	events, err := subscriber.Subscribe(ctx, 1, time.Time{})
+	require.NoError(t, err)
+	assert.Equal(t, "audited", receive(t, events).Message)
+}
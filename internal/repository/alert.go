@@ -0,0 +1,249 @@
+package repository
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+
+	"log-beacon/internal/alert"
+)
+
+// AlertRepository stores alert rules, silences, alerts and their notification
+// deliveries in Postgres. It implements alert.Store.
+type AlertRepository struct {
+	db *sql.DB
+}
+
+// NewAlertRepository creates a new AlertRepository on a pool opened with Open.
+func NewAlertRepository(db *sql.DB) *AlertRepository {
+	return &AlertRepository{db: db}
+}
+
+const ruleColumns = `id, name, query, threshold, window_seconds, group_by, enabled, created_at, updated_at`
+
+func scanRule(row interface{ Scan(...interface{}) error }) (alert.Rule, error) {
+	var rule alert.Rule
+	var windowSeconds int64
+	err := row.Scan(&rule.ID, &rule.Name, &rule.Query, &rule.Threshold, &windowSeconds, &rule.GroupBy, &rule.Enabled, &rule.CreatedAt, &rule.UpdatedAt)
+	rule.Window = alert.Duration(time.Duration(windowSeconds) * time.Second)
+	return rule, err
+}
+
+// ListRules returns every alert rule.
+func (r *AlertRepository) ListRules() ([]alert.Rule, error) {
+	rows, err := r.db.Query(`SELECT ` + ruleColumns + ` FROM alert_rules ORDER BY id`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	rules := []alert.Rule{}
+	for rows.Next() {
+		rule, err := scanRule(rows)
+		if err != nil {
+			return nil, err
+		}
+		rules = append(rules, rule)
+	}
+	return rules, rows.Err()
+}
+
+// GetRule retrieves an alert rule by its ID.
+func (r *AlertRepository) GetRule(id int64) (*alert.Rule, error) {
+	rule, err := scanRule(r.db.QueryRow(`SELECT `+ruleColumns+` FROM alert_rules WHERE id = $1`, id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, alert.ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &rule, nil
+}
+
+// CreateRule inserts an alert rule, setting its ID and timestamps.
+func (r *AlertRepository) CreateRule(rule *alert.Rule) error {
+	query := `INSERT INTO alert_rules (name, query, threshold, window_seconds, group_by, enabled)
+		VALUES ($1, $2, $3, $4, $5, $6) RETURNING id, created_at, updated_at`
+	return r.db.QueryRow(query, rule.Name, rule.Query, rule.Threshold, windowSeconds(rule.Window), rule.GroupBy, rule.Enabled).
+		Scan(&rule.ID, &rule.CreatedAt, &rule.UpdatedAt)
+}
+
+// UpdateRule replaces an alert rule, updating its timestamps.
+func (r *AlertRepository) UpdateRule(rule *alert.Rule) error {
+	query := `UPDATE alert_rules SET name = $2, query = $3, threshold = $4, window_seconds = $5, group_by = $6, enabled = $7,
+		updated_at = CURRENT_TIMESTAMP WHERE id = $1 RETURNING created_at, updated_at`
+	err := r.db.QueryRow(query, rule.ID, rule.Name, rule.Query, rule.Threshold, windowSeconds(rule.Window), rule.GroupBy, rule.Enabled).
+		Scan(&rule.CreatedAt, &rule.UpdatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return alert.ErrNotFound
+	}
+	return err
+}
+
+// DeleteRule deletes an alert rule.
+func (r *AlertRepository) DeleteRule(id int64) error {
+	return r.deleteByID(`DELETE FROM alert_rules WHERE id = $1`, id)
+}
+
+// ListSilences returns every silence.
+func (r *AlertRepository) ListSilences() ([]alert.Silence, error) {
+	rows, err := r.db.Query(`SELECT id, rule_id, group_value, comment, created_by, starts_at, ends_at FROM alert_silences ORDER BY id`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	silences := []alert.Silence{}
+	for rows.Next() {
+		var s alert.Silence
+		if err := rows.Scan(&s.ID, &s.RuleID, &s.Group, &s.Comment, &s.CreatedBy, &s.StartsAt, &s.EndsAt); err != nil {
+			return nil, err
+		}
+		silences = append(silences, s)
+	}
+	return silences, rows.Err()
+}
+
+// CreateSilence inserts a silence, setting its ID.
+func (r *AlertRepository) CreateSilence(s *alert.Silence) error {
+	query := `INSERT INTO alert_silences (rule_id, group_value, comment, created_by, starts_at, ends_at)
+		VALUES ($1, $2, $3, $4, $5, $6) RETURNING id`
+	return r.db.QueryRow(query, s.RuleID, s.Group, s.Comment, s.CreatedBy, s.StartsAt, s.EndsAt).Scan(&s.ID)
+}
+
+// DeleteSilence deletes a silence.
+func (r *AlertRepository) DeleteSilence(id int64) error {
+	return r.deleteByID(`DELETE FROM alert_silences WHERE id = $1`, id)
+}
+
+// SaveAlert inserts an alert with a zero ID, setting its ID, or updates it.
+// A new firing alert is merged into the firing alert of its rule and group,
+// and only firing alerts are updated.
+func (r *AlertRepository) SaveAlert(a *alert.Alert) error {
+	if a.ID == 0 {
+		query := `INSERT INTO alerts (rule_id, rule_name, group_value, state, count, threshold, silenced, starts_at, resolved_at)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+			ON CONFLICT (rule_id, group_value) WHERE state = 'firing' DO UPDATE
+			SET rule_name = EXCLUDED.rule_name, count = EXCLUDED.count, threshold = EXCLUDED.threshold, silenced = EXCLUDED.silenced
+			RETURNING id, starts_at`
+		return r.db.QueryRow(query, a.RuleID, a.RuleName, a.Group, a.State, a.Count, a.Threshold, a.Silenced, a.StartsAt, a.ResolvedAt).Scan(&a.ID, &a.StartsAt)
+	}
+	query := `UPDATE alerts SET rule_name = $2, state = $3, count = $4, threshold = $5, silenced = $6, resolved_at = $7
+		WHERE id = $1 AND state = 'firing'`
+	result, err := r.db.Exec(query, a.ID, a.RuleName, a.State, a.Count, a.Threshold, a.Silenced, a.ResolvedAt)
+	if err != nil {
+		return err
+	}
+	return expectOneRow(result)
+}
+
+// ClaimNotification records that the alert's state is being announced and
+// reports whether no instance announced it before.
+func (r *AlertRepository) ClaimNotification(a *alert.Alert) (bool, error) {
+	before := ""
+	if a.State == alert.StateResolved {
+		before = alert.StateFiring
+	}
+	result, err := r.db.Exec(`UPDATE alerts SET notified = $2 WHERE id = $1 AND notified = $3`, a.ID, a.State, before)
+	if err != nil {
+		return false, err
+	}
+	n, err := result.RowsAffected()
+	return n == 1, err
+}
+
+// ReleaseNotification undoes ClaimNotification after a failed announcement.
+func (r *AlertRepository) ReleaseNotification(a *alert.Alert) error {
+	before := ""
+	if a.State == alert.StateResolved {
+		before = alert.StateFiring
+	}
+	_, err := r.db.Exec(`UPDATE alerts SET notified = $3 WHERE id = $1 AND notified = $2`, a.ID, a.State, before)
+	return err
+}
+
+// ListAlerts returns the most recent alerts first, optionally only those in
+// the given state. A limit of zero returns every alert.
+func (r *AlertRepository) ListAlerts(state string, limit int) ([]alert.Alert, error) {
+	query := `SELECT id, rule_id, rule_name, group_value, state, count, threshold, silenced, starts_at, resolved_at
+		FROM alerts WHERE ($1 = '' OR state = $1) ORDER BY id DESC`
+	args := []interface{}{state}
+	if limit > 0 {
+		query += ` LIMIT $2`
+		args = append(args, limit)
+	}
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	alerts := []alert.Alert{}
+	for rows.Next() {
+		var a alert.Alert
+		if err := rows.Scan(&a.ID, &a.RuleID, &a.RuleName, &a.Group, &a.State, &a.Count, &a.Threshold, &a.Silenced, &a.StartsAt, &a.ResolvedAt); err != nil {
+			return nil, err
+		}
+		alerts = append(alerts, a)
+	}
+	return alerts, rows.Err()
+}
+
+// SaveDelivery records a notification delivery, setting its ID.
+func (r *AlertRepository) SaveDelivery(d *alert.Delivery) error {
+	query := `INSERT INTO alert_deliveries (alert_id, rule_name, alert_state, channel, status, attempts, error, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8) RETURNING id`
+	return r.db.QueryRow(query, d.AlertID, d.RuleName, d.AlertState, d.Channel, d.Status, d.Attempts, d.Error, d.CreatedAt).Scan(&d.ID)
+}
+
+// ListDeliveries returns the most recent deliveries first, optionally only
+// those of one alert. A limit of zero returns every delivery.
+func (r *AlertRepository) ListDeliveries(alertID int64, limit int) ([]alert.Delivery, error) {
+	query := `SELECT id, alert_id, rule_name, alert_state, channel, status, attempts, error, created_at
+		FROM alert_deliveries WHERE ($1 = 0 OR alert_id = $1) ORDER BY id DESC`
+	args := []interface{}{alertID}
+	if limit > 0 {
+		query += ` LIMIT $2`
+		args = append(args, limit)
+	}
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	deliveries := []alert.Delivery{}
+	for rows.Next() {
+		var d alert.Delivery
+		if err := rows.Scan(&d.ID, &d.AlertID, &d.RuleName, &d.AlertState, &d.Channel, &d.Status, &d.Attempts, &d.Error, &d.CreatedAt); err != nil {
+			return nil, err
+		}
+		deliveries = append(deliveries, d)
+	}
+	return deliveries, rows.Err()
+}
+
+// deleteByID runs a delete statement, returning alert.ErrNotFound if no row matched.
+func (r *AlertRepository) deleteByID(query string, id int64) error {
+	res, err := r.db.Exec(query, id)
+	if err != nil {
+		return err
+	}
+	return expectOneRow(res)
+}
+
+// expectOneRow returns alert.ErrNotFound if a statement matched no row.
+func expectOneRow(res sql.Result) error {
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return alert.ErrNotFound
+	}
+	return nil
+}
+
+func windowSeconds(d alert.Duration) int64 {
+	return int64(time.Duration(d) / time.Second)
+}
@@ -0,0 +1,31 @@
+// Package repository stores users, alerting state and saved searches in
+// Postgres. The repositories share one connection pool, opened with Open;
+// the schema is created by db/init.sql.
+package repository
+
+import (
+	"database/sql"
+	"fmt"
+	"log"
+
+	_ "github.com/lib/pq"
+)
+
+// Open connects to the Postgres database at dbURL and verifies the
+// connection. The caller closes the returned pool once the repositories
+// built on it are no longer used.
+func Open(dbURL string) (*sql.DB, error) {
+	db, err := sql.Open("postgres", dbURL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open database: %w", err)
+	}
+
+	// Verify the connection
+	if err := db.Ping(); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to ping database: %w", err)
+	}
+
+	log.Println("Successfully connected to Postgres")
+	return db, nil
+}
@@ -0,0 +1,150 @@
+package repository
+
+import (
+	"crypto/rand"
+	"database/sql"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// Saved search visibilities.
+const (
+	VisibilityPrivate = "private"
+	VisibilityShared  = "shared"
+)
+
+// ErrSavedSearchNotFound is returned for unknown saved search IDs.
+var ErrSavedSearchNotFound = errors.New("saved search not found")
+
+// SavedSearch is a named query with its time range and columns. Its ID is
+// random, so it can be shared in links without revealing other searches.
+type SavedSearch struct {
+	ID    string `json:"id"`
+	Name  string `json:"name"`
+	Query string `json:"query"`
+	// From and To bound the time range as RFC 3339 timestamps or as durations
+	// before the time the search runs, e.g. "15m". Empty values are open.
+	From string `json:"from"`
+	To   string `json:"to"`
+	// Columns are the labels shown next to the message.
+	Columns []string `json:"columns"`
+	Owner   string   `json:"owner"`
+	// Visibility is private, visible to the owner only, or shared with every user.
+	Visibility string    `json:"visibility"`
+	CreatedAt  time.Time `json:"created_at"`
+	UpdatedAt  time.Time `json:"updated_at"`
+}
+
+// SavedSearchRepository stores saved searches in Postgres.
+type SavedSearchRepository struct {
+	db *sql.DB
+}
+
+// NewSavedSearchRepository creates a new SavedSearchRepository on a pool
+// opened with Open.
+func NewSavedSearchRepository(db *sql.DB) *SavedSearchRepository {
+	return &SavedSearchRepository{db: db}
+}
+
+const savedSearchColumns = `id, name, query, time_from, time_to, columns, owner, visibility, created_at, updated_at`
+
+func scanSavedSearch(row interface{ Scan(...interface{}) error }) (SavedSearch, error) {
+	var s SavedSearch
+	err := row.Scan(&s.ID, &s.Name, &s.Query, &s.From, &s.To, pq.Array(&s.Columns), &s.Owner, &s.Visibility, &s.CreatedAt, &s.UpdatedAt)
+	if s.Columns == nil {
+		s.Columns = []string{}
+	}
+	return s, err
+}
+
+// ListSavedSearches returns the searches owned by a user and those shared
+// by others, by name.
+func (r *SavedSearchRepository) ListSavedSearches(username string) ([]SavedSearch, error) {
+	query := `SELECT ` + savedSearchColumns + ` FROM saved_searches WHERE owner = $1 OR visibility = $2 ORDER BY name, id`
+	rows, err := r.db.Query(query, username, VisibilityShared)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	searches := []SavedSearch{}
+	for rows.Next() {
+		s, err := scanSavedSearch(rows)
+		if err != nil {
+			return nil, err
+		}
+		searches = append(searches, s)
+	}
+	return searches, rows.Err()
+}
+
+// GetSavedSearch retrieves a saved search by its ID.
+func (r *SavedSearchRepository) GetSavedSearch(id string) (*SavedSearch, error) {
+	s, err := scanSavedSearch(r.db.QueryRow(`SELECT `+savedSearchColumns+` FROM saved_searches WHERE id = $1`, id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrSavedSearchNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &s, nil
+}
+
+// CreateSavedSearch inserts a saved search, setting its ID and timestamps.
+func (r *SavedSearchRepository) CreateSavedSearch(s *SavedSearch) error {
+	id, err := newSavedSearchID()
+	if err != nil {
+		return err
+	}
+	query := `INSERT INTO saved_searches (id, name, query, time_from, time_to, columns, owner, visibility)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8) RETURNING created_at, updated_at`
+	err = r.db.QueryRow(query, id, s.Name, s.Query, s.From, s.To, pq.Array(s.Columns), s.Owner, s.Visibility).
+		Scan(&s.CreatedAt, &s.UpdatedAt)
+	if err != nil {
+		return err
+	}
+	s.ID = id
+	return nil
+}
+
+// UpdateSavedSearch replaces the name, query, time range, columns and
+// visibility of a saved search, updating its timestamps. The owner is kept.
+func (r *SavedSearchRepository) UpdateSavedSearch(s *SavedSearch) error {
+	query := `UPDATE saved_searches SET name = $2, query = $3, time_from = $4, time_to = $5, columns = $6, visibility = $7,
+		updated_at = CURRENT_TIMESTAMP WHERE id = $1 RETURNING owner, created_at, updated_at`
+	err := r.db.QueryRow(query, s.ID, s.Name, s.Query, s.From, s.To, pq.Array(s.Columns), s.Visibility).
+		Scan(&s.Owner, &s.CreatedAt, &s.UpdatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrSavedSearchNotFound
+	}
+	return err
+}
+
+// DeleteSavedSearch deletes a saved search.
+func (r *SavedSearchRepository) DeleteSavedSearch(id string) error {
+	res, err := r.db.Exec(`DELETE FROM saved_searches WHERE id = $1`, id)
+	if err != nil {
+		return err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return ErrSavedSearchNotFound
+	}
+	return nil
+}
+
+// newSavedSearchID returns a random, URL-safe saved search ID.
+func newSavedSearchID() (string, error) {
+	b := make([]byte, 9)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate saved search ID: %w", err)
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
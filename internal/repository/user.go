@@ -1,11 +1,8 @@
 package repository
 
 import (
+	"context"
 	"database/sql"
-	"fmt"
-	"log"
-
-	_ "github.com/lib/pq"
 )
 
 // User represents the user schema in the database.
@@ -20,25 +17,14 @@ type UserRepository struct {
 	db *sql.DB
 }
 
-// NewUserRepository creates a new UserRepository.
-func NewUserRepository(dbURL string) (*UserRepository, error) {
-	db, err := sql.Open("postgres", dbURL)
-	if err != nil {
-		return nil, fmt.Errorf("failed to open database: %w", err)
-	}
-
-	// Verify the connection
-	if err := db.Ping(); err != nil {
-		return nil, fmt.Errorf("failed to ping database: %w", err)
-	}
-
-	log.Println("Successfully connected to Postgres for Authentication")
-	return &UserRepository{db: db}, nil
+// NewUserRepository creates a new UserRepository on a pool opened with Open.
+func NewUserRepository(db *sql.DB) *UserRepository {
+	return &UserRepository{db: db}
 }
 
-// Close closes the database connection.
-func (r *UserRepository) Close() error {
-	return r.db.Close()
+// Ping verifies that the database is reachable.
+func (r *UserRepository) Ping(ctx context.Context) error {
+	return r.db.PingContext(ctx)
 }
 
 // CreateUser inserts a new user into the database.
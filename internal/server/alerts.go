@@ -0,0 +1,296 @@
+package server
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"log-beacon/internal/alert"
+	"log-beacon/internal/notify"
+
+	"github.com/gin-gonic/gin"
+)
+
+// WithAlertStore enables the alerting API on the given store.
+func WithAlertStore(store alert.Store) Option {
+	return func(s *Server) {
+		s.alertStore = store
+	}
+}
+
+// WithAlertChannels enables listing and testing the notification channels
+// of the given dispatcher.
+func WithAlertChannels(d *notify.Dispatcher) Option {
+	return func(s *Server) {
+		s.alertChannels = d
+	}
+}
+
+// requireAlerts responds with 404 when alerting is not enabled.
+func (s *Server) requireAlerts(c *gin.Context) {
+	if s.alertStore == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Alerting is not enabled"})
+		c.Abort()
+		return
+	}
+	c.Next()
+}
+
+// handleListAlerts returns the most recent alerts, optionally filtered by
+// state=firing|resolved.
+func (s *Server) handleListAlerts(c *gin.Context) {
+	state := c.Query("state")
+	if state != "" && state != alert.StateFiring && state != alert.StateResolved {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Query parameter 'state' must be 'firing' or 'resolved'"})
+		return
+	}
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "100"))
+	if err != nil || limit < 1 || limit > 1000 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Query parameter 'limit' must be between 1 and 1000"})
+		return
+	}
+
+	alerts, err := s.alertStore.ListAlerts(state, limit)
+	if err != nil {
+		log.Printf("Error listing alerts: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list alerts"})
+		return
+	}
+	c.JSON(http.StatusOK, alerts)
+}
+
+// handleListAlertRules returns every alert rule.
+func (s *Server) handleListAlertRules(c *gin.Context) {
+	rules, err := s.alertStore.ListRules()
+	if err != nil {
+		log.Printf("Error listing alert rules: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list alert rules"})
+		return
+	}
+	c.JSON(http.StatusOK, rules)
+}
+
+// handleGetAlertRule returns one alert rule.
+func (s *Server) handleGetAlertRule(c *gin.Context) {
+	id, ok := parseID(c)
+	if !ok {
+		return
+	}
+	rule, err := s.alertStore.GetRule(id)
+	if err != nil {
+		respondAlertStoreError(c, "alert rule", err)
+		return
+	}
+	c.JSON(http.StatusOK, rule)
+}
+
+// handleCreateAlertRule creates an alert rule. Rules are enabled unless the
+// request says otherwise.
+func (s *Server) handleCreateAlertRule(c *gin.Context) {
+	rule := alert.Rule{Enabled: true}
+	if err := c.ShouldBindJSON(&rule); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if _, err := rule.Validate(); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := s.alertStore.CreateRule(&rule); err != nil {
+		log.Printf("Error creating alert rule: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create alert rule"})
+		return
+	}
+	log.Printf("Alert rule %q created by %s", rule.Name, c.GetString("username"))
+	c.JSON(http.StatusCreated, rule)
+}
+
+// handleUpdateAlertRule replaces an alert rule.
+func (s *Server) handleUpdateAlertRule(c *gin.Context) {
+	id, ok := parseID(c)
+	if !ok {
+		return
+	}
+	rule := alert.Rule{Enabled: true}
+	if err := c.ShouldBindJSON(&rule); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	rule.ID = id
+	if _, err := rule.Validate(); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := s.alertStore.UpdateRule(&rule); err != nil {
+		respondAlertStoreError(c, "alert rule", err)
+		return
+	}
+	log.Printf("Alert rule %q updated by %s", rule.Name, c.GetString("username"))
+	c.JSON(http.StatusOK, rule)
+}
+
+// handleDeleteAlertRule deletes an alert rule. Its firing alerts resolve at
+// the next evaluation.
+func (s *Server) handleDeleteAlertRule(c *gin.Context) {
+	id, ok := parseID(c)
+	if !ok {
+		return
+	}
+	if err := s.alertStore.DeleteRule(id); err != nil {
+		respondAlertStoreError(c, "alert rule", err)
+		return
+	}
+	log.Printf("Alert rule %d deleted by %s", id, c.GetString("username"))
+	c.Status(http.StatusNoContent)
+}
+
+// silenceRequest creates a silence. It lasts from StartsAt, by default now,
+// until EndsAt or for Duration.
+type silenceRequest struct {
+	RuleID   int64          `json:"rule_id"`
+	Group    string         `json:"group"`
+	Comment  string         `json:"comment"`
+	StartsAt time.Time      `json:"starts_at"`
+	EndsAt   time.Time      `json:"ends_at"`
+	Duration alert.Duration `json:"duration"`
+}
+
+// handleListSilences returns every silence, including expired ones.
+func (s *Server) handleListSilences(c *gin.Context) {
+	silences, err := s.alertStore.ListSilences()
+	if err != nil {
+		log.Printf("Error listing silences: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list silences"})
+		return
+	}
+	c.JSON(http.StatusOK, silences)
+}
+
+// handleCreateSilence creates a silence on behalf of the calling user.
+func (s *Server) handleCreateSilence(c *gin.Context) {
+	var req silenceRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	silence := alert.Silence{
+		RuleID:    req.RuleID,
+		Group:     req.Group,
+		Comment:   req.Comment,
+		CreatedBy: c.GetString("username"),
+		StartsAt:  req.StartsAt,
+		EndsAt:    req.EndsAt,
+	}
+	if silence.StartsAt.IsZero() {
+		silence.StartsAt = time.Now().UTC()
+	}
+	if silence.EndsAt.IsZero() && req.Duration > 0 {
+		silence.EndsAt = silence.StartsAt.Add(time.Duration(req.Duration))
+	}
+	if err := silence.Validate(); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := s.alertStore.CreateSilence(&silence); err != nil {
+		log.Printf("Error creating silence: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create silence"})
+		return
+	}
+	c.JSON(http.StatusCreated, silence)
+}
+
+// handleDeleteSilence deletes a silence, ending it early.
+func (s *Server) handleDeleteSilence(c *gin.Context) {
+	id, ok := parseID(c)
+	if !ok {
+		return
+	}
+	if err := s.alertStore.DeleteSilence(id); err != nil {
+		respondAlertStoreError(c, "silence", err)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// handleListAlertChannels returns the configured notification channels.
+func (s *Server) handleListAlertChannels(c *gin.Context) {
+	channels := []notify.ChannelInfo{}
+	if s.alertChannels != nil {
+		channels = s.alertChannels.Channels()
+	}
+	c.JSON(http.StatusOK, channels)
+}
+
+// handleTestAlertChannel sends a test notification through one channel and
+// reports whether it was delivered.
+func (s *Server) handleTestAlertChannel(c *gin.Context) {
+	name := c.Param("name")
+	err := notify.ErrUnknownChannel
+	if s.alertChannels != nil {
+		err = s.alertChannels.Test(c.Request.Context(), name)
+	}
+	if errors.Is(err, notify.ErrUnknownChannel) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Unknown notification channel"})
+		return
+	}
+	if err != nil {
+		log.Printf("Test notification through %s failed: %v", name, err)
+		c.JSON(http.StatusBadGateway, gin.H{"error": "Test notification failed: " + err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"status": "sent"})
+}
+
+// handleListDeliveries returns the most recent notification deliveries,
+// optionally only those of one alert.
+func (s *Server) handleListDeliveries(c *gin.Context) {
+	var alertID int64
+	if v := c.Query("alert_id"); v != "" {
+		id, err := strconv.ParseInt(v, 10, 64)
+		if err != nil || id < 1 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Query parameter 'alert_id' must be a positive integer"})
+			return
+		}
+		alertID = id
+	}
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "100"))
+	if err != nil || limit < 1 || limit > 1000 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Query parameter 'limit' must be between 1 and 1000"})
+		return
+	}
+
+	deliveries, err := s.alertStore.ListDeliveries(alertID, limit)
+	if err != nil {
+		log.Printf("Error listing notification deliveries: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list notification deliveries"})
+		return
+	}
+	c.JSON(http.StatusOK, deliveries)
+}
+
+// parseID reads the id path parameter. On invalid values it responds with
+// 400 and returns false.
+func parseID(c *gin.Context) (int64, bool) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil || id < 1 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID"})
+		return 0, false
+	}
+	return id, true
+}
+
+// respondAlertStoreError reports a store error about the named kind of object.
+func respondAlertStoreError(c *gin.Context, kind string, err error) {
+	if errors.Is(err, alert.ErrNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Unknown " + kind})
+		return
+	}
+	log.Printf("Error accessing %s: %v", kind, err)
+	c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to access " + kind})
+}
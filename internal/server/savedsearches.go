@@ -0,0 +1,228 @@
+package server
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"log-beacon/internal/repository"
+
+	"github.com/gin-gonic/gin"
+)
+
+// SavedSearchStore persists saved searches. It is implemented by
+// repository.SavedSearchRepository.
+type SavedSearchStore interface {
+	// ListSavedSearches returns the searches owned by username and those shared by others.
+	ListSavedSearches(username string) ([]repository.SavedSearch, error)
+	GetSavedSearch(id string) (*repository.SavedSearch, error)
+	CreateSavedSearch(search *repository.SavedSearch) error
+	UpdateSavedSearch(search *repository.SavedSearch) error
+	DeleteSavedSearch(id string) error
+}
+
+// WithSavedSearches enables the saved searches API on the given store.
+func WithSavedSearches(store SavedSearchStore) Option {
+	return func(s *Server) {
+		s.savedSearches = store
+	}
+}
+
+// requireSavedSearches responds with 404 when saved searches are not enabled.
+func (s *Server) requireSavedSearches(c *gin.Context) {
+	if s.savedSearches == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Saved searches are not enabled"})
+		c.Abort()
+		return
+	}
+	c.Next()
+}
+
+// savedSearchRequest creates or replaces a saved search. Searches are
+// private unless the request says otherwise.
+type savedSearchRequest struct {
+	Name       string   `json:"name"`
+	Query      string   `json:"query"`
+	From       string   `json:"from"`
+	To         string   `json:"to"`
+	Columns    []string `json:"columns"`
+	Visibility string   `json:"visibility"`
+}
+
+// savedSearch validates the request and returns the saved search it describes.
+func (r savedSearchRequest) savedSearch() (repository.SavedSearch, error) {
+	search := repository.SavedSearch{
+		Name:       strings.TrimSpace(r.Name),
+		Query:      strings.TrimSpace(r.Query),
+		From:       r.From,
+		To:         r.To,
+		Columns:    r.Columns,
+		Visibility: r.Visibility,
+	}
+	if search.Name == "" {
+		return search, errors.New("name is required")
+	}
+	if len(search.Name) > 255 {
+		return search, errors.New("name must be at most 255 characters")
+	}
+	if search.Query == "" {
+		return search, errors.New("query is required")
+	}
+	for _, v := range []string{search.From, search.To} {
+		if !validTimeBound(v) {
+			return search, errors.New("from and to must be RFC 3339 timestamps or durations such as 15m")
+		}
+	}
+	if search.Columns == nil {
+		search.Columns = []string{}
+	}
+	for _, col := range search.Columns {
+		if strings.TrimSpace(col) == "" {
+			return search, errors.New("columns must not be empty")
+		}
+	}
+	switch search.Visibility {
+	case "":
+		search.Visibility = repository.VisibilityPrivate
+	case repository.VisibilityPrivate, repository.VisibilityShared:
+	default:
+		return search, errors.New("visibility must be 'private' or 'shared'")
+	}
+	return search, nil
+}
+
+// validTimeBound reports whether v is empty, an RFC 3339 timestamp or a
+// positive duration.
+func validTimeBound(v string) bool {
+	if v == "" {
+		return true
+	}
+	if _, err := time.Parse(time.RFC3339Nano, v); err == nil {
+		return true
+	}
+	d, err := time.ParseDuration(v)
+	return err == nil && d > 0
+}
+
+// handleListSavedSearches returns the caller's saved searches and those
+// shared by other users.
+func (s *Server) handleListSavedSearches(c *gin.Context) {
+	searches, err := s.savedSearches.ListSavedSearches(c.GetString("username"))
+	if err != nil {
+		log.Printf("Error listing saved searches: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list saved searches"})
+		return
+	}
+	c.JSON(http.StatusOK, searches)
+}
+
+// handleGetSavedSearch returns a saved search that is shared or owned by the
+// caller, so that shared links can be opened by anyone signed in.
+func (s *Server) handleGetSavedSearch(c *gin.Context) {
+	search, ok := s.visibleSavedSearch(c)
+	if !ok {
+		return
+	}
+	c.JSON(http.StatusOK, search)
+}
+
+// handleCreateSavedSearch saves a search owned by the caller.
+func (s *Server) handleCreateSavedSearch(c *gin.Context) {
+	var req savedSearchRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	search, err := req.savedSearch()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	search.Owner = c.GetString("username")
+
+	if err := s.savedSearches.CreateSavedSearch(&search); err != nil {
+		log.Printf("Error creating saved search: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create saved search"})
+		return
+	}
+	c.JSON(http.StatusCreated, search)
+}
+
+// handleUpdateSavedSearch replaces one of the caller's saved searches.
+func (s *Server) handleUpdateSavedSearch(c *gin.Context) {
+	existing, ok := s.ownedSavedSearch(c)
+	if !ok {
+		return
+	}
+	var req savedSearchRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	search, err := req.savedSearch()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	search.ID = existing.ID
+
+	if err := s.savedSearches.UpdateSavedSearch(&search); err != nil {
+		respondSavedSearchError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, search)
+}
+
+// handleDeleteSavedSearch deletes one of the caller's saved searches.
+func (s *Server) handleDeleteSavedSearch(c *gin.Context) {
+	existing, ok := s.ownedSavedSearch(c)
+	if !ok {
+		return
+	}
+	if err := s.savedSearches.DeleteSavedSearch(existing.ID); err != nil {
+		respondSavedSearchError(c, err)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// visibleSavedSearch loads the saved search of the id path parameter. Other
+// users' private searches are reported as unknown. On failure it responds and
+// returns false.
+func (s *Server) visibleSavedSearch(c *gin.Context) (*repository.SavedSearch, bool) {
+	search, err := s.savedSearches.GetSavedSearch(c.Param("id"))
+	if err == nil && search.Owner != c.GetString("username") && search.Visibility != repository.VisibilityShared {
+		err = repository.ErrSavedSearchNotFound
+	}
+	if err != nil {
+		respondSavedSearchError(c, err)
+		return nil, false
+	}
+	return search, true
+}
+
+// ownedSavedSearch is like visibleSavedSearch, but responds with 403 for
+// searches shared by other users, which they alone may change.
+func (s *Server) ownedSavedSearch(c *gin.Context) (*repository.SavedSearch, bool) {
+	search, ok := s.visibleSavedSearch(c)
+	if !ok {
+		return nil, false
+	}
+	if search.Owner != c.GetString("username") {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Only the owner can change a saved search"})
+		return nil, false
+	}
+	return search, true
+}
+
+// respondSavedSearchError reports a saved search store error.
+func respondSavedSearchError(c *gin.Context, err error) {
+	if errors.Is(err, repository.ErrSavedSearchNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Unknown saved search"})
+		return
+	}
+	log.Printf("Error accessing saved search: %v", err)
+	c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to access saved search"})
+}
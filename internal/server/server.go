@@ -1,21 +1,43 @@
 package server
 
 import (
+	"bytes"
 	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
 	"io"
 	"log"
+	"net"
 	"net/http"
 	"net/url"
 	"path"
+	"strconv"
 	"strings"
+	"sync"
 	"time"
 
+	"log-beacon/internal/alert"
 	"log-beacon/internal/auth"
+	"log-beacon/internal/compress"
+	"log-beacon/internal/config"
+	"log-beacon/internal/elastic"
+	"log-beacon/internal/health"
+	"log-beacon/internal/logmetrics"
+	"log-beacon/internal/loki"
+	"log-beacon/internal/metrics"
 	"log-beacon/internal/model"
+	"log-beacon/internal/notify"
+	"log-beacon/internal/otlp"
+	"log-beacon/internal/pipeline"
 	"log-beacon/internal/repository"
+	"log-beacon/internal/tail"
 
 	"github.com/gin-gonic/gin"
 	"github.com/gorilla/websocket"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
 )
 
 // LogPublisher defines the interface for publishing log entries.
@@ -25,28 +47,143 @@ type LogPublisher interface {
 
 // LogSubscriber defines the interface for subscribing to log entries.
 type LogSubscriber interface {
-	Subscribe(ctx context.Context) (<-chan model.Log, error)
+	// Subscribe streams new logs, or replays from startSeq or startTime when set.
+	Subscribe(ctx context.Context, startSeq uint64, startTime time.Time) (<-chan model.TailEvent, error)
 }
 
 // Server holds dependencies for the HTTP server.
 type Server struct {
 	router        *gin.Engine
 	publisher     LogPublisher
-	subscriber    LogSubscriber
 	userRepo      *repository.UserRepository
 	hotStorageURL string
+	pipeline      *pipeline.Pipeline
+	elasticConfig elastic.Config
+	maxBodyBytes  int64
+	tailConfig    tail.Config
+	tailHub       *tail.Hub
+	alertStore    alert.Store
+	alertChannels *notify.Dispatcher
+	savedSearches SavedSearchStore
+	logMetrics    *prometheus.Registry
+	readiness     *health.Checker
+	bcryptCost    int
+
+	// mu guards httpSrv and shuttingDown. closing is closed when Shutdown
+	// starts, ending live tails; streams counts the tail handlers still
+	// running, which http.Server does not track once WebSockets are hijacked.
+	mu           sync.Mutex
+	httpSrv      *http.Server
+	shuttingDown bool
+	closing      chan struct{}
+	streams      sync.WaitGroup
+}
+
+// Ingest results.
+const (
+	ingestPublished = "published"
+	ingestDropped   = "dropped"
+	ingestRejected  = "rejected"
+	ingestFailed    = "failed"
+)
+
+var (
+	ingestedLogs = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "beacon_ingest_logs_total",
+		Help: "Logs received by the ingest endpoints, by result: published, dropped by sampling, rejected by the pipeline or failed to publish.",
+	}, []string{"result"})
+	ingestDuration = promauto.NewHistogram(prometheus.HistogramOpts{
+		Name: "beacon_ingest_duration_seconds",
+		Help: "Time to process and publish a log entry.",
+	})
+)
+
+// DefaultMaxBodyBytes bounds the decoded size of ingest requests.
+const DefaultMaxBodyBytes = config.DefaultMaxBodyBytes
+
+// Option configures optional Server dependencies.
+type Option func(*Server)
+
+// WithPipeline sets the processing pipeline run on every entry before it is published.
+func WithPipeline(p *pipeline.Pipeline) Option {
+	return func(s *Server) {
+		s.pipeline = p
+	}
+}
+
+// WithElasticConfig sets the document field mapping used by the Elasticsearch bulk endpoint.
+func WithElasticConfig(cfg elastic.Config) Option {
+	return func(s *Server) {
+		s.elasticConfig = cfg
+	}
+}
+
+// WithMaxBodyBytes sets the maximum decoded size of ingest request bodies.
+func WithMaxBodyBytes(n int64) Option {
+	return func(s *Server) {
+		s.maxBodyBytes = n
+	}
+}
+
+// WithTailConfig sets the per-client buffering of live tail clients.
+func WithTailConfig(cfg tail.Config) Option {
+	return func(s *Server) {
+		s.tailConfig = cfg
+	}
+}
+
+// WithLogMetrics adds the metrics derived from logs to /metrics.
+func WithLogMetrics(r *logmetrics.Registry) Option {
+	return func(s *Server) {
+		if r == nil {
+			return
+		}
+		s.logMetrics = prometheus.NewRegistry()
+		s.logMetrics.MustRegister(r)
+	}
+}
+
+// WithBcryptCost sets the bcrypt cost of the password hashes of new users.
+func WithBcryptCost(cost int) Option {
+	return func(s *Server) {
+		s.bcryptCost = cost
+	}
+}
+
+// WithReadiness sets the dependency checks reported at /readyz.
+func WithReadiness(c *health.Checker) Option {
+	return func(s *Server) {
+		s.readiness = c
+	}
 }
 
 // New creates a new HTTP server and sets up routing.
-func New(pub LogPublisher, sub LogSubscriber, userRepo *repository.UserRepository, hotStorageURL string) *Server {
+func New(pub LogPublisher, sub LogSubscriber, userRepo *repository.UserRepository, hotStorageURL string, opts ...Option) *Server {
 	router := gin.Default()
+	// Live tail connections last as long as the client stays, so their
+	// duration says nothing about latency.
+	router.Use(metrics.Middleware("/api/v1/tail", "/api/v1/tail/sse"))
 	s := &Server{
 		router:        router,
 		publisher:     pub,
-		subscriber:    sub,
 		userRepo:      userRepo,
 		hotStorageURL: hotStorageURL,
+		pipeline:      pipeline.New(),
+		elasticConfig: elastic.DefaultConfig(),
+		maxBodyBytes:  DefaultMaxBodyBytes,
+		tailConfig:    tail.DefaultConfig(),
+		readiness:     health.NewChecker(0),
+		bcryptCost:    auth.DefaultBcryptCost,
+		closing:       make(chan struct{}),
+	}
+	for _, opt := range opts {
+		opt(s)
 	}
+	// Every live tail client shares one subscription to the log stream.
+	s.tailHub = tail.NewHub(sub, s.tailConfig)
+
+	// Ingest routes accept compressed request bodies.
+	decompress := s.DecompressMiddleware()
 
 	// --- API Route Group ---
 	api := router.Group("/api/v1")
@@ -60,20 +197,84 @@ func New(pub LogPublisher, sub LogSubscriber, userRepo *repository.UserRepositor
 		}
 
 		// Public routes
-		api.POST("/ingest", s.handleIngest)
+		api.POST("/ingest", decompress, s.handleIngest)
+
+		// Server-Sent Events tail for clients behind proxies that break WebSockets.
+		// It only accepts header tokens, so tokens never appear in URLs.
+		api.GET("/tail/sse", s.HeaderAuthMiddleware(), s.handleTailSSE)
 
 		// Protected routes
 		protected := api.Group("")
 		protected.Use(s.AuthMiddleware())
 		{
 			protected.GET("/search", s.handleSearch)
+			protected.GET("/export", s.handleExport)
 			protected.GET("/tail", s.handleLiveTail)
+
+			alerts := protected.Group("/alerts", s.requireAlerts)
+			{
+				alerts.GET("", s.handleListAlerts)
+				alerts.GET("/rules", s.handleListAlertRules)
+				alerts.POST("/rules", s.handleCreateAlertRule)
+				alerts.GET("/rules/:id", s.handleGetAlertRule)
+				alerts.PUT("/rules/:id", s.handleUpdateAlertRule)
+				alerts.DELETE("/rules/:id", s.handleDeleteAlertRule)
+				alerts.GET("/silences", s.handleListSilences)
+				alerts.POST("/silences", s.handleCreateSilence)
+				alerts.DELETE("/silences/:id", s.handleDeleteSilence)
+				alerts.GET("/channels", s.handleListAlertChannels)
+				alerts.POST("/channels/:name/test", s.handleTestAlertChannel)
+				alerts.GET("/deliveries", s.handleListDeliveries)
+			}
+
+			saved := protected.Group("/saved-searches", s.requireSavedSearches)
+			{
+				saved.GET("", s.handleListSavedSearches)
+				saved.POST("", s.handleCreateSavedSearch)
+				saved.GET("/:id", s.handleGetSavedSearch)
+				saved.PUT("/:id", s.handleUpdateSavedSearch)
+				saved.DELETE("/:id", s.handleDeleteSavedSearch)
+			}
+
+			admin := protected.Group("/admin")
+			{
+				admin.GET("/pipeline/stats", s.handlePipelineStats)
+				admin.GET("/sampling/rules", s.handleGetSamplingRules)
+				admin.PUT("/sampling/rules", s.handleSetSamplingRules)
+			}
 		}
 	}
 
-	router.GET("/health", func(c *gin.Context) {
-		c.JSON(http.StatusOK, gin.H{"status": "ok"})
-	})
+	// OpenTelemetry collectors append /v1/logs to a configured endpoint of http://<api>/otlp.
+	router.POST("/otlp/v1/logs", decompress, otlp.NewReceiver(s.Ingest).HandleHTTP)
+
+	// Loki push API for Promtail, Grafana Agent and the Docker logging driver.
+	router.POST("/loki/api/v1/push", decompress, loki.NewReceiver(s.Ingest).HandlePush)
+
+	// Elasticsearch bulk API for Fluent Bit, Filebeat and Vector, configured with a host of http://<api>/es.
+	bulk := elastic.NewBulkHandler(s.Ingest, s.elasticConfig)
+	es := router.Group("/es")
+	{
+		es.GET("", bulk.HandleInfo)
+		es.HEAD("", bulk.HandleInfo)
+		es.POST("/_bulk", decompress, bulk.HandleBulk)
+		es.PUT("/_bulk", decompress, bulk.HandleBulk)
+		es.POST("/:index/_bulk", decompress, bulk.HandleBulk)
+		es.PUT("/:index/_bulk", decompress, bulk.HandleBulk)
+	}
+
+	// The service metrics and the metrics derived from logs.
+	gatherers := prometheus.Gatherers{prometheus.DefaultGatherer}
+	if s.logMetrics != nil {
+		gatherers = append(gatherers, s.logMetrics)
+	}
+	router.GET("/metrics", gin.WrapH(promhttp.HandlerFor(gatherers, promhttp.HandlerOpts{})))
+
+	// Liveness and readiness probes; /health predates them and stays an
+	// alias of /livez.
+	router.GET("/livez", gin.WrapF(health.Live))
+	router.GET("/readyz", gin.WrapH(s.readiness))
+	router.GET("/health", gin.WrapF(health.Live))
 
 	return s
 }
@@ -102,7 +303,7 @@ func (s *Server) handleRegister(c *gin.Context) {
 		return
 	}
 
-	hashedPassword, err := auth.HashPassword(req.Password)
+	hashedPassword, err := auth.HashPassword(req.Password, s.bcryptCost)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to process password"})
 		return
@@ -144,8 +345,53 @@ func (s *Server) handleLogin(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"token": token})
 }
 
+// DecompressMiddleware decodes request bodies sent with a Content-Encoding of
+// gzip, deflate, zstd or snappy, so handlers always read plain bodies. Bodies
+// larger than maxBodyBytes once decoded are rejected.
+func (s *Server) DecompressMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		encoding := c.GetHeader("Content-Encoding")
+		if encoding == "" {
+			c.Next()
+			return
+		}
+
+		body, err := compress.Decode(c.Request.Body, encoding, s.maxBodyBytes)
+		if err != nil {
+			switch {
+			case errors.Is(err, compress.ErrUnsupportedEncoding):
+				c.JSON(http.StatusUnsupportedMediaType, gin.H{"error": err.Error()})
+			case errors.Is(err, compress.ErrTooLarge):
+				c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": fmt.Sprintf("Request body exceeds %d bytes once decoded", s.maxBodyBytes)})
+			default:
+				c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			}
+			c.Abort()
+			return
+		}
+
+		c.Request.Body = io.NopCloser(bytes.NewReader(body))
+		c.Request.ContentLength = int64(len(body))
+		c.Request.Header.Del("Content-Encoding")
+		c.Request.Header.Set("Content-Length", strconv.Itoa(len(body)))
+		c.Next()
+	}
+}
+
 // AuthMiddleware validates the JWT token in the Authorization header.
+// Browsers cannot set headers on WebSocket requests, so the token may also be
+// passed as the token query parameter.
 func (s *Server) AuthMiddleware() gin.HandlerFunc {
+	return authMiddleware(true)
+}
+
+// HeaderAuthMiddleware validates the JWT token in the Authorization header
+// only, keeping tokens out of URLs and therefore out of access logs.
+func (s *Server) HeaderAuthMiddleware() gin.HandlerFunc {
+	return authMiddleware(false)
+}
+
+func authMiddleware(allowQueryToken bool) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		authHeader := c.GetHeader("Authorization")
 		tokenString := ""
@@ -158,7 +404,7 @@ func (s *Server) AuthMiddleware() gin.HandlerFunc {
 		}
 
 		// Fallback to query parameter for WebSockets or other cases
-		if tokenString == "" {
+		if tokenString == "" && allowQueryToken {
 			tokenString = c.Query("token")
 		}
 
@@ -181,31 +427,219 @@ func (s *Server) AuthMiddleware() gin.HandlerFunc {
 }
 
 // Start runs the HTTP server on a given address.
+// It returns nil once Shutdown is called.
 func (s *Server) Start(addr string) error {
-	return s.router.Run(addr)
+	l, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+	return s.Serve(l)
+}
+
+// Serve accepts HTTP connections on l until Shutdown is called, and then
+// returns nil.
+func (s *Server) Serve(l net.Listener) error {
+	s.mu.Lock()
+	if s.shuttingDown {
+		s.mu.Unlock()
+		l.Close()
+		return nil
+	}
+	s.httpSrv = &http.Server{Handler: s.router}
+	s.mu.Unlock()
+
+	if err := s.httpSrv.Serve(l); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
+
+// Shutdown stops accepting connections and ends every live tail, sending
+// WebSocket clients a "going away" close frame so that they reconnect
+// elsewhere. It then waits for in-flight requests and tail handlers to
+// finish. If ctx expires first, the remaining connections are closed and
+// ctx's error is returned.
+func (s *Server) Shutdown(ctx context.Context) error {
+	s.mu.Lock()
+	if !s.shuttingDown {
+		s.shuttingDown = true
+		close(s.closing)
+	}
+	httpSrv := s.httpSrv
+	s.mu.Unlock()
+
+	if httpSrv != nil {
+		if err := httpSrv.Shutdown(ctx); err != nil {
+			httpSrv.Close()
+			return err
+		}
+	}
+
+	done := make(chan struct{})
+	go func() {
+		s.streams.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// trackStream registers a live tail handler with Shutdown. It returns false
+// when the server is shutting down and the handler must not start.
+func (s *Server) trackStream() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.shuttingDown {
+		return false
+	}
+	s.streams.Add(1)
+	return true
 }
 
 // handleIngest processes incoming log entries and publishes them to NATS.
 func (s *Server) handleIngest(c *gin.Context) {
+	body, err := compress.ReadLimited(c.Request.Body, s.maxBodyBytes)
+	if errors.Is(err, compress.ErrTooLarge) {
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": fmt.Sprintf("Request body exceeds %d bytes", s.maxBodyBytes)})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// A JSON array is a batch of log entries, as sent by shippers.
+	if trimmed := bytes.TrimSpace(body); len(trimmed) > 0 && trimmed[0] == '[' {
+		s.handleIngestBatch(c, trimmed)
+		return
+	}
+
 	var logEntry model.Log
+	if err := json.Unmarshal(body, &logEntry); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := s.Ingest(logEntry); err != nil {
+		if errors.Is(err, pipeline.ErrDropped) {
+			c.JSON(http.StatusAccepted, gin.H{"status": "dropped"})
+			return
+		}
+		var rejection *pipeline.RejectionError
+		if errors.As(err, &rejection) {
+			c.JSON(http.StatusUnprocessableEntity, gin.H{"error": "Log entry rejected", "reasons": rejection.Reasons})
+			return
+		}
+		log.Printf("Error publishing log to NATS: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to process log"})
+		return
+	}
+
+	c.JSON(http.StatusAccepted, gin.H{"status": "accepted"})
+}
 
-	if err := c.ShouldBindJSON(&logEntry); err != nil {
+// batchRejection reports an entry of a batch refused by the ingest pipeline.
+type batchRejection struct {
+	Index   int      `json:"index"`
+	Reasons []string `json:"reasons"`
+}
+
+// handleIngestBatch ingests a JSON array of log entries. Entries rejected by
+// the pipeline are reported but do not fail the batch; a publish failure fails
+// the whole batch so that the client retries it.
+func (s *Server) handleIngestBatch(c *gin.Context, body []byte) {
+	var logEntries []model.Log
+	if err := json.Unmarshal(body, &logEntries); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
+	result, err := pipeline.IngestBatch(s.Ingest, logEntries)
+	if err != nil {
+		log.Printf("Error publishing log batch to NATS: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to process logs"})
+		return
+	}
+	rejected := make([]batchRejection, 0, len(result.Rejected))
+	for _, rejection := range result.Rejected {
+		rejected = append(rejected, batchRejection{Index: rejection.Index, Reasons: rejection.Err.Reasons})
+	}
+
+	c.JSON(http.StatusAccepted, gin.H{
+		"status":   "accepted",
+		"accepted": result.Accepted,
+		"dropped":  result.Dropped,
+		"rejected": rejected,
+	})
+}
+
+// Ingest runs a log entry through the processing pipeline and publishes it.
+// It is shared by every ingestion route and listener. Entries discarded by
+// sampling return pipeline.ErrDropped.
+func (s *Server) Ingest(logEntry model.Log) error {
 	// Ensure timestamp is set
 	if logEntry.Timestamp.IsZero() {
 		logEntry.Timestamp = time.Now().UTC()
 	}
 
+	start := time.Now()
+	if err := s.pipeline.Process(&logEntry); err != nil {
+		if errors.Is(err, pipeline.ErrDropped) {
+			ingestedLogs.WithLabelValues(ingestDropped).Inc()
+		} else {
+			ingestedLogs.WithLabelValues(ingestRejected).Inc()
+		}
+		return err
+	}
+
 	if err := s.publisher.Publish(logEntry); err != nil {
-		log.Printf("Error publishing log to NATS: %v", err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to process log"})
+		ingestedLogs.WithLabelValues(ingestFailed).Inc()
+		return err
+	}
+	ingestDuration.Observe(time.Since(start).Seconds())
+	ingestedLogs.WithLabelValues(ingestPublished).Inc()
+	return nil
+}
+
+// handlePipelineStats reports the per-rule statistics of the ingest pipeline.
+func (s *Server) handlePipelineStats(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"rules": s.pipeline.Stats()})
+}
+
+// handleGetSamplingRules returns the active sampling rules.
+func (s *Server) handleGetSamplingRules(c *gin.Context) {
+	sampler := s.pipeline.Sampler()
+	if sampler == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Sampling is not enabled"})
 		return
 	}
+	c.JSON(http.StatusOK, gin.H{"rules": sampler.Rules()})
+}
 
-	c.JSON(http.StatusAccepted, gin.H{"status": "accepted"})
+// handleSetSamplingRules replaces the sampling rules at runtime.
+func (s *Server) handleSetSamplingRules(c *gin.Context) {
+	sampler := s.pipeline.Sampler()
+	if sampler == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Sampling is not enabled"})
+		return
+	}
+
+	var req pipeline.SampleConfig
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := sampler.SetRules(req.Rules); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	log.Printf("Sampling rules updated by %s: %d rules", c.GetString("username"), len(req.Rules))
+	c.JSON(http.StatusOK, gin.H{"rules": sampler.Rules()})
 }
 
 // handleSearch proxies search requests to the hot-storage service.
@@ -216,32 +650,22 @@ func (s *Server) handleSearch(c *gin.Context) {
 		return
 	}
 
-	// Build the request to the hot-storage service, including pagination params.
-	// We use s.hotStorageURL which is injected (env var in main, mock URL in tests).
-	baseURLStr := s.hotStorageURL
-	if baseURLStr == "" {
-		// Fallback if not set (should be set in main)
-		baseURLStr = "http://hot-storage:8081"
-	}
-	// Ensure scheme
-	if !strings.HasPrefix(baseURLStr, "http://") && !strings.HasPrefix(baseURLStr, "https://") {
-		baseURLStr = "http://" + baseURLStr
-	}
-
-	u, err := url.Parse(baseURLStr)
+	u, err := s.hotStorageEndpoint("search")
 	if err != nil {
 		log.Printf("Error parsing hot-storage URL: %v", err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal configuration error"})
 		return
 	}
 
-	// Append /search path if not present.
-	u.Path = path.Join(u.Path, "search")
-
 	q := u.Query()
 	q.Set("q", c.Query("q"))
 	q.Set("page", c.DefaultQuery("page", "1"))
 	q.Set("size", c.DefaultQuery("size", "50"))
+	for _, param := range []string{"from", "to", "order"} {
+		if value := c.Query(param); value != "" {
+			q.Set(param, value)
+		}
+	}
 	u.RawQuery = q.Encode()
 
 	resp, err := http.Get(u.String())
@@ -257,6 +681,94 @@ func (s *Server) handleSearch(c *gin.Context) {
 	io.Copy(c.Writer, resp.Body)
 }
 
+// handleExport streams every log matching a query from the hot-storage
+// service as an NDJSON or CSV download. The upstream request is bound to the
+// client request, so a disconnecting client stops the export.
+func (s *Server) handleExport(c *gin.Context) {
+	if c.Query("q") == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Query parameter 'q' is required"})
+		return
+	}
+	format := c.DefaultQuery("format", "ndjson")
+	if format != "ndjson" && format != "csv" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Unsupported export format %q, expected ndjson or csv", format)})
+		return
+	}
+
+	u, err := s.hotStorageEndpoint("export")
+	if err != nil {
+		log.Printf("Error parsing hot-storage URL: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal configuration error"})
+		return
+	}
+	q := u.Query()
+	q.Set("format", format)
+	for _, param := range []string{"q", "from", "to", "order", "columns"} {
+		if value := c.Query(param); value != "" {
+			q.Set(param, value)
+		}
+	}
+	u.RawQuery = q.Encode()
+
+	req, err := http.NewRequestWithContext(c.Request.Context(), http.MethodGet, u.String(), nil)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start export"})
+		return
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		log.Printf("Error contacting hot-storage service: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start export"})
+		return
+	}
+	defer resp.Body.Close()
+
+	c.Header("Content-Type", resp.Header.Get("Content-Type"))
+	if resp.StatusCode == http.StatusOK {
+		c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="logs-%s.%s"`, time.Now().UTC().Format("20060102T150405Z"), format))
+	}
+	c.Status(resp.StatusCode)
+
+	// Forward each chunk as it arrives instead of buffering the export.
+	buf := make([]byte, 32*1024)
+	for {
+		n, err := resp.Body.Read(buf)
+		if n > 0 {
+			if _, werr := c.Writer.Write(buf[:n]); werr != nil {
+				return
+			}
+			c.Writer.Flush()
+		}
+		if err != nil {
+			if err != io.EOF && c.Request.Context().Err() == nil {
+				log.Printf("Error streaming export: %v", err)
+			}
+			return
+		}
+	}
+}
+
+// hotStorageEndpoint returns the URL of an endpoint of the hot-storage service.
+func (s *Server) hotStorageEndpoint(endpoint string) (*url.URL, error) {
+	// We use s.hotStorageURL which is injected (env var in main, mock URL in tests).
+	baseURLStr := s.hotStorageURL
+	if baseURLStr == "" {
+		// Fallback if not set (should be set in main)
+		baseURLStr = "http://hot-storage:8081"
+	}
+	// Ensure scheme
+	if !strings.HasPrefix(baseURLStr, "http://") && !strings.HasPrefix(baseURLStr, "https://") {
+		baseURLStr = "http://" + baseURLStr
+	}
+
+	u, err := url.Parse(baseURLStr)
+	if err != nil {
+		return nil, err
+	}
+	u.Path = path.Join(u.Path, endpoint)
+	return u, nil
+}
+
 var upgrader = websocket.Upgrader{
 	ReadBufferSize:  1024,
 	WriteBufferSize: 1024,
@@ -266,7 +778,70 @@ var upgrader = websocket.Upgrader{
 	},
 }
 
+// parseTailStart reads where a live tail starts. A client resuming a tail
+// passes the last sequence number it received as afterSeq, or a time to
+// replay from as the since query parameter. Both unset means new logs only.
+// On invalid values it responds with 400 and returns false.
+func parseTailStart(c *gin.Context, afterSeq string) (uint64, time.Time, bool) {
+	var startSeq uint64
+	if afterSeq != "" {
+		seq, err := strconv.ParseUint(afterSeq, 10, 64)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "The position to resume after must be a sequence number"})
+			return 0, time.Time{}, false
+		}
+		startSeq = seq + 1
+	}
+	var startTime time.Time
+	if v := c.Query("since"); v != "" {
+		t, err := time.Parse(time.RFC3339Nano, v)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Query parameter 'since' must be an RFC 3339 timestamp"})
+			return 0, time.Time{}, false
+		}
+		startTime = t
+	}
+	return startSeq, startTime, true
+}
+
+// tailResult is a message of a live tail subscription, or the error that ended it.
+type tailResult struct {
+	msg tail.Message
+	err error
+}
+
+// tailMessages feeds the messages of a live tail subscription to a channel,
+// so that handlers can select on it alongside their keepalive ticker. The
+// last value carries the error that ended the subscription.
+func tailMessages(ctx context.Context, sub *tail.Subscription) <-chan tailResult {
+	results := make(chan tailResult)
+	go func() {
+		defer close(results)
+		for {
+			msg, err := sub.Next(ctx)
+			select {
+			case results <- tailResult{msg: msg, err: err}:
+			case <-ctx.Done():
+				return
+			}
+			if err != nil {
+				return
+			}
+		}
+	}()
+	return results
+}
+
+// droppedNotice tells a live tail client that it fell behind and missed logs.
+type droppedNotice struct {
+	Type    string `json:"type"`
+	Dropped uint64 `json:"dropped"`
+}
+
 // handleLiveTail upgrades the HTTP connection to a WebSocket and streams logs.
+// A client that falls behind receives a droppedNotice in place of the logs it
+// missed or, under the disconnect policy, is closed with a "try again later"
+// status and can resume from the last sequence number it received.
 func (s *Server) handleLiveTail(c *gin.Context) {
 	// Note: Gorilla WebSocket doesn't easily support middleware headers like Authorization automatically,
 	// but the client can pass the token in a query param or manually via Sec-WebSocket-Protocol.
@@ -274,6 +849,16 @@ func (s *Server) handleLiveTail(c *gin.Context) {
 	// If the client is a browser WebSocket, it might not send custom headers.
 	// However, our middleware already ran and validated the token for this GET request.
 
+	startSeq, startTime, ok := parseTailStart(c, c.Query("after_seq"))
+	if !ok {
+		return
+	}
+	if !s.trackStream() {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Server is shutting down"})
+		return
+	}
+	defer s.streams.Done()
+
 	ws, err := upgrader.Upgrade(c.Writer, c.Request, nil)
 	if err != nil {
 		log.Printf("Failed to upgrade to WebSocket: %v", err)
@@ -293,11 +878,13 @@ func (s *Server) handleLiveTail(c *gin.Context) {
 		}
 	}()
 
-	logChan, err := s.subscriber.Subscribe(ctx)
+	sub, err := s.tailHub.Subscribe(ctx, startSeq, startTime)
 	if err != nil {
 		log.Printf("Failed to subscribe to logs: %v", err)
 		return
 	}
+	defer sub.Close()
+	results := tailMessages(ctx, sub)
 
 	ticker := time.NewTicker(30 * time.Second)
 	defer ticker.Stop()
@@ -306,15 +893,30 @@ func (s *Server) handleLiveTail(c *gin.Context) {
 		select {
 		case <-ctx.Done():
 			return
+		case <-s.closing:
+			msg := websocket.FormatCloseMessage(websocket.CloseGoingAway, "server shutting down")
+			ws.WriteControl(websocket.CloseMessage, msg, time.Now().Add(time.Second))
+			return
 		case <-ticker.C:
 			if err := ws.WriteMessage(websocket.PingMessage, []byte{}); err != nil {
 				return
 			}
-		case logEntry, ok := <-logChan:
+		case result, ok := <-results:
 			if !ok {
 				return
 			}
-			if err := ws.WriteJSON(logEntry); err != nil {
+			if result.err != nil {
+				if errors.Is(result.err, tail.ErrSlowConsumer) {
+					msg := websocket.FormatCloseMessage(websocket.CloseTryAgainLater, "slow consumer")
+					ws.WriteControl(websocket.CloseMessage, msg, time.Now().Add(time.Second))
+				}
+				return
+			}
+			var payload interface{} = result.msg.Event
+			if result.msg.Dropped > 0 {
+				payload = droppedNotice{Type: "dropped", Dropped: result.msg.Dropped}
+			}
+			if err := ws.WriteJSON(payload); err != nil {
 				log.Printf("Error writing to WebSocket: %v", err)
 				return
 			}
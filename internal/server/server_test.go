@@ -1,20 +1,37 @@
 package server
 
 import (
+	"bufio"
 	"bytes"
+	"compress/gzip"
 	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log-beacon/internal/alert"
+	"log-beacon/internal/auth"
+	"log-beacon/internal/health"
+	"log-beacon/internal/logmetrics"
 	"log-beacon/internal/model"
+	"log-beacon/internal/notify"
+	"log-beacon/internal/pipeline"
+	"log-beacon/internal/repository"
+	"net"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
+	"sort"
 	"strings"
 	"testing"
 	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/gorilla/websocket"
+	"github.com/prometheus/client_golang/prometheus/testutil"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
 )
 
 // MockPublisher is a mock implementation of the queue.Publisher for testing.
@@ -36,17 +53,27 @@ type MockSubscriber struct {
 	mock.Mock
 }
 
-func (m *MockSubscriber) Subscribe(ctx context.Context) (<-chan model.Log, error) {
-	args := m.Called(ctx)
-	return args.Get(0).(<-chan model.Log), args.Error(1)
+func (m *MockSubscriber) Subscribe(ctx context.Context, startSeq uint64, startTime time.Time) (<-chan model.TailEvent, error) {
+	args := m.Called(ctx, startSeq, startTime)
+	return args.Get(0).(<-chan model.TailEvent), args.Error(1)
 }
 
-func setupTestServer(publisher *MockPublisher, subscriber *MockSubscriber, hotStorageURL string) *gin.Engine {
+func setupTestServer(publisher *MockPublisher, subscriber *MockSubscriber, hotStorageURL string, opts ...Option) *gin.Engine {
 	gin.SetMode(gin.TestMode)
-	server := New(publisher, subscriber, hotStorageURL)
+	server := New(publisher, subscriber, nil, hotStorageURL, opts...)
 	return server.router
 }
 
+// testToken returns a valid JWT for calling protected routes.
+func testToken(t *testing.T) string {
+	t.Helper()
+	token, err := auth.GenerateJWT("tester")
+	if err != nil {
+		t.Fatalf("Failed to generate token: %v", err)
+	}
+	return token
+}
+
 func TestHealthCheck(t *testing.T) {
 	mockPublisher := new(MockPublisher)
 	mockSubscriber := new(MockSubscriber)
@@ -60,6 +87,44 @@ func TestHealthCheck(t *testing.T) {
 	assert.JSONEq(t, `{"status": "ok"}`, w.Body.String())
 }
 
+func TestProbes(t *testing.T) {
+	mockPublisher := new(MockPublisher)
+	mockSubscriber := new(MockSubscriber)
+	hotStorageUp := true
+	readiness := health.NewChecker(0)
+	readiness.Add("nats", func(ctx context.Context) error { return nil })
+	readiness.Add("hot_storage", func(ctx context.Context) error {
+		if !hotStorageUp {
+			return errors.New("connection refused")
+		}
+		return nil
+	})
+	router := setupTestServer(mockPublisher, mockSubscriber, "", WithReadiness(readiness))
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("GET", "/readyz", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+	var report health.Report
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &report))
+	assert.Equal(t, health.StatusOK, report.Status)
+	assert.Len(t, report.Checks, 2)
+
+	hotStorageUp = false
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("GET", "/readyz", nil))
+	assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &report))
+	assert.Equal(t, health.StatusFail, report.Status)
+	assert.Equal(t, "hot_storage", report.Checks[1].Name)
+	assert.Equal(t, "connection refused", report.Checks[1].Error)
+
+	// Liveness does not depend on the checks.
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("GET", "/livez", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"status": "ok"}`, w.Body.String())
+}
+
 func TestHandleIngest(t *testing.T) {
 	t.Run("successful ingest", func(t *testing.T) {
 		mockPublisher := new(MockPublisher)
@@ -91,6 +156,45 @@ func TestHandleIngest(t *testing.T) {
 
 		assert.Equal(t, http.StatusBadRequest, w.Code)
 	})
+
+	t.Run("normalized by pipeline", func(t *testing.T) {
+		mockPublisher := new(MockPublisher)
+		mockSubscriber := new(MockSubscriber)
+		normalizer, err := pipeline.NewNormalizer(pipeline.DefaultNormalizeConfig())
+		assert.NoError(t, err)
+		router := setupTestServer(mockPublisher, mockSubscriber, "", WithPipeline(pipeline.New(normalizer)))
+
+		mockPublisher.On("Publish", mock.MatchedBy(func(l model.Log) bool {
+			return l.Level == "warn" && l.Labels["http.status"] == "503"
+		})).Return(nil)
+
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("POST", "/api/v1/ingest", bytes.NewBufferString(`{"level":"WARNING","message":"slow","http":{"status":503}}`))
+		req.Header.Set("Content-Type", "application/json")
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusAccepted, w.Code)
+		mockPublisher.AssertExpectations(t)
+	})
+
+	t.Run("rejected by pipeline", func(t *testing.T) {
+		mockPublisher := new(MockPublisher)
+		mockSubscriber := new(MockSubscriber)
+		cfg := pipeline.DefaultNormalizeConfig()
+		cfg.MaxMessageBytes = 4
+		normalizer, err := pipeline.NewNormalizer(cfg)
+		assert.NoError(t, err)
+		router := setupTestServer(mockPublisher, mockSubscriber, "", WithPipeline(pipeline.New(normalizer)))
+
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("POST", "/api/v1/ingest", bytes.NewBufferString(`{"level":"info","message":"too long"}`))
+		req.Header.Set("Content-Type", "application/json")
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusUnprocessableEntity, w.Code)
+		assert.JSONEq(t, `{"error":"Log entry rejected","reasons":["message exceeds 4 bytes"]}`, w.Body.String())
+		mockPublisher.AssertNotCalled(t, "Publish", mock.Anything)
+	})
 }
 
 func TestHandleSearch(t *testing.T) {
@@ -108,6 +212,7 @@ func TestHandleSearch(t *testing.T) {
 
 	w := httptest.NewRecorder()
 	req, _ := http.NewRequest("GET", "/api/v1/search?q=error", nil)
+	req.Header.Set("Authorization", "Bearer "+testToken(t))
 	router.ServeHTTP(w, req)
 
 	assert.Equal(t, http.StatusOK, w.Code)
@@ -116,12 +221,68 @@ func TestHandleSearch(t *testing.T) {
 	// Test with AND query (spaces)
 	w2 := httptest.NewRecorder()
 	req2, _ := http.NewRequest("GET", "/api/v1/search?q=level:error AND service:auth", nil)
+	req2.Header.Set("Authorization", "Bearer "+testToken(t))
 	router.ServeHTTP(w2, req2)
 
 	assert.Equal(t, http.StatusOK, w2.Code)
 	// The mock server returns the query param as is.
 	// Since we are now encoding it properly, the mock server (which uses r.URL.Query().Get("q")) should decode it back to the original string.
 	assert.Contains(t, w2.Body.String(), `"query":"level:error AND service:auth"`)
+
+	// Time range and ordering are passed through.
+	var forwarded url.Values
+	rangeStorage := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		forwarded = r.URL.Query()
+		w.Write([]byte(`[]`))
+	}))
+	defer rangeStorage.Close()
+	router = setupTestServer(mockPublisher, mockSubscriber, rangeStorage.URL)
+
+	w3 := httptest.NewRecorder()
+	req3, _ := http.NewRequest("GET", "/api/v1/search?q=error&from=2024-05-01T12:00:00Z&order=desc", nil)
+	req3.Header.Set("Authorization", "Bearer "+testToken(t))
+	router.ServeHTTP(w3, req3)
+
+	assert.Equal(t, http.StatusOK, w3.Code)
+	assert.Equal(t, "2024-05-01T12:00:00Z", forwarded.Get("from"))
+	assert.Equal(t, "desc", forwarded.Get("order"))
+	assert.False(t, forwarded.Has("to"))
+}
+
+func TestHandleExport(t *testing.T) {
+	var forwarded url.Values
+	mockStorageServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/export", r.URL.Path)
+		forwarded = r.URL.Query()
+		w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+		w.Write([]byte("timestamp,level,message,service\n"))
+		w.(http.Flusher).Flush()
+		w.Write([]byte("2024-05-01T12:00:00Z,error,boom,api\n"))
+	}))
+	defer mockStorageServer.Close()
+
+	router := setupTestServer(new(MockPublisher), new(MockSubscriber), mockStorageServer.URL)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/api/v1/export?q=level:error&format=csv&columns=service&from=2024-05-01T00:00:00Z", nil)
+	req.Header.Set("Authorization", "Bearer "+testToken(t))
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "text/csv; charset=utf-8", w.Header().Get("Content-Type"))
+	assert.Contains(t, w.Header().Get("Content-Disposition"), `attachment; filename="logs-`)
+	assert.Equal(t, "timestamp,level,message,service\n2024-05-01T12:00:00Z,error,boom,api\n", w.Body.String())
+	assert.Equal(t, "level:error", forwarded.Get("q"))
+	assert.Equal(t, "csv", forwarded.Get("format"))
+	assert.Equal(t, "service", forwarded.Get("columns"))
+	assert.Equal(t, "2024-05-01T00:00:00Z", forwarded.Get("from"))
+
+	// Invalid formats are rejected before contacting hot storage.
+	w2 := httptest.NewRecorder()
+	req2, _ := http.NewRequest("GET", "/api/v1/export?q=x&format=xml", nil)
+	req2.Header.Set("Authorization", "Bearer "+testToken(t))
+	router.ServeHTTP(w2, req2)
+	assert.Equal(t, http.StatusBadRequest, w2.Code)
 }
 
 func TestHandleLiveTail(t *testing.T) {
@@ -130,15 +291,15 @@ func TestHandleLiveTail(t *testing.T) {
 	router := setupTestServer(mockPublisher, mockSubscriber, "")
 
 	// Setup mock subscriber to return a channel
-	logChan := make(chan model.Log, 1)
-	mockSubscriber.On("Subscribe", mock.Anything).Return((<-chan model.Log)(logChan), nil)
+	logChan := make(chan model.TailEvent, 1)
+	mockSubscriber.On("Subscribe", mock.Anything, uint64(0), time.Time{}).Return((<-chan model.TailEvent)(logChan), nil)
 
 	// Start a test server
 	s := httptest.NewServer(router)
 	defer s.Close()
 
 	// Convert http URL to ws URL
-	wsURL := "ws" + strings.TrimPrefix(s.URL, "http") + "/api/v1/tail"
+	wsURL := "ws" + strings.TrimPrefix(s.URL, "http") + "/api/v1/tail?token=" + testToken(t)
 
 	// Connect to the WebSocket
 	ws, _, err := websocket.DefaultDialer.Dial(wsURL, nil)
@@ -146,15 +307,693 @@ func TestHandleLiveTail(t *testing.T) {
 	defer ws.Close()
 
 	// Send a log to the channel
-	testLog := model.Log{Message: "live log"}
-	logChan <- testLog
+	logChan <- model.TailEvent{Seq: 7, Log: model.Log{Message: "live log"}}
 
 	// Read from WebSocket
-	var receivedLog model.Log
-	err = ws.ReadJSON(&receivedLog)
+	var received model.TailEvent
+	err = ws.ReadJSON(&received)
 	assert.NoError(t, err)
-	assert.Equal(t, "live log", receivedLog.Message)
+	assert.Equal(t, "live log", received.Message)
+	assert.Equal(t, uint64(7), received.Seq)
 
 	// Clean up
 	close(logChan)
 }
+
+func TestHandleLiveTail_SharedSubscription(t *testing.T) {
+	mockSubscriber := new(MockSubscriber)
+	logChan := make(chan model.TailEvent, 1)
+	mockSubscriber.On("Subscribe", mock.Anything, uint64(0), time.Time{}).Return((<-chan model.TailEvent)(logChan), nil).Once()
+
+	s := httptest.NewServer(setupTestServer(new(MockPublisher), mockSubscriber, ""))
+	defer s.Close()
+
+	wsURL := "ws" + strings.TrimPrefix(s.URL, "http") + "/api/v1/tail?token=" + testToken(t)
+	var clients []*websocket.Conn
+	for i := 0; i < 2; i++ {
+		ws, _, err := websocket.DefaultDialer.Dial(wsURL, nil)
+		require.NoError(t, err)
+		defer ws.Close()
+		clients = append(clients, ws)
+	}
+	// Give the handlers a moment to subscribe after the upgrade.
+	time.Sleep(100 * time.Millisecond)
+
+	logChan <- model.TailEvent{Seq: 1, Log: model.Log{Message: "shared"}}
+	for _, ws := range clients {
+		var received model.TailEvent
+		require.NoError(t, ws.ReadJSON(&received))
+		assert.Equal(t, "shared", received.Message)
+	}
+	mockSubscriber.AssertNumberOfCalls(t, "Subscribe", 1)
+}
+
+func TestHandleLiveTail_Resume(t *testing.T) {
+	since := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name      string
+		query     string
+		startSeq  uint64
+		startTime time.Time
+	}{
+		{name: "After sequence", query: "&after_seq=41", startSeq: 42},
+		{name: "Since time", query: "&since=2024-05-01T12:00:00Z", startTime: since},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockSubscriber := new(MockSubscriber)
+			logChan := make(chan model.TailEvent)
+			close(logChan)
+			// The live subscription shared by all clients, and this client's replay.
+			mockSubscriber.On("Subscribe", mock.Anything, uint64(0), time.Time{}).Return((<-chan model.TailEvent)(logChan), nil)
+			mockSubscriber.On("Subscribe", mock.Anything, tt.startSeq, tt.startTime).Return((<-chan model.TailEvent)(logChan), nil)
+
+			s := httptest.NewServer(setupTestServer(new(MockPublisher), mockSubscriber, ""))
+			defer s.Close()
+
+			wsURL := "ws" + strings.TrimPrefix(s.URL, "http") + "/api/v1/tail?token=" + testToken(t) + tt.query
+			ws, _, err := websocket.DefaultDialer.Dial(wsURL, nil)
+			require.NoError(t, err)
+			defer ws.Close()
+
+			// The server closes the connection once the live subscription ends.
+			_, _, err = ws.ReadMessage()
+			assert.Error(t, err)
+			mockSubscriber.AssertExpectations(t)
+		})
+	}
+
+	t.Run("Invalid position", func(t *testing.T) {
+		router := setupTestServer(new(MockPublisher), new(MockSubscriber), "")
+		for _, query := range []string{"after_seq=-1", "since=yesterday"} {
+			w := httptest.NewRecorder()
+			req, _ := http.NewRequest("GET", "/api/v1/tail?"+query, nil)
+			req.Header.Set("Authorization", "Bearer "+testToken(t))
+			router.ServeHTTP(w, req)
+			assert.Equal(t, http.StatusBadRequest, w.Code, query)
+		}
+	})
+}
+
+func TestHandleTailSSE(t *testing.T) {
+	mockSubscriber := new(MockSubscriber)
+	liveChan := make(chan model.TailEvent)
+	logChan := make(chan model.TailEvent, 1)
+	mockSubscriber.On("Subscribe", mock.Anything, uint64(0), time.Time{}).Return((<-chan model.TailEvent)(liveChan), nil)
+	mockSubscriber.On("Subscribe", mock.Anything, uint64(42), time.Time{}).Return((<-chan model.TailEvent)(logChan), nil)
+
+	s := httptest.NewServer(setupTestServer(new(MockPublisher), mockSubscriber, ""))
+	defer s.Close()
+
+	// Tokens in the query string are refused.
+	resp, err := http.Get(s.URL + "/api/v1/tail/sse?token=" + testToken(t))
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+
+	// A reconnecting EventSource resumes after its Last-Event-ID.
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	req, _ := http.NewRequestWithContext(ctx, "GET", s.URL+"/api/v1/tail/sse", nil)
+	req.Header.Set("Authorization", "Bearer "+testToken(t))
+	req.Header.Set("Last-Event-ID", "41")
+	resp, err = http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "text/event-stream", resp.Header.Get("Content-Type"))
+
+	logChan <- model.TailEvent{Seq: 42, Log: model.Log{Message: "live log"}}
+
+	reader := bufio.NewReader(resp.Body)
+	var lines []string
+	for len(lines) < 6 {
+		line, err := reader.ReadString('\n')
+		require.NoError(t, err)
+		lines = append(lines, strings.TrimSuffix(line, "\n"))
+	}
+	assert.Equal(t, "retry: 3000", lines[0])
+	assert.Equal(t, "id: 42", lines[2])
+	assert.Equal(t, "event: log", lines[3])
+	require.True(t, strings.HasPrefix(lines[4], "data: "))
+	var received model.TailEvent
+	require.NoError(t, json.Unmarshal([]byte(strings.TrimPrefix(lines[4], "data: ")), &received))
+	assert.Equal(t, "live log", received.Message)
+	assert.Equal(t, uint64(42), received.Seq)
+
+	// An invalid Last-Event-ID is rejected.
+	req2, _ := http.NewRequest("GET", s.URL+"/api/v1/tail/sse", nil)
+	req2.Header.Set("Authorization", "Bearer "+testToken(t))
+	req2.Header.Set("Last-Event-ID", "abc")
+	resp2, err := http.DefaultClient.Do(req2)
+	require.NoError(t, err)
+	resp2.Body.Close()
+	assert.Equal(t, http.StatusBadRequest, resp2.StatusCode)
+}
+
+func TestShutdown(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	mockSubscriber := new(MockSubscriber)
+	liveChan := make(chan model.TailEvent)
+	mockSubscriber.On("Subscribe", mock.Anything, uint64(0), time.Time{}).Return((<-chan model.TailEvent)(liveChan), nil)
+	srv := New(new(MockPublisher), mockSubscriber, nil, "")
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	serveErr := make(chan error, 1)
+	go func() { serveErr <- srv.Serve(l) }()
+	baseURL := "http://" + l.Addr().String()
+
+	ws, _, err := websocket.DefaultDialer.Dial("ws://"+l.Addr().String()+"/api/v1/tail?token="+testToken(t), nil)
+	require.NoError(t, err)
+	defer ws.Close()
+
+	req, _ := http.NewRequest("GET", baseURL+"/api/v1/tail/sse", nil)
+	req.Header.Set("Authorization", "Bearer "+testToken(t))
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	// Give the handlers a moment to subscribe.
+	time.Sleep(100 * time.Millisecond)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	require.NoError(t, srv.Shutdown(ctx))
+	assert.NoError(t, <-serveErr)
+
+	// WebSocket clients are told the server is going away.
+	_, _, err = ws.ReadMessage()
+	var closeErr *websocket.CloseError
+	require.ErrorAs(t, err, &closeErr)
+	assert.Equal(t, websocket.CloseGoingAway, closeErr.Code)
+
+	// Event streams end.
+	_, err = io.ReadAll(resp.Body)
+	assert.NoError(t, err)
+
+	// New connections are refused.
+	_, err = http.Get(baseURL + "/livez")
+	assert.Error(t, err)
+}
+
+func TestHandlePipelineStats(t *testing.T) {
+	extractor, err := pipeline.NewExtractor(pipeline.ParseConfig{Rules: []pipeline.ParseRule{{Name: "kv", Type: pipeline.ParseKeyValue}}})
+	assert.NoError(t, err)
+	router := setupTestServer(new(MockPublisher), new(MockSubscriber), "", WithPipeline(pipeline.New(extractor)))
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/api/v1/admin/pipeline/stats", nil)
+	req.Header.Set("Authorization", "Bearer "+testToken(t))
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"rules":[{"stage":"parse","rule":"kv","counters":{"evaluated":0,"matched":0,"labels_extracted":0}}]}`, w.Body.String())
+}
+
+func TestSamplingRulesAdmin(t *testing.T) {
+	ingestPipeline, err := pipeline.Build(pipeline.DefaultConfig())
+	assert.NoError(t, err)
+	mockPublisher := new(MockPublisher)
+	router := setupTestServer(mockPublisher, new(MockSubscriber), "", WithPipeline(ingestPipeline))
+	token := testToken(t)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("PUT", "/api/v1/admin/sampling/rules", bytes.NewBufferString(`{"rules":[{"name":"no-debug","levels":["debug"],"action":"drop"}]}`))
+	req.Header.Set("Authorization", "Bearer "+token)
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("GET", "/api/v1/admin/sampling/rules", nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	router.ServeHTTP(w, req)
+	assert.JSONEq(t, `{"rules":[{"name":"no-debug","levels":["debug"],"action":"drop"}]}`, w.Body.String())
+
+	// A dropped entry is accepted but never published.
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("POST", "/api/v1/ingest", bytes.NewBufferString(`{"level":"DEBUG","message":"noise"}`))
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusAccepted, w.Code)
+	assert.JSONEq(t, `{"status":"dropped"}`, w.Body.String())
+	mockPublisher.AssertNotCalled(t, "Publish", mock.Anything)
+
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("PUT", "/api/v1/admin/sampling/rules", bytes.NewBufferString(`{"rules":[{"action":"explode"}]}`))
+	req.Header.Set("Authorization", "Bearer "+token)
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestHandleOTLPLogs(t *testing.T) {
+	mockPublisher := new(MockPublisher)
+	mockSubscriber := new(MockSubscriber)
+	router := setupTestServer(mockPublisher, mockSubscriber, "")
+
+	mockPublisher.On("Publish", mock.MatchedBy(func(l model.Log) bool {
+		return l.Message == "payment failed" && l.Level == "error" && l.Labels["service.name"] == "checkout"
+	})).Return(nil)
+
+	body := `{"resourceLogs":[{"resource":{"attributes":[{"key":"service.name","value":{"stringValue":"checkout"}}]},` +
+		`"scopeLogs":[{"logRecords":[{"timeUnixNano":"1714564800000000000","severityNumber":17,"body":{"stringValue":"payment failed"}}]}]}]}`
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/otlp/v1/logs", bytes.NewBufferString(body))
+	req.Header.Set("Content-Type", "application/json")
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{}`, w.Body.String())
+	mockPublisher.AssertExpectations(t)
+}
+
+func TestDecompressMiddleware(t *testing.T) {
+	logEntry := model.Log{Level: "info", Message: "compressed log", Labels: map[string]string{}, Timestamp: time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)}
+	payload, _ := json.Marshal(logEntry)
+
+	var gzipped bytes.Buffer
+	gz := gzip.NewWriter(&gzipped)
+	gz.Write(payload)
+	gz.Close()
+
+	t.Run("gzip ingest", func(t *testing.T) {
+		mockPublisher := new(MockPublisher)
+		router := setupTestServer(mockPublisher, new(MockSubscriber), "")
+		mockPublisher.On("Publish", logEntry).Return(nil)
+
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("POST", "/api/v1/ingest", bytes.NewReader(gzipped.Bytes()))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Content-Encoding", "gzip")
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusAccepted, w.Code)
+		mockPublisher.AssertExpectations(t)
+	})
+
+	t.Run("decoded body too large", func(t *testing.T) {
+		mockPublisher := new(MockPublisher)
+		router := setupTestServer(mockPublisher, new(MockSubscriber), "", WithMaxBodyBytes(16))
+
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("POST", "/api/v1/ingest", bytes.NewReader(gzipped.Bytes()))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Content-Encoding", "gzip")
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusRequestEntityTooLarge, w.Code)
+		mockPublisher.AssertNotCalled(t, "Publish", mock.Anything)
+	})
+
+	t.Run("uncompressed body too large", func(t *testing.T) {
+		mockPublisher := new(MockPublisher)
+		router := setupTestServer(mockPublisher, new(MockSubscriber), "", WithMaxBodyBytes(16))
+
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("POST", "/api/v1/ingest", bytes.NewReader(payload))
+		req.Header.Set("Content-Type", "application/json")
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusRequestEntityTooLarge, w.Code)
+		mockPublisher.AssertNotCalled(t, "Publish", mock.Anything)
+	})
+
+	t.Run("unsupported encoding", func(t *testing.T) {
+		router := setupTestServer(new(MockPublisher), new(MockSubscriber), "")
+
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("POST", "/loki/api/v1/push", bytes.NewReader(payload))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Content-Encoding", "br")
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusUnsupportedMediaType, w.Code)
+	})
+
+	t.Run("corrupt body", func(t *testing.T) {
+		router := setupTestServer(new(MockPublisher), new(MockSubscriber), "")
+
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("POST", "/es/_bulk", bytes.NewReader(payload))
+		req.Header.Set("Content-Encoding", "zstd")
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+}
+
+func TestHandleIngestBatch(t *testing.T) {
+	t.Run("accepts an array of entries", func(t *testing.T) {
+		mockPublisher := new(MockPublisher)
+		router := setupTestServer(mockPublisher, new(MockSubscriber), "")
+		mockPublisher.On("Publish", mock.AnythingOfType("model.Log")).Return(nil).Twice()
+
+		body := `[{"level":"info","message":"first","service":"agent"},{"level":"error","message":"second"}]`
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("POST", "/api/v1/ingest", bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "application/json")
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusAccepted, w.Code)
+		assert.JSONEq(t, `{"status":"accepted","accepted":2,"dropped":0,"rejected":[]}`, w.Body.String())
+		mockPublisher.AssertExpectations(t)
+		assert.Equal(t, "agent", mockPublisher.Calls[0].Arguments.Get(0).(model.Log).Labels["service"])
+	})
+
+	t.Run("reports rejected entries", func(t *testing.T) {
+		mockPublisher := new(MockPublisher)
+		p, err := pipeline.Build(pipeline.Config{Normalize: pipeline.NormalizeConfig{DefaultLevel: "info", RejectUnknownLevels: true}})
+		if err != nil {
+			t.Fatalf("Failed to build pipeline: %v", err)
+		}
+		router := setupTestServer(mockPublisher, new(MockSubscriber), "", WithPipeline(p))
+		mockPublisher.On("Publish", mock.AnythingOfType("model.Log")).Return(nil).Once()
+
+		body := `[{"level":"bogus","message":"first"},{"level":"info","message":"second"}]`
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("POST", "/api/v1/ingest", bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "application/json")
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusAccepted, w.Code)
+		var resp struct {
+			Accepted int `json:"accepted"`
+			Rejected []struct {
+				Index int `json:"index"`
+			} `json:"rejected"`
+		}
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+		assert.Equal(t, 1, resp.Accepted)
+		if assert.Len(t, resp.Rejected, 1) {
+			assert.Equal(t, 0, resp.Rejected[0].Index)
+		}
+		mockPublisher.AssertExpectations(t)
+	})
+
+	t.Run("publish failure fails the batch", func(t *testing.T) {
+		mockPublisher := new(MockPublisher)
+		router := setupTestServer(mockPublisher, new(MockSubscriber), "")
+		mockPublisher.On("Publish", mock.AnythingOfType("model.Log")).Return(assert.AnError)
+
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("POST", "/api/v1/ingest", bytes.NewBufferString(`[{"message":"first"}]`))
+		req.Header.Set("Content-Type", "application/json")
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusInternalServerError, w.Code)
+	})
+}
+
+func TestAlertsAPI(t *testing.T) {
+	token := testToken(t)
+	call := func(router http.Handler, method, path, body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set("Authorization", "Bearer "+token)
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	t.Run("Disabled", func(t *testing.T) {
+		router := setupTestServer(new(MockPublisher), new(MockSubscriber), "")
+		assert.Equal(t, http.StatusNotFound, call(router, "GET", "/api/v1/alerts/rules", "").Code)
+	})
+
+	store := alert.NewMemoryStore()
+	router := setupTestServer(new(MockPublisher), new(MockSubscriber), "", WithAlertStore(store))
+
+	t.Run("Rules", func(t *testing.T) {
+		w := call(router, "POST", "/api/v1/alerts/rules", `{"name":"payment errors","query":"service:payments AND level:error","threshold":50,"window":"5m","group_by":"region"}`)
+		require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+		var created alert.Rule
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &created))
+		assert.True(t, created.Enabled)
+		assert.Equal(t, alert.Duration(5*time.Minute), created.Window)
+
+		path := fmt.Sprintf("/api/v1/alerts/rules/%d", created.ID)
+		w = call(router, "PUT", path, `{"name":"payment errors","query":"service:payments AND level:error","threshold":100,"window":"10m","enabled":false}`)
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+		w = call(router, "GET", path, "")
+		require.Equal(t, http.StatusOK, w.Code)
+		var updated alert.Rule
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &updated))
+		assert.Equal(t, 100, updated.Threshold)
+		assert.False(t, updated.Enabled)
+		assert.Contains(t, w.Body.String(), `"window":"10m0s"`)
+
+		assert.Equal(t, http.StatusNoContent, call(router, "DELETE", path, "").Code)
+		assert.Equal(t, http.StatusNotFound, call(router, "GET", path, "").Code)
+		assert.Equal(t, http.StatusNotFound, call(router, "DELETE", path, "").Code)
+	})
+
+	t.Run("Invalid rules", func(t *testing.T) {
+		for _, body := range []string{
+			`{"query":"level:error","threshold":1,"window":"1m"}`,
+			`{"name":"r","query":"level:error","threshold":1}`,
+			`{"name":"r","query":"level:error","threshold":1,"window":"soon"}`,
+			`{"name":"r","query":"level:error","threshold":-1,"window":"1m"}`,
+		} {
+			assert.Equal(t, http.StatusBadRequest, call(router, "POST", "/api/v1/alerts/rules", body).Code, body)
+		}
+		assert.Equal(t, http.StatusBadRequest, call(router, "GET", "/api/v1/alerts/rules/abc", "").Code)
+	})
+
+	t.Run("Silences", func(t *testing.T) {
+		w := call(router, "POST", "/api/v1/alerts/silences", `{"rule_id":1,"group":"eu-west-1","comment":"deploy","duration":"1h"}`)
+		require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+		var silence alert.Silence
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &silence))
+		assert.Equal(t, "tester", silence.CreatedBy)
+		assert.Equal(t, time.Hour, silence.EndsAt.Sub(silence.StartsAt))
+
+		assert.Equal(t, http.StatusBadRequest, call(router, "POST", "/api/v1/alerts/silences", `{"comment":"forever"}`).Code)
+
+		w = call(router, "GET", "/api/v1/alerts/silences", "")
+		require.Equal(t, http.StatusOK, w.Code)
+		assert.Contains(t, w.Body.String(), `"comment":"deploy"`)
+
+		assert.Equal(t, http.StatusNoContent, call(router, "DELETE", fmt.Sprintf("/api/v1/alerts/silences/%d", silence.ID), "").Code)
+	})
+
+	t.Run("Alerts", func(t *testing.T) {
+		require.NoError(t, store.SaveAlert(&alert.Alert{RuleID: 1, RuleName: "errors", State: alert.StateFiring, Count: 60, Threshold: 50}))
+		require.NoError(t, store.SaveAlert(&alert.Alert{RuleID: 1, RuleName: "errors", State: alert.StateResolved, Count: 0, Threshold: 50}))
+
+		w := call(router, "GET", "/api/v1/alerts?state=firing", "")
+		require.Equal(t, http.StatusOK, w.Code)
+		var alerts []alert.Alert
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &alerts))
+		require.Len(t, alerts, 1)
+		assert.Equal(t, 60, alerts[0].Count)
+
+		assert.Equal(t, http.StatusBadRequest, call(router, "GET", "/api/v1/alerts?state=pending", "").Code)
+	})
+}
+
+func TestAlertChannelsAPI(t *testing.T) {
+	token := testToken(t)
+	call := func(router http.Handler, method, path string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(method, path, nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	received := make(chan string, 1)
+	slack := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		received <- string(body)
+	}))
+	defer slack.Close()
+	broken := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusForbidden)
+	}))
+	defer broken.Close()
+
+	store := alert.NewMemoryStore()
+	dispatcher, err := notify.New(notify.Config{Channels: []notify.ChannelConfig{
+		{Name: "ops", Type: notify.TypeSlack, URL: slack.URL},
+		{Name: "broken", Type: notify.TypeWebhook, URL: broken.URL},
+	}}, store)
+	require.NoError(t, err)
+	defer dispatcher.Close(context.Background())
+	router := setupTestServer(new(MockPublisher), new(MockSubscriber), "", WithAlertStore(store), WithAlertChannels(dispatcher))
+
+	w := call(router, "GET", "/api/v1/alerts/channels")
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `[{"name":"ops","type":"slack"},{"name":"broken","type":"webhook"}]`, w.Body.String())
+
+	w = call(router, "POST", "/api/v1/alerts/channels/ops/test")
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.Contains(t, <-received, "Test notification")
+	assert.Equal(t, http.StatusBadGateway, call(router, "POST", "/api/v1/alerts/channels/broken/test").Code)
+	assert.Equal(t, http.StatusNotFound, call(router, "POST", "/api/v1/alerts/channels/missing/test").Code)
+
+	require.NoError(t, store.SaveDelivery(&alert.Delivery{AlertID: 3, RuleName: "errors", AlertState: alert.StateFiring, Channel: "ops", Status: alert.DeliverySent, Attempts: 1}))
+	require.NoError(t, store.SaveDelivery(&alert.Delivery{AlertID: 4, RuleName: "errors", AlertState: alert.StateFiring, Channel: "ops", Status: alert.DeliveryFailed, Attempts: 4, Error: "timeout"}))
+
+	w = call(router, "GET", "/api/v1/alerts/deliveries?alert_id=4")
+	require.Equal(t, http.StatusOK, w.Code)
+	var deliveries []alert.Delivery
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &deliveries))
+	require.Len(t, deliveries, 1)
+	assert.Equal(t, alert.DeliveryFailed, deliveries[0].Status)
+	assert.Equal(t, http.StatusBadRequest, call(router, "GET", "/api/v1/alerts/deliveries?alert_id=x").Code)
+}
+
+// memorySavedSearches is an in-memory SavedSearchStore.
+type memorySavedSearches struct {
+	nextID   int
+	searches map[string]repository.SavedSearch
+}
+
+func (m *memorySavedSearches) ListSavedSearches(username string) ([]repository.SavedSearch, error) {
+	searches := []repository.SavedSearch{}
+	for _, s := range m.searches {
+		if s.Owner == username || s.Visibility == repository.VisibilityShared {
+			searches = append(searches, s)
+		}
+	}
+	sort.Slice(searches, func(i, j int) bool { return searches[i].Name < searches[j].Name })
+	return searches, nil
+}
+
+func (m *memorySavedSearches) GetSavedSearch(id string) (*repository.SavedSearch, error) {
+	s, ok := m.searches[id]
+	if !ok {
+		return nil, repository.ErrSavedSearchNotFound
+	}
+	return &s, nil
+}
+
+func (m *memorySavedSearches) CreateSavedSearch(s *repository.SavedSearch) error {
+	m.nextID++
+	s.ID = fmt.Sprintf("s%d", m.nextID)
+	m.searches[s.ID] = *s
+	return nil
+}
+
+func (m *memorySavedSearches) UpdateSavedSearch(s *repository.SavedSearch) error {
+	old, ok := m.searches[s.ID]
+	if !ok {
+		return repository.ErrSavedSearchNotFound
+	}
+	s.Owner = old.Owner
+	m.searches[s.ID] = *s
+	return nil
+}
+
+func (m *memorySavedSearches) DeleteSavedSearch(id string) error {
+	if _, ok := m.searches[id]; !ok {
+		return repository.ErrSavedSearchNotFound
+	}
+	delete(m.searches, id)
+	return nil
+}
+
+func TestSavedSearchesAPI(t *testing.T) {
+	tester := testToken(t)
+	other, err := auth.GenerateJWT("other")
+	require.NoError(t, err)
+	call := func(router http.Handler, token, method, path, body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set("Authorization", "Bearer "+token)
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	t.Run("Disabled", func(t *testing.T) {
+		router := setupTestServer(new(MockPublisher), new(MockSubscriber), "")
+		assert.Equal(t, http.StatusNotFound, call(router, tester, "GET", "/api/v1/saved-searches", "").Code)
+	})
+
+	router := setupTestServer(new(MockPublisher), new(MockSubscriber), "",
+		WithSavedSearches(&memorySavedSearches{searches: map[string]repository.SavedSearch{}}))
+	create := func(token, body string) repository.SavedSearch {
+		t.Helper()
+		w := call(router, token, "POST", "/api/v1/saved-searches", body)
+		require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+		var search repository.SavedSearch
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &search))
+		return search
+	}
+
+	shared := create(tester, `{"name":"payment errors","query":"service:payments AND level:error","from":"1h","columns":["region"],"visibility":"shared"}`)
+	assert.Equal(t, "tester", shared.Owner)
+	assert.Equal(t, []string{"region"}, shared.Columns)
+	private := create(tester, `{"name":"my auth logs","query":"service:auth","from":"2024-05-01T12:00:00Z","to":"2024-05-01T13:00:00Z"}`)
+	assert.Equal(t, repository.VisibilityPrivate, private.Visibility)
+	create(other, `{"name":"other's","query":"level:warn"}`)
+
+	// Users see their own searches and shared ones.
+	w := call(router, other, "GET", "/api/v1/saved-searches", "")
+	require.Equal(t, http.StatusOK, w.Code)
+	var listed []repository.SavedSearch
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &listed))
+	require.Len(t, listed, 2)
+	assert.Equal(t, "other's", listed[0].Name)
+	assert.Equal(t, "payment errors", listed[1].Name)
+
+	// Shared searches load by ID for anyone, private ones only for their owner.
+	assert.Equal(t, http.StatusOK, call(router, other, "GET", "/api/v1/saved-searches/"+shared.ID, "").Code)
+	assert.Equal(t, http.StatusNotFound, call(router, other, "GET", "/api/v1/saved-searches/"+private.ID, "").Code)
+	assert.Equal(t, http.StatusOK, call(router, tester, "GET", "/api/v1/saved-searches/"+private.ID, "").Code)
+	assert.Equal(t, http.StatusNotFound, call(router, tester, "GET", "/api/v1/saved-searches/missing", "").Code)
+
+	// Only the owner changes a search.
+	update := `{"name":"payment errors","query":"service:payments AND level:error","from":"24h","visibility":"shared"}`
+	assert.Equal(t, http.StatusForbidden, call(router, other, "PUT", "/api/v1/saved-searches/"+shared.ID, update).Code)
+	assert.Equal(t, http.StatusForbidden, call(router, other, "DELETE", "/api/v1/saved-searches/"+shared.ID, "").Code)
+	w = call(router, tester, "PUT", "/api/v1/saved-searches/"+shared.ID, update)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	var updated repository.SavedSearch
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &updated))
+	assert.Equal(t, "24h", updated.From)
+	assert.Equal(t, "tester", updated.Owner)
+	assert.Equal(t, []string{}, updated.Columns)
+
+	assert.Equal(t, http.StatusNoContent, call(router, tester, "DELETE", "/api/v1/saved-searches/"+shared.ID, "").Code)
+	assert.Equal(t, http.StatusNotFound, call(router, other, "GET", "/api/v1/saved-searches/"+shared.ID, "").Code)
+
+	for _, body := range []string{
+		`{"query":"level:error"}`,
+		`{"name":"n"}`,
+		`{"name":"n","query":"level:error","from":"yesterday"}`,
+		`{"name":"n","query":"level:error","to":"-5m"}`,
+		`{"name":"n","query":"level:error","visibility":"public"}`,
+		`{"name":"n","query":"level:error","columns":[""]}`,
+	} {
+		assert.Equal(t, http.StatusBadRequest, call(router, tester, "POST", "/api/v1/saved-searches", body).Code, body)
+	}
+}
+
+func TestMetricsEndpoint(t *testing.T) {
+	registry, err := logmetrics.New(logmetrics.Config{Rules: []logmetrics.Rule{
+		{Name: "errors_total", Type: logmetrics.TypeCounter, Query: "level:error", GroupBy: []string{"service"}},
+	}})
+	require.NoError(t, err)
+	registry.Observe(model.Log{Level: "error", Labels: map[string]string{"service": "api"}})
+	publisher := new(MockPublisher)
+	publisher.On("Publish", mock.Anything).Return(nil)
+	router := setupTestServer(publisher, new(MockSubscriber), "", WithLogMetrics(registry))
+
+	published := testutil.ToFloat64(ingestedLogs.WithLabelValues(ingestPublished))
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/api/v1/ingest", strings.NewReader(`{"message":"hello"}`))
+	router.ServeHTTP(w, req)
+	require.Equal(t, http.StatusAccepted, w.Code, w.Body.String())
+	assert.Equal(t, published+1, testutil.ToFloat64(ingestedLogs.WithLabelValues(ingestPublished)))
+
+	// Scrapes need no token.
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("GET", "/metrics", nil)
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Header().Get("Content-Type"), "text/plain")
+	body := w.Body.String()
+	assert.Contains(t, body, `beacon_ingest_logs_total{result="published"}`)
+	assert.Contains(t, body, `beacon_http_request_duration_seconds_count{code="202",method="POST",route="/api/v1/ingest"}`)
+	assert.Contains(t, body, `errors_total{service="api"} 1`)
+}
@@ -0,0 +1,103 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// sseHeartbeatInterval is how often an idle event stream sends a comment so
+// that proxies do not time the connection out.
+const sseHeartbeatInterval = 15 * time.Second
+
+// handleTailSSE streams live logs as Server-Sent Events. Each log is a "log"
+// event whose ID is its stream sequence number, so a reconnecting
+// EventSource resumes after the last log it received through the
+// Last-Event-ID header. The after_seq and since query parameters select the
+// start position of a new stream, as for the WebSocket tail. A client that
+// falls behind receives a "dropped" event counting the logs it missed or,
+// under the disconnect policy, has its stream ended so that it reconnects.
+func (s *Server) handleTailSSE(c *gin.Context) {
+	afterSeq := c.GetHeader("Last-Event-ID")
+	if afterSeq == "" {
+		afterSeq = c.Query("after_seq")
+	}
+	startSeq, startTime, ok := parseTailStart(c, afterSeq)
+	if !ok {
+		return
+	}
+	if !s.trackStream() {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Server is shutting down"})
+		return
+	}
+	defer s.streams.Done()
+
+	ctx, cancel := context.WithCancel(c.Request.Context())
+	defer cancel()
+
+	sub, err := s.tailHub.Subscribe(ctx, startSeq, startTime)
+	if err != nil {
+		log.Printf("Failed to subscribe to logs: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to subscribe to logs"})
+		return
+	}
+	defer sub.Close()
+	results := tailMessages(ctx, sub)
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	// Disable response buffering in nginx.
+	c.Header("X-Accel-Buffering", "no")
+	c.Status(http.StatusOK)
+
+	// Ask clients to reconnect quickly after a dropped connection.
+	if _, err := fmt.Fprint(c.Writer, "retry: 3000\n\n"); err != nil {
+		return
+	}
+	c.Writer.Flush()
+
+	ticker := time.NewTicker(sseHeartbeatInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-s.closing:
+			// EventSource reconnects with Last-Event-ID, resuming elsewhere.
+			return
+		case <-ticker.C:
+			if _, err := fmt.Fprint(c.Writer, ": ping\n\n"); err != nil {
+				return
+			}
+			c.Writer.Flush()
+		case result, ok := <-results:
+			if !ok || result.err != nil {
+				return
+			}
+			if result.msg.Dropped > 0 {
+				if _, err := fmt.Fprintf(c.Writer, "event: dropped\ndata: {\"dropped\":%d}\n\n", result.msg.Dropped); err != nil {
+					return
+				}
+				c.Writer.Flush()
+				continue
+			}
+			event := result.msg.Event
+			data, err := json.Marshal(event)
+			if err != nil {
+				log.Printf("Error encoding log event: %v", err)
+				continue
+			}
+			if _, err := fmt.Fprintf(c.Writer, "id: %d\nevent: log\ndata: %s\n\n", event.Seq, data); err != nil {
+				return
+			}
+			c.Writer.Flush()
+		}
+	}
+}
@@ -3,6 +3,7 @@ package storage
 import (
 	"bytes"
 	"context"
+	"fmt"
 	"log"
 
 	"github.com/minio/minio-go/v7"
@@ -49,6 +50,18 @@ func (s *MinioStorage) EnsureBucket(ctx context.Context, bucketName string) erro
 	return s.client.MakeBucket(ctx, bucketName, minio.MakeBucketOptions{})
 }
 
+// CheckBucket verifies that MinIO is reachable and the bucket exists.
+func (s *MinioStorage) CheckBucket(ctx context.Context, bucketName string) error {
+	found, err := s.client.BucketExists(ctx, bucketName)
+	if err != nil {
+		return err
+	}
+	if !found {
+		return fmt.Errorf("bucket '%s' does not exist", bucketName)
+	}
+	return nil
+}
+
 // Write uploads data to a MinIO bucket.
 func (s *MinioStorage) Write(ctx context.Context, bucketName, objectName string, data []byte, contentType string) error {
 	_, err := s.client.PutObject(ctx, bucketName, objectName, bytes.NewReader(data), int64(len(data)), minio.PutObjectOptions{ContentType: contentType})
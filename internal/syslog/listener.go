@@ -0,0 +1,280 @@
+package syslog
+
+import (
+	"bufio"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"log-beacon/internal/model"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"golang.org/x/time/rate"
+)
+
+// maxMessageSize bounds a single syslog message on every transport.
+const maxMessageSize = 64 * 1024
+
+// failureLogInterval bounds how often failed messages are logged, so that a
+// noisy sender cannot flood the log. The metrics count every failure.
+const failureLogInterval = 10 * time.Second
+
+// Message results.
+const (
+	resultOK      = "ok"
+	resultInvalid = "invalid"
+	resultError   = "error"
+)
+
+var syslogMessages = promauto.NewCounterVec(prometheus.CounterOpts{
+	Name: "beacon_syslog_messages_total",
+	Help: "Syslog messages received, by result: ok, invalid when unparseable or error when the handler failed.",
+}, []string{"result"})
+
+// Handler receives every parsed syslog message.
+type Handler func(logEntry model.Log) error
+
+// Listener accepts syslog messages over UDP, TCP and TLS and passes them to a Handler.
+type Listener struct {
+	handler Handler
+
+	// failureLog limits the logging of failed messages; suppressed counts
+	// the failures not logged since the last log line.
+	failureLog *rate.Limiter
+	suppressed atomic.Int64
+
+	mu      sync.Mutex
+	closers map[io.Closer]struct{}
+	closed  bool
+	wg      sync.WaitGroup
+}
+
+// NewListener creates a syslog listener that delivers messages to handler.
+func NewListener(handler Handler) *Listener {
+	return &Listener{
+		handler:    handler,
+		failureLog: rate.NewLimiter(rate.Every(failureLogInterval), 1),
+		closers:    make(map[io.Closer]struct{}),
+	}
+}
+
+// ListenUDP starts receiving datagrams on addr, one message per datagram.
+// It returns the bound address.
+func (l *Listener) ListenUDP(addr string) (net.Addr, error) {
+	conn, err := net.ListenPacket("udp", addr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to listen on udp %s: %w", addr, err)
+	}
+	if !l.track(conn) {
+		conn.Close()
+		return nil, net.ErrClosed
+	}
+
+	log.Printf("Syslog listener receiving UDP on %s", conn.LocalAddr())
+	l.wg.Add(1)
+	go func() {
+		defer l.wg.Done()
+		defer l.untrack(conn)
+		buf := make([]byte, maxMessageSize)
+		for {
+			n, _, err := conn.ReadFrom(buf)
+			if err != nil {
+				if !errors.Is(err, net.ErrClosed) {
+					log.Printf("Error reading syslog datagram: %v", err)
+				}
+				return
+			}
+			l.deliver(buf[:n])
+		}
+	}()
+	return conn.LocalAddr(), nil
+}
+
+// ListenTCP starts accepting plain TCP connections on addr.
+// It returns the bound address.
+func (l *Listener) ListenTCP(addr string) (net.Addr, error) {
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to listen on tcp %s: %w", addr, err)
+	}
+	return l.serve(ln, "TCP")
+}
+
+// ListenTLS starts accepting TLS connections on addr.
+// It returns the bound address.
+func (l *Listener) ListenTLS(addr string, cfg *tls.Config) (net.Addr, error) {
+	ln, err := tls.Listen("tcp", addr, cfg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to listen on tls %s: %w", addr, err)
+	}
+	return l.serve(ln, "TLS")
+}
+
+// Close stops all listeners, closes open connections and waits for them to finish.
+func (l *Listener) Close() error {
+	l.mu.Lock()
+	l.closed = true
+	for c := range l.closers {
+		c.Close()
+	}
+	l.mu.Unlock()
+
+	l.wg.Wait()
+	return nil
+}
+
+func (l *Listener) serve(ln net.Listener, transport string) (net.Addr, error) {
+	if !l.track(ln) {
+		ln.Close()
+		return nil, net.ErrClosed
+	}
+
+	log.Printf("Syslog listener accepting %s on %s", transport, ln.Addr())
+	l.wg.Add(1)
+	go func() {
+		defer l.wg.Done()
+		defer l.untrack(ln)
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				if !errors.Is(err, net.ErrClosed) {
+					log.Printf("Error accepting syslog connection: %v", err)
+				}
+				return
+			}
+			if !l.track(conn) {
+				conn.Close()
+				return
+			}
+			l.wg.Add(1)
+			go func() {
+				defer l.wg.Done()
+				defer l.untrack(conn)
+				l.handleConn(conn)
+			}()
+		}
+	}()
+	return ln.Addr(), nil
+}
+
+// handleConn reads framed messages from a stream connection until it is closed.
+func (l *Listener) handleConn(conn net.Conn) {
+	defer conn.Close()
+	reader := bufio.NewReaderSize(conn, 4096)
+	for {
+		frame, err := readFrame(reader)
+		if err != nil {
+			if err != io.EOF && !errors.Is(err, net.ErrClosed) {
+				log.Printf("Error reading syslog stream from %s: %v", conn.RemoteAddr(), err)
+			}
+			return
+		}
+		l.deliver(frame)
+	}
+}
+
+// readFrame reads one message using RFC 6587 framing: octet counting
+// ("<length> <message>") when the frame starts with a digit, otherwise
+// newline-delimited.
+func readFrame(r *bufio.Reader) ([]byte, error) {
+	for {
+		first, err := r.Peek(1)
+		if err != nil {
+			return nil, err
+		}
+
+		if first[0] >= '0' && first[0] <= '9' {
+			lengthStr, err := r.ReadString(' ')
+			if err != nil {
+				return nil, err
+			}
+			length, err := strconv.Atoi(lengthStr[:len(lengthStr)-1])
+			if err != nil || length <= 0 || length > maxMessageSize {
+				return nil, fmt.Errorf("invalid octet count %q", lengthStr)
+			}
+			frame := make([]byte, length)
+			if _, err := io.ReadFull(r, frame); err != nil {
+				return nil, err
+			}
+			return frame, nil
+		}
+
+		var frame []byte
+		for {
+			chunk, err := r.ReadSlice('\n')
+			frame = append(frame, chunk...)
+			if len(frame) > maxMessageSize {
+				return nil, fmt.Errorf("syslog message exceeds %d bytes", maxMessageSize)
+			}
+			if err == bufio.ErrBufferFull {
+				continue
+			}
+			if err != nil {
+				if err == io.EOF && len(frame) > 0 {
+					return frame, nil
+				}
+				return nil, err
+			}
+			break
+		}
+		// Skip blank lines between frames.
+		if len(frame) > 1 && !(len(frame) == 2 && frame[0] == '\r') {
+			return frame, nil
+		}
+	}
+}
+
+func (l *Listener) deliver(data []byte) {
+	logEntry, err := Parse(data, time.Now())
+	if err != nil {
+		syslogMessages.WithLabelValues(resultInvalid).Inc()
+		l.logFailure("Discarding unparseable syslog message", err)
+		return
+	}
+	if err := l.handler(logEntry); err != nil {
+		syslogMessages.WithLabelValues(resultError).Inc()
+		l.logFailure("Error handling syslog message", err)
+		return
+	}
+	syslogMessages.WithLabelValues(resultOK).Inc()
+}
+
+// logFailure logs a failed message at most once per failureLogInterval,
+// with the number of failures left out since the previous line.
+func (l *Listener) logFailure(msg string, err error) {
+	if !l.failureLog.Allow() {
+		l.suppressed.Add(1)
+		return
+	}
+	if n := l.suppressed.Swap(0); n > 0 {
+		log.Printf("%s: %v (%d more failures not logged)", msg, err, n)
+		return
+	}
+	log.Printf("%s: %v", msg, err)
+}
+
+// track registers a listener or connection to be closed by Close.
+// It reports false if the Listener is already closed.
+func (l *Listener) track(c io.Closer) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.closed {
+		return false
+	}
+	l.closers[c] = struct{}{}
+	return true
+}
+
+func (l *Listener) untrack(c io.Closer) {
+	l.mu.Lock()
+	delete(l.closers, c)
+	l.mu.Unlock()
+}
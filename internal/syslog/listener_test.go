@@ -0,0 +1,122 @@
+package syslog
+
+import (
+	"errors"
+	"net"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"log-beacon/internal/model"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// collector records the messages delivered to a Listener.
+type collector struct {
+	mu   sync.Mutex
+	logs []model.Log
+}
+
+func (c *collector) handle(logEntry model.Log) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.logs = append(c.logs, logEntry)
+	return nil
+}
+
+func (c *collector) messages() []string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	var msgs []string
+	for _, l := range c.logs {
+		msgs = append(msgs, l.Message)
+	}
+	return msgs
+}
+
+func TestListener_UDP(t *testing.T) {
+	c := &collector{}
+	l := NewListener(c.handle)
+	defer l.Close()
+
+	addr, err := l.ListenUDP("127.0.0.1:0")
+	require.NoError(t, err)
+
+	conn, err := net.Dial("udp", addr.String())
+	require.NoError(t, err)
+	defer conn.Close()
+
+	_, err = conn.Write([]byte("<14>1 2024-01-01T00:00:00Z host app - - - hello over udp"))
+	require.NoError(t, err)
+
+	assert.Eventually(t, func() bool {
+		return len(c.messages()) == 1
+	}, 2*time.Second, 10*time.Millisecond)
+	assert.Equal(t, []string{"hello over udp"}, c.messages())
+}
+
+func TestListener_TCPFraming(t *testing.T) {
+	c := &collector{}
+	l := NewListener(c.handle)
+
+	addr, err := l.ListenTCP("127.0.0.1:0")
+	require.NoError(t, err)
+
+	conn, err := net.Dial("tcp", addr.String())
+	require.NoError(t, err)
+
+	octetCounted := "<14>1 - host app - - - counted\nwith newline"
+	_, err = conn.Write([]byte("<14>Jan  1 00:00:00 host app: first\n\n<14>Jan  1 00:00:01 host app: second\r\n"))
+	require.NoError(t, err)
+	_, err = conn.Write([]byte(strconv.Itoa(len(octetCounted)) + " " + octetCounted))
+	require.NoError(t, err)
+
+	assert.Eventually(t, func() bool {
+		return len(c.messages()) == 3
+	}, 2*time.Second, 10*time.Millisecond)
+	assert.Equal(t, []string{"first", "second", "counted\nwith newline"}, c.messages())
+
+	// Close must also terminate open connections.
+	done := make(chan struct{})
+	go func() {
+		l.Close()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(2 * time.Second):
+		t.Fatal("Close did not return")
+	}
+	conn.Close()
+}
+
+func TestListener_DeliverCountsResults(t *testing.T) {
+	count := func(result string) float64 { return testutil.ToFloat64(syslogMessages.WithLabelValues(result)) }
+	ok, invalid, failed := count(resultOK), count(resultInvalid), count(resultError)
+
+	var calls int
+	l := NewListener(func(model.Log) error {
+		calls++
+		if calls > 1 {
+			return errors.New("rejected")
+		}
+		return nil
+	})
+	defer l.Close()
+
+	l.deliver([]byte("<14>1 2024-01-01T00:00:00Z host app - - - accepted"))
+	l.deliver([]byte(""))
+	for i := 0; i < 3; i++ {
+		l.deliver([]byte("<14>1 2024-01-01T00:00:00Z host app - - - rejected"))
+	}
+
+	assert.Equal(t, ok+1, count(resultOK))
+	assert.Equal(t, invalid+1, count(resultInvalid))
+	assert.Equal(t, failed+3, count(resultError))
+	// Only the first failure is logged within the interval.
+	assert.Equal(t, int64(3), l.suppressed.Load())
+}
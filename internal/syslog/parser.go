@@ -0,0 +1,216 @@
+package syslog
+
+import (
+	"bytes"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"log-beacon/internal/model"
+)
+
+// facilityNames maps syslog facility codes to their conventional names.
+var facilityNames = []string{
+	"kern", "user", "mail", "daemon", "auth", "syslog", "lpr", "news",
+	"uucp", "cron", "authpriv", "ftp", "ntp", "security", "console", "solaris-cron",
+	"local0", "local1", "local2", "local3", "local4", "local5", "local6", "local7",
+}
+
+// severityLevels maps syslog severities (0-7) onto Log Beacon levels.
+var severityLevels = []string{"fatal", "fatal", "fatal", "error", "warn", "info", "info", "debug"}
+
+// rfc3164Timestamp is the BSD syslog timestamp layout, e.g. "Oct 11 22:14:15".
+const rfc3164Timestamp = "Jan _2 15:04:05"
+
+var errEmptyMessage = errors.New("empty syslog message")
+
+// Parse converts a single syslog message in RFC 5424 or RFC 3164 format into a log entry.
+// Messages without a valid priority are kept verbatim with the default
+// user.notice priority, as RFC 3164 recommends for relays.
+func Parse(data []byte, receivedAt time.Time) (model.Log, error) {
+	data = bytes.TrimRight(data, "\r\n\x00")
+	if len(bytes.TrimSpace(data)) == 0 {
+		return model.Log{}, errEmptyMessage
+	}
+
+	logEntry := model.Log{Timestamp: receivedAt.UTC(), Labels: make(map[string]string)}
+
+	priority, rest, ok := parsePriority(string(data))
+	if !ok {
+		priority = 1*8 + 5
+		rest = string(data)
+	}
+	setPriority(&logEntry, priority)
+
+	if strings.HasPrefix(rest, "1 ") {
+		parseRFC5424(&logEntry, rest[2:])
+	} else {
+		parseRFC3164(&logEntry, rest, receivedAt)
+	}
+
+	if !utf8.ValidString(logEntry.Message) {
+		logEntry.Message = strings.ToValidUTF8(logEntry.Message, "\uFFFD")
+	}
+	return logEntry, nil
+}
+
+// parsePriority reads the "<PRI>" prefix.
+func parsePriority(s string) (int, string, bool) {
+	if !strings.HasPrefix(s, "<") {
+		return 0, s, false
+	}
+	end := strings.IndexByte(s, '>')
+	if end < 2 || end > 4 {
+		return 0, s, false
+	}
+	priority, err := strconv.Atoi(s[1:end])
+	if err != nil || priority < 0 || priority > 191 {
+		return 0, s, false
+	}
+	return priority, s[end+1:], true
+}
+
+func setPriority(logEntry *model.Log, priority int) {
+	facility, severity := priority/8, priority%8
+	logEntry.Level = severityLevels[severity]
+	logEntry.Labels["severity"] = strconv.Itoa(severity)
+	if facility < len(facilityNames) {
+		logEntry.Labels["facility"] = facilityNames[facility]
+	}
+}
+
+// parseRFC5424 parses the part of an RFC 5424 message that follows the version:
+// TIMESTAMP HOSTNAME APP-NAME PROCID MSGID STRUCTURED-DATA [MSG]
+func parseRFC5424(logEntry *model.Log, s string) {
+	var fields [5]string
+	for i := range fields {
+		fields[i], s = nextToken(s)
+	}
+
+	if ts, err := time.Parse(time.RFC3339Nano, fields[0]); err == nil {
+		logEntry.Timestamp = ts.UTC()
+	}
+	setLabel(logEntry, "hostname", fields[1])
+	setLabel(logEntry, "app_name", fields[2])
+	setLabel(logEntry, "procid", fields[3])
+	setLabel(logEntry, "msgid", fields[4])
+
+	s = parseStructuredData(logEntry, s)
+
+	s = strings.TrimPrefix(s, " ")
+	s = strings.TrimPrefix(s, "\ufeff") // UTF-8 byte order mark
+	logEntry.Message = s
+}
+
+// parseStructuredData parses zero or more SD-ELEMENTs, storing each parameter as
+// a "sd.<id>.<name>" label, and returns the remainder of the message.
+func parseStructuredData(logEntry *model.Log, s string) string {
+	if strings.HasPrefix(s, "-") {
+		return s[1:]
+	}
+
+	for strings.HasPrefix(s, "[") {
+		end := strings.IndexAny(s, " ]")
+		if end < 0 {
+			return s
+		}
+		id := s[1:end]
+		s = s[end:]
+
+		for strings.HasPrefix(s, " ") {
+			s = s[1:]
+			eq := strings.Index(s, `="`)
+			if eq < 0 {
+				return s
+			}
+			name := s[:eq]
+			value, rest, ok := readParamValue(s[eq+2:])
+			if !ok {
+				return s
+			}
+			logEntry.Labels["sd."+id+"."+name] = value
+			s = rest
+		}
+
+		if !strings.HasPrefix(s, "]") {
+			return s
+		}
+		s = s[1:]
+	}
+	return s
+}
+
+// readParamValue reads an SD-PARAM value up to the closing quote, unescaping \" \\ and \].
+func readParamValue(s string) (string, string, bool) {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			if i+1 < len(s) && (s[i+1] == '"' || s[i+1] == '\\' || s[i+1] == ']') {
+				i++
+			}
+			b.WriteByte(s[i])
+		case '"':
+			return b.String(), s[i+1:], true
+		default:
+			b.WriteByte(s[i])
+		}
+	}
+	return "", s, false
+}
+
+// parseRFC3164 parses a BSD syslog message: TIMESTAMP HOSTNAME TAG[PID]: MSG.
+// Missing parts are tolerated; whatever cannot be parsed becomes the message.
+func parseRFC3164(logEntry *model.Log, s string, receivedAt time.Time) {
+	if len(s) >= len(rfc3164Timestamp) {
+		if ts, err := time.ParseInLocation(rfc3164Timestamp, s[:len(rfc3164Timestamp)], time.UTC); err == nil {
+			// The timestamp carries no year, so assume the most recent one.
+			year := receivedAt.UTC().Year()
+			ts = ts.AddDate(year, 0, 0)
+			if ts.After(receivedAt.Add(24 * time.Hour)) {
+				ts = ts.AddDate(-1, 0, 0)
+			}
+			logEntry.Timestamp = ts
+			s = strings.TrimPrefix(s[len(rfc3164Timestamp):], " ")
+
+			var hostname string
+			hostname, s = nextToken(s)
+			setLabel(logEntry, "hostname", hostname)
+		}
+	}
+
+	// The tag is alphanumeric and ends at the first character that is not, usually "[" or ":".
+	tagEnd := strings.IndexFunc(s, func(r rune) bool {
+		return !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '_' || r == '-' || r == '.' || r == '/')
+	})
+	if tagEnd > 0 && tagEnd <= 48 && (s[tagEnd] == '[' || s[tagEnd] == ':') {
+		setLabel(logEntry, "app_name", s[:tagEnd])
+		s = s[tagEnd:]
+		if strings.HasPrefix(s, "[") {
+			if end := strings.IndexByte(s, ']'); end > 0 {
+				setLabel(logEntry, "procid", s[1:end])
+				s = s[end+1:]
+			}
+		}
+		s = strings.TrimPrefix(s, ":")
+	}
+
+	logEntry.Message = strings.TrimPrefix(s, " ")
+}
+
+// nextToken splits s at the first space.
+func nextToken(s string) (string, string) {
+	if i := strings.IndexByte(s, ' '); i >= 0 {
+		return s[:i], s[i+1:]
+	}
+	return s, ""
+}
+
+// setLabel stores a header field, skipping the RFC 5424 NILVALUE.
+func setLabel(logEntry *model.Log, key, value string) {
+	if value != "" && value != "-" {
+		logEntry.Labels[key] = value
+	}
+}
@@ -0,0 +1,83 @@
+package syslog
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParse_RFC5424(t *testing.T) {
+	msg := `<165>1 2003-10-11T22:14:15.003Z mymachine.example.com evntslog 1234 ID47 [exampleSDID@32473 iut="3" eventSource="App\"lication"][meta seq="1"] ` + "\ufeff" + `An application event log entry`
+
+	logEntry, err := Parse([]byte(msg), time.Now())
+	require.NoError(t, err)
+
+	assert.Equal(t, time.Date(2003, 10, 11, 22, 14, 15, 3000000, time.UTC), logEntry.Timestamp)
+	assert.Equal(t, "info", logEntry.Level)
+	assert.Equal(t, "An application event log entry", logEntry.Message)
+	assert.Equal(t, map[string]string{
+		"facility":                         "local4",
+		"severity":                         "5",
+		"hostname":                         "mymachine.example.com",
+		"app_name":                         "evntslog",
+		"procid":                           "1234",
+		"msgid":                            "ID47",
+		"sd.exampleSDID@32473.iut":         "3",
+		"sd.exampleSDID@32473.eventSource": `App"lication`,
+		"sd.meta.seq":                      "1",
+	}, logEntry.Labels)
+}
+
+func TestParse_RFC5424NilValues(t *testing.T) {
+	logEntry, err := Parse([]byte("<11>1 - - - - - -"), time.Now())
+	require.NoError(t, err)
+
+	assert.Equal(t, "error", logEntry.Level)
+	assert.Equal(t, "", logEntry.Message)
+	assert.Equal(t, map[string]string{"facility": "user", "severity": "3"}, logEntry.Labels)
+}
+
+func TestParse_RFC3164(t *testing.T) {
+	receivedAt := time.Date(2024, 10, 12, 0, 0, 0, 0, time.UTC)
+	logEntry, err := Parse([]byte("<34>Oct 11 22:14:15 mymachine su[230]: 'su root' failed for lonvick on /dev/pts/8\n"), receivedAt)
+	require.NoError(t, err)
+
+	assert.Equal(t, time.Date(2024, 10, 11, 22, 14, 15, 0, time.UTC), logEntry.Timestamp)
+	assert.Equal(t, "fatal", logEntry.Level)
+	assert.Equal(t, "'su root' failed for lonvick on /dev/pts/8", logEntry.Message)
+	assert.Equal(t, map[string]string{
+		"facility": "auth",
+		"severity": "2",
+		"hostname": "mymachine",
+		"app_name": "su",
+		"procid":   "230",
+	}, logEntry.Labels)
+}
+
+func TestParse_RFC3164YearRollover(t *testing.T) {
+	receivedAt := time.Date(2025, 1, 1, 0, 0, 5, 0, time.UTC)
+	logEntry, err := Parse([]byte("<13>Dec 31 23:59:59 host app: last message of the year"), receivedAt)
+	require.NoError(t, err)
+	assert.Equal(t, 2024, logEntry.Timestamp.Year())
+}
+
+func TestParse_Fallbacks(t *testing.T) {
+	receivedAt := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	logEntry, err := Parse([]byte("plain text without priority"), receivedAt)
+	require.NoError(t, err)
+	assert.Equal(t, "info", logEntry.Level)
+	assert.Equal(t, "plain text without priority", logEntry.Message)
+	assert.Equal(t, receivedAt, logEntry.Timestamp)
+
+	logEntry, err = Parse([]byte("<15>kernel: oops"), receivedAt)
+	require.NoError(t, err)
+	assert.Equal(t, "debug", logEntry.Level)
+	assert.Equal(t, "kernel", logEntry.Labels["app_name"])
+	assert.Equal(t, "oops", logEntry.Message)
+
+	_, err = Parse([]byte(" \r\n"), receivedAt)
+	assert.Error(t, err)
+}
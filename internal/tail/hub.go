@@ -0,0 +1,414 @@
+// Package tail fans live logs out to many tail clients from one upstream
+// subscription.
+package tail
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"log-beacon/internal/config"
+	"log-beacon/internal/model"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+var (
+	activeSessions = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "beacon_tail_sessions",
+		Help: "Live tail clients connected.",
+	})
+	droppedLogs = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "beacon_tail_dropped_logs_total",
+		Help: "Logs dropped because a live tail client fell behind.",
+	})
+	slowDisconnects = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "beacon_tail_slow_disconnects_total",
+		Help: "Live tail clients disconnected because they fell behind.",
+	})
+)
+
+// Policy decides what happens when a client falls behind and its buffer is full.
+type Policy string
+
+const (
+	// PolicyDropOldest discards the oldest buffered logs and tells the client
+	// how many it missed.
+	PolicyDropOldest Policy = config.TailPolicyDropOldest
+	// PolicyDisconnect ends the subscription with ErrSlowConsumer. The client
+	// can reconnect and resume after the last sequence number it received.
+	PolicyDisconnect Policy = config.TailPolicyDisconnect
+)
+
+// DefaultBufferSize is the number of logs buffered per client by default.
+const DefaultBufferSize = config.DefaultTailBufferSize
+
+var (
+	// ErrSlowConsumer ends subscriptions that fell behind under PolicyDisconnect.
+	ErrSlowConsumer = errors.New("tail client too slow, disconnected")
+	// ErrClosed ends subscriptions when the upstream subscription ends.
+	ErrClosed = errors.New("tail closed")
+)
+
+// Source subscribes to the log stream. A zero startSeq and startTime
+// subscribe to new logs only. It is implemented by queue.Subscriber.
+type Source interface {
+	Subscribe(ctx context.Context, startSeq uint64, startTime time.Time) (<-chan model.TailEvent, error)
+}
+
+// Config configures a Hub.
+type Config struct {
+	// BufferSize is the number of logs buffered per client.
+	BufferSize int    `json:"buffer_size"`
+	Policy     Policy `json:"slow_consumer_policy"`
+}
+
+// DefaultConfig returns the default hub settings.
+func DefaultConfig() Config {
+	return Config{BufferSize: DefaultBufferSize, Policy: config.DefaultTailPolicy}
+}
+
+// Validate checks the configuration.
+func (c Config) Validate() error {
+	if c.BufferSize < 1 {
+		return fmt.Errorf("tail buffer size must be positive")
+	}
+	switch c.Policy {
+	case PolicyDropOldest, PolicyDisconnect:
+		return nil
+	default:
+		return fmt.Errorf("unknown slow consumer policy %q, expected %q or %q", c.Policy, PolicyDropOldest, PolicyDisconnect)
+	}
+}
+
+// Hub consumes the live log stream once and broadcasts it to every
+// subscribed client. The upstream subscription is opened when the first
+// client subscribes and closed when the last one leaves. A slow client never
+// blocks the upstream subscription or other clients: its bounded buffer
+// overflows according to the configured policy.
+type Hub struct {
+	src Source
+	cfg Config
+
+	mu   sync.Mutex
+	subs map[*Subscription]struct{}
+	// upstream is the open upstream subscription, nil while nobody tails.
+	upstream *upstream
+}
+
+// upstream is one generation of the shared upstream subscription.
+type upstream struct {
+	cancel context.CancelFunc
+}
+
+// NewHub creates a hub reading from src. Zero config values take their
+// defaults; other values are expected to have passed Validate.
+func NewHub(src Source, cfg Config) *Hub {
+	if cfg.BufferSize == 0 {
+		cfg.BufferSize = DefaultBufferSize
+	}
+	if cfg.Policy == "" {
+		cfg.Policy = PolicyDropOldest
+	}
+	return &Hub{src: src, cfg: cfg, subs: make(map[*Subscription]struct{})}
+}
+
+// Clients returns the number of subscribed clients.
+func (h *Hub) Clients() int {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return len(h.subs)
+}
+
+// Subscribe registers a client for new logs. A non-zero startSeq or
+// startTime first replays the stream from that position through a dedicated
+// upstream subscription, then switches to the shared broadcast without gaps
+// or duplicates. The subscription ends when ctx is done or Close is called.
+func (h *Hub) Subscribe(ctx context.Context, startSeq uint64, startTime time.Time) (*Subscription, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	replayCtx, replayCancel := context.WithCancel(ctx)
+	sub := &Subscription{
+		hub:          h,
+		size:         h.cfg.BufferSize,
+		policy:       h.cfg.Policy,
+		replaying:    startSeq > 0 || !startTime.IsZero(),
+		replayCancel: replayCancel,
+		notify:       make(chan struct{}, 1),
+		done:         make(chan struct{}),
+		cancel:       cancel,
+	}
+
+	// Register before replaying so that nothing published in between is missed.
+	if err := h.add(sub); err != nil {
+		cancel()
+		return nil, err
+	}
+
+	if sub.replaying {
+		replay, err := h.src.Subscribe(replayCtx, startSeq, startTime)
+		if err != nil {
+			sub.Close()
+			return nil, err
+		}
+		sub.replay = replay
+	}
+
+	context.AfterFunc(ctx, sub.Close)
+	return sub, nil
+}
+
+// add registers a subscription, opening the upstream subscription for the first one.
+func (h *Hub) add(sub *Subscription) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.upstream == nil {
+		ctx, cancel := context.WithCancel(context.Background())
+		events, err := h.src.Subscribe(ctx, 0, time.Time{})
+		if err != nil {
+			cancel()
+			return err
+		}
+		h.upstream = &upstream{cancel: cancel}
+		go h.broadcast(h.upstream, events)
+	}
+	h.subs[sub] = struct{}{}
+	activeSessions.Inc()
+	return nil
+}
+
+// remove unregisters a subscription, closing the upstream subscription after the last one.
+func (h *Hub) remove(sub *Subscription) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if _, ok := h.subs[sub]; !ok {
+		return
+	}
+	delete(h.subs, sub)
+	activeSessions.Dec()
+	if len(h.subs) == 0 && h.upstream != nil {
+		h.upstream.cancel()
+		h.upstream = nil
+	}
+}
+
+// broadcast delivers the events of an upstream generation to every
+// subscription until the upstream channel is closed. Events still in flight
+// from a closed generation are discarded.
+func (h *Hub) broadcast(up *upstream, events <-chan model.TailEvent) {
+	for event := range events {
+		h.mu.Lock()
+		if h.upstream == up {
+			for sub := range h.subs {
+				sub.push(event)
+			}
+		}
+		h.mu.Unlock()
+	}
+
+	// The upstream ended on its own: end the subscriptions that relied on it.
+	h.mu.Lock()
+	var orphans []*Subscription
+	if h.upstream == up {
+		for sub := range h.subs {
+			orphans = append(orphans, sub)
+		}
+		up.cancel()
+		h.upstream = nil
+	}
+	h.mu.Unlock()
+	if len(orphans) > 0 {
+		log.Printf("Live tail upstream subscription ended, closing %d clients", len(orphans))
+	}
+	for _, sub := range orphans {
+		sub.fail(ErrClosed)
+	}
+}
+
+// Message is delivered to a tail client: either a log event or, when
+// Dropped is non-zero, a notice that that many logs were discarded because
+// the client fell behind.
+type Message struct {
+	Event   model.TailEvent
+	Dropped uint64
+}
+
+// Subscription is one client's view of the hub.
+type Subscription struct {
+	hub    *Hub
+	size   int
+	policy Policy
+
+	mu      sync.Mutex
+	buf     []model.TailEvent
+	dropped uint64
+	err     error
+	// replaying is set while a replay is catching up with the broadcast.
+	replaying    bool
+	replayCancel context.CancelFunc
+	// lastSeq is the sequence number of the last delivered event.
+	lastSeq uint64
+
+	// replay delivers the requested history before the broadcast buffer.
+	// It is only used by the reading goroutine.
+	replay <-chan model.TailEvent
+
+	notify    chan struct{}
+	done      chan struct{}
+	closeOnce sync.Once
+	cancel    context.CancelFunc
+}
+
+// Next returns the next message, waiting until one is available. It returns
+// ErrSlowConsumer or ErrClosed when the subscription ended, and ctx.Err()
+// when ctx is done.
+func (s *Subscription) Next(ctx context.Context) (Message, error) {
+	for {
+		if s.replay != nil {
+			select {
+			case event, ok := <-s.replay:
+				if !ok {
+					s.endReplay()
+					continue
+				}
+				s.mu.Lock()
+				s.lastSeq = event.Seq
+				// Switch to the broadcast once the replay reaches what it buffered.
+				if s.replaying && len(s.buf) > 0 && s.buf[0].Seq <= s.lastSeq+1 {
+					s.stopReplay()
+				}
+				s.mu.Unlock()
+				return Message{Event: event}, nil
+			case <-ctx.Done():
+				return Message{}, ctx.Err()
+			case <-s.done:
+				return Message{}, s.closeErr()
+			}
+		}
+
+		s.mu.Lock()
+		if s.err != nil {
+			err := s.err
+			s.mu.Unlock()
+			return Message{}, err
+		}
+		if s.dropped > 0 {
+			n := s.dropped
+			s.dropped = 0
+			s.mu.Unlock()
+			return Message{Dropped: n}, nil
+		}
+		if len(s.buf) > 0 {
+			event := s.buf[0]
+			s.buf = s.buf[1:]
+			if s.lastSeq > 0 && event.Seq <= s.lastSeq {
+				// Already delivered by the replay.
+				s.mu.Unlock()
+				continue
+			}
+			s.lastSeq = event.Seq
+			s.mu.Unlock()
+			return Message{Event: event}, nil
+		}
+		s.mu.Unlock()
+
+		select {
+		case <-s.notify:
+		case <-ctx.Done():
+			return Message{}, ctx.Err()
+		case <-s.done:
+			return Message{}, s.closeErr()
+		}
+	}
+}
+
+// Close ends the subscription and releases its upstream resources.
+func (s *Subscription) Close() {
+	s.fail(ErrClosed)
+}
+
+// fail ends the subscription with err, unless it already ended or is being
+// disconnected.
+func (s *Subscription) fail(err error) {
+	s.closeOnce.Do(func() {
+		s.mu.Lock()
+		if s.err == nil {
+			s.err = err
+		}
+		s.mu.Unlock()
+		close(s.done)
+		s.cancel()
+		s.hub.remove(s)
+	})
+}
+
+func (s *Subscription) closeErr() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.err
+}
+
+// stopReplay cancels the replay subscription once the broadcast buffer
+// continues where it left off. The reading goroutine drains what the replay
+// already delivered and then switches over. It is called with s.mu held.
+func (s *Subscription) stopReplay() {
+	s.replaying = false
+	s.replayCancel()
+}
+
+// endReplay switches to the broadcast buffer after the replay channel closed.
+func (s *Subscription) endReplay() {
+	s.replay = nil
+	s.mu.Lock()
+	s.stopReplay()
+	s.mu.Unlock()
+}
+
+// push buffers a broadcast event, applying the slow consumer policy when
+// the buffer is full. It is called with the hub lock held and never blocks.
+func (s *Subscription) push(event model.TailEvent) {
+	s.mu.Lock()
+	if s.err != nil {
+		s.mu.Unlock()
+		return
+	}
+	if s.replaying && event.Seq <= s.lastSeq+1 {
+		// The replay caught up with the broadcast.
+		s.stopReplay()
+	}
+	if event.Seq <= s.lastSeq {
+		// Already delivered by the replay.
+		s.mu.Unlock()
+		return
+	}
+	if len(s.buf) >= s.size {
+		switch {
+		case s.replaying:
+			// The replay delivers these events, so overflow is not the client's fault.
+			s.buf = s.buf[1:]
+		case s.policy == PolicyDisconnect:
+			s.err = ErrSlowConsumer
+			s.mu.Unlock()
+			slowDisconnects.Inc()
+			// Closing takes the hub lock, which the caller holds.
+			go s.fail(ErrSlowConsumer)
+			return
+		default:
+			s.buf = s.buf[1:]
+			s.dropped++
+			droppedLogs.Inc()
+		}
+	}
+	s.buf = append(s.buf, event)
+	s.mu.Unlock()
+
+	select {
+	case s.notify <- struct{}{}:
+	default:
+	}
+}
@@ -0,0 +1,262 @@
+package tail
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"log-beacon/internal/model"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeSource is an in-memory log stream that numbers published logs from 1.
+type fakeSource struct {
+	mu      sync.Mutex
+	history []model.TailEvent
+	subs    map[chan model.TailEvent]struct{}
+	calls   int
+}
+
+func newFakeSource() *fakeSource {
+	return &fakeSource{subs: make(map[chan model.TailEvent]struct{})}
+}
+
+func (f *fakeSource) Subscribe(ctx context.Context, startSeq uint64, startTime time.Time) (<-chan model.TailEvent, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.calls++
+
+	ch := make(chan model.TailEvent, 1000)
+	if startSeq > 0 {
+		for _, event := range f.history {
+			if event.Seq >= startSeq {
+				ch <- event
+			}
+		}
+	}
+	f.subs[ch] = struct{}{}
+
+	go func() {
+		<-ctx.Done()
+		f.mu.Lock()
+		defer f.mu.Unlock()
+		if _, ok := f.subs[ch]; ok {
+			delete(f.subs, ch)
+			close(ch)
+		}
+	}()
+	return ch, nil
+}
+
+func (f *fakeSource) publish(messages ...string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for _, msg := range messages {
+		event := model.TailEvent{Seq: uint64(len(f.history) + 1), Log: model.Log{Message: msg}}
+		f.history = append(f.history, event)
+		for ch := range f.subs {
+			ch <- event
+		}
+	}
+}
+
+// end closes every subscription as if the stream went away.
+func (f *fakeSource) end() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for ch := range f.subs {
+		delete(f.subs, ch)
+		close(ch)
+	}
+}
+
+func (f *fakeSource) active() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return len(f.subs)
+}
+
+func (f *fakeSource) subscribeCalls() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.calls
+}
+
+// next reads one message, failing the test if none arrives in time.
+func next(t *testing.T, sub *Subscription) Message {
+	t.Helper()
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	msg, err := sub.Next(ctx)
+	require.NoError(t, err)
+	return msg
+}
+
+// buffered reports how many logs sub buffered and dropped so far.
+func buffered(sub *Subscription) (int, uint64) {
+	sub.mu.Lock()
+	defer sub.mu.Unlock()
+	return len(sub.buf), sub.dropped
+}
+
+func TestHub_SharesUpstream(t *testing.T) {
+	src := newFakeSource()
+	hub := NewHub(src, DefaultConfig())
+
+	ctx := context.Background()
+	first, err := hub.Subscribe(ctx, 0, time.Time{})
+	require.NoError(t, err)
+	second, err := hub.Subscribe(ctx, 0, time.Time{})
+	require.NoError(t, err)
+	assert.Equal(t, 2, hub.Clients())
+	assert.Equal(t, 1, src.active())
+
+	src.publish("one", "two")
+	for _, sub := range []*Subscription{first, second} {
+		assert.Equal(t, "one", next(t, sub).Event.Message)
+		assert.Equal(t, "two", next(t, sub).Event.Message)
+	}
+
+	first.Close()
+	assert.Equal(t, 1, hub.Clients())
+	assert.Equal(t, 1, src.active())
+
+	// The upstream subscription goes away with the last client.
+	second.Close()
+	assert.Equal(t, 0, hub.Clients())
+	assert.Eventually(t, func() bool { return src.active() == 0 }, 2*time.Second, 10*time.Millisecond)
+
+	// And comes back with the next one.
+	third, err := hub.Subscribe(ctx, 0, time.Time{})
+	require.NoError(t, err)
+	defer third.Close()
+	src.publish("three")
+	assert.Equal(t, "three", next(t, third).Event.Message)
+	assert.Equal(t, 2, src.subscribeCalls())
+}
+
+func TestHub_ContextEndsSubscription(t *testing.T) {
+	src := newFakeSource()
+	hub := NewHub(src, DefaultConfig())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	sub, err := hub.Subscribe(ctx, 0, time.Time{})
+	require.NoError(t, err)
+
+	cancel()
+	assert.Eventually(t, func() bool { return hub.Clients() == 0 }, 2*time.Second, 10*time.Millisecond)
+	_, err = sub.Next(context.Background())
+	assert.ErrorIs(t, err, ErrClosed)
+}
+
+func TestHub_DropOldest(t *testing.T) {
+	src := newFakeSource()
+	hub := NewHub(src, Config{BufferSize: 2, Policy: PolicyDropOldest})
+
+	slow, err := hub.Subscribe(context.Background(), 0, time.Time{})
+	require.NoError(t, err)
+	defer slow.Close()
+
+	src.publish("1", "2", "3", "4", "5")
+	assert.Eventually(t, func() bool {
+		n, dropped := buffered(slow)
+		return n == 2 && dropped == 3
+	}, 2*time.Second, 10*time.Millisecond)
+
+	// The notice comes before the logs that survived.
+	assert.Equal(t, Message{Dropped: 3}, next(t, slow))
+	assert.Equal(t, "4", next(t, slow).Event.Message)
+	assert.Equal(t, "5", next(t, slow).Event.Message)
+
+	src.publish("6")
+	assert.Equal(t, Message{Event: model.TailEvent{Seq: 6, Log: model.Log{Message: "6"}}}, next(t, slow))
+}
+
+func TestHub_Disconnect(t *testing.T) {
+	src := newFakeSource()
+	hub := NewHub(src, Config{BufferSize: 2, Policy: PolicyDisconnect})
+
+	ctx := context.Background()
+	slow, err := hub.Subscribe(ctx, 0, time.Time{})
+	require.NoError(t, err)
+	fast, err := hub.Subscribe(ctx, 0, time.Time{})
+	require.NoError(t, err)
+	defer fast.Close()
+
+	for i := 1; i <= 3; i++ {
+		src.publish(fmt.Sprint(i))
+		assert.Equal(t, fmt.Sprint(i), next(t, fast).Event.Message)
+	}
+
+	_, err = slow.Next(ctx)
+	assert.ErrorIs(t, err, ErrSlowConsumer)
+	assert.Eventually(t, func() bool { return hub.Clients() == 1 }, 2*time.Second, 10*time.Millisecond)
+
+	// The other client is unaffected.
+	src.publish("4")
+	assert.Equal(t, "4", next(t, fast).Event.Message)
+}
+
+func TestHub_ReplayHandover(t *testing.T) {
+	src := newFakeSource()
+	// A small buffer: overflow while replaying is not reported as drops.
+	hub := NewHub(src, Config{BufferSize: 2, Policy: PolicyDisconnect})
+
+	src.publish("1", "2", "3")
+	sub, err := hub.Subscribe(context.Background(), 2, time.Time{})
+	require.NoError(t, err)
+	defer sub.Close()
+	assert.Equal(t, 2, src.active())
+
+	src.publish("4", "5", "6")
+	for seq := uint64(2); seq <= 6; seq++ {
+		assert.Equal(t, seq, next(t, sub).Event.Seq)
+	}
+
+	src.publish("7")
+	assert.Equal(t, uint64(7), next(t, sub).Event.Seq)
+
+	// The replay subscription is released once it caught up.
+	assert.Eventually(t, func() bool { return src.active() == 1 }, 2*time.Second, 10*time.Millisecond)
+}
+
+func TestHub_UpstreamEnds(t *testing.T) {
+	src := newFakeSource()
+	hub := NewHub(src, DefaultConfig())
+
+	sub, err := hub.Subscribe(context.Background(), 0, time.Time{})
+	require.NoError(t, err)
+
+	src.end()
+	_, err = sub.Next(context.Background())
+	assert.ErrorIs(t, err, ErrClosed)
+	assert.Equal(t, 0, hub.Clients())
+}
+
+func TestConfig_Validate(t *testing.T) {
+	tests := []struct {
+		name    string
+		cfg     Config
+		wantErr bool
+	}{
+		{name: "Default", cfg: DefaultConfig()},
+		{name: "Disconnect", cfg: Config{BufferSize: 1, Policy: PolicyDisconnect}},
+		{name: "Zero buffer", cfg: Config{BufferSize: 0, Policy: PolicyDropOldest}, wantErr: true},
+		{name: "Unknown policy", cfg: Config{BufferSize: 10, Policy: "block"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.cfg.Validate()
+			if tt.wantErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
@@ -1,61 +1,305 @@
 package main
 
 import (
+	"context"
+	"crypto/tls"
+	"errors"
 	"log"
+	"net"
 	"os"
+	"os/signal"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
 
+	"log-beacon/internal/alert"
+	"log-beacon/internal/config"
+	"log-beacon/internal/elastic"
+	"log-beacon/internal/health"
+	"log-beacon/internal/logmetrics"
+	"log-beacon/internal/model"
+	"log-beacon/internal/notify"
+	"log-beacon/internal/otlp"
+	"log-beacon/internal/pipeline"
 	"log-beacon/internal/queue"
 	"log-beacon/internal/repository"
 	"log-beacon/internal/server"
+	"log-beacon/internal/syslog"
+	"log-beacon/internal/tail"
+
+	"google.golang.org/grpc"
 )
 
 func main() {
-	// Get NATS URL from environment variable.
-	natsURL := os.Getenv("NATS_URL")
-	if natsURL == "" {
-		log.Fatal("NATS_URL environment variable not set.")
-	}
-
-	// Get DB URL from environment variable.
-	dbURL := os.Getenv("DB_URL")
-	if dbURL == "" {
-		log.Fatal("DB_URL environment variable not set.")
-	}
+	// Load the configuration from the config file, environment and flags.
+	cfg := config.MustLoad(config.ServiceAPI)
+	dbURL := cfg.API.DatabaseURL
+	shutdownTimeout := time.Duration(cfg.API.ShutdownTimeout)
 
-	// Initialize User repository.
-	userRepo, err := repository.NewUserRepository(dbURL)
+	// Open the database shared by the repositories.
+	db, err := repository.Open(dbURL)
 	if err != nil {
-		log.Fatalf("Failed to initialize user repository: %v", err)
+		log.Fatalf("Failed to connect to the database: %v", err)
 	}
-	defer userRepo.Close()
+	defer db.Close()
+	userRepo := repository.NewUserRepository(db)
+	alertRepo := repository.NewAlertRepository(db)
+	savedSearchRepo := repository.NewSavedSearchRepository(db)
 
-	// Ensure the NATS stream exists and is configured correctly.
-	queue.EnsureStream(natsURL)
+	// Ensure the NATS stream exists and is configured correctly. A replay window
+	// trades interest retention for time-based retention so that live tail
+	// clients can resume after a disconnect; it is off unless configured.
+	natsURL := cfg.NATS.URL
+	streamName := queue.WithStreamName(cfg.NATS.Stream, cfg.NATS.Subject)
+	queue.EnsureStream(natsURL, streamName, queue.WithReplayWindow(time.Duration(cfg.API.Tail.ReplayWindow)))
 
 	// Create a new NATS publisher.
-	publisher, err := queue.NewPublisher(natsURL)
+	publisher, err := queue.NewPublisher(natsURL, streamName)
 	if err != nil {
 		log.Fatalf("Failed to create NATS publisher: %v", err)
 	}
-	defer publisher.Close()
 
 	// Create a new NATS subscriber.
-	subscriber, err := queue.NewSubscriber(natsURL)
+	subscriber, err := queue.NewSubscriber(natsURL, streamName)
 	if err != nil {
 		log.Fatalf("Failed to create NATS subscriber: %v", err)
 	}
-	defer subscriber.Close()
 
-	hotStorageURL := os.Getenv("HOT_STORAGE_URL")
-	if hotStorageURL == "" {
-		log.Fatal("HOT_STORAGE_URL environment variable not set.")
+	hotStorageURL := cfg.API.HotStorageURL
+	// Build the ingest processing pipeline, optionally from a JSON config file.
+	pipelineConfig := pipeline.DefaultConfig()
+	if path := cfg.API.PipelineConfig; path != "" {
+		pipelineConfig, err = pipeline.LoadConfig(path)
+		if err != nil {
+			log.Fatalf("Failed to load pipeline config: %v", err)
+		}
+	}
+	ingestPipeline, err := pipeline.Build(pipelineConfig)
+	if err != nil {
+		log.Fatalf("Failed to build ingest pipeline: %v", err)
+	}
+
+	// Map Elasticsearch bulk documents onto log fields.
+	elasticConfig := elastic.Config{
+		MessageField:   cfg.API.Elastic.MessageField,
+		LevelField:     cfg.API.Elastic.LevelField,
+		TimestampField: cfg.API.Elastic.TimestampField,
+	}
+
+	// Bound the logs buffered for each live tail client and choose what
+	// happens to clients that fall behind.
+	tailConfig := tail.Config{
+		BufferSize: cfg.API.Tail.BufferSize,
+		Policy:     tail.Policy(cfg.API.Tail.SlowConsumerPolicy),
 	}
+
+	// The alert engine and the log metrics run until shutdown cancels runCtx.
+	runCtx, stopRunning := context.WithCancel(context.Background())
+	defer stopRunning()
+	var running sync.WaitGroup
+
+	// Evaluate alert rules against the live stream. The engine has its own
+	// subscription: unlike tail clients, it must not skip logs.
+	alertInterval := time.Duration(cfg.API.AlertEvalInterval)
+	alertEvents, err := subscriber.Subscribe(runCtx, 0, time.Time{})
+	if err != nil {
+		log.Fatalf("Failed to subscribe to logs for alerting: %v", err)
+	}
+
+	// Send alert notifications through the channels of an optional JSON config
+	// file, recording every delivery.
+	var notifyConfig notify.Config
+	if path := cfg.API.AlertChannelsConfig; path != "" {
+		notifyConfig, err = notify.LoadConfig(path)
+		if err != nil {
+			log.Fatalf("Failed to load alert channels config: %v", err)
+		}
+	}
+	notifier, err := notify.New(notifyConfig, alertRepo)
+	if err != nil {
+		log.Fatalf("Failed to set up alert channels: %v", err)
+	}
+
+	alertEngine := alert.NewEngine(alertRepo, notifier, alertInterval)
+	running.Add(1)
+	go func() {
+		defer running.Done()
+		if err := alertEngine.Run(runCtx, alertEvents); err != nil && runCtx.Err() == nil {
+			log.Printf("Alert engine stopped: %v", err)
+		}
+	}()
+
+	// Derive Prometheus metrics from the log stream, when rules are configured.
+	// Like the alert engine, the registry needs a subscription of its own.
+	var logMetrics *logmetrics.Registry
+	if path := cfg.API.LogMetricsConfig; path != "" {
+		metricsConfig, err := logmetrics.LoadConfig(path)
+		if err != nil {
+			log.Fatalf("Failed to load log metrics config: %v", err)
+		}
+		logMetrics, err = logmetrics.New(metricsConfig)
+		if err != nil {
+			log.Fatalf("Invalid log metrics config: %v", err)
+		}
+		metricEvents, err := subscriber.Subscribe(runCtx, 0, time.Time{})
+		if err != nil {
+			log.Fatalf("Failed to subscribe to logs for metrics: %v", err)
+		}
+		running.Add(1)
+		go func() {
+			defer running.Done()
+			if err := logMetrics.Run(runCtx, metricEvents); err != nil && runCtx.Err() == nil {
+				log.Printf("Log metrics stopped: %v", err)
+			}
+		}()
+	}
+
+	// Report the API ready once NATS, Postgres and hot storage all answer.
+	readiness := health.NewChecker(0)
+	readiness.Add("nats", publisher.Check)
+	readiness.Add("postgres", userRepo.Ping)
+	readiness.Add("hot_storage", health.HTTP(nil, strings.TrimSuffix(hotStorageURL, "/")+"/livez"))
+
 	// Create a new server with the publisher, subscriber, and userRepo dependencies.
-	srv := server.New(publisher, subscriber, userRepo, hotStorageURL)
+	srv := server.New(publisher, subscriber, userRepo, hotStorageURL,
+		server.WithPipeline(ingestPipeline),
+		server.WithElasticConfig(elasticConfig),
+		server.WithMaxBodyBytes(cfg.API.MaxBodyBytes),
+		server.WithTailConfig(tailConfig),
+		server.WithAlertStore(alertRepo),
+		server.WithAlertChannels(notifier),
+		server.WithSavedSearches(savedSearchRepo),
+		server.WithLogMetrics(logMetrics),
+		server.WithReadiness(readiness),
+		server.WithBcryptCost(cfg.API.BcryptCost),
+	)
 
-	// Start the server on port 8080.
-	log.Println("Starting API server on port 8080...")
-	if err := srv.Start(":8080"); err != nil {
-		panic(err)
+	// Start the optional syslog listeners, which share the ingest pipeline.
+	syslogListener, err := startSyslog(srv, cfg.API.Syslog)
+	if err != nil {
+		log.Fatalf("Failed to start syslog listener: %v", err)
+	}
+
+	// Start the optional OTLP/gRPC logs receiver.
+	var grpcServer *grpc.Server
+	if addr := cfg.API.OTLPGRPCAddr; addr != "" {
+		lis, err := net.Listen("tcp", addr)
+		if err != nil {
+			log.Fatalf("Failed to start OTLP gRPC receiver: %v", err)
+		}
+		grpcServer = otlp.NewReceiver(srv.Ingest).NewGRPCServer()
+		go func() {
+			log.Printf("OTLP gRPC receiver listening on %s", addr)
+			if err := grpcServer.Serve(lis); err != nil {
+				log.Fatalf("OTLP gRPC receiver error: %v", err)
+			}
+		}()
+	}
+
+	// Start the server.
+	serveErr := make(chan error, 1)
+	go func() {
+		log.Printf("Starting API server on %s...", cfg.API.Addr)
+		serveErr <- srv.Start(cfg.API.Addr)
+	}()
+
+	// --- Graceful Shutdown ---
+	signalChan := make(chan os.Signal, 1)
+	signal.Notify(signalChan, syscall.SIGINT, syscall.SIGTERM)
+	select {
+	case err := <-serveErr:
+		log.Fatalf("API server error: %v", err)
+	case <-signalChan:
+	}
+
+	// Stop in order, all within one deadline: first the HTTP server, closing
+	// live tails and letting in-flight requests finish, then the other
+	// receivers, then the alert engine and log metrics, and once the engine
+	// can no longer notify, the queued notifications. Only then is the
+	// publisher flushed, so that no accepted log is lost.
+	log.Println("Shutting down API server...")
+	ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+	if err := srv.Shutdown(ctx); err != nil {
+		log.Printf("Failed to drain API server: %v", err)
+	}
+	syslogListener.Close()
+	if grpcServer != nil {
+		stopGRPC(ctx, grpcServer)
+	}
+	stopRunning()
+	if !wait(ctx, &running) {
+		log.Printf("Alert engine did not stop in time: %v", ctx.Err())
+	}
+	if err := notifier.Close(ctx); err != nil {
+		log.Printf("Failed to deliver queued notifications: %v", err)
+	}
+	publisher.Close()
+	subscriber.Close()
+	log.Println("API server shut down.")
+}
+
+// wait waits for wg, but not beyond ctx. It reports whether wg finished.
+func wait(ctx context.Context, wg *sync.WaitGroup) bool {
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+// stopGRPC lets in-flight exports finish, but not beyond ctx.
+func stopGRPC(ctx context.Context, s *grpc.Server) {
+	stopped := make(chan struct{})
+	go func() {
+		s.GracefulStop()
+		close(stopped)
+	}()
+	select {
+	case <-stopped:
+	case <-ctx.Done():
+		s.Stop()
+	}
+}
+
+// startSyslog starts the syslog transports enabled in cfg.
+func startSyslog(srv *server.Server, cfg config.Syslog) (*syslog.Listener, error) {
+	listener := syslog.NewListener(func(logEntry model.Log) error {
+		if err := srv.Ingest(logEntry); err != nil && !errors.Is(err, pipeline.ErrDropped) {
+			return err
+		}
+		return nil
+	})
+
+	if addr := cfg.UDPAddr; addr != "" {
+		if _, err := listener.ListenUDP(addr); err != nil {
+			listener.Close()
+			return nil, err
+		}
+	}
+	if addr := cfg.TCPAddr; addr != "" {
+		if _, err := listener.ListenTCP(addr); err != nil {
+			listener.Close()
+			return nil, err
+		}
+	}
+	if addr := cfg.TLSAddr; addr != "" {
+		cert, err := tls.LoadX509KeyPair(cfg.TLSCert, cfg.TLSKey)
+		if err != nil {
+			listener.Close()
+			return nil, err
+		}
+		if _, err := listener.ListenTLS(addr, &tls.Config{Certificates: []tls.Certificate{cert}, MinVersion: tls.VersionTLS12}); err != nil {
+			listener.Close()
+			return nil, err
+		}
 	}
+	return listener, nil
 }
@@ -0,0 +1,281 @@
+// Package client sends log entries to the Log Beacon ingest API.
+//
+// A Client queues entries in memory and ships them from a background
+// goroutine in gzip-compressed JSON batches, retrying while the API is
+// unavailable. When the queue is full, Send blocks until there is room, so a
+// slow or unreachable API pushes back on the application instead of growing
+// memory without bound.
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"log-beacon/internal/model"
+)
+
+// Log is the log entry accepted by the ingest API. It is an alias so that
+// programs outside this module can build entries.
+type Log = model.Log
+
+var (
+	// ErrClosed is returned when sending to a closed client.
+	ErrClosed = errors.New("client: closed")
+	// ErrQueueFull is returned by TrySend when the queue has no room.
+	ErrQueueFull = errors.New("client: queue full")
+)
+
+// Config configures a Client. Zero values select the defaults.
+type Config struct {
+	// URL is the ingest endpoint, e.g. http://localhost:8080/api/v1/ingest.
+	URL string
+	// BatchSize is the maximum number of entries per request. Default 500.
+	BatchSize int
+	// FlushInterval bounds how long an entry waits for a batch to fill. Default 1s.
+	FlushInterval time.Duration
+	// QueueSize is the number of entries buffered before Send blocks. Default 10000.
+	QueueSize int
+	// Retries is the number of extra attempts for a failed batch. Default 3;
+	// a negative value disables retries.
+	Retries int
+	// Backoff is the delay before the first retry; it doubles on every retry. Default 200ms.
+	Backoff time.Duration
+	// DisableCompression sends batches without gzip.
+	DisableCompression bool
+	// HTTPClient is used for requests. Default is a client with a 10s timeout.
+	HTTPClient *http.Client
+	// ErrorHandler is called with batches that could not be delivered.
+	// It runs on the sending goroutine and must not block for long.
+	ErrorHandler func(err error, batch []Log)
+}
+
+// Client is a batching, retrying, asynchronous ingest client. It is safe for
+// concurrent use.
+type Client struct {
+	cfg    Config
+	sender *Sender
+
+	queue   chan Log
+	flushes chan chan struct{}
+	done    chan struct{}
+
+	// mu guards closed; senders hold the read lock while queueing so that
+	// Close never closes the queue under them.
+	mu     sync.RWMutex
+	closed bool
+
+	// ctx is cancelled when Close gives up, aborting in-flight retries.
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	sent    atomic.Uint64
+	dropped atomic.Uint64
+}
+
+// New creates a client and starts its background sender.
+func New(cfg Config) (*Client, error) {
+	if cfg.URL == "" {
+		return nil, fmt.Errorf("client: URL is required")
+	}
+	if cfg.BatchSize <= 0 {
+		cfg.BatchSize = 500
+	}
+	if cfg.FlushInterval <= 0 {
+		cfg.FlushInterval = time.Second
+	}
+	if cfg.QueueSize <= 0 {
+		cfg.QueueSize = 10000
+	}
+	if cfg.Retries < 0 {
+		cfg.Retries = 0
+	} else if cfg.Retries == 0 {
+		cfg.Retries = 3
+	}
+	if cfg.Backoff <= 0 {
+		cfg.Backoff = 200 * time.Millisecond
+	}
+	if cfg.HTTPClient == nil {
+		cfg.HTTPClient = &http.Client{Timeout: 10 * time.Second}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	c := &Client{
+		cfg: cfg,
+		sender: &Sender{
+			URL:        cfg.URL,
+			HTTPClient: cfg.HTTPClient,
+			Retries:    cfg.Retries,
+			Backoff:    cfg.Backoff,
+		},
+		queue:   make(chan Log, cfg.QueueSize),
+		flushes: make(chan chan struct{}),
+		done:    make(chan struct{}),
+		ctx:     ctx,
+		cancel:  cancel,
+	}
+	go c.run()
+	return c, nil
+}
+
+// Send queues a log entry, blocking while the queue is full until there is
+// room or ctx is done. Entries without a timestamp are stamped with the
+// current time.
+func (c *Client) Send(ctx context.Context, entry Log) error {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	if c.closed {
+		return ErrClosed
+	}
+	if entry.Timestamp.IsZero() {
+		entry.Timestamp = time.Now().UTC()
+	}
+	select {
+	case c.queue <- entry:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// TrySend queues a log entry without blocking. It returns ErrQueueFull when
+// the queue has no room; the entry is then counted as dropped.
+func (c *Client) TrySend(entry Log) error {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	if c.closed {
+		return ErrClosed
+	}
+	if entry.Timestamp.IsZero() {
+		entry.Timestamp = time.Now().UTC()
+	}
+	select {
+	case c.queue <- entry:
+		return nil
+	default:
+		c.dropped.Add(1)
+		return ErrQueueFull
+	}
+}
+
+// Flush ships every entry queued before the call and waits until they are
+// delivered or given up on.
+func (c *Client) Flush(ctx context.Context) error {
+	ack := make(chan struct{})
+	select {
+	case c.flushes <- ack:
+	case <-c.done:
+		return ErrClosed
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	select {
+	case <-ack:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Close stops accepting entries and ships everything still queued. If ctx is
+// done first, pending retries are abandoned and ctx.Err() is returned.
+func (c *Client) Close(ctx context.Context) error {
+	c.mu.Lock()
+	if !c.closed {
+		c.closed = true
+		close(c.queue)
+	}
+	c.mu.Unlock()
+
+	select {
+	case <-c.done:
+		return nil
+	case <-ctx.Done():
+		c.cancel()
+		<-c.done
+		return ctx.Err()
+	}
+}
+
+// Sent returns the number of entries delivered to the API.
+func (c *Client) Sent() uint64 {
+	return c.sent.Load()
+}
+
+// Dropped returns the number of entries that were refused by TrySend or
+// could not be delivered.
+func (c *Client) Dropped() uint64 {
+	return c.dropped.Load()
+}
+
+// run is the background sender. It ships a batch when it is full or the
+// flush interval elapsed, and drains the queue once it is closed.
+func (c *Client) run() {
+	defer close(c.done)
+	defer c.cancel()
+
+	ticker := time.NewTicker(c.cfg.FlushInterval)
+	defer ticker.Stop()
+
+	batch := make([]Log, 0, c.cfg.BatchSize)
+	ship := func() {
+		if len(batch) > 0 {
+			c.ship(batch)
+			batch = make([]Log, 0, c.cfg.BatchSize)
+		}
+	}
+
+	for {
+		select {
+		case entry, ok := <-c.queue:
+			if !ok {
+				ship()
+				return
+			}
+			batch = append(batch, entry)
+			if len(batch) >= c.cfg.BatchSize {
+				ship()
+			}
+		case <-ticker.C:
+			ship()
+		case ack := <-c.flushes:
+			// Take what was queued before the flush request.
+			for n := len(c.queue); n > 0; n-- {
+				entry, ok := <-c.queue
+				if !ok {
+					break
+				}
+				batch = append(batch, entry)
+				if len(batch) >= c.cfg.BatchSize {
+					ship()
+				}
+			}
+			ship()
+			close(ack)
+		}
+	}
+}
+
+// ship delivers a batch, retrying transient failures with exponential backoff.
+func (c *Client) ship(batch []Log) {
+	compression := CompressionGzip
+	if c.cfg.DisableCompression {
+		compression = CompressionNone
+	}
+	payload, encoding, err := Encode(batch, compression)
+	if err == nil {
+		err = c.sender.SendWithRetry(c.ctx, payload, encoding)
+	}
+	if err != nil {
+		c.dropped.Add(uint64(len(batch)))
+		if c.cfg.ErrorHandler != nil {
+			c.cfg.ErrorHandler(err, batch)
+		}
+		return
+	}
+	c.sent.Add(uint64(len(batch)))
+}
@@ -0,0 +1,181 @@
+package client
+
+import (
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeAPI records the entries it receives and answers with status.
+type fakeAPI struct {
+	mu       sync.Mutex
+	status   int
+	requests int
+	entries  []Log
+}
+
+func (f *fakeAPI) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.requests++
+	if f.status != http.StatusAccepted {
+		w.WriteHeader(f.status)
+		return
+	}
+
+	var body io.Reader = r.Body
+	if r.Header.Get("Content-Encoding") == "gzip" {
+		gz, err := gzip.NewReader(r.Body)
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		body = gz
+	}
+	var batch []Log
+	if err := json.NewDecoder(body).Decode(&batch); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	f.entries = append(f.entries, batch...)
+	w.WriteHeader(http.StatusAccepted)
+}
+
+func (f *fakeAPI) messages() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	var out []string
+	for _, l := range f.entries {
+		out = append(out, l.Message)
+	}
+	return out
+}
+
+func newTestClient(t *testing.T, api http.Handler, cfg Config) *Client {
+	t.Helper()
+	server := httptest.NewServer(api)
+	t.Cleanup(server.Close)
+	cfg.URL = server.URL
+	if cfg.Backoff == 0 {
+		cfg.Backoff = time.Millisecond
+	}
+	c, err := New(cfg)
+	require.NoError(t, err)
+	return c
+}
+
+func TestClient_BatchesAndCloses(t *testing.T) {
+	api := &fakeAPI{status: http.StatusAccepted}
+	c := newTestClient(t, api, Config{BatchSize: 2, FlushInterval: time.Hour})
+	ctx := context.Background()
+
+	for _, msg := range []string{"one", "two", "three"} {
+		require.NoError(t, c.Send(ctx, Log{Message: msg}))
+	}
+	require.NoError(t, c.Close(ctx))
+
+	assert.Equal(t, []string{"one", "two", "three"}, api.messages())
+	assert.Equal(t, 2, api.requests)
+	assert.False(t, api.entries[0].Timestamp.IsZero(), "entries are stamped when sent")
+	assert.Equal(t, uint64(3), c.Sent())
+	assert.ErrorIs(t, c.Send(ctx, Log{Message: "late"}), ErrClosed)
+}
+
+func TestClient_Flush(t *testing.T) {
+	api := &fakeAPI{status: http.StatusAccepted}
+	c := newTestClient(t, api, Config{FlushInterval: time.Hour})
+	defer c.Close(context.Background())
+
+	require.NoError(t, c.Send(context.Background(), Log{Message: "one"}))
+	require.NoError(t, c.Flush(context.Background()))
+	assert.Equal(t, []string{"one"}, api.messages())
+}
+
+func TestClient_RetriesTransientFailures(t *testing.T) {
+	var calls atomic.Int32
+	api := &fakeAPI{status: http.StatusAccepted}
+	flaky := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if calls.Add(1) <= 2 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		api.ServeHTTP(w, r)
+	})
+	c := newTestClient(t, flaky, Config{Retries: 3})
+
+	require.NoError(t, c.Send(context.Background(), Log{Message: "one"}))
+	require.NoError(t, c.Close(context.Background()))
+	assert.Equal(t, []string{"one"}, api.messages())
+	assert.Equal(t, int32(3), calls.Load())
+}
+
+func TestClient_ReportsUndeliverableBatches(t *testing.T) {
+	api := &fakeAPI{status: http.StatusBadRequest}
+	var failed []Log
+	var failErr error
+	c := newTestClient(t, api, Config{Retries: 3, ErrorHandler: func(err error, batch []Log) {
+		failErr = err
+		failed = append(failed, batch...)
+	}})
+
+	require.NoError(t, c.Send(context.Background(), Log{Message: "bad"}))
+	require.NoError(t, c.Close(context.Background()))
+
+	assert.Equal(t, 1, api.requests, "refused batches are not retried")
+	require.Len(t, failed, 1)
+	assert.Equal(t, "bad", failed[0].Message)
+	assert.Error(t, failErr)
+	assert.Equal(t, uint64(1), c.Dropped())
+}
+
+func TestClient_Backpressure(t *testing.T) {
+	release := make(chan struct{})
+	api := &fakeAPI{status: http.StatusAccepted}
+	blocked := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+		api.ServeHTTP(w, r)
+	})
+	c := newTestClient(t, blocked, Config{BatchSize: 1, QueueSize: 1})
+
+	// The first entry is in flight and the second fills the queue.
+	require.NoError(t, c.Send(context.Background(), Log{Message: "one"}))
+	require.Eventually(t, func() bool { return len(c.queue) == 0 }, time.Second, time.Millisecond)
+	require.NoError(t, c.Send(context.Background(), Log{Message: "two"}))
+
+	assert.ErrorIs(t, c.TrySend(Log{Message: "three"}), ErrQueueFull)
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	assert.True(t, errors.Is(c.Send(ctx, Log{Message: "three"}), context.DeadlineExceeded))
+
+	close(release)
+	require.NoError(t, c.Close(context.Background()))
+	assert.Equal(t, []string{"one", "two"}, api.messages())
+	assert.Equal(t, uint64(1), c.Dropped())
+}
+
+func TestClient_CloseGivesUp(t *testing.T) {
+	api := &fakeAPI{status: http.StatusServiceUnavailable}
+	c := newTestClient(t, api, Config{Retries: 100, Backoff: time.Hour})
+	require.NoError(t, c.Send(context.Background(), Log{Message: "one"}))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	assert.ErrorIs(t, c.Close(ctx), context.DeadlineExceeded)
+	assert.Equal(t, uint64(1), c.Dropped())
+}
+
+func TestNew_RequiresURL(t *testing.T) {
+	_, err := New(Config{})
+	assert.Error(t, err)
+}
@@ -0,0 +1,128 @@
+package client
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+// Supported batch compressions.
+const (
+	CompressionNone = "none"
+	CompressionGzip = "gzip"
+	CompressionZstd = "zstd"
+)
+
+// ErrRefused marks a batch the API refused and will keep refusing, so that
+// retrying it is pointless.
+var ErrRefused = errors.New("client: batch refused")
+
+// Sender posts encoded batches to the ingest API. It is the transport of
+// Client, and is used directly by programs that buffer batches themselves,
+// such as the beacon agent.
+type Sender struct {
+	// URL is the ingest endpoint, e.g. http://localhost:8080/api/v1/ingest.
+	URL        string
+	HTTPClient *http.Client
+	// Retries is the number of extra attempts for a failed batch.
+	Retries int
+	// Backoff is the delay before the first retry; it doubles on every retry.
+	Backoff time.Duration
+}
+
+// Encode renders a batch as a JSON array and compresses it. It returns the
+// payload and its Content-Encoding, which is empty when uncompressed.
+func Encode(batch []Log, compression string) ([]byte, string, error) {
+	data, err := json.Marshal(batch)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to encode batch: %w", err)
+	}
+
+	switch compression {
+	case "", CompressionNone:
+		return data, "", nil
+	case CompressionGzip:
+		var buf bytes.Buffer
+		gz := gzip.NewWriter(&buf)
+		if _, err := gz.Write(data); err != nil {
+			return nil, "", err
+		}
+		if err := gz.Close(); err != nil {
+			return nil, "", err
+		}
+		return buf.Bytes(), "gzip", nil
+	case CompressionZstd:
+		enc, err := zstd.NewWriter(nil)
+		if err != nil {
+			return nil, "", err
+		}
+		defer enc.Close()
+		return enc.EncodeAll(data, nil), "zstd", nil
+	default:
+		return nil, "", fmt.Errorf("unsupported compression %q", compression)
+	}
+}
+
+// SendWithRetry posts an encoded batch, retrying transient failures with
+// exponential backoff. It gives up at once on batches the API refuses, and
+// when ctx is done.
+func (s *Sender) SendWithRetry(ctx context.Context, payload []byte, encoding string) error {
+	backoff := s.Backoff
+	var err error
+	for attempt := 0; attempt <= s.Retries; attempt++ {
+		if attempt > 0 {
+			select {
+			case <-ctx.Done():
+				return fmt.Errorf("%w (last error: %v)", ctx.Err(), err)
+			case <-time.After(backoff):
+			}
+			backoff *= 2
+		}
+		err = s.Send(ctx, payload, encoding)
+		if err == nil || errors.Is(err, ErrRefused) {
+			return err
+		}
+	}
+	return err
+}
+
+// Send posts an encoded batch once. Rate limiting, timeouts and server errors
+// are transient; any other non-2xx answer is reported as ErrRefused.
+func (s *Sender) Send(ctx context.Context, payload []byte, encoding string) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.URL, bytes.NewReader(payload))
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrRefused, err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if encoding != "" {
+		req.Header.Set("Content-Encoding", encoding)
+	}
+
+	httpClient := s.HTTPClient
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+
+	switch {
+	case resp.StatusCode >= 200 && resp.StatusCode < 300:
+		return nil
+	case resp.StatusCode == http.StatusTooManyRequests, resp.StatusCode == http.StatusRequestTimeout, resp.StatusCode >= 500:
+		return fmt.Errorf("ingest API returned %s: %s", resp.Status, body)
+	default:
+		return fmt.Errorf("%w: ingest API returned %s: %s", ErrRefused, resp.Status, body)
+	}
+}
@@ -0,0 +1,60 @@
+package client
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/klauspost/compress/zstd"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEncode(t *testing.T) {
+	batch := []Log{{Level: "info", Message: "hello", Labels: map[string]string{"service": "api"}}}
+	want, err := json.Marshal(batch)
+	require.NoError(t, err)
+
+	payload, encoding, err := Encode(batch, CompressionNone)
+	require.NoError(t, err)
+	assert.Empty(t, encoding)
+	assert.JSONEq(t, string(want), string(payload))
+
+	payload, encoding, err = Encode(batch, CompressionGzip)
+	require.NoError(t, err)
+	assert.Equal(t, "gzip", encoding)
+	gz, err := gzip.NewReader(bytes.NewReader(payload))
+	require.NoError(t, err)
+	data, err := io.ReadAll(gz)
+	require.NoError(t, err)
+	assert.JSONEq(t, string(want), string(data))
+
+	payload, encoding, err = Encode(batch, CompressionZstd)
+	require.NoError(t, err)
+	assert.Equal(t, "zstd", encoding)
+	dec, err := zstd.NewReader(nil)
+	require.NoError(t, err)
+	defer dec.Close()
+	data, err = dec.DecodeAll(payload, nil)
+	require.NoError(t, err)
+	assert.JSONEq(t, string(want), string(data))
+
+	_, _, err = Encode(batch, "brotli")
+	assert.Error(t, err)
+}
+
+func TestSender_RefusedBatchIsNotRetried(t *testing.T) {
+	api := &fakeAPI{status: http.StatusBadRequest}
+	srv := httptest.NewServer(api)
+	defer srv.Close()
+
+	s := &Sender{URL: srv.URL, Retries: 3}
+	err := s.SendWithRetry(context.Background(), []byte(`[]`), "")
+	assert.ErrorIs(t, err, ErrRefused)
+	assert.Equal(t, 1, api.requests)
+}
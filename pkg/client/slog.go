@@ -0,0 +1,168 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+)
+
+// HandlerOptions configures a Handler.
+type HandlerOptions struct {
+	// Level is the minimum level handled. Default slog.LevelInfo.
+	Level slog.Leveler
+	// Labels are attached to every entry, e.g. service and environment.
+	Labels map[string]string
+	// AddSource adds a "source" label with the file:line of the log call.
+	AddSource bool
+	// DropWhenFull drops records instead of blocking when the client queue is
+	// full, so logging never stalls the application.
+	DropWhenFull bool
+}
+
+// Handler is a slog.Handler that sends records through a Client. Attributes
+// become labels; attributes inside groups are keyed by their dotted group
+// path, e.g. slog.Group("http", "status", 500) becomes "http.status" = "500".
+type Handler struct {
+	client *Client
+	opts   HandlerOptions
+	// labels holds the attributes added with WithAttrs, already prefixed.
+	labels map[string]string
+	// prefix is the dotted path of the groups opened with WithGroup.
+	prefix string
+}
+
+// NewHandler creates a handler sending records through c. opts may be nil.
+func NewHandler(c *Client, opts *HandlerOptions) *Handler {
+	h := &Handler{client: c, labels: make(map[string]string)}
+	if opts != nil {
+		h.opts = *opts
+	}
+	for k, v := range h.opts.Labels {
+		h.labels[k] = v
+	}
+	return h
+}
+
+// Enabled implements slog.Handler.
+func (h *Handler) Enabled(_ context.Context, level slog.Level) bool {
+	minLevel := slog.LevelInfo
+	if h.opts.Level != nil {
+		minLevel = h.opts.Level.Level()
+	}
+	return level >= minLevel
+}
+
+// Handle implements slog.Handler. It blocks while the client queue is full
+// unless DropWhenFull is set.
+func (h *Handler) Handle(ctx context.Context, r slog.Record) error {
+	labels := make(map[string]string, len(h.labels)+r.NumAttrs())
+	for k, v := range h.labels {
+		labels[k] = v
+	}
+	r.Attrs(func(a slog.Attr) bool {
+		addAttr(labels, h.prefix, a)
+		return true
+	})
+	if h.opts.AddSource && r.PC != 0 {
+		if src := r.Source(); src != nil {
+			labels["source"] = fmt.Sprintf("%s:%d", src.File, src.Line)
+		}
+	}
+
+	entry := Log{
+		Timestamp: r.Time.UTC(),
+		Level:     levelName(r.Level),
+		Message:   r.Message,
+		Labels:    labels,
+	}
+	if h.opts.DropWhenFull {
+		return h.client.TrySend(entry)
+	}
+	return h.client.Send(ctx, entry)
+}
+
+// WithAttrs implements slog.Handler.
+func (h *Handler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	if len(attrs) == 0 {
+		return h
+	}
+	h2 := h.clone()
+	for _, a := range attrs {
+		addAttr(h2.labels, h2.prefix, a)
+	}
+	return h2
+}
+
+// WithGroup implements slog.Handler.
+func (h *Handler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+	h2 := h.clone()
+	h2.prefix = h.prefix + name + "."
+	return h2
+}
+
+func (h *Handler) clone() *Handler {
+	labels := make(map[string]string, len(h.labels))
+	for k, v := range h.labels {
+		labels[k] = v
+	}
+	return &Handler{client: h.client, opts: h.opts, labels: labels, prefix: h.prefix}
+}
+
+// addAttr stores an attribute under prefix, recursing into groups. Empty
+// attributes are ignored and groups with an empty key are inlined, as
+// required by slog.Handler.
+func addAttr(labels map[string]string, prefix string, a slog.Attr) {
+	a.Value = a.Value.Resolve()
+	if a.Equal(slog.Attr{}) {
+		return
+	}
+
+	if a.Value.Kind() == slog.KindGroup {
+		if a.Key != "" {
+			prefix += a.Key + "."
+		}
+		for _, ga := range a.Value.Group() {
+			addAttr(labels, prefix, ga)
+		}
+		return
+	}
+
+	var value string
+	switch a.Value.Kind() {
+	case slog.KindTime:
+		value = a.Value.Time().UTC().Format(time.RFC3339Nano)
+	case slog.KindAny:
+		if err, ok := a.Value.Any().(error); ok {
+			value = err.Error()
+		} else {
+			value = a.Value.String()
+		}
+	default:
+		value = a.Value.String()
+	}
+	labels[prefix+a.Key] = value
+}
+
+// levelName maps a slog level onto the canonical Log Beacon levels. Levels
+// between the standard ones are rounded down; levels below debug are trace
+// and levels well above error are fatal.
+func levelName(level slog.Level) string {
+	switch {
+	case level < slog.LevelDebug:
+		return "trace"
+	case level < slog.LevelInfo:
+		return "debug"
+	case level < slog.LevelWarn:
+		return "info"
+	case level < slog.LevelError:
+		return "warn"
+	case level < slog.LevelError+4:
+		return "error"
+	default:
+		return "fatal"
+	}
+}
@@ -0,0 +1,73 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHandler_MapsAttributesToLabels(t *testing.T) {
+	api := &fakeAPI{status: http.StatusAccepted}
+	c := newTestClient(t, api, Config{})
+	logger := slog.New(NewHandler(c, &HandlerOptions{
+		Level:  slog.LevelDebug,
+		Labels: map[string]string{"service": "checkout"},
+	}))
+
+	logger.With("request_id", "abc").WithGroup("http").Error("request failed",
+		"status", 500,
+		slog.Group("client", "ip", "10.0.0.1"),
+		slog.Duration("latency", 1500*time.Millisecond),
+		slog.Any("err", errors.New("upstream timeout")),
+		slog.Group("", "inlined", true),
+		slog.Group("empty"),
+	)
+	logger.Debug("debugging")
+	require.NoError(t, c.Close(context.Background()))
+
+	require.Len(t, api.entries, 2)
+	entry := api.entries[0]
+	assert.Equal(t, "request failed", entry.Message)
+	assert.Equal(t, "error", entry.Level)
+	assert.Equal(t, map[string]string{
+		"service":        "checkout",
+		"request_id":     "abc",
+		"http.status":    "500",
+		"http.client.ip": "10.0.0.1",
+		"http.latency":   "1.5s",
+		"http.err":       "upstream timeout",
+		"http.inlined":   "true",
+	}, entry.Labels)
+	assert.Equal(t, "debug", api.entries[1].Level)
+}
+
+func TestHandler_Enabled(t *testing.T) {
+	c, err := New(Config{URL: "http://localhost"})
+	require.NoError(t, err)
+	defer c.Close(context.Background())
+
+	h := NewHandler(c, nil)
+	assert.False(t, h.Enabled(context.Background(), slog.LevelDebug))
+	assert.True(t, h.Enabled(context.Background(), slog.LevelInfo))
+}
+
+func TestLevelName(t *testing.T) {
+	tests := map[slog.Level]string{
+		slog.LevelDebug - 4: "trace",
+		slog.LevelDebug:     "debug",
+		slog.LevelInfo:      "info",
+		slog.LevelInfo + 2:  "info",
+		slog.LevelWarn:      "warn",
+		slog.LevelError:     "error",
+		slog.LevelError + 4: "fatal",
+	}
+	for level, expected := range tests {
+		assert.Equal(t, expected, levelName(level), level.String())
+	}
+}